@@ -0,0 +1,161 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command render-template renders a conversation with a model's chat template,
+// exercising the same CGO path used in serving. It is meant for debugging:
+//
+//	PYTHONPATH=pkg/preprocessing/chat_completions:<site-packages> \
+//	  render-template --model ibm-granite/granite-3.3-8b-instruct --messages-file messages.json
+//
+// The messages file holds a JSON array of {"role": ..., "content": ...} objects.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+)
+
+const envHFToken = "HF_TOKEN"
+
+// options holds the parsed command line flags.
+type options struct {
+	model               string
+	revision            string
+	token               string
+	isLocalPath         bool
+	messagesFile        string
+	chatTemplateFile    string
+	addGenerationPrompt bool
+	printTokenCount     bool
+}
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "render-template:", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the command line arguments into options.
+func parseFlags(args []string, output io.Writer) (*options, error) {
+	opts := &options{}
+	flags := flag.NewFlagSet("render-template", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&opts.model, "model", "", "HuggingFace model ID, or local model directory with --local")
+	flags.StringVar(&opts.revision, "revision", "", "model revision to fetch the chat template from")
+	flags.StringVar(&opts.token, "token", os.Getenv(envHFToken), "HuggingFace token, defaults to $"+envHFToken)
+	flags.BoolVar(&opts.isLocalPath, "local", false, "treat --model as a local model directory")
+	flags.StringVar(&opts.messagesFile, "messages-file", "", "JSON file with the conversation messages")
+	flags.StringVar(&opts.chatTemplateFile, "chat-template-file", "", "render with this template instead of the model's")
+	flags.BoolVar(&opts.addGenerationPrompt, "add-generation-prompt", false, "append the generation prompt")
+	flags.BoolVar(&opts.printTokenCount, "token-count", false, "also print the number of tokens in the rendered prompt")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if opts.model == "" {
+		return nil, fmt.Errorf("--model is required")
+	}
+	if opts.messagesFile == "" {
+		return nil, fmt.Errorf("--messages-file is required")
+	}
+	return opts, nil
+}
+
+// run renders the conversation described by args and writes it to stdout.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	opts, err := parseFlags(args, stdout)
+	if err != nil {
+		return err
+	}
+
+	messagesJSON, err := os.ReadFile(opts.messagesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read messages file: %w", err)
+	}
+	var messages []preprocessing.ChatMessage
+	if err := json.Unmarshal(messagesJSON, &messages); err != nil {
+		return fmt.Errorf("failed to parse messages file: %w", err)
+	}
+
+	processor := preprocessing.NewChatTemplatingProcessor()
+	if err := processor.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize chat-templating processor: %w", err)
+	}
+	defer processor.Finalize()
+
+	template, templateVars, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       opts.model,
+		Revision:    opts.revision,
+		Token:       opts.token,
+		IsLocalPath: opts.isLocalPath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch chat template: %w", err)
+	}
+	if opts.chatTemplateFile != "" {
+		override, err := os.ReadFile(opts.chatTemplateFile)
+		if err != nil {
+			return fmt.Errorf("failed to read chat template file: %w", err)
+		}
+		template = string(override)
+	}
+
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations:       messages,
+		ChatTemplate:        template,
+		ChatTemplateKWArgs:  templateVars,
+		AddGenerationPrompt: opts.addGenerationPrompt,
+	}
+	if opts.printTokenCount {
+		req.ReturnTokenIDs = true
+		req.Tokenizer = &preprocessing.TokenizerSource{
+			Model:       opts.model,
+			Revision:    opts.revision,
+			Token:       opts.token,
+			IsLocalPath: opts.isLocalPath,
+		}
+	}
+
+	resp, err := processor.RenderChatTemplate(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to render chat template: %w", err)
+	}
+	if len(resp.RenderedChats) == 0 {
+		return fmt.Errorf("no rendered chat returned")
+	}
+
+	if _, err := fmt.Fprint(stdout, resp.RenderedChats[0]); err != nil {
+		return err
+	}
+	if opts.printTokenCount {
+		if _, err := fmt.Fprintf(stdout, "\n---\ntokens: %d\n", len(resp.TokenIDs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:testpackage // run is unexported in package main.
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testModelPath = "../../pkg/tokenization/testdata/test-model"
+
+// TestRun tests rendering a messages file with the local test model.
+func TestRun(t *testing.T) {
+	messagesFile := filepath.Join(t.TempDir(), "messages.json")
+	require.NoError(t, os.WriteFile(messagesFile,
+		[]byte(`[{"role": "user", "content": "Hello"}, {"role": "assistant", "content": "Hi there"}]`), 0o600))
+
+	var stdout bytes.Buffer
+	err := run(context.Background(), []string{
+		"--model", testModelPath,
+		"--local",
+		"--messages-file", messagesFile,
+		"--token-count",
+	}, &stdout)
+	require.NoError(t, err, "run should not return an error")
+
+	output := stdout.String()
+	assert.Contains(t, output, "user: Hello", "Output should contain the rendered user message")
+	assert.Contains(t, output, "assistant: Hi there", "Output should contain the rendered assistant message")
+	assert.Contains(t, output, "tokens: ", "Output should contain the token count")
+}
+
+// TestRunMissingFlags tests that required flags are enforced.
+func TestRunMissingFlags(t *testing.T) {
+	err := run(context.Background(), []string{"--model", testModelPath}, &bytes.Buffer{})
+	assert.Error(t, err, "run should fail without --messages-file")
+}
@@ -72,6 +72,32 @@ var (
 			Namespace: "kvcache", Subsystem: "tokenization", Name: "tokenized_tokens_total",
 			Help: "Number of tokens tokenized",
 		}, []string{"tokenizer"})
+
+	// TemplateCompileCacheHits counts renders whose chat template was already compiled.
+	TemplateCompileCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kvcache", Subsystem: "preprocessing", Name: "template_compile_cache_hits_total",
+		Help: "Number of renders served by a cached compiled chat template",
+	})
+	// RenderCancellations counts renders that failed because their context was canceled or timed out.
+	RenderCancellations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kvcache", Subsystem: "preprocessing", Name: "render_cancellations_total",
+		Help: "Number of renders canceled by their context",
+	})
+	// InterpreterRestarts counts reinitializations and hot reloads of the embedded Python interpreter.
+	InterpreterRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kvcache", Subsystem: "preprocessing", Name: "interpreter_restarts_total",
+		Help: "Number of times the embedded Python interpreter was reinitialized or hot-reloaded",
+	})
+	// PythonAllocatedBlocks reports the memory blocks allocated by the embedded Python interpreter.
+	PythonAllocatedBlocks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kvcache", Subsystem: "preprocessing", Name: "python_allocated_blocks",
+		Help: "Number of memory blocks allocated by the embedded Python interpreter",
+	})
+	// PythonGCObjects reports the objects tracked by the embedded Python interpreter's garbage collector.
+	PythonGCObjects = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kvcache", Subsystem: "preprocessing", Name: "python_gc_objects",
+		Help: "Number of objects tracked by the embedded Python interpreter's garbage collector",
+	})
 )
 
 // Collectors returns a slice of all registered Prometheus collectors.
@@ -80,6 +106,8 @@ func Collectors() []prometheus.Collector {
 		Admissions, Evictions,
 		LookupRequests, LookupHits, LookupLatency,
 		RenderChatTemplateLatency, TokenizationLatency, TokenizedTokensCount,
+		TemplateCompileCacheHits, RenderCancellations, InterpreterRestarts,
+		PythonAllocatedBlocks, PythonGCObjects,
 	}
 }
 
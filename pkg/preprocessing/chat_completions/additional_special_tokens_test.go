@@ -0,0 +1,61 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateAdditionalSpecialTokens tests that special tokens added at render time tokenize as single IDs.
+func TestRenderChatTemplateAdditionalSpecialTokens(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	const specialToken = "<|tool_sep|>"
+	newRequest := func(additionalSpecialTokens []string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:           []preprocessing.ChatMessage{{Role: "user", Content: "search" + specialToken + "weather"}},
+			ChatTemplate:            "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			ReturnOffsetMapping:     true,
+			Tokenizer:               &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+			AdditionalSpecialTokens: additionalSpecialTokens,
+		}
+	}
+	// tokensOf returns the texts of the tokens of a response.
+	tokensOf := func(response *preprocessing.RenderJinjaTemplateResponse) []string {
+		tokens := make([]string, len(response.OffsetMapping))
+		for i, offsets := range response.OffsetMapping {
+			tokens[i] = response.RenderedChats[0][offsets[0]:offsets[1]]
+		}
+		return tokens
+	}
+
+	response, err := wrapper.RenderChatTemplate(ctx, newRequest([]string{specialToken}))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Contains(t, tokensOf(response), specialToken, "The added special token should be a single token")
+
+	// The shared tokenizer is left unchanged.
+	response, err = wrapper.RenderChatTemplate(ctx, newRequest(nil))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.NotContains(t, tokensOf(response), specialToken, "Other renders should not see the added token")
+}
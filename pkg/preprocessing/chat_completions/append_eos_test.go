@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateAppendEOS tests that the EOS token closes the final assistant turn on request.
+func TestRenderChatTemplateAppendEOS(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	details, err := wrapper.FetchChatTemplateDetails(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	}, preprocessing.FetchOptions{})
+	require.NoError(t, err, "FetchChatTemplateDetails should not return an error")
+	require.NotEmpty(t, details.EOSTokenIDs, "The test model should have an EOS token")
+
+	newRequest := func(appendEOS bool) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "What is 2+2?"},
+				{Role: "assistant", Content: "4"},
+			},
+			ChatTemplate:       "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			ChatTemplateKWArgs: details.ChatTemplateKWArgs,
+			AppendEOS:          appendEOS,
+			ReturnTokenIDs:     true,
+			Tokenizer:          &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+		}
+	}
+
+	plain, err := wrapper.RenderChatTemplate(ctx, newRequest(false))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	withEOS, err := wrapper.RenderChatTemplate(ctx, newRequest(true))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	eosToken, ok := details.ChatTemplateKWArgs["eos_token"].(string)
+	require.True(t, ok, "The EOS token should be a template variable")
+	assert.Equal(t, plain.RenderedChats[0]+eosToken, withEOS.RenderedChats[0], "The EOS token should close the render")
+	assert.Equal(t, append(plain.TokenIDs, uint32(details.EOSTokenIDs[0])), withEOS.TokenIDs,
+		"The EOS token ID should follow the final assistant turn")
+
+	t.Run("Continuing the final message", func(t *testing.T) {
+		request := newRequest(true)
+		request.ContinueFinalMessage = true
+		continued, err := wrapper.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.NotContains(t, continued.RenderedChats[0], eosToken, "A continued message should stay open")
+	})
+
+	t.Run("Generation prompt", func(t *testing.T) {
+		request := newRequest(true)
+		request.AddGenerationPrompt = true
+		_, err := wrapper.RenderChatTemplate(ctx, request)
+		assert.Error(t, err, "AppendEOS should not be combined with AddGenerationPrompt")
+	})
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "slices"
+
+const assistantRole = "assistant"
+
+// AssistantMergePolicy selects how consecutive assistant messages are
+// rendered. Multi-agent transcripts often have several assistant turns in a
+// row, which templates enforcing alternating roles reject.
+type AssistantMergePolicy int
+
+const (
+	// AssistantMergePassThrough renders consecutive assistant messages as sent.
+	AssistantMergePassThrough AssistantMergePolicy = iota
+	// AssistantMergeConcatenate merges each run of consecutive assistant
+	// messages into its first, concatenating their content, separated by a
+	// blank line, and their tool calls.
+	AssistantMergeConcatenate
+)
+
+// String returns the name of the assistant merge policy.
+func (p AssistantMergePolicy) String() string {
+	switch p {
+	case AssistantMergePassThrough:
+		return "pass-through"
+	case AssistantMergeConcatenate:
+		return "concatenate"
+	default:
+		return "unknown"
+	}
+}
+
+// WithAssistantMergePolicy sets how consecutive assistant messages are
+// rendered. Defaults to AssistantMergePassThrough.
+func WithAssistantMergePolicy(policy AssistantMergePolicy) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.assistantMergePolicy = policy
+	}
+}
+
+// mergeAssistantMessages merges each run of consecutive assistant messages
+// into its first. The name and Harmony channel of the first are kept; messages
+// on another channel are not merged, as they are distinct parts of a Harmony
+// turn. The input slice is not modified.
+func mergeAssistantMessages(messages []ChatMessage) []ChatMessage {
+	merged := make([]ChatMessage, 0, len(messages))
+	for i := range messages {
+		last := len(merged) - 1
+		if last >= 0 && messages[i].Role == assistantRole && merged[last].Role == assistantRole &&
+			messages[i].Channel == merged[last].Channel {
+			toolCalls := slices.Concat(merged[last].ToolCalls, messages[i].ToolCalls)
+			merged[last] = mergeMessageContent(merged[last], &messages[i])
+			merged[last].ToolCalls = toolCalls
+			continue
+		}
+		merged = append(merged, messages[i])
+	}
+	return merged
+}
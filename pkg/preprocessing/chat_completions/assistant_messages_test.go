@@ -0,0 +1,70 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAssistantMergePolicy tests rendering two back-to-back assistant messages under each policy.
+func TestAssistantMergePolicy(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	newRequest := func() *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "Plan a trip to Paris."},
+				{Role: "assistant", Content: "I will check the flights."},
+				{Role: "assistant", Content: "I will check the hotels."},
+				{Role: "user", Content: "Thanks!"},
+			},
+			ChatTemplate:             "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			ReturnNormalizedMessages: true,
+		}
+	}
+
+	t.Run("Pass through", func(t *testing.T) {
+		response, err := preprocessing.NewChatTemplatingProcessor().RenderChatTemplate(ctx, newRequest())
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, newRequest().Conversations, response.NormalizedMessages,
+			"Consecutive assistant messages should be kept by default")
+		assert.Equal(t, "user: Plan a trip to Paris.\nassistant: I will check the flights.\n"+
+			"assistant: I will check the hotels.\nuser: Thanks!\n", response.RenderedChats[0])
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithAssistantMergePolicy(preprocessing.AssistantMergeConcatenate))
+		request := newRequest()
+		response, err := processor.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, []preprocessing.ChatMessage{
+			{Role: "user", Content: "Plan a trip to Paris."},
+			{Role: "assistant", Content: "I will check the flights.\n\nI will check the hotels."},
+			{Role: "user", Content: "Thanks!"},
+		}, response.NormalizedMessages, "The assistant messages should be merged")
+		assert.Equal(t, "user: Plan a trip to Paris.\nassistant: I will check the flights.\n\n"+
+			"I will check the hotels.\nuser: Thanks!\n", response.RenderedChats[0])
+		assert.Len(t, request.Conversations, 4, "The request should not be modified")
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateAssistantStopStrings tests deriving the strings ending an assistant turn from the template.
+func TestRenderChatTemplateAssistantStopStrings(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	tests := []struct {
+		name     string
+		template string
+		kwargs   map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "Llama-3",
+			template: llama31SystemTemplate +
+				`{%- if add_generation_prompt %}{{- '<|start_header_id|>assistant<|end_header_id|>\n\n' }}{%- endif %}`,
+			kwargs:   map[string]interface{}{"bos_token": "<|begin_of_text|>", "eos_token": "<|eot_id|>"},
+			expected: []string{"<|eot_id|>"},
+		},
+		{
+			name: "ChatML with a different EOS token",
+			template: "{% for message in messages %}<|im_start|>{{ message.role }}\n{{ message.content }}<|im_end|>\n" +
+				"{% endfor %}{% if add_generation_prompt %}<|im_start|>assistant\n{% endif %}",
+			kwargs:   map[string]interface{}{"eos_token": "<|endoftext|>"},
+			expected: []string{"<|im_end|>", "<|endoftext|>"},
+		},
+		{
+			name:     "Unclosed turns",
+			template: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			kwargs:   map[string]interface{}{"eos_token": "</s>"},
+			expected: []string{"</s>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+				Conversations:              []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+				ChatTemplate:               tt.template,
+				ChatTemplateKWArgs:         tt.kwargs,
+				AddGenerationPrompt:        true,
+				ReturnAssistantStopStrings: true,
+			})
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			assert.Equal(t, tt.expected, response.AssistantStopStrings)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// BatchResult is the outcome of one request of RenderChatTemplateBatch:
+// either Response or Err is set.
+type BatchResult struct {
+	Response *RenderJinjaTemplateResponse
+	Err      error
+}
+
+// renderBatchRequest is the request of the Python render_jinja_template_batch.
+type renderBatchRequest struct {
+	Requests []*renderRequestWire `json:"requests"`
+}
+
+// renderBatchResponse is the response of the Python render_jinja_template_batch.
+type renderBatchResponse struct {
+	Results []struct {
+		Response *RenderJinjaTemplateResponse `json:"response"`
+		Error    string                       `json:"error"`
+	} `json:"results"`
+}
+
+// RenderChatTemplateBatch renders several requests in a single call into
+// Python, returning one result per request, in order. Each request may carry
+// its own ChatTemplate; each distinct template is compiled once. A request
+// failing, e.g. on invalid template vars or a template error, only fails its
+// own result. The returned error is only set when the whole batch fails.
+//
+// Batches are always exchanged as JSON, whatever the wire format. The render
+// hook is called for each request, with the duration of the whole batch.
+func (w *ChatTemplatingProcessor) RenderChatTemplateBatch(ctx context.Context,
+	reqs []*RenderJinjaTemplateRequest,
+) ([]BatchResult, error) {
+	if w.renderHook == nil {
+		return w.renderChatTemplateBatch(ctx, reqs)
+	}
+
+	start := time.Now()
+	results, err := w.renderChatTemplateBatch(ctx, reqs)
+	dur := time.Since(start)
+	for i, req := range reqs {
+		if err != nil {
+			w.runRenderHook(ctx, req, nil, err, dur)
+			continue
+		}
+		w.runRenderHook(ctx, req, results[i].Response, results[i].Err, dur)
+	}
+	return results, err
+}
+
+// renderChatTemplateBatch renders a batch for RenderChatTemplateBatch, without running the render hook.
+func (w *ChatTemplatingProcessor) renderChatTemplateBatch(ctx context.Context,
+	reqs []*RenderJinjaTemplateRequest,
+) (_ []BatchResult, err error) {
+	defer func() { countRenderCancellation(err) }()
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("RenderChatTemplateBatch")
+
+	if err := w.checkMemoryLimit(); err != nil {
+		traceLogger.Error(err, "Rejecting batch under memory pressure")
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(reqs))
+	droppedMessages := make([]int, len(reqs))
+	// indices maps the requests sent to Python back to their position in reqs.
+	indices := make([]int, 0, len(reqs))
+	batch := renderBatchRequest{Requests: make([]*renderRequestWire, 0, len(reqs))}
+	for i, req := range reqs {
+		if req == nil {
+			results[i].Err = fmt.Errorf("received nil request")
+			continue
+		}
+		prepared, dropped, err := w.prepareRender(req)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		droppedMessages[i] = dropped
+		indices = append(indices, i)
+		batch.Requests = append(batch.Requests, newRenderRequestWire(prepared))
+	}
+	if len(indices) == 0 {
+		return results, nil
+	}
+
+	var resp renderBatchResponse
+	if err := callPythonFunction(ctx, "render_jinja_template_batch", batch, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(indices) {
+		return nil, fmt.Errorf("python returned %d results for %d requests", len(resp.Results), len(indices))
+	}
+
+	for j, result := range resp.Results {
+		i := indices[j]
+		switch {
+		case result.Error != "":
+			results[i].Err = fmt.Errorf("python render_jinja_template failed: %s", result.Error)
+		case result.Response == nil:
+			results[i].Err = fmt.Errorf("python returned no response")
+		default:
+			if err := w.finishRender(batch.Requests[j].RenderJinjaTemplateRequest, result.Response,
+				droppedMessages[i]); err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i].Response = result.Response
+		}
+	}
+	return results, nil
+}
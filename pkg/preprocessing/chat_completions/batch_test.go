@@ -0,0 +1,65 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateBatch tests a batch mixing templates, with failing items, keeps its order and per-item errors.
+func TestRenderChatTemplateBatch(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	const (
+		plainTemplate    = "{% for message in messages %}{{ message.content }}{% endfor %}"
+		bracketsTemplate = "{% for message in messages %}[{{ message.content }}]{% endfor %}"
+	)
+	newRequest := func(template, content string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: content}},
+			ChatTemplate:  template,
+		}
+	}
+
+	invalidVars := newRequest(plainTemplate, "invalid")
+	invalidVars.TemplateVars = map[string]interface{}{"messages": "shadowed"}
+
+	results, err := wrapper.RenderChatTemplateBatch(context.Background(), []*preprocessing.RenderJinjaTemplateRequest{
+		newRequest(plainTemplate, "first"),
+		newRequest(bracketsTemplate, "second"),
+		newRequest(`{{ raise_exception("broken template") }}`, "third"),
+		invalidVars,
+		newRequest(plainTemplate, "fifth"),
+		newRequest(bracketsTemplate, "sixth"),
+	})
+	require.NoError(t, err, "RenderChatTemplateBatch should not return an error")
+	require.Len(t, results, 6, "There should be one result per request")
+
+	for i, expected := range map[int]string{0: "first", 1: "[second]", 4: "fifth", 5: "[sixth]"} {
+		require.NoError(t, results[i].Err, "Request %d should render", i)
+		assert.Equal(t, expected, results[i].Response.RenderedChats[0], "Request %d should use its own template", i)
+	}
+	require.Error(t, results[2].Err, "A template error should fail its own request")
+	assert.Contains(t, results[2].Err.Error(), "broken template", "The template error should be reported")
+	assert.ErrorIs(t, results[3].Err, preprocessing.ErrReservedTemplateVar, "Invalid requests should fail before rendering")
+	assert.True(t, results[4].Response.CompileCacheHit, "A template repeated in the batch should be compiled once")
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// BlockAlignment describes how the tokens of a rendered chat fall into the
+// fixed-size blocks of a KV-cache, so the cache layer can compute block keys.
+type BlockAlignment struct {
+	// BlockSize is the requested RenderJinjaTemplateRequest.BlockAlign.
+	BlockSize int `json:"block_size"`
+	// FullBlocks is the number of blocks the tokens fill completely.
+	FullBlocks int `json:"full_blocks"`
+	// TailTokens is the number of tokens in the last, partial block, zero
+	// when the tokens end on a block boundary.
+	TailTokens int `json:"tail_tokens"`
+	// PaddingTokens is the number of tokens the last, partial block lacks to
+	// reach the next block boundary. The tokens themselves are not padded.
+	PaddingTokens int `json:"padding_tokens"`
+}
+
+// validateBlockAlign checks that a block alignment can be reported for req.
+func validateBlockAlign(req *RenderJinjaTemplateRequest) error {
+	if req.BlockAlign < 0 {
+		return fmt.Errorf("invalid block size %d", req.BlockAlign)
+	}
+	if req.BlockAlign > 0 && !req.ReturnTokenIDs && !req.ReturnOffsetMapping {
+		return fmt.Errorf("block_align requires return_token_ids")
+	}
+	return nil
+}
+
+// blockAlignment returns how numTokens tokens align to blocks of blockSize
+// tokens, or nil if blockSize is not positive.
+func blockAlignment(numTokens, blockSize int) *BlockAlignment {
+	if blockSize <= 0 {
+		return nil
+	}
+
+	alignment := &BlockAlignment{
+		BlockSize:  blockSize,
+		FullBlocks: numTokens / blockSize,
+		TailTokens: numTokens % blockSize,
+	}
+	if alignment.TailTokens > 0 {
+		alignment.PaddingTokens = blockSize - alignment.TailTokens
+	}
+	return alignment
+}
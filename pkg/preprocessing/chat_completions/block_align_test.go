@@ -0,0 +1,81 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateBlockAlign tests reporting how the rendered tokens align to KV-cache blocks.
+func TestRenderChatTemplateBlockAlign(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	newRequest := func(blockSize int) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "What is the capital of France?"},
+				{Role: "assistant", Content: "The capital of France is Paris."},
+			},
+			ChatTemplate:   "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			ReturnTokenIDs: true,
+			Tokenizer:      &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+			BlockAlign:     blockSize,
+		}
+	}
+
+	unaligned, err := wrapper.RenderChatTemplate(ctx, newRequest(0))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Nil(t, unaligned.BlockAlignment, "No alignment should be reported without a block size")
+	numTokens := len(unaligned.TokenIDs)
+	require.Greater(t, numTokens, 4, "The conversation should span several blocks")
+
+	const blockSize = 4
+	response, err := wrapper.RenderChatTemplate(ctx, newRequest(blockSize))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, unaligned.TokenIDs, response.TokenIDs, "Block alignment should not alter the tokens")
+	require.NotNil(t, response.BlockAlignment)
+	alignment := response.BlockAlignment
+	assert.Equal(t, blockSize, alignment.BlockSize)
+	assert.Equal(t, numTokens/blockSize, alignment.FullBlocks)
+	assert.Equal(t, numTokens%blockSize, alignment.TailTokens)
+	assert.Equal(t, numTokens, alignment.FullBlocks*blockSize+alignment.TailTokens)
+	if alignment.TailTokens > 0 {
+		assert.Equal(t, blockSize, alignment.TailTokens+alignment.PaddingTokens, "Padding should reach the boundary")
+	} else {
+		assert.Zero(t, alignment.PaddingTokens, "Aligned tokens should need no padding")
+	}
+
+	// A block size of the number of tokens aligns them exactly.
+	response, err = wrapper.RenderChatTemplate(ctx, newRequest(numTokens))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, &preprocessing.BlockAlignment{BlockSize: numTokens, FullBlocks: 1}, response.BlockAlignment)
+
+	invalid := newRequest(-1)
+	_, err = wrapper.RenderChatTemplate(ctx, invalid)
+	assert.Error(t, err, "A negative block size should be rejected")
+	invalid = newRequest(blockSize)
+	invalid.ReturnTokenIDs = false
+	_, err = wrapper.RenderChatTemplate(ctx, invalid)
+	assert.Error(t, err, "Block alignment without token IDs should be rejected")
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/kvblock"
+)
+
+// BlockKeys renders req and returns the KV-block key of each full block of
+// the rendered chat's tokens, in order, as computed by tokenProcessor for
+// modelName. These are the keys the KV-block index stores, so they can be
+// looked up in or inserted into it directly, provided tokenProcessor is
+// configured like the indexer's, with the same block size and hash seed.
+// req.Tokenizer must be set. The tokens of a trailing partial block are not
+// keyed, as its KV is only cached once the block fills; see
+// RenderJinjaTemplateResponse.BlockAlignment. The request is not modified.
+func (w *ChatTemplatingProcessor) BlockKeys(ctx context.Context, req *RenderJinjaTemplateRequest,
+	tokenProcessor kvblock.TokenProcessor, modelName string,
+) ([]kvblock.Key, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+	if tokenProcessor == nil {
+		return nil, fmt.Errorf("computing block keys requires a token processor")
+	}
+	if req.Tokenizer == nil {
+		return nil, fmt.Errorf("computing block keys requires a tokenizer")
+	}
+
+	tokenized := *req
+	tokenized.ReturnTokenIDs = true
+	response, err := w.RenderChatTemplate(ctx, &tokenized)
+	if err != nil {
+		return nil, err
+	}
+	return tokenProcessor.TokensToKVBlockKeys(nil, response.TokenIDs, modelName), nil
+}
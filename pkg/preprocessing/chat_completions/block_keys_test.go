@@ -0,0 +1,77 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/kvblock"
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlockKeys tests that block keys are the KV-block index's keys of the rendered tokens, and are chained.
+func TestBlockKeys(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	const model = "test-model"
+	tokenProcessor := kvblock.NewChunkedTokenDatabase(&kvblock.TokenProcessorConfig{BlockSize: 4, HashSeed: "42"})
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "What is the capital of France?"},
+		},
+		ChatTemplate: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		Tokenizer:    &preprocessing.TokenizerSource{Model: "../../tokenization/testdata/test-model", IsLocalPath: true},
+	}
+	keys, err := wrapper.BlockKeys(ctx, req, tokenProcessor, model)
+	require.NoError(t, err, "BlockKeys should not return an error")
+	assert.False(t, req.ReturnTokenIDs, "The request should not be modified")
+
+	tokenized := *req
+	tokenized.ReturnTokenIDs = true
+	response, err := wrapper.RenderChatTemplate(ctx, &tokenized)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Len(t, keys, len(response.TokenIDs)/4, "Only full blocks should be keyed")
+	assert.Equal(t, tokenProcessor.TokensToKVBlockKeys(nil, response.TokenIDs, model), keys,
+		"Block keys should be the keys the index stores for the rendered tokens")
+
+	// Appending a turn keeps the keys of the blocks before it.
+	longer := *req
+	longer.Conversations = append(longer.Conversations[:len(longer.Conversations):len(longer.Conversations)],
+		preprocessing.ChatMessage{Role: "assistant", Content: "Paris is the capital of France."})
+	longerKeys, err := wrapper.BlockKeys(ctx, &longer, tokenProcessor, model)
+	require.NoError(t, err, "BlockKeys should not return an error")
+	require.Greater(t, len(longerKeys), len(keys))
+	assert.Equal(t, keys, longerKeys[:len(keys)], "A longer conversation should share the keys of its prefix blocks")
+
+	otherKeys, err := wrapper.BlockKeys(ctx, req, tokenProcessor, "other-model")
+	require.NoError(t, err, "BlockKeys should not return an error")
+	assert.NotEqual(t, keys[0].ChunkHash, otherKeys[0].ChunkHash, "Block keys should be seeded per model")
+
+	// A trailing partial block is not keyed.
+	partialProcessor := kvblock.NewChunkedTokenDatabase(&kvblock.TokenProcessorConfig{BlockSize: len(response.TokenIDs) - 1})
+	partial, err := wrapper.BlockKeys(ctx, req, partialProcessor, model)
+	require.NoError(t, err, "BlockKeys should not return an error")
+	assert.Len(t, partial, 1, "The last token should be left in an unkeyed partial block")
+
+	_, err = wrapper.BlockKeys(ctx, req, nil, model)
+	assert.Error(t, err, "A missing token processor should be rejected")
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "context"
+
+// BundledChatMLTemplate is a minimal ChatML chat template shipped with the
+// package: every message is framed by `<|im_start|>` and `<|im_end|>`. It is
+// meant for tests needing deterministic renders without any model.
+const BundledChatMLTemplate = "{% for message in messages %}" +
+	"<|im_start|>{{ message.role }}\n{{ message.content }}<|im_end|>\n" +
+	"{% endfor %}" +
+	"{% if add_generation_prompt %}<|im_start|>assistant\n{% endif %}"
+
+// RenderWithBundledTemplate renders messages with BundledChatMLTemplate, so
+// tests of downstream components, such as prefix caching, get reproducible
+// renders without fetching any model.
+func (w *ChatTemplatingProcessor) RenderWithBundledTemplate(ctx context.Context, messages []ChatMessage,
+	opts RenderOptions,
+) (*RenderJinjaTemplateResponse, error) {
+	return w.RenderFromConfig(ctx, ModelConfig{ChatTemplate: BundledChatMLTemplate}, messages, opts)
+}
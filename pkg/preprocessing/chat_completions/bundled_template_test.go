@@ -0,0 +1,43 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderWithBundledTemplate tests that the bundled template renders a fixed conversation to a stable output.
+func TestRenderWithBundledTemplate(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	messages := []preprocessing.ChatMessage{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "Hello"},
+	}
+	response, err := wrapper.RenderWithBundledTemplate(context.Background(), messages,
+		preprocessing.RenderOptions{AddGenerationPrompt: true})
+	require.NoError(t, err, "RenderWithBundledTemplate should not return an error")
+	require.Len(t, response.RenderedChats, 1)
+	assert.Equal(t, "<|im_start|>system\nYou are a helpful assistant.<|im_end|>\n"+
+		"<|im_start|>user\nHello<|im_end|>\n"+
+		"<|im_start|>assistant\n", response.RenderedChats[0], "The render should be stable")
+}
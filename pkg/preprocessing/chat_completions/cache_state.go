@@ -0,0 +1,99 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// TemplateSource is where a cached template was fetched from.
+type TemplateSource string
+
+const (
+	// TemplateSourceHub is a template fetched from the HuggingFace Hub.
+	TemplateSourceHub TemplateSource = "hub"
+	// TemplateSourceLocal is a template loaded from a local model directory.
+	TemplateSourceLocal TemplateSource = "local"
+	// TemplateSourceGGUF is a template read from the metadata of a GGUF file.
+	TemplateSourceGGUF TemplateSource = "gguf"
+)
+
+// CacheState is the state of the template cache, as dumped by DumpCacheState.
+type CacheState struct {
+	// Templates are the cached templates, from the least to the most
+	// recently used, so the first ones are evicted first unless pinned.
+	Templates []TemplateCacheEntry `json:"templates"`
+}
+
+// TemplateCacheEntry describes a cached template.
+type TemplateCacheEntry struct {
+	Model    string         `json:"model"`
+	Revision string         `json:"revision,omitempty"`
+	Source   TemplateSource `json:"source"`
+	// Size is the size of the model's templates, in characters.
+	Size int `json:"size"`
+	// Pinned reports that the template is pinned by PinTemplate.
+	Pinned bool `json:"pinned"`
+	// LastAccess is when the template was last fetched or served from cache.
+	LastAccess time.Time `json:"last_access"`
+	// Hits is the number of fetches served from cache.
+	Hits int `json:"hits"`
+}
+
+// templateCacheEntryWire is a TemplateCacheEntry as returned by
+// dump_template_cache, with the last access as unix time.
+type templateCacheEntryWire struct {
+	TemplateCacheEntry
+	LastAccess float64 `json:"last_access"`
+}
+
+// DumpCacheState returns the JSON encoding of the CacheState, e.g. for an
+// admin dashboard. Tokens of private models are never included. It only
+// reads the cache: neither the order of eviction nor the hit counts change.
+func (w *ChatTemplatingProcessor) DumpCacheState(ctx context.Context) (_ []byte, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var resp struct {
+		Templates []templateCacheEntryWire `json:"templates"`
+	}
+	if err := callPythonFunction(ctx, "dump_template_cache", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+
+	state := CacheState{Templates: make([]TemplateCacheEntry, len(resp.Templates))}
+	for i, wire := range resp.Templates {
+		entry := wire.TemplateCacheEntry
+		seconds, fraction := math.Modf(wire.LastAccess)
+		entry.LastAccess = time.Unix(int64(seconds), int64(fraction*float64(time.Second)))
+		state.Templates[i] = entry
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cache state: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDumpCacheState tests serializing the metadata of cached templates.
+func TestDumpCacheState(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	require.NoError(t, preprocessing.ClearCaches(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+	})
+
+	pinnedModel := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.CopyFS(pinnedModel, os.DirFS(testModelPath)))
+
+	start := time.Now()
+	request := preprocessing.FetchChatTemplateRequest{Model: testModelPath, IsLocalPath: true}
+	for range 3 {
+		_, _, err := wrapper.FetchChatTemplate(ctx, request)
+		require.NoError(t, err, "FetchChatTemplate should not return an error")
+	}
+	require.NoError(t, wrapper.PinTemplate(ctx, preprocessing.FetchChatTemplateRequest{Model: pinnedModel, IsLocalPath: true}),
+		"PinTemplate should not return an error")
+
+	data, err := wrapper.DumpCacheState(ctx)
+	require.NoError(t, err, "DumpCacheState should not return an error")
+	var state preprocessing.CacheState
+	require.NoError(t, json.Unmarshal(data, &state), "The cache state should be valid JSON")
+
+	require.Len(t, state.Templates, 2)
+	fetched, pinned := state.Templates[0], state.Templates[1]
+	assert.Equal(t, testModelPath, fetched.Model)
+	assert.Equal(t, preprocessing.TemplateSourceLocal, fetched.Source)
+	assert.Positive(t, fetched.Size)
+	assert.False(t, fetched.Pinned)
+	assert.Equal(t, 2, fetched.Hits, "Fetches after the first should be cache hits")
+	assert.WithinRange(t, fetched.LastAccess, start.Add(-time.Second), time.Now().Add(time.Second))
+
+	assert.Equal(t, pinnedModel, pinned.Model)
+	assert.True(t, pinned.Pinned)
+	assert.Zero(t, pinned.Hits)
+
+	// Dumping does not count as an access.
+	again, err := wrapper.DumpCacheState(ctx)
+	require.NoError(t, err, "DumpCacheState should not return an error")
+	assert.JSONEq(t, string(data), string(again), "Dumping should not change the cache state")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// canonicalRequest is a RenderJinjaTemplateRequest as serialized by
+// CanonicalKey, with the Go-only fields and the processor options affecting
+// the render included.
+type canonicalRequest struct {
+	*renderRequestWire
+	MaxMessages              int    `json:"max_messages,omitempty"`
+	TruncationMarker         string `json:"truncation_marker,omitempty"`
+	Harmony                  bool   `json:"harmony,omitempty"`
+	BlockAlign               int    `json:"block_align,omitempty"`
+	DefaultSystemPrompt      string `json:"default_system_prompt,omitempty"`
+	ReturnNormalizedMessages bool   `json:"return_normalized_messages,omitempty"`
+	ToolsInUserMessage       *bool  `json:"tools_in_user_message,omitempty"`
+	MaxToolDescriptionBytes  int    `json:"max_tool_description_bytes,omitempty"`
+	ThinkingBudget           *int   `json:"thinking_budget,omitempty"`
+
+	SystemMergePolicy    SystemMergePolicy    `json:"system_merge_policy,omitempty"`
+	AssistantMergePolicy AssistantMergePolicy `json:"assistant_merge_policy,omitempty"`
+	SchemaDialect        SchemaDialect        `json:"schema_dialect,omitempty"`
+	Normalization        UnicodeNormalization `json:"normalization,omitempty"`
+	EmptyRenderPolicy    EmptyRenderPolicy    `json:"empty_render_policy,omitempty"`
+	PromptHashSource     PromptHashSource     `json:"prompt_hash_source,omitempty"`
+	CustomFilters        bool                 `json:"custom_filters,omitempty"`
+}
+
+// CanonicalKey returns the canonical key of req for renders by a processor
+// with the default options, see ChatTemplatingProcessor.CanonicalKey.
+func CanonicalKey(req *RenderJinjaTemplateRequest) (string, error) {
+	return (&ChatTemplatingProcessor{}).CanonicalKey(req)
+}
+
+// CanonicalKey returns a canonical serialization of req as rendered by
+// RenderChatTemplate of this processor, e.g. to key a render cache shared
+// across processes. Logically equal requests have the same key regardless of
+// how they were built: map keys are sorted, whatever the order they were
+// inserted or decoded in, and numbers are normalized, so that a whole float64
+// such as 2.0, the integer 2 and the json.Number "2" are alike. Strings,
+// including tool call arguments, are kept verbatim, as templates render them
+// as is, and TraceParent and tokenizer handles are left out. The options of
+// the processor changing the render, such as its merge policies, schema
+// dialect, Unicode normalization and the tool arguments format and Jinja
+// whitespace applied to requests not setting them, are part of the key, so
+// that processors configured differently do not share renders. Options only
+// rejecting requests, the policies of RenderForModel and template loaders
+// and selectors are not. The key is only stable for a given version of this
+// package.
+func (w *ChatTemplatingProcessor) CanonicalKey(req *RenderJinjaTemplateRequest) (string, error) {
+	if req == nil {
+		return "", errors.New("received nil request")
+	}
+	normalized := wireNumbers(req)
+	if normalized.ToolArgsFormat == "" {
+		normalized.ToolArgsFormat = w.toolArgsFormat
+	}
+	if normalized.JinjaWhitespace == nil {
+		normalized.JinjaWhitespace = w.jinjaWhitespace
+	}
+	// The traceparent identifies the caller's span, not the render.
+	normalized.TraceParent = ""
+	// Handles only shortcut the lookup of the tokenizer of their source.
+	if normalized.Tokenizer != nil && normalized.Tokenizer.Handle != 0 {
+		source := *normalized.Tokenizer
+		source.Handle = 0
+		normalized.Tokenizer = &source
+	}
+	key, err := json.Marshal(canonicalRequest{
+		renderRequestWire:        newRenderRequestWire(normalized),
+		MaxMessages:              req.MaxMessages,
+		TruncationMarker:         req.TruncationMarker,
+		Harmony:                  req.Harmony,
+		BlockAlign:               req.BlockAlign,
+		DefaultSystemPrompt:      req.DefaultSystemPrompt,
+		ReturnNormalizedMessages: req.ReturnNormalizedMessages,
+		ToolsInUserMessage:       req.ToolsInUserMessage,
+		MaxToolDescriptionBytes:  req.MaxToolDescriptionBytes,
+		ThinkingBudget:           req.ThinkingBudget,
+		SystemMergePolicy:        w.systemMergePolicy,
+		AssistantMergePolicy:     w.assistantMergePolicy,
+		SchemaDialect:            w.schemaDialect,
+		Normalization:            w.normalization,
+		EmptyRenderPolicy:        w.emptyRenderPolicy,
+		PromptHashSource:         w.promptHashSource,
+		CustomFilters:            w.customFiltersEnabled,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
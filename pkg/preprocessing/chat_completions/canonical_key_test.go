@@ -0,0 +1,108 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCanonicalKey tests that logically equal requests have the same canonical key, and different ones do not.
+func TestCanonicalKey(t *testing.T) {
+	built := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplateKWArgs: map[string]interface{}{
+			"enable_thinking": true,
+			"max_depth":       2,
+			"temperature":     0.5,
+		},
+		Tools: []interface{}{map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": "search", "parameters": map[string]interface{}{"limit": 10.0}},
+		}},
+		AddGenerationPrompt: true,
+		MaxMessages:         8,
+		TraceParent:         "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+
+	// The same request decoded from JSON with other key orders and number forms.
+	var decoded preprocessing.RenderJinjaTemplateRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"add_generation_prompt": true,
+		"tools": [{"function": {"parameters": {"limit": 10}, "name": "search"}, "type": "function"}],
+		"chat_template_kwargs": {"temperature": 0.50, "max_depth": 2.0, "enable_thinking": true},
+		"messages": [{"content": "Hello", "role": "user"}]
+	}`), &decoded))
+	decoded.MaxMessages = 8
+
+	builtKey, err := preprocessing.CanonicalKey(built)
+	require.NoError(t, err, "CanonicalKey should not return an error")
+	decodedKey, err := preprocessing.CanonicalKey(&decoded)
+	require.NoError(t, err, "CanonicalKey should not return an error")
+	assert.Equal(t, builtKey, decodedKey, "Logically equal requests should have the same key")
+
+	for i := 0; i < 10; i++ {
+		again, err := preprocessing.CanonicalKey(built)
+		require.NoError(t, err)
+		require.Equal(t, builtKey, again, "The key should not depend on map iteration order")
+	}
+
+	decoded.MaxMessages = 4
+	otherKey, err := preprocessing.CanonicalKey(&decoded)
+	require.NoError(t, err, "CanonicalKey should not return an error")
+	assert.NotEqual(t, builtKey, otherKey, "Go-only fields affecting the render should be part of the key")
+}
+
+// TestProcessorCanonicalKey tests that the options of a processor changing the render are part of its canonical keys.
+func TestProcessorCanonicalKey(t *testing.T) {
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+	}
+	defaultKey, err := preprocessing.CanonicalKey(request)
+	require.NoError(t, err, "CanonicalKey should not return an error")
+	processorKey, err := preprocessing.NewChatTemplatingProcessor().CanonicalKey(request)
+	require.NoError(t, err, "CanonicalKey should not return an error")
+	assert.Equal(t, defaultKey, processorKey, "CanonicalKey should be the key under the default options")
+
+	keys := map[string]string{"default": defaultKey}
+	for name, opt := range map[string]preprocessing.Option{
+		"system merge":    preprocessing.WithSystemMergePolicy(preprocessing.SystemMergeIntoFirst),
+		"assistant merge": preprocessing.WithAssistantMergePolicy(preprocessing.AssistantMergeConcatenate),
+		"schema dialect":  preprocessing.WithSchemaDialect(preprocessing.SchemaDialectDraft07),
+		"normalization":   preprocessing.WithUnicodeNormalization(preprocessing.NormalizationNFC),
+		"tool arguments":  preprocessing.WithToolArgsFormat(preprocessing.ToolArgsString),
+		"whitespace":      preprocessing.WithJinjaWhitespace(preprocessing.JinjaWhitespace{}),
+	} {
+		key, err := preprocessing.NewChatTemplatingProcessor(opt).CanonicalKey(request)
+		require.NoError(t, err, "CanonicalKey should not return an error")
+		for other, otherKey := range keys {
+			assert.NotEqual(t, otherKey, key, "The %s option should change the key from %s", name, other)
+		}
+		keys[name] = key
+	}
+
+	// A request setting the format a processor defaults to renders alike.
+	formatted := *request
+	formatted.ToolArgsFormat = preprocessing.ToolArgsString
+	explicitKey, err := preprocessing.CanonicalKey(&formatted)
+	require.NoError(t, err, "CanonicalKey should not return an error")
+	assert.Equal(t, keys["tool arguments"], explicitKey, "The default tool arguments format should be keyed as applied")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Capabilities describes which request inputs a chat template makes use of.
+// It is derived from a static analysis of the template, without rendering it.
+type Capabilities struct {
+	// SupportsTools is true if the template references the `tools` variable.
+	SupportsTools bool `json:"supports_tools"`
+	// SupportsDocuments is true if the template references the `documents` variable.
+	SupportsDocuments bool `json:"supports_documents"`
+	// SupportsSystemRole is true if the template handles messages with the `system` role.
+	SupportsSystemRole bool `json:"supports_system_role"`
+	// RequiresGenerationPrompt is true if the template only emits the
+	// assistant turn header when `add_generation_prompt` is set, so requests
+	// expecting a model reply must set AddGenerationPrompt.
+	RequiresGenerationPrompt bool `json:"requires_generation_prompt"`
+}
+
+// templateCapabilitiesRequest is the request sent to get_template_capabilities.
+type templateCapabilitiesRequest struct {
+	ChatTemplate string `json:"chat_template"`
+}
+
+// TemplateCapabilities reports the capabilities of a chat template. It fails
+// if the template cannot be compiled.
+func (w *ChatTemplatingProcessor) TemplateCapabilities(ctx context.Context, template string) (_ Capabilities, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return Capabilities{}, err
+	}
+	defer release()
+	if template == "" {
+		return Capabilities{}, fmt.Errorf("template cannot be empty")
+	}
+
+	var capabilities Capabilities
+	if err := callPythonFunction(ctx, "get_template_capabilities",
+		templateCapabilitiesRequest{ChatTemplate: template}, &capabilities); err != nil {
+		return Capabilities{}, err
+	}
+
+	return capabilities, nil
+}
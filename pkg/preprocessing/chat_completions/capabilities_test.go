@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTemplateCapabilities tests the static capability analysis of chat templates.
+func TestTemplateCapabilities(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	toolTemplate := `{%- if messages[0]['role'] == 'system' %}{{ messages[0]['content'] }}{%- endif %}
+{%- if tools %}Available tools: {{ tools | tojson }}{%- endif %}
+{%- for message in messages %}{{ message.role }}: {{ message.content }}
+{%- endfor %}
+{%- if add_generation_prompt %}assistant:{%- endif %}`
+	plainTemplate := `{% for message in messages %}{{ message.role }}: {{ message.content }}
+{% endfor %}`
+
+	tests := []struct {
+		name     string
+		template string
+		expected preprocessing.Capabilities
+	}{
+		{
+			name:     "Tool-capable template",
+			template: toolTemplate,
+			expected: preprocessing.Capabilities{
+				SupportsTools:            true,
+				SupportsSystemRole:       true,
+				RequiresGenerationPrompt: true,
+			},
+		},
+		{
+			name:     "Plain template",
+			template: plainTemplate,
+			expected: preprocessing.Capabilities{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capabilities, err := wrapper.TemplateCapabilities(context.Background(), tt.template)
+			require.NoError(t, err, "TemplateCapabilities should not return an error")
+			assert.Equal(t, tt.expected, capabilities)
+		})
+	}
+
+	_, err := wrapper.TemplateCapabilities(context.Background(), "{% for message in messages %}")
+	assert.Error(t, err, "TemplateCapabilities should fail on an invalid template")
+}
@@ -14,6 +14,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+#include <pthread.h> // for the executor thread
 #include <unistd.h> // for getpid() and usleep()
 
 #include "cgo_functions.h"
@@ -22,6 +23,7 @@ limitations under the License.
 PyObject* g_chat_template_module = NULL;
 PyObject* g_render_jinja_template_func = NULL;
 PyObject* g_get_model_chat_template_func = NULL;
+PyObject* g_render_jinja_template_msgpack_func = NULL;
 int g_initialized = 0;
 int g_python_initialized = 0;
 
@@ -34,6 +36,76 @@ static pid_t g_init_pid = 0;
 static PyThread_type_lock g_init_lock = NULL;
 static PyThread_type_lock g_python_init_lock = NULL;
 
+// Cause of the last module initialization failure, NULL if none
+static char* g_init_error = NULL;
+
+// Record the cause of a module initialization failure
+static void set_init_error(const char* message) {
+    free(g_init_error);
+    g_init_error = message ? strdup(message) : NULL;
+}
+
+// Record the pending Python exception, with its traceback, as the cause of a
+// module initialization failure. Must be called with the GIL held.
+static void set_init_error_from_exception(void) {
+    PyObject *type = NULL, *value = NULL, *traceback = NULL;
+    PyErr_Fetch(&type, &value, &traceback);
+    if (!type) {
+        return;
+    }
+    PyErr_NormalizeException(&type, &value, &traceback);
+
+    PyObject* formatted = NULL;
+    PyObject* traceback_module = PyImport_ImportModule("traceback");
+    if (traceback_module) {
+        PyObject* lines = PyObject_CallMethod(traceback_module, "format_exception", "OOO",
+                                              type, value ? value : Py_None, traceback ? traceback : Py_None);
+        if (lines) {
+            PyObject* separator = PyUnicode_FromString("");
+            if (separator) {
+                formatted = PyUnicode_Join(separator, lines);
+                Py_DECREF(separator);
+            }
+            Py_DECREF(lines);
+        }
+        Py_DECREF(traceback_module);
+    }
+    // Fall back to the exception message if the traceback could not be formatted
+    if (!formatted && value) {
+        formatted = PyObject_Str(value);
+    }
+
+    const char* message = formatted ? PyUnicode_AsUTF8(formatted) : NULL;
+    set_init_error(message ? message : "unknown Python error");
+    fprintf(stderr, "%s", g_init_error);
+    fflush(stderr);
+
+    PyErr_Clear();
+    Py_XDECREF(formatted);
+    Py_XDECREF(type);
+    Py_XDECREF(value);
+    Py_XDECREF(traceback);
+}
+
+// Whether the last render call on this thread failed transiently, see Py_TakeCallTransient
+static __thread int g_call_transient = 0;
+
+// Classify the pending Python exception of a failed render call. Running out of
+// memory depends on the state of the interpreter when called, e.g. other renders
+// holding memory, so the same call may succeed when retried. Running out of stack
+// does not: the same input recurses as deep again. Must be called with the GIL
+// held, before the exception is printed.
+static void classify_call_failure(void) {
+    g_call_transient = PyErr_ExceptionMatches(PyExc_MemoryError);
+}
+
+// Take whether the last render call on this thread failed transiently
+int Py_TakeCallTransient(void) {
+    int transient = g_call_transient;
+    g_call_transient = 0;
+    return transient;
+}
+
 // === ORIGINAL FUNCTION IMPLEMENTATIONS ===
 
 // Initialize Python interpreter
@@ -82,6 +154,7 @@ int Py_InitializeGo() {
 
     g_python_initialized = 1;
     g_process_initialized = 1;
+    g_finalized = 0;
     g_init_pid = getpid();
     PyThread_release_lock(g_python_init_lock);
 
@@ -98,8 +171,17 @@ void Py_FinalizeGo() {
     
     // Mark as finalized first to prevent race conditions
     g_finalized = 1;
-    
-    // Clean up module references safely
+
+    // Nothing to release if the interpreter was never started
+    if (!Py_IsInitialized()) {
+        g_python_initialized = 0;
+        g_process_initialized = 0;
+        g_initialized = 0;
+        return;
+    }
+
+    // Clean up module references safely, decrementing requires the GIL
+    PyGILState_STATE gstate = PyGILState_Ensure();
     if (g_render_jinja_template_func) {
         Py_DECREF(g_render_jinja_template_func);
         g_render_jinja_template_func = NULL;
@@ -108,10 +190,15 @@ void Py_FinalizeGo() {
         Py_DECREF(g_get_model_chat_template_func);
         g_get_model_chat_template_func = NULL;
     }
+    if (g_render_jinja_template_msgpack_func) {
+        Py_DECREF(g_render_jinja_template_msgpack_func);
+        g_render_jinja_template_msgpack_func = NULL;
+    }
     if (g_chat_template_module) {
         Py_DECREF(g_chat_template_module);
         g_chat_template_module = NULL;
     }
+    PyGILState_Release(gstate);
     
     // Reset state without finalizing Python
     // Python will be cleaned up when the process exits
@@ -160,6 +247,7 @@ int Py_InitChatTemplateModule() {
     }
     
     PyThread_acquire_lock(g_init_lock, NOWAIT_LOCK);
+    set_init_error(NULL);
     
     // Check if already initialized
     if (g_initialized) {
@@ -171,6 +259,7 @@ int Py_InitChatTemplateModule() {
     // Ensure Python is initialized
     if (!g_python_initialized) {
         printf("[C] Py_InitChatTemplateModule ERROR - Python not initialized\n");
+        set_init_error("Python interpreter not initialized");
         PyThread_release_lock(g_init_lock);
         return -1;
     }
@@ -184,7 +273,7 @@ int Py_InitChatTemplateModule() {
     g_chat_template_module = PyImport_ImportModule("render_jinja_template_wrapper");
     if (!g_chat_template_module) {
         printf("[C] Py_InitChatTemplateModule ERROR - Failed to import render_jinja_template_wrapper module\n");
-        PyErr_Print();
+        set_init_error_from_exception();
         PyGILState_Release(gil_state);
         PyThread_release_lock(g_init_lock);
         return -1;
@@ -194,6 +283,7 @@ int Py_InitChatTemplateModule() {
     PyObject* module_dict = PyModule_GetDict(g_chat_template_module);
     if (!module_dict) {
         printf("[C] Py_InitChatTemplateModule ERROR - Failed to get module dictionary\n");
+        set_init_error("failed to get the render_jinja_template_wrapper module dictionary");
         PyGILState_Release(gil_state);
         PyThread_release_lock(g_init_lock);
         return -1;
@@ -203,6 +293,7 @@ int Py_InitChatTemplateModule() {
     g_render_jinja_template_func = PyDict_GetItemString(module_dict, "render_jinja_template");
     if (!g_render_jinja_template_func || !PyCallable_Check(g_render_jinja_template_func)) {
         printf("[C] Py_InitChatTemplateModule ERROR - render_jinja_template function not found or not callable\n");
+        set_init_error("render_jinja_template function not found or not callable");
         PyGILState_Release(gil_state);
         PyThread_release_lock(g_init_lock);
         return -1;
@@ -213,11 +304,37 @@ int Py_InitChatTemplateModule() {
     g_get_model_chat_template_func = PyDict_GetItemString(module_dict, "get_model_chat_template");
     if (!g_get_model_chat_template_func || !PyCallable_Check(g_get_model_chat_template_func)) {
         printf("[C] Py_InitChatTemplateModule ERROR - get_model_chat_template function not found or not callable\n");
+        set_init_error("get_model_chat_template function not found or not callable");
         PyGILState_Release(gil_state);
         PyThread_release_lock(g_init_lock);
         return -1;
     }
     Py_INCREF(g_get_model_chat_template_func); // Keep a reference
+
+    // Get the render_jinja_template_msgpack function
+    g_render_jinja_template_msgpack_func = PyDict_GetItemString(module_dict, "render_jinja_template_msgpack");
+    if (!g_render_jinja_template_msgpack_func || !PyCallable_Check(g_render_jinja_template_msgpack_func)) {
+        printf("[C] Py_InitChatTemplateModule ERROR - render_jinja_template_msgpack function not found or not callable\n");
+        set_init_error("render_jinja_template_msgpack function not found or not callable");
+        PyGILState_Release(gil_state);
+        PyThread_release_lock(g_init_lock);
+        return -1;
+    }
+    Py_INCREF(g_render_jinja_template_msgpack_func); // Keep a reference
+
+    // Fail early, with the import error as the cause, if transformers cannot be imported
+    PyObject* check_func = PyDict_GetItemString(module_dict, "check_transformers_available");
+    if (check_func && PyCallable_Check(check_func)) {
+        PyObject* check_result = PyObject_CallObject(check_func, NULL);
+        if (!check_result) {
+            printf("[C] Py_InitChatTemplateModule ERROR - transformers is not available\n");
+            set_init_error_from_exception();
+            PyGILState_Release(gil_state);
+            PyThread_release_lock(g_init_lock);
+            return -1;
+        }
+        Py_DECREF(check_result);
+    }
     
     // Release GIL
     PyGILState_Release(gil_state);
@@ -229,6 +346,13 @@ int Py_InitChatTemplateModule() {
 
 
 
+// Take the cause of the last module initialization failure
+char* Py_TakeInitError(void) {
+    char* error = g_init_error;
+    g_init_error = NULL;
+    return error;
+}
+
 // Call the cached render_jinja_template function
 char* Py_CallRenderJinjaTemplate(const char* json_request) {
     // Try direct call first (fast path)
@@ -243,7 +367,8 @@ char* Py_CallRenderJinjaTemplate(const char* json_request) {
 
 // Internal function that does the actual work
 char* Py_CallRenderJinjaTemplateInternal(const char* json_request) {    
-    // Check if Python interpreter is still valid
+    g_call_transient = 0;
+    // Check if Python interpreter is still valid, it may be restarting
     if (!Py_IsInitialized()) {
         printf("[C] Py_CallRenderJinjaTemplateInternal ERROR - Python interpreter not initialized\n");
         return NULL;
@@ -261,6 +386,8 @@ char* Py_CallRenderJinjaTemplateInternal(const char* json_request) {
     PyObject* py_json = PyUnicode_FromString(json_request);
     if (!py_json) {
         printf("[C] Py_CallRenderJinjaTemplateInternal ERROR - Failed to create Python string\n");
+        classify_call_failure();
+        PyErr_Clear();
         PyGILState_Release(gil_state);
         return NULL;
     }
@@ -274,8 +401,11 @@ char* Py_CallRenderJinjaTemplateInternal(const char* json_request) {
         return NULL;
     }    
 
-    // Call the cached function
-    PyObject* py_result = PyObject_CallObject(g_render_jinja_template_func, args);
+    // Call the cached function, holding our own reference across a concurrent hot reload
+    PyObject* func = g_render_jinja_template_func;
+    Py_INCREF(func);
+    PyObject* py_result = PyObject_CallObject(func, args);
+    Py_DECREF(func);
     
     // Clean up args
     Py_DECREF(args);
@@ -293,6 +423,7 @@ char* Py_CallRenderJinjaTemplateInternal(const char* json_request) {
         Py_DECREF(py_result);
     } else {
         printf("[C] Py_CallRenderJinjaTemplateInternal ERROR - Python function returned NULL\n");
+        classify_call_failure();
         PyErr_Print();
         fflush(stderr);
     }
@@ -303,6 +434,162 @@ char* Py_CallRenderJinjaTemplateInternal(const char* json_request) {
     return cresult;
 }
 
+// Call the cached render_jinja_template function, copying the result into buf
+char* Py_CallRenderJinjaTemplateInto(const char* json_request, char* buf, size_t buf_cap, size_t* result_len) {
+    g_call_transient = 0;
+    // Check if Python interpreter is still valid, it may be restarting
+    if (!Py_IsInitialized()) {
+        printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Python interpreter not initialized\n");
+        return NULL;
+    }
+
+    // Simple validation
+    if (!json_request || !result_len) {
+        printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Input is NULL\n");
+        return NULL;
+    }
+    if (!g_render_jinja_template_func) {
+        printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Cached function is NULL\n");
+        return NULL;
+    }
+
+    // Acquire GIL for Python operations
+    PyGILState_STATE gil_state = PyGILState_Ensure();
+    PyObject* py_json = PyUnicode_FromString(json_request);
+    if (!py_json) {
+        printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Failed to create Python string\n");
+        classify_call_failure();
+        PyErr_Clear();
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+    PyObject* args = PyTuple_Pack(1, py_json);
+    Py_DECREF(py_json);
+    if (!args) {
+        printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Failed to create args tuple\n");
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+
+    // Call the cached function, holding our own reference across a concurrent hot reload
+    PyObject* func = g_render_jinja_template_func;
+    Py_INCREF(func);
+    PyObject* py_result = PyObject_CallObject(func, args);
+    Py_DECREF(func);
+    Py_DECREF(args);
+
+    char* cresult = NULL;
+    if (py_result) {
+        Py_ssize_t len = 0;
+        const char* s = PyUnicode_AsUTF8AndSize(py_result, &len);
+        if (s) {
+            // Reuse the caller's buffer when the result fits, falling back to a fresh allocation
+            cresult = (buf && (size_t)len <= buf_cap) ? buf : malloc(len > 0 ? (size_t)len : 1);
+            if (cresult) {
+                memcpy(cresult, s, (size_t)len);
+                *result_len = (size_t)len;
+            } else {
+                printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Failed to allocate result buffer\n");
+            }
+        } else {
+            printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Failed to convert result to C string\n");
+            PyErr_Print();
+        }
+        Py_DECREF(py_result);
+    } else {
+        printf("[C] Py_CallRenderJinjaTemplateInto ERROR - Python function returned NULL\n");
+        classify_call_failure();
+        PyErr_Print();
+        fflush(stderr);
+    }
+
+    // Release GIL
+    PyGILState_Release(gil_state);
+
+    return cresult;
+}
+
+// Call the cached render_jinja_template_msgpack function
+char* Py_CallRenderJinjaTemplateMsgpack(const char* request, size_t request_len, size_t* result_len) {
+    g_call_transient = 0;
+    // Check if Python interpreter is still valid, it may be restarting
+    if (!Py_IsInitialized()) {
+        printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Python interpreter not initialized\n");
+        return NULL;
+    }
+
+    // Simple validation
+    if (!request || !result_len) {
+        printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Input is NULL\n");
+        return NULL;
+    }
+    if (!g_render_jinja_template_msgpack_func) {
+        printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Cached function is NULL\n");
+        return NULL;
+    }
+
+    // Acquire GIL for Python operations
+    PyGILState_STATE gil_state = PyGILState_Ensure();
+    // Create Python bytes from the msgpack request
+    PyObject* py_request = PyBytes_FromStringAndSize(request, (Py_ssize_t)request_len);
+    if (!py_request) {
+        printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Failed to create Python bytes\n");
+        classify_call_failure();
+        PyErr_Clear();
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+
+    // Create arguments tuple
+    PyObject* args = PyTuple_Pack(1, py_request);
+    if (!args) {
+        printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Failed to create args tuple\n");
+        Py_DECREF(py_request);
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+
+    // Call the cached function, holding our own reference across a concurrent hot reload
+    PyObject* func = g_render_jinja_template_msgpack_func;
+    Py_INCREF(func);
+    PyObject* py_result = PyObject_CallObject(func, args);
+    Py_DECREF(func);
+
+    // Clean up args
+    Py_DECREF(args);
+    Py_DECREF(py_request);
+
+    char* cresult = NULL;
+    if (py_result) {
+        // Copy the bytes out, keeping track of the length
+        char* data = NULL;
+        Py_ssize_t len = 0;
+        if (PyBytes_AsStringAndSize(py_result, &data, &len) == 0) {
+            cresult = malloc(len > 0 ? (size_t)len : 1);
+            if (cresult) {
+                memcpy(cresult, data, (size_t)len);
+                *result_len = (size_t)len;
+            } else {
+                printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Failed to allocate result buffer\n");
+            }
+        } else {
+            printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Python function did not return bytes\n");
+            PyErr_Print();
+        }
+        Py_DECREF(py_result);
+    } else {
+        printf("[C] Py_CallRenderJinjaTemplateMsgpack ERROR - Python function returned NULL\n");
+        classify_call_failure();
+        PyErr_Print();
+        fflush(stderr);
+    }
+
+    // Release GIL
+    PyGILState_Release(gil_state);
+
+    return cresult;
+}
+
 // Call the cached get_model_chat_template function
 char* Py_CallGetModelChatTemplate(const char* json_request) {    
     // Try direct call first (fast path)
@@ -368,8 +655,11 @@ char* Py_CallGetModelChatTemplateInternal(const char* json_request) {
         return NULL;
     }
     
-    // Call the cached function
-    PyObject* py_result = PyObject_CallObject(g_get_model_chat_template_func, args);
+    // Call the cached function, holding our own reference across a concurrent hot reload
+    PyObject* func = g_get_model_chat_template_func;
+    Py_INCREF(func);
+    PyObject* py_result = PyObject_CallObject(func, args);
+    Py_DECREF(func);
     
     // Clean up args
     Py_DECREF(args);
@@ -399,6 +689,81 @@ char* Py_CallGetModelChatTemplateInternal(const char* json_request) {
     return cresult;
 }
 
+// Call a function of the chat template module by name, with a JSON request
+char* Py_CallChatTemplateFunction(const char* func_name, const char* json_request) {
+    if (!g_initialized) {
+        printf("[C] Py_CallChatTemplateFunction ERROR - Module not initialized\n");
+        return NULL;
+    }
+
+    // Validate input
+    if (!func_name || !json_request) {
+        printf("[C] Py_CallChatTemplateFunction ERROR - Input is NULL\n");
+        return NULL;
+    }
+
+    PyGILState_STATE gil_state = PyGILState_Ensure();
+
+    // Look up the function in the module dictionary
+    PyObject* func = PyDict_GetItemString(PyModule_GetDict(g_chat_template_module), func_name);
+    if (!func || !PyCallable_Check(func)) {
+        printf("[C] Py_CallChatTemplateFunction ERROR - %s function not found or not callable\n", func_name);
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+    // The module dictionary only lends the function; keep it alive across a concurrent hot reload
+    Py_INCREF(func);
+
+    // Create Python string from JSON request
+    PyObject* py_json = PyUnicode_FromString(json_request);
+    if (!py_json) {
+        printf("[C] Py_CallChatTemplateFunction ERROR - Failed to create Python string\n");
+        Py_DECREF(func);
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+
+    // Create arguments tuple
+    PyObject* args = PyTuple_Pack(1, py_json);
+    if (!args) {
+        printf("[C] Py_CallChatTemplateFunction ERROR - Failed to create args tuple\n");
+        Py_DECREF(py_json);
+        Py_DECREF(func);
+        PyGILState_Release(gil_state);
+        return NULL;
+    }
+
+    // Call the function
+    PyObject* py_result = PyObject_CallObject(func, args);
+
+    // Clean up args
+    Py_DECREF(args);
+    Py_DECREF(py_json);
+    Py_DECREF(func);
+
+    char* cresult = NULL;
+    if (py_result) {
+        // Convert to C string
+        const char* s = PyUnicode_AsUTF8(py_result);
+        if (s) {
+            cresult = strdup(s);
+        } else {
+            printf("[C] Py_CallChatTemplateFunction ERROR - Failed to convert %s result to C string\n", func_name);
+            PyErr_Print();
+        }
+        Py_DECREF(py_result);
+    } else {
+        printf("[C] Py_CallChatTemplateFunction ERROR - %s returned NULL\n", func_name);
+        PyErr_Print();
+        fflush(stderr);
+    }
+
+    // Release GIL
+    PyGILState_Release(gil_state);
+
+    return cresult;
+}
+
 // Clear all caches for testing purposes
 char* Py_ClearCaches() {
     if (!g_initialized) {
@@ -416,7 +781,9 @@ char* Py_ClearCaches() {
         return NULL;
     }
     
+    Py_INCREF(clear_caches_func);
     PyObject* result = PyObject_CallObject(clear_caches_func, NULL);
+    Py_DECREF(clear_caches_func);
     if (!result) {
         printf("[C] Py_ClearCaches ERROR - Failed to call clear_caches function\n");
         PyErr_Print();
@@ -440,15 +807,249 @@ char* Py_ClearCaches() {
     return c_result;
 }
 
+// Look up a callable of a module dictionary, returning a new reference or NULL
+static PyObject* lookup_module_function(PyObject* module_dict, const char* func_name) {
+    PyObject* func = PyDict_GetItemString(module_dict, func_name);
+    if (!func || !PyCallable_Check(func)) {
+        return NULL;
+    }
+    Py_INCREF(func);
+    return func;
+}
+
+// Load a standby instance of the chat template module next to the active one
+// and swap to it once it is healthy. Calls in flight hold their own reference
+// to the old functions, so they complete on the old instance.
+int Py_HotReloadChatTemplateModule() {
+    set_init_error(NULL);
+    if (!g_initialized || !g_chat_template_module) {
+        set_init_error("chat template module not initialized");
+        return -1;
+    }
+
+    PyGILState_STATE gil_state = PyGILState_Ensure();
+
+    PyObject* module = NULL;
+    PyObject* render_func = NULL;
+    PyObject* fetch_func = NULL;
+    PyObject* msgpack_func = NULL;
+    PyObject* check_result = NULL;
+
+    // Build a fresh module object from the same source file as the active one,
+    // registered in sys.modules only once it is swapped in
+    PyObject* importlib_util = PyImport_ImportModule("importlib.util");
+    PyObject* file = PyObject_GetAttrString(g_chat_template_module, "__file__");
+    PyObject* spec = NULL;
+    if (importlib_util && file) {
+        spec = PyObject_CallMethod(importlib_util, "spec_from_file_location", "sO",
+                                   "render_jinja_template_wrapper", file);
+    }
+    if (spec) {
+        module = PyObject_CallMethod(importlib_util, "module_from_spec", "O", spec);
+    }
+    if (module) {
+        PyObject* loader = PyObject_GetAttrString(spec, "loader");
+        PyObject* exec_result = loader ? PyObject_CallMethod(loader, "exec_module", "O", module) : NULL;
+        Py_XDECREF(loader);
+        if (!exec_result) {
+            Py_CLEAR(module);
+        }
+        Py_XDECREF(exec_result);
+    }
+    Py_XDECREF(spec);
+    Py_XDECREF(file);
+    Py_XDECREF(importlib_util);
+    if (!module) {
+        printf("[C] Py_HotReloadChatTemplateModule ERROR - Failed to load standby module\n");
+        set_init_error_from_exception();
+        goto fail;
+    }
+
+    PyObject* module_dict = PyModule_GetDict(module);
+    render_func = lookup_module_function(module_dict, "render_jinja_template");
+    fetch_func = lookup_module_function(module_dict, "get_model_chat_template");
+    msgpack_func = lookup_module_function(module_dict, "render_jinja_template_msgpack");
+    if (!render_func || !fetch_func || !msgpack_func) {
+        printf("[C] Py_HotReloadChatTemplateModule ERROR - Standby module is missing entry points\n");
+        set_init_error("standby module is missing entry points");
+        goto fail;
+    }
+
+    // Health check the standby instance before it takes any traffic
+    PyObject* check_func = PyDict_GetItemString(module_dict, "check_transformers_available");
+    if (check_func && PyCallable_Check(check_func)) {
+        check_result = PyObject_CallObject(check_func, NULL);
+        if (!check_result) {
+            printf("[C] Py_HotReloadChatTemplateModule ERROR - Standby module is not healthy\n");
+            set_init_error_from_exception();
+            goto fail;
+        }
+        Py_DECREF(check_result);
+    }
+
+    // Later imports, such as by Py_InitChatTemplateModule after a Finalize,
+    // must get the swapped in instance rather than the old one
+    if (PyDict_SetItemString(PyImport_GetModuleDict(), "render_jinja_template_wrapper", module) < 0) {
+        printf("[C] Py_HotReloadChatTemplateModule ERROR - Failed to register standby module\n");
+        set_init_error_from_exception();
+        goto fail;
+    }
+
+    // Swap while holding the GIL, then drop the references to the old instance
+    PyObject* old_module = g_chat_template_module;
+    PyObject* old_render_func = g_render_jinja_template_func;
+    PyObject* old_fetch_func = g_get_model_chat_template_func;
+    PyObject* old_msgpack_func = g_render_jinja_template_msgpack_func;
+    g_chat_template_module = module;
+    g_render_jinja_template_func = render_func;
+    g_get_model_chat_template_func = fetch_func;
+    g_render_jinja_template_msgpack_func = msgpack_func;
+    Py_XDECREF(old_render_func);
+    Py_XDECREF(old_fetch_func);
+    Py_XDECREF(old_msgpack_func);
+    Py_XDECREF(old_module);
+
+    PyGILState_Release(gil_state);
+    return 0;
+
+fail:
+    Py_XDECREF(render_func);
+    Py_XDECREF(fetch_func);
+    Py_XDECREF(msgpack_func);
+    Py_XDECREF(module);
+    PyGILState_Release(gil_state);
+    return -1;
+}
+
+// Call a no-argument function of a module, returning a new reference or NULL
+static PyObject* call_module_function(const char* module_name, const char* func_name) {
+    PyObject* module = PyImport_ImportModule(module_name);
+    if (!module) {
+        return NULL;
+    }
+    PyObject* result = PyObject_CallMethod(module, func_name, NULL);
+    Py_DECREF(module);
+    return result;
+}
+
+// Native ID of the calling OS thread
+unsigned long Py_NativeThreadID(void) {
+    return PyThread_get_thread_native_id();
+}
+
+// Serves the calls into Python, exported by executor.go. It never returns.
+extern void goServeExecutorCalls(void);
+
+static void* executor_thread_main(void* arg) {
+    (void)arg;
+    goServeExecutorCalls();
+    return NULL;
+}
+
+int Py_StartExecutorThread(size_t stack_size) {
+    pthread_attr_t attr;
+    int rc = pthread_attr_init(&attr);
+    if (rc != 0) {
+        return rc;
+    }
+    rc = pthread_attr_setstacksize(&attr, stack_size);
+    if (rc == 0) {
+        rc = pthread_attr_setdetachstate(&attr, PTHREAD_CREATE_DETACHED);
+    }
+    if (rc == 0) {
+        pthread_t thread;
+        rc = pthread_create(&thread, &attr, executor_thread_main, NULL);
+    }
+    pthread_attr_destroy(&attr);
+    return rc;
+}
+
+// Get the interpreter's memory statistics
+long long Py_ResidentBytes(void) {
+    // The second field of /proc/self/statm is the resident set size, in pages
+    FILE* statm = fopen("/proc/self/statm", "r");
+    if (!statm) {
+        return -1;
+    }
+    long long size = 0;
+    long long resident = -1;
+    if (fscanf(statm, "%lld %lld", &size, &resident) != 2) {
+        resident = -1;
+    }
+    fclose(statm);
+    if (resident < 0) {
+        return -1;
+    }
+    return resident * (long long)sysconf(_SC_PAGESIZE);
+}
+
+int Py_MemStats(PyMemStatsGo* stats) {
+    if (!g_python_initialized || !Py_IsInitialized()) {
+        printf("[C] Py_MemStats ERROR - Python not initialized\n");
+        return -1;
+    }
+
+    PyGILState_STATE gil_state = PyGILState_Ensure();
+    int status = -1;
+
+    PyObject* blocks = NULL;
+    PyObject* objects = NULL;
+    PyObject* tracing = NULL;
+    PyObject* traced = NULL;
+
+    blocks = call_module_function("sys", "getallocatedblocks");
+    if (!blocks) {
+        goto done;
+    }
+    stats->allocated_blocks = PyLong_AsLongLong(blocks);
+
+    objects = call_module_function("gc", "get_objects");
+    if (!objects) {
+        goto done;
+    }
+    stats->gc_objects = (long long)PyList_Size(objects);
+
+    stats->traced_bytes = 0;
+    tracing = call_module_function("tracemalloc", "is_tracing");
+    if (!tracing) {
+        goto done;
+    }
+    if (PyObject_IsTrue(tracing)) {
+        // get_traced_memory returns (current, peak)
+        traced = call_module_function("tracemalloc", "get_traced_memory");
+        if (!traced) {
+            goto done;
+        }
+        stats->traced_bytes = PyLong_AsLongLong(PyTuple_GetItem(traced, 0));
+    }
+
+    stats->resident_bytes = Py_ResidentBytes();
+    status = PyErr_Occurred() ? -1 : 0;
+
+done:
+    if (status != 0) {
+        printf("[C] Py_MemStats ERROR - Failed to collect memory statistics\n");
+        PyErr_Print();
+    }
+    Py_XDECREF(blocks);
+    Py_XDECREF(objects);
+    Py_XDECREF(tracing);
+    Py_XDECREF(traced);
+    PyGILState_Release(gil_state);
+    return status;
+}
+
 // Clean up cached objects
 void Py_CleanupChatTemplateModule() {
     if (g_initialized && Py_IsInitialized()) {
         PyGILState_STATE state = PyGILState_Ensure();
         Py_XDECREF(g_render_jinja_template_func);
         Py_XDECREF(g_get_model_chat_template_func);
+        Py_XDECREF(g_render_jinja_template_msgpack_func);
         Py_XDECREF(g_chat_template_module);
         g_render_jinja_template_func = NULL;
         g_get_model_chat_template_func = NULL;
+        g_render_jinja_template_msgpack_func = NULL;
         g_chat_template_module = NULL;
         g_initialized = 0;
         PyGILState_Release(state);
@@ -471,6 +1072,10 @@ int Py_ReinitializeGo() {
         Py_DECREF(g_get_model_chat_template_func);
         g_get_model_chat_template_func = NULL;
     }
+    if (g_render_jinja_template_msgpack_func) {
+        Py_DECREF(g_render_jinja_template_msgpack_func);
+        g_render_jinja_template_msgpack_func = NULL;
+    }
     if (g_chat_template_module) {
         Py_DECREF(g_chat_template_module);
         g_chat_template_module = NULL;
@@ -20,7 +20,12 @@ package preprocessing
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	/*
@@ -29,7 +34,9 @@ import (
 	*/
 	"C"
 
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/metrics"
 	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"golang.org/x/time/rate"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -37,6 +44,40 @@ import (
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Name optionally identifies the participant, e.g. an agent in a
+	// multi-agent conversation, for templates rendering `message.name`.
+	Name string `json:"name,omitempty"`
+	// ToolCalls are the tool calls made by an assistant message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the ID of the tool call a `tool` message answers. If set,
+	// it must be the ID of a tool call of an earlier message.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// StructuredContent is the structured result of a `tool` message, such as
+	// a JSON object, passed to templates as `message.content` in place of
+	// Content, so templates can render it with `tojson`.
+	StructuredContent interface{} `json:"structured_content,omitempty"`
+	// Channel is the Harmony channel of the message, such as `analysis` or
+	// `final`, for templates rendering gpt-oss conversations.
+	Channel string `json:"channel,omitempty"`
+	// ContentParts is the content of a multimodal message, such as text and
+	// image references, in place of Content.
+	ContentParts []ContentPart `json:"content_parts,omitempty"`
+}
+
+// ToolCall is a tool call made by an assistant message, as in the OpenAI API.
+type ToolCall struct {
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function called by a ToolCall.
+type ToolCallFunction struct {
+	Name string `json:"name"`
+	// Arguments are the JSON-encoded call arguments, as in the OpenAI API.
+	// Arguments sent as a JSON object decode into their compact encoding.
+	// They are passed to templates in the form set by ToolArgsFormat.
+	Arguments string `json:"arguments"`
 }
 
 // RenderJinjaTemplateRequest represents the request to render a chat template.
@@ -51,6 +92,193 @@ type RenderJinjaTemplateRequest struct {
 	ContinueFinalMessage      bool                   `json:"continue_final_message,omitempty"`
 	AddGenerationPrompt       bool                   `json:"add_generation_prompt,omitempty"`
 	ChatTemplateKWArgs        map[string]interface{} `json:"chat_template_kwargs,omitempty"`
+	// TemplateVars are extra variables merged into the template context, such
+	// as `date_string`. Unlike ChatTemplateKWArgs they may not shadow reserved
+	// template names or the kwargs; such collisions fail the render.
+	TemplateVars map[string]interface{} `json:"template_vars,omitempty"`
+	// RenderVariants also renders the first conversation with the generation
+	// prompt toggled, returning both in RenderJinjaTemplateResponse.Variants.
+	// This is cheaper than two separate renders.
+	RenderVariants bool `json:"render_variants,omitempty"`
+	// TrimTrailingWhitespace strips trailing whitespace from renders without a
+	// generation prompt, so that templates ending turns with newlines still
+	// produce stable prefixes. Renders with a generation prompt are left as is,
+	// since its trailing whitespace is part of the prompt. Trimming happens
+	// before tokenization, so the last token may differ from the untrimmed
+	// render: both sides of a prefix match must use the same setting.
+	TrimTrailingWhitespace bool `json:"trim_trailing_whitespace,omitempty"`
+	// ReturnPerTurnSegments splits each rendered conversation into the text
+	// contributed by each of its messages, returned in
+	// RenderJinjaTemplateResponse.TurnSegments. The conversation is rendered
+	// once per message to find the boundaries, and the template must render
+	// each conversation prefix as a prefix of the full render.
+	ReturnPerTurnSegments bool `json:"return_per_turn_segments,omitempty"`
+	// ReturnRoleBoundaries returns the role and token range of each message
+	// in RenderJinjaTemplateResponse.RoleBoundaries, e.g. to cache at turn
+	// boundaries. It requires ReturnTokenIDs, and renders the conversation
+	// once per message like ReturnPerTurnSegments.
+	ReturnRoleBoundaries bool `json:"return_role_boundaries,omitempty"`
+	// ReturnPerMessageTokens returns the number of tokens attributable to
+	// each message in RenderJinjaTemplateResponse.PerMessageTokens, e.g. to
+	// bill by message, and the tokens of the template itself in
+	// TemplateOverheadTokens. It requires ReturnTokenIDs, and renders the
+	// conversation once per message like ReturnRoleBoundaries.
+	ReturnPerMessageTokens bool `json:"return_per_message_tokens,omitempty"`
+	// MaxMessages, if positive, keeps only the most recent MaxMessages messages
+	// before rendering. Leading system messages are always kept and do not
+	// count towards the window. The number of dropped messages is reported in
+	// RenderJinjaTemplateResponse.DroppedMessages. This is a cheaper, though
+	// coarser, alternative to token-based trimming.
+	MaxMessages int `json:"-"`
+	// TruncationMarker, if set, is the content of a system message inserted
+	// in place of the messages dropped by the MaxMessages window, e.g.
+	// "[earlier messages omitted]", so the model is aware of the omitted
+	// context. It is inserted after the leading system messages, and reported
+	// in RenderJinjaTemplateResponse.TruncationMarkerInserted.
+	TruncationMarker string `json:"-"`
+	// FixedDateTime, if set, is the "now" seen by the template's date
+	// functions such as `strftime_now`, to the second and in its own location,
+	// so date-dependent templates render deterministically.
+	FixedDateTime *time.Time `json:"-"`
+	// Harmony renders the conversation in the Harmony format of gpt-oss
+	// models, with each message's Channel, instead of ChatTemplate.
+	Harmony bool `json:"-"`
+	// ReturnTokenIDs tokenizes the rendered chat on the Python side, using the
+	// tokenizer identified by `Tokenizer`, and returns the IDs in the response.
+	ReturnTokenIDs bool `json:"return_token_ids,omitempty"`
+	// ReturnOffsetMapping also returns, for each token, the range of the
+	// rendered chat it was produced from, in RenderJinjaTemplateResponse.OffsetMapping.
+	// It implies ReturnTokenIDs and requires a fast tokenizer.
+	ReturnOffsetMapping bool             `json:"return_offset_mapping,omitempty"`
+	Tokenizer           *TokenizerSource `json:"tokenizer,omitempty"`
+	// ReturnSystemPromptTokenSpan returns the token range of the leading
+	// system messages in RenderJinjaTemplateResponse.SystemPromptTokenSpan.
+	// It requires ReturnTokenIDs, and renders the system messages once more.
+	ReturnSystemPromptTokenSpan bool `json:"return_system_prompt_token_span,omitempty"`
+	// ReturnGenerationPromptTokens returns the number of trailing tokens
+	// added by the generation prompt in
+	// RenderJinjaTemplateResponse.GenerationPromptTokens. It requires
+	// ReturnTokenIDs, and renders the conversation once more without the
+	// generation prompt when AddGenerationPrompt is set.
+	ReturnGenerationPromptTokens bool `json:"return_generation_prompt_tokens,omitempty"`
+	// ReturnGenerationPromptApplied reports whether AddGenerationPrompt
+	// changed the render in RenderJinjaTemplateResponse.GenerationPromptApplied.
+	// Unless RenderVariants is set, the conversations are rendered once more
+	// without the generation prompt.
+	ReturnGenerationPromptApplied bool `json:"return_generation_prompt_applied,omitempty"`
+	// AddSpecialTokens lets the tokenizer add its special tokens, such as a
+	// BOS token, when tokenizing the rendered chat. Chat templates usually
+	// render them already, so it is off by default.
+	AddSpecialTokens bool `json:"add_special_tokens,omitempty"`
+	// AppendEOS appends the model's EOS token, the `eos_token` template
+	// variable, after a final assistant message, closing the last turn as
+	// training data expects. It has no effect with ContinueFinalMessage and
+	// cannot be combined with AddGenerationPrompt.
+	AppendEOS bool `json:"append_eos,omitempty"`
+	// MaxPromptTokens, if positive, truncates TokenIDs and OffsetMapping to at
+	// most MaxPromptTokens tokens, removing the excess from TruncationSide,
+	// and reports the number removed in RenderJinjaTemplateResponse.TruncatedTokens.
+	// The rendered chats are not truncated. It only applies with ReturnTokenIDs.
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+	// TruncationSide is the side MaxPromptTokens removes tokens from, and so
+	// which turns survive. Empty follows the tokenizer's `truncation_side`,
+	// the model's convention.
+	TruncationSide TruncationSide `json:"truncation_side,omitempty"`
+	// GenerationPromptOverride, if set, is appended in place of the
+	// template's own generation prompt, for deployments using a non-standard
+	// one. It requires AddGenerationPrompt, and a template supporting
+	// generation prompts.
+	GenerationPromptOverride string `json:"generation_prompt_override,omitempty"`
+	// ReturnAssistantStopStrings returns the strings ending an assistant turn
+	// of the template in RenderJinjaTemplateResponse.AssistantStopStrings, so
+	// decoding stops can be configured from the template. It costs an extra
+	// render of a sample conversation.
+	ReturnAssistantStopStrings bool `json:"return_assistant_stop_strings,omitempty"`
+	// ReturnEncoderDecoderInputs splits the rendered chat into the inputs of
+	// an encoder-decoder (seq2seq) model, such as T5 chat variants, in
+	// RenderJinjaTemplateResponse.EncoderInput and DecoderInput. The model is
+	// identified by `Tokenizer`, and detected from its config. Decoder-only
+	// models are rendered as usual.
+	ReturnEncoderDecoderInputs bool `json:"return_encoder_decoder_inputs,omitempty"`
+	// BlockAlign, if positive, is the block size of the KV-cache, in tokens.
+	// How TokenIDs align to blocks of that size is reported in
+	// RenderJinjaTemplateResponse.BlockAlignment, without padding or
+	// otherwise altering the tokens. It requires ReturnTokenIDs.
+	BlockAlign int `json:"-"`
+	// ImagePlaceholder is the text the template renders for each image part,
+	// located to report RenderJinjaTemplateResponse.ImageTokenSpans. Defaults
+	// to `<image>`.
+	ImagePlaceholder string `json:"image_placeholder,omitempty"`
+	// AdditionalSpecialTokens are special tokens registered with the
+	// tokenizer before tokenizing the rendered chat, e.g. those added by a
+	// fine-tune, so each is encoded as a single token ID. The tokenizer of
+	// `Tokenizer` is not modified: a copy with the tokens is cached apart,
+	// for the 16 most recently used sets of tokens.
+	AdditionalSpecialTokens []string `json:"additional_special_tokens,omitempty"`
+	// ToolArgsFormat is the form tool call arguments are passed to the
+	// template in. Empty follows the processor's WithToolArgsFormat.
+	ToolArgsFormat ToolArgsFormat `json:"tool_args_format,omitempty"`
+	// JinjaWhitespace, if set, is the whitespace control the template is
+	// compiled with. Nil follows the processor's WithJinjaWhitespace.
+	JinjaWhitespace *JinjaWhitespace `json:"jinja_whitespace,omitempty"`
+	// DefaultSystemPrompt, if set, is injected as a leading system message
+	// into conversations that do not start with one.
+	DefaultSystemPrompt string `json:"-"`
+	// ReturnNormalizedMessages returns the messages actually rendered, after
+	// system prompt injection, the MaxMessages window and normalization, in
+	// RenderJinjaTemplateResponse.NormalizedMessages, e.g. for debugging.
+	ReturnNormalizedMessages bool `json:"-"`
+	// ToolsInUserMessage, if set, selects whether Tools are rendered in the
+	// first user message or in the system message, for templates supporting
+	// both, such as Llama-3.1's. It is mapped to the template variable of the
+	// model family, and fails the render for templates without one.
+	ToolsInUserMessage *bool `json:"-"`
+	// MaxToolDescriptionBytes, if positive, truncates the description of
+	// each of Tools longer than MaxToolDescriptionBytes bytes to that length,
+	// ending it with "...", to bound the prompt. Values below 3, leaving no
+	// room for the description, fail the render. The truncated tools are
+	// reported in RenderJinjaTemplateResponse.TruncatedTools.
+	MaxToolDescriptionBytes int `json:"-"`
+	// SpecialTokenOverrides replace special tokens of the tokenizer config,
+	// such as `bos_token` or `eos_token`, for this render only, e.g. to
+	// experiment with formats. They take precedence over the same names in
+	// ChatTemplateKWArgs, which carry the fetched special tokens, and are
+	// applied to the tokenizer of ReturnTokenIDs, so that the overriding
+	// tokens are encoded as single special token IDs. The tokenizer would
+	// still add its own special tokens, so they cannot be combined with
+	// AddSpecialTokens. The shared tokenizer is not modified: a copy with the
+	// overrides is cached apart, like for AdditionalSpecialTokens, so
+	// concurrent renders are unaffected.
+	SpecialTokenOverrides map[string]string `json:"special_token_overrides,omitempty"`
+	// ThinkingBudget, if set, bounds the reasoning of templates accepting a
+	// thinking token budget, such as Seed-OSS's. It is mapped to the template
+	// variable of the model family, taking precedence over the same name in
+	// ChatTemplateKWArgs. Templates without one render as if it was unset,
+	// and RenderJinjaTemplateResponse.ThinkingBudgetApplied reports which.
+	ThinkingBudget *int `json:"-"`
+	// TraceParent, if set, is the W3C `traceparent` of the caller's span,
+	// logged by the Python side with the render and echoed in
+	// RenderJinjaTemplateResponse.TraceParent, along with InterpreterDuration.
+	TraceParent string `json:"traceparent,omitempty"`
+
+	// truncatedTools are the names of the tools truncated by prepareRender.
+	truncatedTools []string
+	// thinkingBudgetApplied reports that prepareRender mapped ThinkingBudget
+	// to a template variable.
+	thinkingBudgetApplied bool
+}
+
+// TokenizerSource identifies the tokenizer used to tokenize rendered chats.
+// It follows the same loading rules as FetchChatTemplateRequest, and shares
+// its cache, so a tokenizer loaded by a fetch is reused for tokenization.
+type TokenizerSource struct {
+	Model       string `json:"model"`
+	Revision    string `json:"revision,omitempty"`
+	Token       string `json:"token,omitempty"`
+	IsLocalPath bool   `json:"is_local_path,omitempty"`
+	// Handle is set by TokenizerHandle.Source to use its tokenizer without
+	// looking it up. Zero, or a released handle, looks the tokenizer up.
+	Handle uint64 `json:"handle,omitempty"`
 }
 
 // DeepCopy creates a deep copy of the RenderJinjaTemplateRequest.
@@ -64,6 +292,30 @@ func (req *RenderJinjaTemplateRequest) DeepCopy() (*RenderJinjaTemplateRequest,
 	if err != nil {
 		return nil, err
 	}
+	// Go-only fields are not serialized.
+	out.MaxMessages = req.MaxMessages
+	out.TruncationMarker = req.TruncationMarker
+	out.Harmony = req.Harmony
+	out.BlockAlign = req.BlockAlign
+	out.DefaultSystemPrompt = req.DefaultSystemPrompt
+	out.ReturnNormalizedMessages = req.ReturnNormalizedMessages
+	out.MaxToolDescriptionBytes = req.MaxToolDescriptionBytes
+	if req.FixedDateTime != nil {
+		fixed := *req.FixedDateTime
+		out.FixedDateTime = &fixed
+	}
+	if req.ToolsInUserMessage != nil {
+		toolsInUserMessage := *req.ToolsInUserMessage
+		out.ToolsInUserMessage = &toolsInUserMessage
+	}
+	if req.ThinkingBudget != nil {
+		thinkingBudget := *req.ThinkingBudget
+		out.ThinkingBudget = &thinkingBudget
+	}
+	if req.JinjaWhitespace != nil {
+		jinjaWhitespace := *req.JinjaWhitespace
+		out.JinjaWhitespace = &jinjaWhitespace
+	}
 	return &out, nil
 }
 
@@ -71,13 +323,147 @@ func (req *RenderJinjaTemplateRequest) DeepCopy() (*RenderJinjaTemplateRequest,
 type RenderJinjaTemplateResponse struct {
 	RenderedChats     []string  `json:"rendered_chats"`
 	GenerationIndices [][][]int `json:"generation_indices"`
+	// TokenIDs holds the token IDs of the rendered chat, without any special
+	// tokens added by the tokenizer. Only set when ReturnTokenIDs is requested.
+	TokenIDs []uint32 `json:"token_ids,omitempty"`
+	// OffsetMapping holds, for each of TokenIDs, the [start, end) byte range of
+	// the first rendered chat the token was produced from, so tokens can be
+	// mapped back to text. Whitespace absorbed by the tokenizer's
+	// pre-tokenization may not be covered by any token. Only set when
+	// ReturnOffsetMapping is requested.
+	OffsetMapping [][2]int `json:"offset_mapping,omitempty"`
+	// SystemPromptTokenSpan is the [start, end) range of TokenIDs rendered from
+	// the conversation's leading system messages, e.g. to pin their KV-cache
+	// blocks when the system prompt is stable across requests. A token merged
+	// across the end of the system prompt is excluded. It is [0, 0] when the
+	// conversation has no system prompt, or ReturnSystemPromptTokenSpan or
+	// ReturnTokenIDs is not set.
+	SystemPromptTokenSpan [2]int `json:"system_prompt_token_span"`
+	// TruncatedTokens is the number of tokens removed from TokenIDs to fit
+	// MaxPromptTokens. SystemPromptTokenSpan is adjusted to the kept tokens.
+	TruncatedTokens int `json:"truncated_tokens,omitempty"`
+	// ExceedsModelMaxLength reports that TokenIDs are more than the
+	// tokenizer's `model_max_length`, which engines reject or truncate, by
+	// ModelMaxLengthOverflow tokens. Tokenizers setting no limit never do.
+	// A warning is also added to Warnings.
+	ExceedsModelMaxLength  bool `json:"exceeds_model_max_length,omitempty"`
+	ModelMaxLengthOverflow int  `json:"model_max_length_overflow,omitempty"`
+	// GenerationPromptTokens is the number of trailing TokenIDs added by the
+	// generation prompt, e.g. to account for them apart from the prompt.
+	// Tokens the tokenizer adds, such as BOS, are not counted. It is 0 unless
+	// ReturnGenerationPromptTokens and AddGenerationPrompt are set.
+	GenerationPromptTokens int `json:"generation_prompt_tokens,omitempty"`
+	// GenerationPromptApplied is true if AddGenerationPrompt, or
+	// GenerationPromptOverride, actually changed the rendered chats, i.e. the
+	// model is expected to continue with an assistant turn. It is false for
+	// templates ignoring add_generation_prompt, and unless
+	// ReturnGenerationPromptApplied is set.
+	GenerationPromptApplied bool `json:"generation_prompt_applied,omitempty"`
+	// Variants holds the first conversation rendered with and without the
+	// generation prompt, keyed by VariantWithGenerationPrompt and
+	// VariantWithoutGenerationPrompt. Only set when RenderVariants is requested.
+	Variants map[string]string `json:"variants,omitempty"`
+	// TurnSegments holds, per rendered chat, one segment per input message,
+	// which joined give back the rendered chat. Text before the first message
+	// belongs to the first segment and the generation prompt to the last one.
+	// Only set when ReturnPerTurnSegments is requested.
+	TurnSegments [][]string `json:"turn_segments,omitempty"`
+	// TurnTokenCounts holds the number of tokens each segment of the first
+	// rendered chat tokenizes to on its own, tokens added by the tokenizer
+	// counting towards the first one. Only set when ReturnPerTurnSegments and
+	// ReturnTokenIDs are requested. See WithConsistencyChecks.
+	TurnTokenCounts []int `json:"turn_token_counts,omitempty"`
+	// RoleBoundaries holds the role and [StartToken, EndToken) range of
+	// TokenIDs of each message of the first conversation, in order. A token
+	// merged across two messages belongs to the second one. Tokens before the
+	// first message belong to it, and the generation prompt to the last one.
+	// Only set when ReturnRoleBoundaries is requested.
+	RoleBoundaries []RoleBoundary `json:"role_boundaries,omitempty"`
+	// PerMessageTokens holds the number of tokens of the content of each
+	// message of the first conversation, in order: its content tokenized on
+	// its own, capped to the tokens of its role boundary. The rest of the
+	// tokens, such as role headers, separators and the generation prompt, are
+	// counted in TemplateOverheadTokens, so that both add up to the tokens of
+	// the render before MaxPromptTokens truncation. Only set when
+	// ReturnPerMessageTokens is requested.
+	PerMessageTokens []int `json:"per_message_tokens,omitempty"`
+	// TemplateOverheadTokens is the number of tokens of the render not
+	// attributed to any message by PerMessageTokens.
+	TemplateOverheadTokens int `json:"template_overhead_tokens,omitempty"`
+	// ImageTokenSpans are the [start, end) ranges of TokenIDs rendered from
+	// the image placeholder of each image part of the conversation, in order,
+	// so the serving layer can splice in image embeddings. Only set when
+	// ReturnTokenIDs is requested for a conversation with images.
+	ImageTokenSpans [][2]int `json:"image_token_spans,omitempty"`
+	// BlockAlignment is how TokenIDs align to blocks of
+	// RenderJinjaTemplateRequest.BlockAlign tokens. Only set when BlockAlign
+	// is requested.
+	BlockAlignment *BlockAlignment `json:"block_alignment,omitempty"`
+	// DroppedMessages is the number of messages dropped by the MaxMessages window.
+	DroppedMessages int `json:"dropped_messages,omitempty"`
+	// TruncationMarkerInserted reports that the TruncationMarker message was
+	// inserted in place of the messages dropped by the MaxMessages window.
+	TruncationMarkerInserted bool `json:"truncation_marker_inserted,omitempty"`
+	// TruncatedTools are the names of the tools whose description was
+	// truncated to RenderJinjaTemplateRequest.MaxToolDescriptionBytes.
+	TruncatedTools []string `json:"truncated_tools,omitempty"`
+	// ThinkingBudgetApplied reports that the template accepts a thinking
+	// budget, to which RenderJinjaTemplateRequest.ThinkingBudget was passed.
+	ThinkingBudgetApplied bool `json:"thinking_budget_applied,omitempty"`
+	// NormalizedMessages are the messages rendered, after the Go-side
+	// transformations of the request. Only set when ReturnNormalizedMessages
+	// is requested.
+	NormalizedMessages []ChatMessage `json:"normalized_messages,omitempty"`
+	// TemplateRaised is the message of the template's `raise_exception`
+	// call. Renders raising one fail with ErrTemplateRaised, so it is never
+	// set on the responses returned.
+	TemplateRaised string `json:"template_raised,omitempty"`
+	// UsedFallback reports that the requested template failed and the request
+	// was rendered with the model's last good template instead, see
+	// WithLastGoodFallback.
+	UsedFallback bool `json:"used_fallback,omitempty"`
+	// CompileCacheHit reports whether the template was already compiled by a
+	// previous render. Compiled templates are cached on the Python side, keyed
+	// by template hash, and evicted by ClearCaches.
+	CompileCacheHit bool `json:"compile_cache_hit,omitempty"`
+	// PromptHash is the sha256 hex digest of the rendered prompt, computed
+	// over the source selected by WithPromptHash. Empty unless configured.
+	PromptHash string `json:"prompt_hash,omitempty"`
+	// TraceParent echoes RenderJinjaTemplateRequest.TraceParent, tagging
+	// InterpreterDuration, the time the render spent in the Python
+	// interpreter. Both are only set when TraceParent is requested.
+	TraceParent         string        `json:"traceparent,omitempty"`
+	InterpreterDuration time.Duration `json:"interpreter_duration_ns,omitempty"`
+	// Warnings holds the Python warnings raised while rendering, such as
+	// deprecation warnings, as "Category: message". They do not fail the render.
+	Warnings []string `json:"warnings,omitempty"`
+	// AssistantStopStrings are the strings ending an assistant turn: the text
+	// the template closes assistant messages with, such as `<|eot_id|>`, then
+	// the `eos_token` template variable if different. Only set when
+	// ReturnAssistantStopStrings is requested.
+	AssistantStopStrings []string `json:"assistant_stop_strings,omitempty"`
+	// EncoderInput is the text read by the encoder of an encoder-decoder
+	// model: the conversation's context, without the generation prompt or
+	// the continued final message. DecoderInput is the rest of the rendered
+	// chat, which the decoder starts from. Only set when
+	// ReturnEncoderDecoderInputs is requested for an encoder-decoder model.
+	EncoderInput string `json:"encoder_input,omitempty"`
+	DecoderInput string `json:"decoder_input,omitempty"`
+	// Diagnostics explains the likely causes of a render producing only
+	// whitespace. Only set under EmptyRenderDiagnose, see WithEmptyRenderPolicy.
+	Diagnostics []string `json:"diagnostics,omitempty"`
+	// Harmony reports that the chat was rendered in the Harmony format, either
+	// as requested or because the template emits Harmony channel tokens.
+	Harmony bool `json:"harmony,omitempty"`
 }
 
 // FetchChatTemplateRequest represents the request to fetch a chat template.
 // This is needed if the fields are not set in the `RenderJinjaTemplateRequest`.
 // When called, it will fetch the `chat_template` from the tokenizer.
 // If the tokenizer is not present, it will be fetched from HuggingFace using
-// the `token` if provided.
+// the `token` if provided. With IsLocalPath, Model may also point at a `.gguf`
+// file, in which case the template is read from its `tokenizer.chat_template`
+// metadata.
 type FetchChatTemplateRequest struct {
 	Model        string        `json:"model"`
 	ChatTemplate string        `json:"chat_template,omitempty"`
@@ -85,12 +471,56 @@ type FetchChatTemplateRequest struct {
 	Revision     string        `json:"revision,omitempty"`
 	Token        string        `json:"token,omitempty"`
 	IsLocalPath  bool          `json:"is_local_path,omitempty"`
+	// TemplateName selects one of the model's named templates. When empty,
+	// models with several templates use their `tool_use` template for
+	// requests with Tools, if they have one, and their `default` one otherwise.
+	TemplateName string `json:"template_name,omitempty"`
+	// ExpectedDigest, if set, is the sha256 hex digest (optionally prefixed
+	// with "sha256:") the fetched template must match. A mismatch fails the
+	// fetch with ErrDigestMismatch.
+	ExpectedDigest string `json:"expected_digest,omitempty"`
+}
+
+// FetchOptions holds per-call overrides for FetchChatTemplateWithOptions.
+// They are sent along with the request rather than set in the interpreter's
+// environment, so concurrent fetches with different options never observe
+// each other's overrides.
+type FetchOptions struct {
+	// ProxyURL routes the HuggingFace requests of this fetch through the
+	// given HTTP(S) proxy, instead of the one set with WithHTTPProxy.
+	ProxyURL string
+	// Token overrides the request's HuggingFace token for this fetch.
+	Token string
+}
+
+// fetchChatTemplatePayload is the JSON payload sent to get_model_chat_template.
+type fetchChatTemplatePayload struct {
+	FetchChatTemplateRequest
+	ProxyURL string `json:"proxy_url,omitempty"`
+}
+
+// fetchChatTemplateResult is the JSON result of get_model_chat_template,
+// which holds NotFound when the model or its template does not exist, or
+// only RateLimited when the Hub throttled the request. CacheKey is the key
+// the template is cached under, for the index of IsTemplateCached.
+type fetchChatTemplateResult struct {
+	FetchChatTemplateResponse
+	templateEvictions
+	NotFound    string `json:"not_found,omitempty"`
+	RateLimited string `json:"rate_limited,omitempty"`
+	CacheKey    string `json:"cache_key,omitempty"`
 }
 
 // FetchChatTemplateResponse represents the response from fetching a chat template.
 type FetchChatTemplateResponse struct {
 	ChatTemplate       string                 `json:"chat_template,omitempty"`
 	ChatTemplateKWArgs map[string]interface{} `json:"chat_template_kwargs,omitempty"`
+	// EOSTokenIDs are the token IDs that end generation, read from the model's
+	// generation config and falling back to the tokenizer's EOS token. Models
+	// such as Llama-3 define several (e.g. `<|end_of_text|>` and `<|eot_id|>`).
+	EOSTokenIDs []int `json:"eos_token_ids,omitempty"`
+	// StopTokens are the strings of EOSTokenIDs, in the same order.
+	StopTokens []string `json:"stop_tokens,omitempty"`
 }
 
 // ChatTemplatingProcessor is a processor that handles chat template rendering
@@ -98,34 +528,213 @@ type FetchChatTemplateResponse struct {
 // it caches the `transformers` function `render_jinja_template` for rendering
 // chat templates. It also provides a method to fetch chat templates from the
 // tokenizer or HuggingFace if the tokenizer is not present.
-type ChatTemplatingProcessor struct{}
+type ChatTemplatingProcessor struct {
+	wireFormat            WireFormat
+	customFiltersEnabled  bool
+	strictDecoding        bool
+	jsonNumbers           bool
+	normalization         UnicodeNormalization
+	promptHashSource      PromptHashSource
+	memoryLimit           int64
+	pythonThreadStackSize int
+	modelPolicies         map[string]ModelPolicy
+	resultBuffer          *resultBuffer
+	negativeCacheTTL      time.Duration
+	traceSampleRate       float64
+	warmupConcurrency     int
+	warmupLimiter         *rate.Limiter
+	warmupFetch           warmupFetcher
+	warmupTokenizers      bool
+	pipelineFetchWorkers  int
+	pipelineRenderWorkers int
+	streamWorkers         int
+	streamBatchSize       int
+	renderHook            RenderHook
+	fetchHook             FetchHook
+	httpProxy             string
+	defaultRevision       string
+	templateCacheSize     int
+	emptyRenderPolicy     EmptyRenderPolicy
+	toolArgsFormat        ToolArgsFormat
+	jinjaWhitespace       *JinjaWhitespace
+	systemMergePolicy     SystemMergePolicy
+	assistantMergePolicy  AssistantMergePolicy
+	schemaDialect         SchemaDialect
+	templateLoader        TemplateLoader
+	templateSelector      TemplateSelector
+	consistencyChecks     bool
+	lazyInit              bool
+	idleTimeout           time.Duration
+	prefixHashes          prefixHashCache
+	prefetcher            *prefetcher
+	renderAttempts        int
+	maxMessages           int
+
+	// mu guards initialized, so that Finalize is a no-op unless this
+	// processor was successfully initialized and not yet finalized.
+	mu          sync.Mutex
+	initialized bool
+	// active counts the calls in flight, lastUsed is when the last one
+	// finished and idleTimer finalizes the interpreter once idle, for
+	// processors managing its lifecycle, all guarded by mu.
+	active    int
+	lastUsed  time.Time
+	idleTimer *time.Timer
+	// shuttingDown rejects new calls once Shutdown started, and drained is
+	// closed once the calls in flight are done, both guarded by mu.
+	shuttingDown bool
+	drained      chan struct{}
+	// modelKWArgs are the template variables registered per model with
+	// SetModelKWArgs, guarded by mu.
+	modelKWArgs map[string]map[string]interface{}
+	// lastGoodTemplates are the last templates rendered successfully per
+	// model, nil unless WithLastGoodFallback is set, guarded by mu.
+	lastGoodTemplates map[string]string
+}
 
 // NewChatTemplatingProcessor creates a new instance of ChatTemplatingProcessor.
-func NewChatTemplatingProcessor() *ChatTemplatingProcessor {
-	return &ChatTemplatingProcessor{}
+func NewChatTemplatingProcessor(opts ...Option) *ChatTemplatingProcessor {
+	w := &ChatTemplatingProcessor{
+		negativeCacheTTL:      DefaultNegativeCacheTTL,
+		warmupConcurrency:     DefaultWarmupConcurrency,
+		pipelineFetchWorkers:  DefaultPipelineFetchWorkers,
+		pipelineRenderWorkers: DefaultPipelineRenderWorkers,
+		streamWorkers:         DefaultStreamWorkers,
+		streamBatchSize:       DefaultStreamBatchSize,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
 // Initialize initializes the Python interpreter and caches the module.
-func (w *ChatTemplatingProcessor) Initialize() error {
-	// Initialize Python interpreter - C handles process-level tracking
-	C.Py_InitializeGo()
+func (w *ChatTemplatingProcessor) Initialize() (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.initializeLocked(); err != nil {
+		return err
+	}
+	w.shuttingDown = false
+	return nil
+}
+
+// initializeLocked initializes the interpreter and module, and holds a
+// reference to them unless the processor already does. w.mu must be held.
+func (w *ChatTemplatingProcessor) initializeLocked() error {
+	interpreterRefs.Lock()
+	defer interpreterRefs.Unlock()
+
+	if err := validateProxyURL(w.httpProxy); err != nil {
+		return fmt.Errorf("%w: %w", ErrInitialize, err)
+	}
+	if err := w.startInterpreterThread(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInitialize, err)
+	}
 
-	// Initialize chat template module - C handles module-level tracking
-	result := C.Py_InitChatTemplateModule()
+	var result C.int
+	var cause *C.char
+	if err := cgoThread.run(context.Background(), func() {
+		// Initialize Python interpreter - C handles process-level tracking
+		C.Py_InitializeGo()
+
+		// Initialize chat template module - C handles module-level tracking
+		result = C.Py_InitChatTemplateModule()
+		if result != 0 {
+			cause = C.Py_TakeInitError()
+		}
+	}); err != nil {
+		return fmt.Errorf("%w: %w", ErrInitialize, err)
+	}
 	if result != 0 {
-		return fmt.Errorf("failed to initialize chat template module")
+		if cause != nil {
+			defer C.free(unsafe.Pointer(cause))
+			return fmt.Errorf("%w: %s", ErrInitialize, C.GoString(cause))
+		}
+		return ErrInitialize
 	}
 
+	if err := registerCustomFilters(context.Background()); err != nil {
+		return err
+	}
+	if w.templateCacheSize > 0 {
+		if err := setTemplateCacheSize(context.Background(), w.templateCacheSize); err != nil {
+			return fmt.Errorf("%w: %w", ErrInitialize, err)
+		}
+	}
+
+	// Starting the interpreter again after it was finalized is a restart,
+	// while initializing an interpreter already running is not.
+	if !interpreterLive.Swap(true) && interpreterStarted.Swap(true) {
+		metrics.InterpreterRestarts.Inc()
+	}
+	if !w.initialized {
+		interpreterRefs.count++
+	}
+	w.initialized = true
 	return nil
 }
 
 // Finalize finalizes the Python interpreter and cleans up the module.
+// They are process-wide, so they are kept up until every processor that
+// initialized them is finalized. It is safe to call in any state: it is a
+// no-op if the processor was never initialized, or was already finalized.
 func (w *ChatTemplatingProcessor) Finalize() {
-	// Clean up the module first
-	C.Py_CleanupChatTemplateModule()
+	// The prefetch workers are stopped first, so that none initializes the
+	// interpreter again once finalized.
+	w.stopPrefetcher()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+	}
+	w.finalizeLocked()
+}
+
+// finalizeLocked releases the reference of this processor to the
+// interpreter and module, if it holds one, and finalizes them once no other
+// processor holds one. w.mu must be held.
+func (w *ChatTemplatingProcessor) finalizeLocked() {
+	if !w.initialized {
+		return
+	}
+	w.initialized = false
+
+	interpreterRefs.Lock()
+	defer interpreterRefs.Unlock()
+	interpreterRefs.count--
+	if interpreterRefs.count > 0 {
+		log.Log.V(logging.DEBUG).WithName("Finalize").Info("Keeping the interpreter for other processors",
+			"processors", interpreterRefs.count)
+		return
+	}
 
-	// Then finalize Python interpreter
-	C.Py_FinalizeGo()
+	if live := liveCAllocations.Load(); live != 0 {
+		log.Log.V(logging.TRACE).WithName("Finalize").Info("C allocations not freed at finalize",
+			"count", live)
+	}
+
+	// Extension modules such as tokenizers cannot be imported again once the
+	// interpreter is finalized, so it stays loaded: the memory held by the
+	// caches of the module is released instead.
+	var resp struct{}
+	if err := callPythonFunction(context.Background(), "release_caches", struct{}{}, &resp); err != nil {
+		log.Log.Error(err, "Failed to release the caches of the chat template module")
+	}
+	interpreterLive.Store(false)
+	cachedTemplates.reset()
+	notFoundModels.reset()
+	resetTemplatePins()
+	_ = cgoThread.run(context.Background(), func() {
+		// Clean up the module first
+		C.Py_CleanupChatTemplateModule()
+
+		// Then finalize Python interpreter
+		C.Py_FinalizeGo()
+	})
 }
 
 // RenderChatTemplate renders a chat template using the cached Python function.
@@ -135,26 +744,267 @@ func (w *ChatTemplatingProcessor) Finalize() {
 func (w *ChatTemplatingProcessor) RenderChatTemplate(ctx context.Context,
 	req *RenderJinjaTemplateRequest,
 ) (*RenderJinjaTemplateResponse, error) {
+	if w.renderHook == nil {
+		response, err := w.renderChatTemplateWithRetry(ctx, req)
+		countRenderCancellation(err)
+		return response, err
+	}
+
+	start := time.Now()
+	response, err := w.renderChatTemplateWithRetry(ctx, req)
+	countRenderCancellation(err)
+	w.runRenderHook(ctx, req, response, err, time.Since(start))
+	return response, err
+}
+
+// countRenderCancellation counts a render failed by its context being
+// canceled or timing out.
+func countRenderCancellation(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		metrics.RenderCancellations.Inc()
+	}
+}
+
+// renderChatTemplate renders a chat template for RenderChatTemplate, without running the render hook.
+func (w *ChatTemplatingProcessor) renderChatTemplate(ctx context.Context,
+	req *RenderJinjaTemplateRequest,
+) (_ *RenderJinjaTemplateResponse, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("RenderChatTemplate")
 	if req == nil {
 		traceLogger.Error(nil, "Received nil request")
 		return nil, fmt.Errorf("received nil request")
 	}
 
-	// Convert request to JSON
-	reqJSON, err := json.Marshal(req)
+	req, droppedMessages, err := w.prepareRender(req)
+	if err != nil {
+		traceLogger.Error(err, "Invalid request")
+		return nil, err
+	}
+
+	if err := w.checkMemoryLimit(); err != nil {
+		traceLogger.Error(err, "Rejecting render under memory pressure")
+		return nil, err
+	}
+
+	sample := w.startTraceSample(ctx)
+	var response *RenderJinjaTemplateResponse
+	if w.wireFormat == WireFormatMsgpack {
+		response, err = w.renderChatTemplateMsgpack(ctx, req)
+	} else {
+		response, err = w.renderChatTemplateJSON(ctx, req)
+	}
+	sample.finish(ctx, response, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.finishRender(req, response, droppedMessages); err != nil {
+		traceLogger.Error(err, "Failed to finish the render")
+		return nil, err
+	}
+	return response, nil
+}
+
+// prepareRender validates req and applies the Go-side transformations to its
+// messages, returning the request to send to Python and the number of
+// messages dropped by the MaxMessages window. The input request is not modified.
+func (w *ChatTemplatingProcessor) prepareRender(req *RenderJinjaTemplateRequest,
+) (*RenderJinjaTemplateRequest, int, error) {
+	if err := w.checkMessageLimit(req); err != nil {
+		return nil, 0, err
+	}
+	if err := validateTemplateVars(req); err != nil {
+		return nil, 0, err
+	}
+	if req.AppendEOS && req.AddGenerationPrompt {
+		return nil, 0, fmt.Errorf("append_eos cannot be combined with add_generation_prompt")
+	}
+	if req.GenerationPromptOverride != "" && !req.AddGenerationPrompt {
+		return nil, 0, fmt.Errorf("generation_prompt_override requires add_generation_prompt")
+	}
+	if err := req.TruncationSide.validate(); err != nil {
+		return nil, 0, err
+	}
+	if err := validateBlockAlign(req); err != nil {
+		return nil, 0, err
+	}
+	if err := validateRoleBoundaries(req); err != nil {
+		return nil, 0, err
+	}
+	if req.ReturnSystemPromptTokenSpan && !req.ReturnTokenIDs && !req.ReturnOffsetMapping {
+		return nil, 0, fmt.Errorf("return_system_prompt_token_span requires return_token_ids")
+	}
+	if req.ReturnGenerationPromptTokens && !req.ReturnTokenIDs && !req.ReturnOffsetMapping {
+		return nil, 0, fmt.Errorf("return_generation_prompt_tokens requires return_token_ids")
+	}
+	if req.MaxToolDescriptionBytes > 0 && req.MaxToolDescriptionBytes < len(toolDescriptionEllipsis) {
+		return nil, 0, fmt.Errorf("max_tool_description_bytes must be at least %d to fit the ellipsis, got %d",
+			len(toolDescriptionEllipsis), req.MaxToolDescriptionBytes)
+	}
+	if err := validateTraceParent(req.TraceParent); err != nil {
+		return nil, 0, err
+	}
+	if err := validateContentParts(req); err != nil {
+		return nil, 0, err
+	}
+	if err := validateToolMessages(req); err != nil {
+		return nil, 0, err
+	}
+
+	if w.systemMergePolicy != SystemMergeKeepAll {
+		merged := *req
+		var err error
+		if merged.Conversations, err = mergeSystemMessages(req.Conversations, w.systemMergePolicy); err != nil {
+			return nil, 0, err
+		}
+		req = &merged
+	}
+	if w.assistantMergePolicy == AssistantMergeConcatenate {
+		merged := *req
+		merged.Conversations = mergeAssistantMessages(req.Conversations)
+		req = &merged
+	}
+	if req.DefaultSystemPrompt != "" {
+		injected := *req
+		injected.Conversations = injectSystemPrompt(req.Conversations, req.DefaultSystemPrompt)
+		req = &injected
+	}
+	var droppedMessages int
+	if req.MaxMessages > 0 {
+		windowed := *req
+		windowed.Conversations, droppedMessages = windowMessages(req.Conversations, req.MaxMessages, req.TruncationMarker)
+		req = &windowed
+	}
+	if req.Harmony {
+		harmony := *req
+		harmony.ChatTemplate = harmonyChatTemplate
+		req = &harmony
+	}
+	if len(req.SpecialTokenOverrides) > 0 {
+		overridden, err := applySpecialTokenOverrides(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		req = overridden
+	}
+	if req.ToolsInUserMessage != nil {
+		placed, err := applyToolPlacement(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		req = placed
+	}
+	if req.ThinkingBudget != nil {
+		req = applyThinkingBudget(req)
+	}
+	if w.schemaDialect != SchemaDialectAsIs && len(req.Tools) > 0 {
+		coerced := *req
+		coerced.Tools = coerceToolSchemas(req.Tools, w.schemaDialect)
+		req = &coerced
+	}
+	if req.MaxToolDescriptionBytes > 0 {
+		truncated := *req
+		truncated.Tools, truncated.truncatedTools = truncateToolDescriptions(req.Tools, req.MaxToolDescriptionBytes)
+		req = &truncated
+	}
+	if req.ToolArgsFormat == "" && w.toolArgsFormat != "" {
+		formatted := *req
+		formatted.ToolArgsFormat = w.toolArgsFormat
+		req = &formatted
+	}
+	if err := req.ToolArgsFormat.validate(); err != nil {
+		return nil, 0, err
+	}
+	if req.JinjaWhitespace == nil && w.jinjaWhitespace != nil {
+		whitespace := *req
+		whitespace.JinjaWhitespace = w.jinjaWhitespace
+		req = &whitespace
+	}
+	if w.normalization != NormalizationNone {
+		normalized := *req
+		normalized.Conversations = normalizeMessages(req.Conversations, w.normalization)
+		req = &normalized
+	}
+	return req, droppedMessages, nil
+}
+
+// finishRender completes a response from Python with the Go-side fields.
+func (w *ChatTemplatingProcessor) finishRender(req *RenderJinjaTemplateRequest, response *RenderJinjaTemplateResponse,
+	droppedMessages int,
+) error {
+	if response.TemplateRaised != "" {
+		return fmt.Errorf("%w: %s", ErrTemplateRaised, response.TemplateRaised)
+	}
+	if response.CompileCacheHit {
+		metrics.TemplateCompileCacheHits.Inc()
+	}
+	response.DroppedMessages = droppedMessages
+	response.TruncationMarkerInserted = droppedMessages > 0 && req.TruncationMarker != ""
+	response.TruncatedTools = req.truncatedTools
+	response.ThinkingBudgetApplied = req.thinkingBudgetApplied
+	if req.ReturnNormalizedMessages {
+		response.NormalizedMessages = slices.Clone(req.Conversations)
+	}
+	response.BlockAlignment = blockAlignment(len(response.TokenIDs), req.BlockAlign)
+	if err := w.checkEmptyRender(req, response, droppedMessages); err != nil {
+		return err
+	}
+	if w.consistencyChecks {
+		if err := checkTurnTokenCounts(response); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	response.PromptHash, err = promptHash(response, w.promptHashSource)
+	return err
+}
+
+// renderChatTemplateJSON renders a chat template, passing the request and
+// response across CGO as JSON.
+func (w *ChatTemplatingProcessor) renderChatTemplateJSON(ctx context.Context,
+	req *RenderJinjaTemplateRequest,
+) (*RenderJinjaTemplateResponse, error) {
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("RenderChatTemplate")
+
+	// Convert request to JSON. encoding/json sorts map keys, so identical
+	// requests always produce the same bytes and the same render.
+	reqJSON, err := json.Marshal(newRenderRequestWire(req))
 	if err != nil {
 		traceLogger.Error(err, "Failed to marshal request")
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 	// Call the cached Python function
-	// Note: C.CString allocates C memory that must be freed to avoid memory leaks
-	cReqJSON := C.CString(string(reqJSON))
-	defer C.free(unsafe.Pointer(cReqJSON))
-	cResult := C.Py_CallRenderJinjaTemplate(cReqJSON)
+	// Note: cString allocates C memory that must be freed to avoid memory leaks
+	cReqJSON := cString(string(reqJSON))
+	defer freeC(unsafe.Pointer(cReqJSON))
+
+	if w.resultBuffer != nil {
+		var response RenderJinjaTemplateResponse
+		if err := w.resultBuffer.render(ctx, cReqJSON, &response); err != nil {
+			traceLogger.Error(err, "Failed to render into the result buffer")
+			return nil, err
+		}
+		return &response, nil
+	}
+
+	var cResult *C.char
+	var transient bool
+	if err := cgoThread.run(ctx, func() {
+		cResult = C.Py_CallRenderJinjaTemplate(cReqJSON)
+		transient = C.Py_TakeCallTransient() != 0
+	}); err != nil {
+		return nil, err
+	}
 	if cResult == nil {
 		traceLogger.Error(nil, "C function returned nil")
-		return nil, fmt.Errorf("python render_jinja_template failed")
+		return nil, renderCallError("render_jinja_template", transient)
 	}
 	defer C.free(unsafe.Pointer(cResult))
 	resultJSON := C.GoString(cResult)
@@ -169,6 +1019,48 @@ func (w *ChatTemplatingProcessor) RenderChatTemplate(ctx context.Context,
 	return &response, nil
 }
 
+// renderChatTemplateMsgpack renders a chat template like RenderChatTemplate,
+// exchanging msgpack buffers instead of JSON strings with Python.
+func (w *ChatTemplatingProcessor) renderChatTemplateMsgpack(ctx context.Context,
+	req *RenderJinjaTemplateRequest,
+) (*RenderJinjaTemplateResponse, error) {
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("RenderChatTemplate")
+
+	reqMsgpack, err := marshalMsgpack(newRenderRequestWire(wireNumbers(req)))
+	if err != nil {
+		traceLogger.Error(err, "Failed to marshal request")
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	// Binary payloads may contain NUL bytes, so they are passed with their length.
+	// Note: cBytes allocates C memory that must be freed to avoid memory leaks
+	cReq := cBytes(reqMsgpack)
+	defer freeC(cReq)
+	var cResultLen C.size_t
+	var cResult *C.char
+	var transient bool
+	if err := cgoThread.run(ctx, func() {
+		cResult = C.Py_CallRenderJinjaTemplateMsgpack((*C.char)(cReq), C.size_t(len(reqMsgpack)), &cResultLen)
+		transient = C.Py_TakeCallTransient() != 0
+	}); err != nil {
+		return nil, err
+	}
+	if cResult == nil {
+		traceLogger.Error(nil, "C function returned nil")
+		return nil, renderCallError("render_jinja_template_msgpack", transient)
+	}
+	defer C.free(unsafe.Pointer(cResult))
+	result := C.GoBytes(unsafe.Pointer(cResult), C.int(cResultLen))
+
+	// Parse the response
+	var response RenderJinjaTemplateResponse
+	if err := unmarshalMsgpack(result, &response); err != nil {
+		traceLogger.Error(err, "Failed to unmarshal response")
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &response, nil
+}
+
 // FetchChatTemplate fetches the model chat template using the cached Python function.
 //
 //nolint:gocritic // hugeParam: req is passed by value intentionally for immutability, but can consider using pointer.
@@ -176,47 +1068,233 @@ func (w *ChatTemplatingProcessor) FetchChatTemplate(
 	ctx context.Context,
 	req FetchChatTemplateRequest,
 ) (string, map[string]interface{}, error) {
+	return w.FetchChatTemplateWithOptions(ctx, req, FetchOptions{})
+}
+
+// FetchChatTemplateWithOptions fetches the model chat template like
+// FetchChatTemplate, applying the given overrides to this call only.
+//
+//nolint:gocritic // hugeParam: req is passed by value intentionally for immutability, but can consider using pointer.
+func (w *ChatTemplatingProcessor) FetchChatTemplateWithOptions(
+	ctx context.Context,
+	req FetchChatTemplateRequest,
+	opts FetchOptions,
+) (string, map[string]interface{}, error) {
+	response, err := w.FetchChatTemplateDetails(ctx, req, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	return response.ChatTemplate, response.ChatTemplateKWArgs, nil
+}
+
+// FetchChatTemplateDetails fetches the model chat template like
+// FetchChatTemplateWithOptions, returning the full response, including the
+// model's stop tokens.
+//
+//nolint:gocritic // hugeParam: req is passed by value intentionally for immutability, but can consider using pointer.
+func (w *ChatTemplatingProcessor) FetchChatTemplateDetails(
+	ctx context.Context,
+	req FetchChatTemplateRequest,
+	opts FetchOptions,
+) (*FetchChatTemplateResponse, error) {
+	if w.fetchHook == nil {
+		return w.fetchChatTemplateDetails(ctx, req, opts)
+	}
+
+	start := time.Now()
+	response, err := w.fetchChatTemplateDetails(ctx, req, opts)
+	w.runFetchHook(ctx, &req, response, err, time.Since(start))
+	return response, err
+}
+
+// fetchChatTemplateDetails fetches a chat template for FetchChatTemplateDetails, without running the fetch hook.
+//
+//nolint:gocritic // hugeParam: req is passed by value intentionally for immutability, but can consider using pointer.
+func (w *ChatTemplatingProcessor) fetchChatTemplateDetails(
+	ctx context.Context,
+	req FetchChatTemplateRequest,
+	opts FetchOptions,
+) (_ *FetchChatTemplateResponse, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("FetchChatTemplate")
 
+	if err := validateProxyURL(opts.ProxyURL); err != nil {
+		traceLogger.Error(err, "Invalid proxy URL")
+		return nil, err
+	}
+	if opts.Token != "" {
+		req.Token = opts.Token
+	}
+	req.Revision = w.revisionOrDefault(req.Revision, req.IsLocalPath)
+	if response, ok := w.selectTemplate(ctx, &req); ok {
+		if err := verifyTemplateDigest(response.ChatTemplate, req.ExpectedDigest); err != nil {
+			traceLogger.Error(err, "Selected template does not match the expected digest", "model", req.Model)
+			return nil, err
+		}
+		return response, nil
+	}
+	if response, ok, err := w.loadTemplate(ctx, &req); err != nil || ok {
+		if err == nil {
+			err = verifyTemplateDigest(response.ChatTemplate, req.ExpectedDigest)
+		}
+		if err != nil {
+			traceLogger.Error(err, "Failed to load template", "model", req.Model)
+			return nil, err
+		}
+		return response, nil
+	}
+
 	// Convert request to JSON
-	reqJSON, err := json.Marshal(req)
+	reqJSON, err := json.Marshal(fetchChatTemplatePayload{
+		FetchChatTemplateRequest: req,
+		ProxyURL:                 w.proxyURLOrDefault(opts.ProxyURL),
+	})
 	if err != nil {
 		traceLogger.Error(err, "Failed to marshal request")
-		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	notFoundKey := newNegativeCacheKey(req)
+	if err := notFoundModels.get(notFoundKey, time.Now(), w.negativeCacheTTL); err != nil {
+		return nil, err
 	}
 	// Call the cached Python function
-	// Note: C.CString allocates C memory that must be freed to avoid memory leaks
-	cReqJSON := C.CString(string(reqJSON))
-	defer C.free(unsafe.Pointer(cReqJSON))
-	cResult := C.Py_CallGetModelChatTemplate(cReqJSON)
+	// Note: cString allocates C memory that must be freed to avoid memory leaks
+	cReqJSON := cString(string(reqJSON))
+	defer freeC(unsafe.Pointer(cReqJSON))
+	var cResult *C.char
+	if err := cgoThread.run(ctx, func() { cResult = C.Py_CallGetModelChatTemplate(cReqJSON) }); err != nil {
+		return nil, err
+	}
 	if cResult == nil {
 		traceLogger.Error(nil, "C function returned nil")
-		return "", nil, fmt.Errorf("python get_model_chat_template failed")
+		return nil, fmt.Errorf("python get_model_chat_template failed")
 	}
 	defer C.free(unsafe.Pointer(cResult))
 	resultJSON := C.GoString(cResult)
 
 	// Parse the response
-	var response FetchChatTemplateResponse
-	if err := json.Unmarshal([]byte(resultJSON), &response); err != nil {
+	var result fetchChatTemplateResult
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
 		traceLogger.Error(err, "Failed to unmarshal response")
-		return "", nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	cachedTemplates.update(req.Model, req.Revision, result.CacheKey, result.EvictedTemplates)
+	if result.RateLimited != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrRateLimited, req.Model, result.RateLimited)
 	}
+	if result.NotFound != "" {
+		err := fmt.Errorf("%w: %s: %s", ErrModelNotFound, req.Model, result.NotFound)
+		if w.negativeCacheTTL > 0 {
+			notFoundModels.add(notFoundKey, err, time.Now())
+		}
+		return nil, err
+	}
+	response := result.FetchChatTemplateResponse
 
-	return response.ChatTemplate, response.ChatTemplateKWArgs, nil
+	if err := verifyTemplateDigest(response.ChatTemplate, req.ExpectedDigest); err != nil {
+		traceLogger.Error(err, "Fetched template failed digest verification", "model", req.Model)
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// callPythonFunction calls a function of the chat template Python module with
+// the JSON encoding of req, and decodes its JSON result into resp.
+func callPythonFunction(ctx context.Context, funcName string, req, resp interface{}) error {
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName(funcName)
+
+	// Convert request to JSON
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		traceLogger.Error(err, "Failed to marshal request")
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	// Call the Python function
+	// Note: cString allocates C memory that must be freed to avoid memory leaks
+	cFuncName := cString(funcName)
+	defer freeC(unsafe.Pointer(cFuncName))
+	cReqJSON := cString(string(reqJSON))
+	defer freeC(unsafe.Pointer(cReqJSON))
+	var cResult *C.char
+	if err := cgoThread.run(ctx, func() { cResult = C.Py_CallChatTemplateFunction(cFuncName, cReqJSON) }); err != nil {
+		return err
+	}
+	if cResult == nil {
+		traceLogger.Error(nil, "C function returned nil")
+		return fmt.Errorf("python %s failed", funcName)
+	}
+	defer C.free(unsafe.Pointer(cResult))
+	resultJSON := C.GoString(cResult)
+
+	// Parse the response
+	if err := json.Unmarshal([]byte(resultJSON), resp); err != nil {
+		traceLogger.Error(err, "Failed to unmarshal response")
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
 }
 
 // ClearCaches clears all caches for testing purposes.
-func ClearCaches(ctx context.Context) error {
+func ClearCaches(ctx context.Context) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
 	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("clearCaches")
 
 	// Call the C function
-	cResult := C.Py_ClearCaches()
+	var cResult *C.char
+	if err := cgoThread.run(ctx, func() { cResult = C.Py_ClearCaches() }); err != nil {
+		return err
+	}
 	if cResult == nil {
 		traceLogger.Error(nil, "Failed to clear caches")
 		return fmt.Errorf("failed to clear caches")
 	}
 	defer C.free(unsafe.Pointer(cResult))
+	cachedTemplates.reset()
+	notFoundModels.reset()
+	resetTemplatePins()
 
 	return nil
 }
+
+// interpreterRefs counts the processors holding the interpreter initialized.
+// The interpreter and module are process-wide, so they are only finalized
+// once the last of them is. The mutex serializes initializing and finalizing.
+var interpreterRefs struct {
+	sync.Mutex
+	count int
+}
+
+// interpreterLive reports that the interpreter is initialized, and
+// interpreterStarted that it ever was, so that Initialize counts restarts.
+// The interpreter is process-wide, so they are shared by all processors.
+var interpreterLive, interpreterStarted atomic.Bool
+
+// liveCAllocations counts the C memory allocated by cString and cBytes that
+// has not yet been released with freeC. It is checked on Finalize to detect
+// leaks.
+var liveCAllocations atomic.Int64
+
+// cString is C.CString with leak accounting. The result must be freed with freeC.
+func cString(s string) *C.char {
+	liveCAllocations.Add(1)
+	return C.CString(s)
+}
+
+// cBytes is C.CBytes with leak accounting. The result must be freed with freeC.
+func cBytes(b []byte) unsafe.Pointer {
+	liveCAllocations.Add(1)
+	return C.CBytes(b)
+}
+
+// freeC frees memory allocated by cString or cBytes.
+func freeC(ptr unsafe.Pointer) {
+	liveCAllocations.Add(-1)
+	C.free(ptr)
+}
@@ -50,25 +50,73 @@ const char* PyUnicode_AsGoString(PyObject* obj);
 extern PyObject* g_chat_template_module;
 extern PyObject* g_render_jinja_template_func;
 extern PyObject* g_get_model_chat_template_func;
+extern PyObject* g_render_jinja_template_msgpack_func;
 
 // Initialize the cached module and functions (call once at startup)
 int Py_InitChatTemplateModule();
 
+// Take the cause of the last Py_InitChatTemplateModule failure, including the
+// Python traceback if any. Returns NULL if there is none; the caller must free it.
+char* Py_TakeInitError(void);
+
 // Call the cached render_jinja_template function
 char* Py_CallRenderJinjaTemplate(const char* json_request);
 
 // Internal function that does the actual work
 char* Py_CallRenderJinjaTemplateInternal(const char* json_request);
 
+// Call the cached render_jinja_template function, copying the JSON result, without a
+// terminating NUL, into buf if it fits in buf_cap bytes. Returns buf, a newly allocated
+// buffer the caller must free if the result did not fit, or NULL on failure.
+char* Py_CallRenderJinjaTemplateInto(const char* json_request, char* buf, size_t buf_cap, size_t* result_len);
+
+// Call the cached render_jinja_template_msgpack function.
+// The request and the returned buffer are msgpack-encoded and may contain NUL bytes,
+// so their lengths are passed explicitly. The returned buffer must be freed by the caller.
+char* Py_CallRenderJinjaTemplateMsgpack(const char* request, size_t request_len, size_t* result_len);
+
+// Take whether the last render call on the calling thread failed transiently, on a
+// MemoryError. Calls made while the interpreter is not running are not. Resets it.
+int Py_TakeCallTransient(void);
+
 // Call the cached get_model_chat_template function
 char* Py_CallGetModelChatTemplate(const char* json_request);
 
 // Internal function that does the actual work
 char* Py_CallGetModelChatTemplateInternal(const char* json_request);
 
+// Call a function of the chat template module by name, with a JSON request.
+// Returns the JSON result, which must be freed by the caller, or NULL on failure.
+char* Py_CallChatTemplateFunction(const char* func_name, const char* json_request);
+
 // Clear all caches for testing purposes
 char* Py_ClearCaches(void);
 
+// Load a standby chat template module and swap to it once it is healthy
+int Py_HotReloadChatTemplateModule(void);
+
+// Interpreter memory statistics filled by Py_MemStats
+typedef struct {
+    long long allocated_blocks; // sys.getallocatedblocks()
+    long long gc_objects;       // number of objects tracked by the garbage collector
+    long long traced_bytes;     // current tracemalloc size, 0 unless tracemalloc is tracing
+    long long resident_bytes;   // resident set size of the process, -1 if unknown
+} PyMemStatsGo;
+
+// Fill stats with the interpreter's memory statistics. Returns 0 on success, -1 on failure.
+int Py_MemStats(PyMemStatsGo* stats);
+
+// Resident set size of the process hosting the interpreter, in bytes, or -1 if unknown.
+// It does not take the GIL, so it is cheap enough to call before every render.
+long long Py_ResidentBytes(void);
+
+// Native ID of the calling OS thread. It does not take the GIL.
+unsigned long Py_NativeThreadID(void);
+
+// Start a detached thread with a stack of stack_size bytes, serving the calls into Python
+// from Go. Returns 0 on success, or the pthread error code.
+int Py_StartExecutorThread(size_t stack_size);
+
 // Clean up cached objects
 void Py_CleanupChatTemplateModule();
 
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -826,10 +827,65 @@ func TestFetchChatTemplateLocalPathNonExistent(t *testing.T) {
 	t.Logf("Expected error for non-existent path: %v", err)
 }
 
+// renderLocalTemplate renders a short conversation with the local test model's
+// template, to check the module is usable.
+func renderLocalTemplate(t *testing.T, wrapper *preprocessing.ChatTemplatingProcessor) {
+	t.Helper()
+	ctx := context.Background()
+
+	template, templateVars, err := wrapper.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	response, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+		Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:       template,
+		ChatTemplateKWArgs: templateVars,
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.NotEmpty(t, response.RenderedChats, "Rendered chats should not be empty")
+}
+
+// TestFinalizeWithoutInitialize tests that finalizing a processor that was never initialized is a no-op.
+func TestFinalizeWithoutInitialize(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	processor := preprocessing.NewChatTemplatingProcessor()
+	assert.NotPanics(t, processor.Finalize, "Finalize without Initialize should not panic")
+
+	// The shared module must not have been torn down.
+	renderLocalTemplate(t, wrapper)
+}
+
+// TestDoubleFinalize tests that a second Finalize is a no-op.
+func TestDoubleFinalize(t *testing.T) {
+	globalWrapperMu.Lock()
+	defer globalWrapperMu.Unlock()
+
+	wrapper := getGlobalWrapper()
+
+	processor := preprocessing.NewChatTemplatingProcessor()
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	processor.Finalize()
+	assert.NotPanics(t, processor.Finalize, "Double Finalize should not panic")
+	assert.Equal(t, 1, preprocessing.InterpreterRefs(), "A double Finalize should release a single reference")
+
+	// The module is process-wide, restore it for the other tests.
+	require.NoError(t, wrapper.Initialize(), "Re-Initialize should not return an error")
+	renderLocalTemplate(t, wrapper)
+}
+
 // TestMain provides a controlled setup and teardown for tests in this package.
 func TestMain(m *testing.M) {
-	// Create a new processor to handle initialization.
-	processor := preprocessing.NewChatTemplatingProcessor()
+	// Create a new processor to handle initialization, with the interpreter
+	// thread stack size of helper processes.
+	var opts []preprocessing.Option
+	if stackSize, err := strconv.Atoi(os.Getenv(pythonThreadStackSizeEnv)); err == nil {
+		opts = append(opts, preprocessing.WithPythonThreadStackSize(stackSize))
+	}
+	processor := preprocessing.NewChatTemplatingProcessor(opts...)
 
 	// Set up: Initialize the Python interpreter.
 	log.Log.Info("Initializing Python interpreter for tests...")
@@ -838,6 +894,8 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 	log.Log.Info("Python interpreter initialized successfully.")
+	// The tests share this processor, so that they hold the interpreter initialized once.
+	globalWrapperOnce.Do(func() { globalWrapper = processor })
 
 	// Run all the tests in the package.
 	exitCode := m.Run()
@@ -0,0 +1,62 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// compileTemplateRequest is the JSON payload sent to compile_chat_template.
+type compileTemplateRequest struct {
+	ChatTemplate string `json:"chat_template"`
+}
+
+// compileTemplateResponse is the JSON result of compile_chat_template, empty
+// unless the template has a syntax error.
+type compileTemplateResponse struct {
+	SyntaxError string `json:"syntax_error,omitempty"`
+	Line        int    `json:"line,omitempty"`
+}
+
+// CompileTemplate checks the syntax of a chat template by compiling it with
+// the environment templates are rendered with, without rendering it, so no
+// sample conversation is needed. Invalid templates fail with
+// ErrTemplateSyntax. The compiled template is cached for later renders.
+// Errors only raised while rendering, such as undefined variables or
+// `raise_exception` calls, are not detected.
+func (w *ChatTemplatingProcessor) CompileTemplate(ctx context.Context, template string) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if template == "" {
+		return fmt.Errorf("template cannot be empty")
+	}
+
+	var resp compileTemplateResponse
+	if err := callPythonFunction(ctx, "compile_chat_template",
+		compileTemplateRequest{ChatTemplate: template}, &resp); err != nil {
+		return err
+	}
+	if resp.SyntaxError != "" {
+		return fmt.Errorf("%w: line %d: %s", ErrTemplateSyntax, resp.Line, resp.SyntaxError)
+	}
+	return nil
+}
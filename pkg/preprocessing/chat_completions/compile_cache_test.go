@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTemplateCompileCache tests that repeated renders of a template reuse its compiled form until caches are cleared.
+func TestTemplateCompileCache(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	require.NoError(t, preprocessing.ClearCaches(ctx), "Failed to clear caches")
+
+	request := largeRenderRequest(2)
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.False(t, response.CompileCacheHit, "First render should compile the template")
+
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.True(t, response.CompileCacheHit, "Second render should reuse the compiled template")
+
+	require.NoError(t, preprocessing.ClearCaches(ctx), "Failed to clear caches")
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.False(t, response.CompileCacheHit, "ClearCaches should evict compiled templates")
+}
+
+// BenchmarkRenderChatTemplateCompileCache compares renders of a repeated template, served by the
+// compile cache, with renders of a distinct template each time.
+func BenchmarkRenderChatTemplateCompileCache(b *testing.B) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	b.Run("repeated", func(b *testing.B) {
+		request := largeRenderRequest(4)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := wrapper.RenderChatTemplate(ctx, request); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("unique", func(b *testing.B) {
+		request := largeRenderRequest(4)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// A distinct comment makes every template a compile-cache miss.
+			request.ChatTemplate = fmt.Sprintf("{# %d #}%s", i, wireFormatTestTemplate)
+			if _, err := wrapper.RenderChatTemplate(ctx, request); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
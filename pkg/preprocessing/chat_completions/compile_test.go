@@ -0,0 +1,49 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompileTemplate tests checking the syntax of chat templates without rendering them.
+func TestCompileTemplate(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	t.Run("Valid template", func(t *testing.T) {
+		err := wrapper.CompileTemplate(ctx, `{% for message in messages %}{{ message.role }}: {{ message.content }}
+{% endfor %}`)
+		assert.NoError(t, err, "A valid template should compile")
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		err := wrapper.CompileTemplate(ctx, `{% for message in messages %}
+{{ message.role }}: {{ message.content }`)
+		require.ErrorIs(t, err, preprocessing.ErrTemplateSyntax, "An invalid template should not compile")
+		assert.Contains(t, err.Error(), "line 2", "The error should locate the syntax error")
+	})
+
+	t.Run("Empty template", func(t *testing.T) {
+		assert.Error(t, wrapper.CompileTemplate(ctx, ""), "An empty template should be rejected")
+	})
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"os"
+)
+
+// CompletionOptions are the options of RenderCompletion.
+type CompletionOptions struct {
+	// Revision and Token select the model revision and authenticate to
+	// HuggingFace, as in FetchChatTemplateRequest.
+	Revision string
+	Token    string
+	// ReturnTokenIDs also returns the token IDs of the prompt.
+	ReturnTokenIDs bool
+}
+
+// CompletionResponse is the result of RenderCompletion.
+type CompletionResponse struct {
+	// Text is the prompt with the tokenizer's special tokens, such as BOS,
+	// rendered around it.
+	Text string `json:"text"`
+	// TokenIDs are the token IDs of Text, including the special tokens. Only
+	// set when CompletionOptions.ReturnTokenIDs is requested.
+	TokenIDs []uint32 `json:"token_ids,omitempty"`
+}
+
+// renderCompletionRequest is the JSON payload sent to render_completion.
+type renderCompletionRequest struct {
+	Tokenizer      *TokenizerSource `json:"tokenizer"`
+	Prompt         string           `json:"prompt"`
+	ReturnTokenIDs bool             `json:"return_token_ids,omitempty"`
+}
+
+// RenderCompletion prepares the raw prompt of a completion (non-chat)
+// request for model, a HuggingFace model ID or a local model path, the way
+// it is tokenized for serving: the tokenizer's special tokens, such as BOS,
+// are added, but no chat template is applied. The tokenizer is cached and
+// shared with the renders of chat requests.
+func (w *ChatTemplatingProcessor) RenderCompletion(ctx context.Context, model, prompt string,
+	opts CompletionOptions,
+) (_ *CompletionResponse, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	_, statErr := os.Stat(model)
+	req := renderCompletionRequest{
+		Tokenizer: &TokenizerSource{
+			Model:       model,
+			Revision:    opts.Revision,
+			Token:       opts.Token,
+			IsLocalPath: statErr == nil,
+		},
+		Prompt:         prompt,
+		ReturnTokenIDs: opts.ReturnTokenIDs,
+	}
+	var resp CompletionResponse
+	if err := callPythonFunction(ctx, "render_completion", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderCompletion tests that completion prompts get the tokenizer's special tokens, without a chat template.
+func TestRenderCompletion(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+	const prompt = "The capital of France is"
+
+	response, err := wrapper.RenderCompletion(ctx, testModelPath, prompt,
+		preprocessing.CompletionOptions{ReturnTokenIDs: true})
+	require.NoError(t, err, "RenderCompletion should not return an error")
+	assert.Equal(t, "[CLS]"+prompt+"[SEP]", response.Text, "The test model's special tokens should surround the prompt")
+
+	// Tokenize the raw prompt by rendering it as is, letting the tokenizer add its special tokens.
+	manual, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+		Conversations:    []preprocessing.ChatMessage{{Role: "user", Content: prompt}},
+		ChatTemplate:     "{{ messages[0].content }}",
+		ReturnTokenIDs:   true,
+		AddSpecialTokens: true,
+		Tokenizer:        &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, manual.TokenIDs, response.TokenIDs, "Completion token IDs should match a manual tokenization")
+
+	response, err = wrapper.RenderCompletion(ctx, testModelPath, prompt, preprocessing.CompletionOptions{})
+	require.NoError(t, err, "RenderCompletion should not return an error")
+	assert.Empty(t, response.TokenIDs, "Token IDs should only be returned when requested")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// ContentPartType is the type of a ContentPart, as in the OpenAI API.
+type ContentPartType string
+
+const (
+	// ContentPartText is a part holding text.
+	ContentPartText ContentPartType = "text"
+	// ContentPartImageURL is a part referencing an image by URL or ID. The
+	// image itself is never loaded: templates render it as their image
+	// placeholder.
+	ContentPartImageURL ContentPartType = "image_url"
+	// ContentPartToolCall is a part placing one of the message's ToolCalls,
+	// referenced by ToolCallID, among its other parts, for assistant messages
+	// interleaving text, tool calls and images. The message's ToolCalls still
+	// hold every call, for templates rendering them apart.
+	ContentPartToolCall ContentPartType = "tool_call"
+)
+
+// ContentPart is a part of a multimodal message's content, as in the OpenAI
+// API. Parts are passed to templates in the format of transformers' vision
+// templates: text parts as `{"type": "text", "text": ...}`, image parts as
+// `{"type": "image", "url": ...}` and tool call parts as
+// `{"type": "tool_call", "tool_call": ...}`, the referenced call as it
+// appears in `tool_calls`.
+type ContentPart struct {
+	Type       ContentPartType `json:"type"`
+	Text       string          `json:"text,omitempty"`
+	ImageURL   *ImageURL       `json:"image_url,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+// ImageURL references the image of a ContentPartImageURL part.
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// validateContentParts checks that the messages of req with content parts
+// are well-formed.
+func validateContentParts(req *RenderJinjaTemplateRequest) error {
+	for i := range req.Conversations {
+		message := &req.Conversations[i]
+		if len(message.ContentParts) == 0 {
+			continue
+		}
+		if message.Content != "" {
+			return fmt.Errorf("message %d has both content and content parts", i)
+		}
+		placed := make(map[string]bool)
+		for j, part := range message.ContentParts {
+			switch part.Type {
+			case ContentPartText:
+			case ContentPartImageURL:
+				if part.ImageURL == nil || part.ImageURL.URL == "" {
+					return fmt.Errorf("image part %d of message %d has no image URL", j, i)
+				}
+			case ContentPartToolCall:
+				if !hasToolCall(message.ToolCalls, part.ToolCallID) {
+					return fmt.Errorf("tool call part %d of message %d references unknown tool call %q", j, i, part.ToolCallID)
+				}
+				if placed[part.ToolCallID] {
+					return fmt.Errorf("tool call part %d of message %d repeats tool call %q", j, i, part.ToolCallID)
+				}
+				placed[part.ToolCallID] = true
+			default:
+				return fmt.Errorf("part %d of message %d has unsupported type %q", j, i, part.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// hasToolCall reports whether toolCalls has a call with the non-empty id.
+func hasToolCall(toolCalls []ToolCall, id string) bool {
+	if id == "" {
+		return false
+	}
+	for i := range toolCalls {
+		if toolCalls[i].ID == id {
+			return true
+		}
+	}
+	return false
+}
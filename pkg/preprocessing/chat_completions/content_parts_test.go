@@ -0,0 +1,156 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// llavaTemplate renders each image part as Llava's `<image>` placeholder.
+const llavaTemplate = "{% for message in messages %}{{ message.role | upper }}: " +
+	"{% if message.content is string %}{{ message.content }}{% else %}" +
+	"{% for part in message.content %}{% if part.type == 'image' %}<image>\n{% else %}{{ part.text }}{% endif %}" +
+	"{% endfor %}{% endif %}\n{% endfor %}{% if add_generation_prompt %}ASSISTANT:{% endif %}"
+
+// TestRenderChatTemplateImageParts tests rendering image references as placeholders and locating their tokens.
+func TestRenderChatTemplateImageParts(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	image := func(url string) preprocessing.ContentPart {
+		return preprocessing.ContentPart{
+			Type:     preprocessing.ContentPartImageURL,
+			ImageURL: &preprocessing.ImageURL{URL: url},
+		}
+	}
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{
+			Role: "user",
+			ContentParts: []preprocessing.ContentPart{
+				image("https://example.com/cat.png"),
+				image("image-id-42"),
+				{Type: preprocessing.ContentPartText, Text: "What differs between these images?"},
+			},
+		}},
+		ChatTemplate:        llavaTemplate,
+		AddGenerationPrompt: true,
+		ReturnOffsetMapping: true,
+		Tokenizer:           &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+	}
+
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	rendered := response.RenderedChats[0]
+	assert.Equal(t, "USER: <image>\n<image>\nWhat differs between these images?\nASSISTANT:", rendered)
+
+	require.Len(t, response.ImageTokenSpans, 2, "Each image should have a token span")
+	previousEnd := 0
+	for _, span := range response.ImageTokenSpans {
+		require.Less(t, span[0], span[1], "Image token spans should not be empty")
+		assert.GreaterOrEqual(t, span[0], previousEnd, "Image token spans should be ordered")
+		start, end := response.OffsetMapping[span[0]][0], response.OffsetMapping[span[1]-1][1]
+		assert.Equal(t, "<image>", rendered[start:end], "Image token spans should cover the placeholder")
+		previousEnd = span[1]
+	}
+
+	// The placeholder must match what the template renders for images.
+	mismatched := *request
+	mismatched.ImagePlaceholder = "<|image_pad|>"
+	_, err = wrapper.RenderChatTemplate(ctx, &mismatched)
+	assert.Error(t, err, "A placeholder not rendered for each image should be rejected")
+
+	invalid := *request
+	invalid.Conversations = []preprocessing.ChatMessage{{
+		Role:         "user",
+		Content:      "Describe this image.",
+		ContentParts: []preprocessing.ContentPart{image("image-id-42")},
+	}}
+	_, err = wrapper.RenderChatTemplate(ctx, &invalid)
+	assert.Error(t, err, "A message with both content and content parts should be rejected")
+
+	invalid.Conversations = []preprocessing.ChatMessage{{
+		Role:         "user",
+		ContentParts: []preprocessing.ContentPart{{Type: preprocessing.ContentPartImageURL}},
+	}}
+	_, err = wrapper.RenderChatTemplate(ctx, &invalid)
+	assert.Error(t, err, "An image part without an image URL should be rejected")
+}
+
+// TestRenderChatTemplateInterleavedToolCallParts tests rendering an assistant message's text, tool call and image
+// parts in the order they appear.
+func TestRenderChatTemplateInterleavedToolCallParts(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	template := "{% for message in messages %}{{ message.role }}:" +
+		"{% if message.content is string %}{{ message.content }}{% else %}{% for part in message.content %}" +
+		"{% if part.type == 'text' %}[{{ part.text }}]{% elif part.type == 'image' %}[image]" +
+		"{% elif part.type == 'tool_call' %}[{{ part.tool_call.function.name }} {{ part.tool_call.id }}]{% endif %}" +
+		"{% endfor %}{% endif %}|{{ message.tool_calls | length if message.tool_calls else 0 }}\n{% endfor %}"
+	toolCall := func(id, name string) preprocessing.ToolCall {
+		return preprocessing.ToolCall{
+			ID:       id,
+			Type:     "function",
+			Function: preprocessing.ToolCallFunction{Name: name, Arguments: `{"city":"Paris"}`},
+		}
+	}
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "Show me the weather in Paris."},
+			{
+				Role: "assistant",
+				ContentParts: []preprocessing.ContentPart{
+					{Type: preprocessing.ContentPartText, Text: "Checking the forecast."},
+					{Type: preprocessing.ContentPartToolCall, ToolCallID: "call_2"},
+					{Type: preprocessing.ContentPartImageURL, ImageURL: &preprocessing.ImageURL{URL: "https://example.com/map.png"}},
+					{Type: preprocessing.ContentPartText, Text: "Here is the map."},
+					{Type: preprocessing.ContentPartToolCall, ToolCallID: "call_1"},
+				},
+				ToolCalls: []preprocessing.ToolCall{toolCall("call_1", "get_forecast"), toolCall("call_2", "get_weather")},
+			},
+		},
+		ChatTemplate: template,
+	}
+
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "user:Show me the weather in Paris.|0\n"+
+		"assistant:[Checking the forecast.][get_weather call_2][image][Here is the map.][get_forecast call_1]|2\n",
+		response.RenderedChats[0], "Parts should render in order, with the tool calls still listed")
+
+	invalid := *request
+	invalid.Conversations = []preprocessing.ChatMessage{{
+		Role:         "assistant",
+		ContentParts: []preprocessing.ContentPart{{Type: preprocessing.ContentPartToolCall, ToolCallID: "call_3"}},
+		ToolCalls:    []preprocessing.ToolCall{toolCall("call_1", "get_forecast")},
+	}}
+	_, err = wrapper.RenderChatTemplate(ctx, &invalid)
+	assert.Error(t, err, "A tool call part referencing an unknown tool call should be rejected")
+
+	invalid.Conversations[0].ContentParts = []preprocessing.ContentPart{
+		{Type: preprocessing.ContentPartToolCall, ToolCallID: "call_1"},
+		{Type: preprocessing.ContentPartToolCall, ToolCallID: "call_1"},
+	}
+	_, err = wrapper.RenderChatTemplate(ctx, &invalid)
+	assert.Error(t, err, "A tool call placed twice should be rejected")
+}
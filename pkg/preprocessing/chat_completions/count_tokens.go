@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CountTokensBatch renders and tokenizes reqs in a single call into Python,
+// like RenderChatTemplateBatch, and returns the number of tokens of each
+// rendered chat, in order. It is cheaper than counting the tokens of each
+// request with its own RenderChatTemplate call. Each request must set
+// Tokenizer; ReturnTokenIDs is implied, and reqs are not modified.
+//
+// A request failing only fails its own count, which is -1; the returned error
+// then joins the errors of the failed requests, each prefixed by its index,
+// alongside the counts of the others. When the whole batch fails, the counts
+// are nil.
+func (w *ChatTemplatingProcessor) CountTokensBatch(ctx context.Context,
+	reqs []*RenderJinjaTemplateRequest,
+) ([]int, error) {
+	counts := make([]int, len(reqs))
+	errs := make([]error, len(reqs))
+	tokenized := make([]*RenderJinjaTemplateRequest, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		switch {
+		case req == nil:
+			errs[i] = fmt.Errorf("received nil request")
+		case req.Tokenizer == nil:
+			errs[i] = fmt.Errorf("counting tokens requires a tokenizer")
+		default:
+			withTokens := *req
+			withTokens.ReturnTokenIDs = true
+			tokenized = append(tokenized, &withTokens)
+			indexes = append(indexes, i)
+		}
+	}
+
+	if len(tokenized) > 0 {
+		results, err := w.RenderChatTemplateBatch(ctx, tokenized)
+		if err != nil {
+			return nil, err
+		}
+		for j, result := range results {
+			if result.Err != nil {
+				errs[indexes[j]] = result.Err
+				continue
+			}
+			counts[indexes[j]] = len(result.Response.TokenIDs)
+		}
+	}
+
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			counts[i] = -1
+			failed = append(failed, fmt.Errorf("request %d: %w", i, err))
+		}
+	}
+	return counts, errors.Join(failed...)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountRequest returns a request rendering content with the test model's tokenizer.
+func newCountRequest(content string) *preprocessing.RenderJinjaTemplateRequest {
+	return &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: content}},
+		ChatTemplate:  "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		Tokenizer:     &preprocessing.TokenizerSource{Model: "../../tokenization/testdata/test-model", IsLocalPath: true},
+	}
+}
+
+// TestCountTokensBatch tests that the batch counts match single renders, and that failures only fail their own count.
+func TestCountTokensBatch(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	short, long := newCountRequest("Hello"), newCountRequest("Hello, how are you doing today?")
+	broken := newCountRequest("broken")
+	broken.TemplateVars = map[string]interface{}{"messages": "shadowed"}
+	untokenized := newCountRequest("untokenized")
+	untokenized.Tokenizer = nil
+
+	counts, err := wrapper.CountTokensBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{
+		short, broken, long, untokenized,
+	})
+	require.Error(t, err, "Failed requests should be reported")
+	assert.ErrorIs(t, err, preprocessing.ErrReservedTemplateVar, "The errors of the failed requests should be joined")
+	assert.Contains(t, err.Error(), "request 1: ", "The error should name the failed request")
+	assert.Contains(t, err.Error(), "request 3: ", "The error should name the failed request")
+	require.Len(t, counts, 4, "There should be one count per request")
+	assert.Equal(t, -1, counts[1], "A failed request should count -1")
+	assert.Equal(t, -1, counts[3], "A request without a tokenizer should count -1")
+	assert.False(t, short.ReturnTokenIDs, "The requests should not be modified")
+
+	for i, req := range map[int]*preprocessing.RenderJinjaTemplateRequest{0: short, 2: long} {
+		withTokens := *req
+		withTokens.ReturnTokenIDs = true
+		response, err := wrapper.RenderChatTemplate(ctx, &withTokens)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, len(response.TokenIDs), counts[i], "Request %d should count its rendered tokens", i)
+	}
+	assert.Greater(t, counts[2], counts[0], "A longer message should count more tokens")
+
+	counts, err = wrapper.CountTokensBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{short, long})
+	require.NoError(t, err, "A batch without failures should not return an error")
+	assert.Len(t, counts, 2)
+}
+
+// BenchmarkCountTokensBatch compares counting the tokens of a batch at once with one render per count.
+func BenchmarkCountTokensBatch(b *testing.B) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	reqs := make([]*preprocessing.RenderJinjaTemplateRequest, 32)
+	for i := range reqs {
+		reqs[i] = newCountRequest(fmt.Sprintf("Message number %d, asking about the weather.", i))
+	}
+
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := wrapper.CountTokensBatch(ctx, reqs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, req := range reqs {
+				withTokens := *req
+				withTokens.ReturnTokenIDs = true
+				if _, err := wrapper.RenderChatTemplate(ctx, &withTokens); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// jinjaFilter is a custom Jinja filter, sent to the Python side as is.
+type jinjaFilter struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// customFilters are the Jinja filters registered with RegisterJinjaFilter,
+// by any processor. The rendering environment is process-wide, so all of them
+// are injected into every instance of the module, by Initialize and HotReload.
+var customFilters struct {
+	sync.Mutex
+	filters []jinjaFilter
+}
+
+// addCustomFilter records filter, replacing a filter of the same name.
+func addCustomFilter(filter jinjaFilter) {
+	customFilters.Lock()
+	defer customFilters.Unlock()
+	customFilters.filters = slices.DeleteFunc(customFilters.filters,
+		func(f jinjaFilter) bool { return f.Name == filter.Name })
+	customFilters.filters = append(customFilters.filters, filter)
+}
+
+// registerCustomFilters injects the filters registered by all processors.
+func registerCustomFilters(ctx context.Context) error {
+	customFilters.Lock()
+	filters := slices.Clone(customFilters.filters)
+	customFilters.Unlock()
+	for _, filter := range filters {
+		if err := registerJinjaFilter(ctx, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterJinjaFilter adds a Jinja filter named name to the template
+// rendering environment, for templates relying on filters transformers does
+// not ship. pyCode is a Python expression evaluating to the filter callable,
+// e.g. `lambda s: s.upper()`. It must be trusted, see WithCustomFilters.
+//
+// Filters registered before Initialize are injected by it; once initialized,
+// they are injected right away. The rendering environment is process-wide, so
+// a filter is visible to every processor, and kept by a HotReload of any of
+// them. It requires the processor to be created with WithCustomFilters,
+// otherwise ErrCustomFiltersDisabled is returned.
+func (w *ChatTemplatingProcessor) RegisterJinjaFilter(name, pyCode string) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	if !w.customFiltersEnabled {
+		return fmt.Errorf("%w: cannot register filter %q", ErrCustomFiltersDisabled, name)
+	}
+	if name == "" || pyCode == "" {
+		return fmt.Errorf("filter name and code are required")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	filter := jinjaFilter{Name: name, Code: pyCode}
+	if w.initialized {
+		if err := registerJinjaFilter(context.Background(), filter); err != nil {
+			return err
+		}
+	}
+	addCustomFilter(filter)
+	return nil
+}
+
+// registerJinjaFilter injects filter into the Python rendering environment.
+func registerJinjaFilter(ctx context.Context, filter jinjaFilter) error {
+	var resp struct{}
+	if err := callPythonFunction(ctx, "register_jinja_filter", filter, &resp); err != nil {
+		return fmt.Errorf("failed to register jinja filter %q: %w", filter.Name, err)
+	}
+	return nil
+}
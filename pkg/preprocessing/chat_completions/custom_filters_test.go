@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegisterJinjaFilter tests rendering a template using a registered custom filter.
+func TestRegisterJinjaFilter(t *testing.T) {
+	getGlobalWrapper()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithCustomFilters())
+	require.NoError(t, processor.RegisterJinjaFilter("shout", "lambda s: s.upper() + '!'"))
+	require.NoError(t, processor.Initialize(), "Initialize should inject the registered filters")
+	t.Cleanup(processor.Finalize)
+
+	response, err := processor.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content | shout }}{% endfor %}",
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "HELLO!", response.RenderedChats[0], "The custom filter should be applied")
+
+	t.Run("Invalid code", func(t *testing.T) {
+		err := processor.RegisterJinjaFilter("broken", "not a callable")
+		assert.Error(t, err, "Registering invalid filter code should fail")
+	})
+
+	t.Run("Restricted builtins", func(t *testing.T) {
+		for _, code := range []string{"open", `__import__("os").getcwd`, "eval", "getattr"} {
+			err := processor.RegisterJinjaFilter("escape", code)
+			assert.Error(t, err, "Filter code should not reach %s", code)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		err := preprocessing.NewChatTemplatingProcessor().RegisterJinjaFilter("shout", "lambda s: s")
+		assert.ErrorIs(t, err, preprocessing.ErrCustomFiltersDisabled)
+	})
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// unknownFieldErrorPrefix prefixes the errors encoding/json returns for
+// unknown fields when DisallowUnknownFields is set.
+const unknownFieldErrorPrefix = "json: unknown field "
+
+// DecodeRenderRequest decodes a JSON render request, e.g. from an HTTP body.
+// With WithStrictDecoding, fields RenderJinjaTemplateRequest does not define,
+// at any nesting level, fail with ErrUnknownField naming the offending key.
+func (w *ChatTemplatingProcessor) DecodeRenderRequest(r io.Reader) (*RenderJinjaTemplateRequest, error) {
+	var req RenderJinjaTemplateRequest
+	if err := w.decodeRenderRequestInto(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// decodeRenderRequestInto decodes a JSON render request over req, as
+// DecodeRenderRequest does, keeping the fields of req it does not set.
+func (w *ChatTemplatingProcessor) decodeRenderRequestInto(r io.Reader, req *RenderJinjaTemplateRequest) error {
+	decoder := json.NewDecoder(r)
+	if w.strictDecoding {
+		decoder.DisallowUnknownFields()
+	}
+	if w.jsonNumbers {
+		decoder.UseNumber()
+	}
+
+	if err := decoder.Decode(req); err != nil {
+		// encoding/json has no typed error for unknown fields.
+		if field, ok := strings.CutPrefix(err.Error(), unknownFieldErrorPrefix); ok {
+			return fmt.Errorf("%w: %s", ErrUnknownField, field)
+		}
+		return fmt.Errorf("failed to decode request: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeRenderRequestStrict tests that a misspelled field is rejected under strict decoding only.
+func TestDecodeRenderRequestStrict(t *testing.T) {
+	const body = `{"messages": [{"role": "user", "content": "Hello"}], "add_generation_promt": true}`
+
+	strict := preprocessing.NewChatTemplatingProcessor(preprocessing.WithStrictDecoding())
+	_, err := strict.DecodeRenderRequest(strings.NewReader(body))
+	require.ErrorIs(t, err, preprocessing.ErrUnknownField)
+	assert.Contains(t, err.Error(), `"add_generation_promt"`, "Error should name the unknown field")
+
+	lenient := preprocessing.NewChatTemplatingProcessor()
+	req, err := lenient.DecodeRenderRequest(strings.NewReader(body))
+	require.NoError(t, err, "Unknown fields should be ignored without strict decoding")
+	assert.False(t, req.AddGenerationPrompt, "The misspelled field should have no effect")
+
+	req, err = strict.DecodeRenderRequest(strings.NewReader(
+		`{"messages": [{"role": "user", "content": "Hello"}], "add_generation_prompt": true}`))
+	require.NoError(t, err, "Known fields should decode under strict decoding")
+	assert.True(t, req.AddGenerationPrompt)
+	assert.Len(t, req.Conversations, 1)
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+// WithDefaultRevision sets the revision fetched for HuggingFace models of
+// requests without a Revision, e.g. a tag or commit pinning templates for
+// reproducible serving, instead of the latest "main". A request's Revision
+// takes precedence; local paths have no revisions. ModelMaxLength,
+// PreloadTokenizer and LoadTokenizer follow it too. IsTemplateCached does
+// not know the processor, so it must be given the revision explicitly.
+func WithDefaultRevision(revision string) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.defaultRevision = revision
+	}
+}
+
+// revisionOrDefault returns revision, or the default revision set with
+// WithDefaultRevision for an unset revision of a HuggingFace model.
+func (w *ChatTemplatingProcessor) revisionOrDefault(revision string, isLocalPath bool) string {
+	if revision != "" || isLocalPath {
+		return revision
+	}
+	return w.defaultRevision
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDefaultRevision tests that fetches without a revision use the processor's default revision.
+func TestDefaultRevision(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	loader := &memoryTemplateLoader{templates: map[string]string{
+		"acme/chat-model@v1": "v1 template",
+		"acme/chat-model@v2": "v2 template",
+		testModelPath + "@":  "local template",
+	}}
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTemplateLoader(loader),
+		preprocessing.WithDefaultRevision("v1"))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	for _, tt := range []struct {
+		name     string
+		req      preprocessing.FetchChatTemplateRequest
+		expected string
+	}{
+		{"Default", preprocessing.FetchChatTemplateRequest{Model: "acme/chat-model"}, "v1 template"},
+		{"Override", preprocessing.FetchChatTemplateRequest{Model: "acme/chat-model", Revision: "v2"}, "v2 template"},
+		{"Local path", preprocessing.FetchChatTemplateRequest{Model: testModelPath, IsLocalPath: true}, "local template"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched, _, err := processor.FetchChatTemplate(ctx, tt.req)
+			require.NoError(t, err, "FetchChatTemplate should not return an error")
+			assert.Equal(t, tt.expected, fetched)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const sha256DigestPrefix = "sha256:"
+
+// TemplateDigest returns the sha256 hex digest of a chat template, in the
+// form expected by FetchChatTemplateRequest.ExpectedDigest.
+func TemplateDigest(template string) string {
+	sum := sha256.Sum256([]byte(template))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyTemplateDigest checks the template against the expected digest.
+// An empty expected digest disables the verification.
+func verifyTemplateDigest(template, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	expected = strings.TrimPrefix(strings.ToLower(expected), sha256DigestPrefix)
+	if actual := TemplateDigest(template); actual != expected {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchChatTemplateDigestVerification tests that pinned template digests are verified.
+func TestFetchChatTemplateDigestVerification(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	request := preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	}
+
+	template, _, err := wrapper.FetchChatTemplate(context.Background(), request)
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	digest := preprocessing.TemplateDigest(template)
+
+	t.Run("Matching digest", func(t *testing.T) {
+		pinned := request
+		pinned.ExpectedDigest = "sha256:" + digest
+
+		fetched, _, err := wrapper.FetchChatTemplate(context.Background(), pinned)
+		require.NoError(t, err, "FetchChatTemplate should accept a matching digest")
+		assert.Equal(t, template, fetched, "Pinned fetch should return the same template")
+	})
+
+	t.Run("Mismatched digest", func(t *testing.T) {
+		pinned := request
+		pinned.ExpectedDigest = preprocessing.TemplateDigest(template + "changed")
+
+		fetched, vars, err := wrapper.FetchChatTemplate(context.Background(), pinned)
+		require.ErrorIs(t, err, preprocessing.ErrDigestMismatch, "FetchChatTemplate should reject a mismatched digest")
+		assert.Empty(t, fetched, "ChatTemplate should be empty on error")
+		assert.Nil(t, vars, "ChatTemplate vars should be nil on error")
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EmptyRenderPolicy selects how renders producing no output, e.g. from a
+// template filtering out every message, are reported. They would otherwise
+// silently reach serving as empty prompts.
+type EmptyRenderPolicy int
+
+const (
+	// EmptyRenderAllow returns empty renders as is.
+	EmptyRenderAllow EmptyRenderPolicy = iota
+	// EmptyRenderDiagnose returns empty renders with
+	// RenderJinjaTemplateResponse.Diagnostics explaining their likely cause.
+	EmptyRenderDiagnose
+	// EmptyRenderError fails empty renders with ErrEmptyRender, carrying the
+	// diagnostics.
+	EmptyRenderError
+)
+
+// String returns the name of the empty render policy.
+func (p EmptyRenderPolicy) String() string {
+	switch p {
+	case EmptyRenderAllow:
+		return "allow"
+	case EmptyRenderDiagnose:
+		return "diagnose"
+	case EmptyRenderError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// WithEmptyRenderPolicy sets how renders producing only whitespace are
+// reported. Defaults to EmptyRenderAllow.
+func WithEmptyRenderPolicy(policy EmptyRenderPolicy) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.emptyRenderPolicy = policy
+	}
+}
+
+// checkEmptyRender applies the empty render policy to a response of req.
+func (w *ChatTemplatingProcessor) checkEmptyRender(req *RenderJinjaTemplateRequest,
+	response *RenderJinjaTemplateResponse, droppedMessages int,
+) error {
+	if w.emptyRenderPolicy == EmptyRenderAllow {
+		return nil
+	}
+	for i, chat := range response.RenderedChats {
+		if strings.TrimSpace(chat) != "" {
+			continue
+		}
+
+		diagnostics := emptyRenderDiagnostics(req, droppedMessages)
+		if w.emptyRenderPolicy == EmptyRenderError {
+			return fmt.Errorf("%w: rendered chat %d: %s", ErrEmptyRender, i, strings.Join(diagnostics, "; "))
+		}
+		response.Diagnostics = append(response.Diagnostics, diagnostics...)
+	}
+	return nil
+}
+
+// emptyRenderDiagnostics explains the likely causes of req rendering empty.
+func emptyRenderDiagnostics(req *RenderJinjaTemplateRequest, droppedMessages int) []string {
+	if len(req.Conversations) == 0 {
+		return []string{"the conversation has no messages"}
+	}
+
+	var roles []string
+	for _, message := range req.Conversations {
+		if !slices.Contains(roles, message.Role) {
+			roles = append(roles, message.Role)
+		}
+	}
+	diagnostics := []string{fmt.Sprintf(
+		"the template rendered nothing for %d messages with roles %s: it may filter out these roles, "+
+			"or read message fields they do not set", len(req.Conversations), strings.Join(roles, ", "))}
+	if droppedMessages > 0 {
+		diagnostics = append(diagnostics, fmt.Sprintf("%d messages were dropped by MaxMessages", droppedMessages))
+	}
+	return diagnostics
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEmptyRenderPolicy tests that renders producing nothing are diagnosed or fail per the policy.
+func TestEmptyRenderPolicy(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	// The template only renders user messages, so a conversation without any renders nothing.
+	newRequest := func() *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "system", Content: "You are a helpful assistant."}},
+			ChatTemplate: "{% for message in messages %}{% if message.role == 'user' %}" +
+				"{{ message.content }}\n{% endif %}{% endfor %}",
+		}
+	}
+
+	t.Run("Allow", func(t *testing.T) {
+		response, err := preprocessing.NewChatTemplatingProcessor().RenderChatTemplate(ctx, newRequest())
+		require.NoError(t, err, "Empty renders should be allowed by default")
+		assert.Empty(t, response.Diagnostics)
+	})
+
+	t.Run("Diagnose", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithEmptyRenderPolicy(preprocessing.EmptyRenderDiagnose))
+		response, err := processor.RenderChatTemplate(ctx, newRequest())
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Empty(t, response.RenderedChats[0])
+		require.NotEmpty(t, response.Diagnostics, "The empty render should be diagnosed")
+		assert.Contains(t, response.Diagnostics[0], "roles system", "The diagnostic should name the rendered roles")
+
+		request := newRequest()
+		request.Conversations = []preprocessing.ChatMessage{}
+		response, err = processor.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, []string{"the conversation has no messages"}, response.Diagnostics)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithEmptyRenderPolicy(preprocessing.EmptyRenderError))
+		_, err := processor.RenderChatTemplate(ctx, newRequest())
+		require.ErrorIs(t, err, preprocessing.ErrEmptyRender, "The empty render should fail")
+		assert.Contains(t, err.Error(), "roles system", "The error should carry the diagnostic")
+
+		request := newRequest()
+		request.Conversations = append(request.Conversations, preprocessing.ChatMessage{Role: "user", Content: "Hello"})
+		_, err = processor.RenderChatTemplate(ctx, request)
+		assert.NoError(t, err, "Renders with output should not fail")
+	})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateEncoderDecoderInputs tests splitting renders into encoder and decoder inputs for seq2seq models.
+func TestRenderChatTemplateEncoderDecoderInputs(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	// The test model with a T5-style config.
+	seq2seqModel := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.CopyFS(seq2seqModel, os.DirFS(testModelPath)))
+	configPath := filepath.Join(seq2seqModel, "config.json")
+	config, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	config = []byte(strings.Replace(string(config), `"model_type": "bert",`,
+		`"model_type": "t5", "is_encoder_decoder": true,`, 1))
+	require.NoError(t, os.WriteFile(configPath, config, 0o600))
+
+	template := "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}" +
+		"{% if add_generation_prompt %}assistant:{% endif %}"
+	render := func(t *testing.T, model string, conversation []preprocessing.ChatMessage,
+		continueFinalMessage bool,
+	) *preprocessing.RenderJinjaTemplateResponse {
+		t.Helper()
+		response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:              conversation,
+			ChatTemplate:               template,
+			AddGenerationPrompt:        !continueFinalMessage,
+			ContinueFinalMessage:       continueFinalMessage,
+			Tokenizer:                  &preprocessing.TokenizerSource{Model: model, IsLocalPath: true},
+			ReturnEncoderDecoderInputs: true,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response
+	}
+	conversation := []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}}
+
+	t.Run("Generation prompt", func(t *testing.T) {
+		response := render(t, seq2seqModel, conversation, false)
+		assert.Equal(t, "user: Hello\n", response.EncoderInput)
+		assert.Equal(t, "assistant:", response.DecoderInput)
+		assert.Equal(t, response.RenderedChats[0], response.EncoderInput+response.DecoderInput)
+	})
+
+	t.Run("Continued final message", func(t *testing.T) {
+		response := render(t, seq2seqModel,
+			append(conversation, preprocessing.ChatMessage{Role: "assistant", Content: "Hi there"}), true)
+		assert.Equal(t, "user: Hello\n", response.EncoderInput)
+		assert.Equal(t, "assistant: Hi there", response.DecoderInput)
+	})
+
+	t.Run("Decoder-only model", func(t *testing.T) {
+		response := render(t, testModelPath, conversation, false)
+		assert.Empty(t, response.EncoderInput, "Decoder-only models should not be split")
+		assert.Empty(t, response.DecoderInput, "Decoder-only models should not be split")
+	})
+}
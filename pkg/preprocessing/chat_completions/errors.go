@@ -0,0 +1,110 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "errors"
+
+var (
+	// ErrInitialize is returned when the chat template module cannot be
+	// initialized. The wrapping error carries the Python cause, such as a
+	// missing or incompatible `transformers` package.
+	ErrInitialize = errors.New("failed to initialize chat template module")
+
+	// ErrDigestMismatch is returned when a fetched chat template does not match
+	// the digest pinned in FetchChatTemplateRequest.ExpectedDigest.
+	ErrDigestMismatch = errors.New("chat template digest mismatch")
+
+	// ErrReservedTemplateVar is returned when RenderJinjaTemplateRequest.TemplateVars
+	// collides with a reserved template name or with ChatTemplateKWArgs.
+	ErrReservedTemplateVar = errors.New("template var collides with a reserved name")
+
+	// ErrCustomFiltersDisabled is returned by RegisterJinjaFilter unless the
+	// processor was created with WithCustomFilters.
+	ErrCustomFiltersDisabled = errors.New("custom jinja filters are disabled")
+
+	// ErrUnknownField is returned by DecodeRenderRequest under strict decoding
+	// when the request has a field RenderJinjaTemplateRequest does not define.
+	ErrUnknownField = errors.New("unknown request field")
+
+	// ErrHotReload is returned by HotReload when the standby chat template
+	// module cannot be loaded or fails its health check. The active module
+	// keeps serving renders.
+	ErrHotReload = errors.New("failed to hot reload chat template module")
+
+	// ErrNotInitialized is returned by the health probes of a processor that
+	// was not initialized, or was finalized.
+	ErrNotInitialized = errors.New("chat template processor is not initialized")
+
+	// ErrShuttingDown is returned by the calls of a processor once Shutdown
+	// started, until it is initialized again.
+	ErrShuttingDown = errors.New("chat template processor is shutting down")
+
+	// ErrMemoryPressure is returned by RenderChatTemplate, before rendering,
+	// when the process exceeds the limit set with WithMemoryLimit.
+	ErrMemoryPressure = errors.New("memory limit exceeded")
+
+	// ErrPrefixMismatch is returned by RenderSuffix when the conversation no
+	// longer renders to the tokens cached for its session.
+	ErrPrefixMismatch = errors.New("cached prefix does not match the conversation")
+
+	// ErrModelNotFound is returned by FetchChatTemplate when the model, its
+	// revision or its chat template does not exist. Such failures are cached
+	// for the TTL set with WithNegativeCacheTTL.
+	ErrModelNotFound = errors.New("model or chat template not found")
+
+	// ErrRateLimited is returned by FetchChatTemplate when the HuggingFace Hub
+	// rejects the request with HTTP 429. Unlike ErrModelNotFound it is not
+	// cached, as the fetch may succeed when retried later.
+	ErrRateLimited = errors.New("rate limited by the model hub")
+
+	// ErrTemplateSyntax is returned by CompileTemplate when a chat template
+	// is not valid Jinja. The wrapping error carries the line and message.
+	ErrTemplateSyntax = errors.New("chat template syntax error")
+
+	// ErrEmptyRender is returned under EmptyRenderError when a render
+	// produces only whitespace. The wrapping error carries the diagnostics.
+	ErrEmptyRender = errors.New("chat template rendered nothing")
+
+	// ErrInconsistentRender is returned under WithConsistencyChecks when the
+	// token counts of the turn segments do not add up to the rendered tokens.
+	ErrInconsistentRender = errors.New("inconsistent render")
+
+	// ErrDuplicateSystemMessages is returned under SystemMergeError when a
+	// conversation has more than one system message.
+	ErrDuplicateSystemMessages = errors.New("conversation has more than one system message")
+
+	// ErrTooManyMessages is returned, before rendering, for conversations
+	// with more messages than the limit set with WithMaxMessages.
+	ErrTooManyMessages = errors.New("conversation has too many messages")
+
+	// ErrTemplateRaised is returned when a chat template rejects a
+	// conversation by calling `raise_exception`, e.g. on a system message
+	// after a user one. The wrapping error carries the template's message.
+	ErrTemplateRaised = errors.New("chat template raised an exception")
+
+	// ErrTransient is returned when a call fails on an interpreter state
+	// expected to clear by itself, so that the same call may succeed when
+	// retried: renders raising a MemoryError. Template and syntax errors,
+	// and calls made while the interpreter is not running, are never
+	// transient.
+	ErrTransient = errors.New("transient interpreter error")
+
+	// ErrInternal is returned when a call panics, e.g. on a bad conversion of
+	// C memory, instead of crashing the process. The wrapping error carries
+	// the panic value and stack.
+	ErrInternal = errors.New("internal error")
+)
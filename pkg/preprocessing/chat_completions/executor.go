@@ -0,0 +1,138 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+//nolint: gocritic // C and unsafe are considered dups by the linter.
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	/*
+		#include "cgo_functions.h"
+	*/
+	"C"
+)
+
+// executor runs functions one at a time on a single goroutine locked to its
+// OS thread. CPython ties interpreter state to OS threads, and the GIL
+// serializes calls into the interpreter anyway, so every call into Python is
+// funneled through the process-wide cgoThread instead of running on whichever
+// thread the calling goroutine happens to be scheduled on.
+type executor struct {
+	start sync.Once
+	calls chan func()
+	// started is closed once the thread serves calls, and threadID is its
+	// native ID.
+	started  chan struct{}
+	threadID uint64
+	// stackSize is the stack size the thread was started with, in bytes, or
+	// 0 for the default stack of Go threads; startErr is why it failed to.
+	stackSize int
+	startErr  error
+}
+
+// cgoThread is the executor all calls into Python go through. Like the
+// interpreter, it lives for the whole process, so a processor may be
+// finalized and initialized again on the same thread.
+var cgoThread = &executor{calls: make(chan func()), started: make(chan struct{})}
+
+// run runs fn on the locked thread and waits for it to return. If ctx is
+// done before fn starts, fn is skipped and the context error is returned;
+// once started, fn runs to completion since calls into C cannot be
+// interrupted. A panic of fn is returned as an ErrInternal error.
+func (e *executor) run(ctx context.Context, fn func()) error {
+	if err := e.startWithStackSize(0); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	var panicErr error
+	select {
+	case e.calls <- func() {
+		defer close(done)
+		// A panic would otherwise end the locked goroutine, and the process.
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr = internalError(r)
+			}
+		}()
+		if hook := faultHook.Load(); hook != nil {
+			(*hook)()
+		}
+		fn()
+	}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-done
+	return panicErr
+}
+
+// startWithStackSize starts the thread serving calls, unless it is already
+// started, with a stack of stackSize bytes, or the default stack of Go
+// threads if 0. It fails if the thread was started with another stack size.
+func (e *executor) startWithStackSize(stackSize int) error {
+	e.start.Do(func() {
+		e.stackSize = stackSize
+		e.startErr = e.loop()
+	})
+	if e.startErr != nil {
+		return e.startErr
+	}
+	if stackSize != 0 && stackSize != e.stackSize {
+		return fmt.Errorf("the interpreter thread already runs with a stack size of %d bytes, not %d",
+			e.stackSize, stackSize)
+	}
+	return nil
+}
+
+// loop starts the thread serving calls, returning once it serves them. With
+// a stack size, the thread is started from C, which calls back into
+// serve; the goroutine of the callback is bound to the thread.
+func (e *executor) loop() error {
+	if e.stackSize == 0 {
+		go func() {
+			// The thread is never unlocked, so that the interpreter state bound to
+			// it stays with this goroutine.
+			runtime.LockOSThread()
+			e.serve()
+		}()
+	} else if rc := C.Py_StartExecutorThread(C.size_t(e.stackSize)); rc != 0 {
+		return fmt.Errorf("failed to start the interpreter thread: pthread error %d", int(rc))
+	}
+	<-e.started
+	return nil
+}
+
+// serve serves calls on the calling thread, forever.
+func (e *executor) serve() {
+	e.threadID = currentThreadID()
+	close(e.started)
+	for call := range e.calls {
+		call()
+	}
+}
+
+// currentThreadID returns the native ID of the calling OS thread.
+func currentThreadID() uint64 {
+	return uint64(C.Py_NativeThreadID())
+}
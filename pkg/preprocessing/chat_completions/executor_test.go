@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCallsRunOnLockedThread tests that calls from any goroutine run on the single locked thread.
+func TestCallsRunOnLockedThread(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	lockedThread, err := preprocessing.CGOThreadID(ctx)
+	require.NoError(t, err, "CGOThreadID should not return an error")
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Pin the caller, so its thread is known to differ from the locked one.
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+
+			_, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+				Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+				ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+			})
+			errs <- err
+
+			threadID, err := preprocessing.CGOThreadID(ctx)
+			assert.NoError(t, err, "CGOThreadID should not return an error")
+			assert.Equal(t, lockedThread, threadID, "Calls should run on the locked thread")
+			assert.NotEqual(t, preprocessing.CurrentThreadID(), threadID, "Calls should not run on the caller's thread")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err, "Concurrent renders should not return an error")
+	}
+
+	t.Run("Canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := preprocessing.CGOThreadID(canceled)
+		assert.ErrorIs(t, err, context.Canceled, "A canceled call should not be run")
+	})
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "context"
+
+// CGOThreadID returns the native ID of the OS thread calls into Python run on.
+func CGOThreadID(ctx context.Context) (uint64, error) {
+	var threadID uint64
+	err := cgoThread.run(ctx, func() { threadID = currentThreadID() })
+	return threadID, err
+}
+
+// CurrentThreadID returns the native ID of the calling OS thread.
+var CurrentThreadID = currentThreadID
+
+// SetFaultHook sets a function run on the locked thread before every call into Python, or clears it if nil.
+func SetFaultHook(hook func()) {
+	if hook == nil {
+		faultHook.Store(nil)
+		return
+	}
+	faultHook.Store(&hook)
+}
+
+// LiveCAllocations returns the number of C allocations owned by Go that were not freed yet.
+func LiveCAllocations() int64 {
+	return liveCAllocations.Load()
+}
+
+// SetWarmupFetcher replaces the fetch of each template by Warmup with fetch.
+func SetWarmupFetcher(w *ChatTemplatingProcessor, fetch func(ctx context.Context, req *FetchChatTemplateRequest) error) {
+	w.warmupFetch = fetch
+}
+
+// PrefetchSeenModels returns the number of models w remembers as seen by NotifyModelSeen.
+func PrefetchSeenModels(w *ChatTemplatingProcessor) int {
+	w.prefetcher.mu.Lock()
+	defer w.prefetcher.mu.Unlock()
+	return len(w.prefetcher.seen)
+}
+
+// MaxPrefetchedModels bounds the models remembered as seen by NotifyModelSeen.
+const MaxPrefetchedModels = maxPrefetchedModels
+
+// IsInitialized reports whether w has the interpreter initialized.
+func IsInitialized(w *ChatTemplatingProcessor) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.initialized
+}
+
+// InterpreterRefs returns the number of processors holding the interpreter initialized.
+func InterpreterRefs() int {
+	interpreterRefs.Lock()
+	defer interpreterRefs.Unlock()
+	return interpreterRefs.count
+}
+
+// NegativeCacheEntries returns the number of fetch failures remembered by the negative cache.
+func NegativeCacheEntries() int {
+	notFoundModels.mu.Lock()
+	defer notFoundModels.mu.Unlock()
+	return len(notFoundModels.entries)
+}
+
+// MaxNegativeCacheEntries bounds the fetch failures remembered by the negative cache.
+const MaxNegativeCacheEntries = maxNegativeCacheEntries
+
+// ResetTemplateCacheSize restores the size of the template cache set with WithTemplateCacheSize to its default.
+func ResetTemplateCacheSize(ctx context.Context) error {
+	return setTemplateCacheSize(ctx, DefaultTemplateCacheSize)
+}
+
+// ResultBufferSize returns the size of the reused result buffer of w, zero before its first render.
+func ResultBufferSize(w *ChatTemplatingProcessor) int {
+	w.resultBuffer.mu.Lock()
+	defer w.resultBuffer.mu.Unlock()
+	return len(w.resultBuffer.buf)
+}
+
+// MinResultBufferSize is the initial size of a reused result buffer.
+const MinResultBufferSize = minResultBufferSize
+
+// ActiveCalls returns the number of calls of w in flight.
+func ActiveCalls(w *ChatTemplatingProcessor) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active
+}
+
+// IsShuttingDown reports whether Shutdown of w started.
+func IsShuttingDown(w *ChatTemplatingProcessor) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.shuttingDown
+}
+
+// TokenizerLoads returns the number of tokenizers loaded by the Python side.
+func TokenizerLoads(ctx context.Context) (int, error) {
+	var resp struct {
+		Loads int `json:"loads"`
+	}
+	err := callPythonFunction(ctx, "tokenizer_load_count", struct{}{}, &resp)
+	return resp.Loads, err
+}
+
+// ModuleInstance returns an ID of the instance of the chat template module serving calls.
+func ModuleInstance(ctx context.Context) (string, error) {
+	var resp struct {
+		Instance string `json:"instance"`
+	}
+	err := callPythonFunction(ctx, "module_instance", struct{}{}, &resp)
+	return resp.Instance, err
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateFixedDateTime tests that a date-dependent template renders deterministically with a fixed time.
+func TestRenderChatTemplateFixedDateTime(t *testing.T) {
+	getGlobalWrapper()
+
+	fixed := time.Date(2024, time.July, 26, 9, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  `Today is {{ strftime_now("%d %b %Y, %H:%M") }}.`,
+		FixedDateTime: &fixed,
+	}
+
+	for _, format := range []preprocessing.WireFormat{preprocessing.WireFormatJSON, preprocessing.WireFormatMsgpack} {
+		t.Run(format.String(), func(t *testing.T) {
+			processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithWireFormat(format))
+			require.NoError(t, processor.Initialize())
+			t.Cleanup(processor.Finalize)
+
+			for range 3 {
+				response, err := processor.RenderChatTemplate(context.Background(), request)
+				require.NoError(t, err, "RenderChatTemplate should not return an error")
+				assert.Equal(t, "Today is 26 Jul 2024, 09:30.", response.RenderedChats[0],
+					"The fixed time should be rendered in its own location")
+			}
+		})
+	}
+}
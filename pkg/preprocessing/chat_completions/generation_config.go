@@ -0,0 +1,95 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// GenerationConfig holds the serving defaults of a model, read from its
+// `generation_config.json`. Unset fields are left at their zero value.
+type GenerationConfig struct {
+	// EOSTokenIDs are the token IDs that end generation.
+	EOSTokenIDs []int `json:"eos_token_ids,omitempty"`
+	// MaxNewTokens is the default number of tokens to generate.
+	MaxNewTokens int `json:"max_new_tokens,omitempty"`
+	// MaxLength is the default maximum length of prompt and generated tokens.
+	MaxLength int `json:"max_length,omitempty"`
+	// Temperature, TopP, TopK and RepetitionPenalty are the default sampling
+	// parameters, nil when the model does not set them.
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	TopK              *int     `json:"top_k,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+	// StopStrings are strings that end generation besides the EOS tokens.
+	StopStrings []string `json:"stop_strings,omitempty"`
+}
+
+// GenerationConfigRequest selects the model FetchGenerationConfig reads the
+// generation config of.
+type GenerationConfigRequest struct {
+	// Model is a HuggingFace model ID or a local model directory.
+	Model string `json:"model"`
+	// Revision is the model revision, the processor's default revision if
+	// empty.
+	Revision string `json:"revision,omitempty"`
+	// Token is the HuggingFace token for private or gated models.
+	Token string `json:"token,omitempty"`
+}
+
+// generationConfigPayload is the JSON payload sent to get_generation_config.
+type generationConfigPayload struct {
+	GenerationConfigRequest
+	ProxyURL string `json:"proxy_url,omitempty"`
+}
+
+// generationConfigResponse is the JSON result of get_generation_config.
+type generationConfigResponse struct {
+	GenerationConfig
+	NotFound string `json:"not_found,omitempty"`
+}
+
+// FetchGenerationConfig returns the serving defaults of the model of req from
+// its `generation_config.json`, so callers get them alongside the chat
+// template. The parsed config is cached per model, revision and token on the
+// Python side, until ClearCaches. Models without a generation config fail
+// with ErrModelNotFound.
+//
+//nolint:gocritic // hugeParam: req is passed by value like FetchChatTemplateRequest.
+func (w *ChatTemplatingProcessor) FetchGenerationConfig(ctx context.Context, req GenerationConfigRequest,
+) (_ *GenerationConfig, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	_, statErr := os.Stat(req.Model)
+	req.Revision = w.revisionOrDefault(req.Revision, statErr == nil)
+	var resp generationConfigResponse
+	payload := generationConfigPayload{GenerationConfigRequest: req, ProxyURL: w.httpProxy}
+	if err := callPythonFunction(ctx, "get_generation_config", payload, &resp); err != nil {
+		return nil, err
+	}
+	if resp.NotFound != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrModelNotFound, req.Model, resp.NotFound)
+	}
+	return &resp.GenerationConfig, nil
+}
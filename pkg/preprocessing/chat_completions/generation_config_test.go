@@ -0,0 +1,72 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchGenerationConfig tests parsing the serving defaults of a sample generation config.
+func TestFetchGenerationConfig(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	// Like Llama-3's generation config, with a stop string added.
+	modelDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "generation_config.json"), []byte(`{
+  "bos_token_id": 128000,
+  "do_sample": true,
+  "eos_token_id": [128001, 128009],
+  "max_new_tokens": 512,
+  "stop_strings": "Observation:",
+  "temperature": 0.6,
+  "top_p": 0.9,
+  "transformers_version": "4.40.0"
+}`), 0o600))
+
+	request := preprocessing.GenerationConfigRequest{Model: modelDir}
+	config, err := wrapper.FetchGenerationConfig(ctx, request)
+	require.NoError(t, err, "FetchGenerationConfig should not return an error")
+	temperature, topP := 0.6, 0.9
+	assert.Equal(t, &preprocessing.GenerationConfig{
+		EOSTokenIDs:  []int{128001, 128009},
+		MaxNewTokens: 512,
+		Temperature:  &temperature,
+		TopP:         &topP,
+		StopStrings:  []string{"Observation:"},
+	}, config, "The generation config should be parsed")
+
+	// The parsed config is cached per model, revision and token.
+	require.NoError(t, os.Remove(filepath.Join(modelDir, "generation_config.json")))
+	cached, err := wrapper.FetchGenerationConfig(ctx, request)
+	require.NoError(t, err, "A cached generation config should be served")
+	assert.Equal(t, config, cached)
+	_, err = wrapper.FetchGenerationConfig(ctx, preprocessing.GenerationConfigRequest{Model: modelDir, Revision: "v2"})
+	assert.ErrorIs(t, err, preprocessing.ErrModelNotFound, "Other revisions should not be served the cached config")
+	_, err = wrapper.FetchGenerationConfig(ctx, preprocessing.GenerationConfigRequest{Model: modelDir, Token: "hf_token"})
+	assert.ErrorIs(t, err, preprocessing.ErrModelNotFound, "Other tokens should not be served the cached config")
+
+	_, err = wrapper.FetchGenerationConfig(ctx, preprocessing.GenerationConfigRequest{Model: t.TempDir()})
+	assert.ErrorIs(t, err, preprocessing.ErrModelNotFound, "A model without a generation config should not be found")
+}
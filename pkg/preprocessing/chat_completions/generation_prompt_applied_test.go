@@ -0,0 +1,85 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerationPromptApplied tests that the response reports whether add_generation_prompt changed the render.
+func TestGenerationPromptApplied(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	const messagesTemplate = "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}"
+	tests := []struct {
+		name                string
+		template            string
+		addGenerationPrompt bool
+		notRequested        bool
+		expected            bool
+	}{
+		{
+			name:                "Applied",
+			template:            messagesTemplate + "{% if add_generation_prompt %}assistant:{% endif %}",
+			addGenerationPrompt: true,
+			expected:            true,
+		},
+		{
+			name:                "Not returned",
+			template:            messagesTemplate + "{% if add_generation_prompt %}assistant:{% endif %}",
+			addGenerationPrompt: true,
+			notRequested:        true,
+			expected:            false,
+		},
+		{
+			name:                "Not requested",
+			template:            messagesTemplate + "{% if add_generation_prompt %}assistant:{% endif %}",
+			addGenerationPrompt: false,
+			expected:            false,
+		},
+		{
+			name:                "Ignored by the template",
+			template:            messagesTemplate,
+			addGenerationPrompt: true,
+			expected:            false,
+		},
+		{
+			name:                "Rendering nothing",
+			template:            messagesTemplate + "{% if add_generation_prompt %}{% endif %}",
+			addGenerationPrompt: true,
+			expected:            false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+				Conversations:                 []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+				ChatTemplate:                  tt.template,
+				AddGenerationPrompt:           tt.addGenerationPrompt,
+				ReturnGenerationPromptApplied: !tt.notRequested,
+			})
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			assert.Equal(t, tt.expected, response.GenerationPromptApplied)
+		})
+	}
+}
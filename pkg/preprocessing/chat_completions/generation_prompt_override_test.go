@@ -0,0 +1,63 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateGenerationPromptOverride tests replacing the template's generation prompt.
+func TestRenderChatTemplateGenerationPromptOverride(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	const promptTemplate = "{% for message in messages %}<|{{ message.role }}|>{{ message.content }}\n{% endfor %}" +
+		"{% if add_generation_prompt %}<|assistant|>{% endif %}"
+	newRequest := func(template string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:            []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:             template,
+			AddGenerationPrompt:      true,
+			GenerationPromptOverride: "<|assistant|><think>\n",
+		}
+	}
+
+	t.Run("Override", func(t *testing.T) {
+		response, err := wrapper.RenderChatTemplate(ctx, newRequest(promptTemplate))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "<|user|>Hello\n<|assistant|><think>\n", response.RenderedChats[0],
+			"The override should replace the generation prompt at the end of the render")
+	})
+
+	t.Run("Without AddGenerationPrompt", func(t *testing.T) {
+		request := newRequest(promptTemplate)
+		request.AddGenerationPrompt = false
+		_, err := wrapper.RenderChatTemplate(ctx, request)
+		assert.Error(t, err, "An override without AddGenerationPrompt should be rejected")
+	})
+
+	t.Run("Template without generation prompt", func(t *testing.T) {
+		_, err := wrapper.RenderChatTemplate(ctx,
+			newRequest("{% for message in messages %}{{ message.content }}{% endfor %}"))
+		assert.Error(t, err, "An override for a template without generation prompt should be rejected")
+	})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateGenerationPromptTokens tests counting the tokens added by the generation prompt.
+func TestRenderChatTemplateGenerationPromptTokens(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	newRequest := func(addGenerationPrompt bool) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "What is the capital of France?"}},
+			ChatTemplate: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}" +
+				"{% if add_generation_prompt %}assistant answer:{% endif %}",
+			AddGenerationPrompt:          addGenerationPrompt,
+			ReturnTokenIDs:               true,
+			ReturnGenerationPromptTokens: true,
+			Tokenizer: &preprocessing.TokenizerSource{
+				Model:       "../../tokenization/testdata/test-model",
+				IsLocalPath: true,
+			},
+		}
+	}
+
+	with, err := wrapper.RenderChatTemplate(ctx, newRequest(true))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	without, err := wrapper.RenderChatTemplate(ctx, newRequest(false))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	require.Greater(t, len(with.TokenIDs), len(without.TokenIDs), "The generation prompt should add tokens")
+	assert.Equal(t, len(with.TokenIDs)-len(without.TokenIDs), with.GenerationPromptTokens,
+		"The count should match the tokens the generation prompt adds")
+	assert.Equal(t, without.TokenIDs, with.TokenIDs[:len(with.TokenIDs)-with.GenerationPromptTokens],
+		"The generation prompt tokens should end the tokens")
+	assert.Zero(t, without.GenerationPromptTokens, "Renders without generation prompt should count none")
+
+	request := newRequest(true)
+	request.ReturnGenerationPromptTokens = false
+	unrequested, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, with.TokenIDs, unrequested.TokenIDs)
+	assert.Zero(t, unrequested.GenerationPromptTokens, "The generation prompt tokens should only be counted on request")
+
+	request = newRequest(true)
+	request.ReturnTokenIDs = false
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	assert.Error(t, err, "Counting the generation prompt tokens should require the token IDs")
+}
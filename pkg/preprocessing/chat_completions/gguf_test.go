@@ -0,0 +1,122 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GGUF metadata value types used by writeGGUF.
+const (
+	ggufTypeUint32 = 4
+	ggufTypeString = 8
+	ggufTypeArray  = 9
+)
+
+// ggufBuffer builds a little-endian GGUF header.
+type ggufBuffer struct {
+	bytes.Buffer
+}
+
+func (b *ggufBuffer) writeUint32(v uint32) {
+	b.Write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (b *ggufBuffer) writeUint64(v uint64) {
+	b.Write(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (b *ggufBuffer) writeString(s string) {
+	b.writeUint64(uint64(len(s)))
+	b.WriteString(s)
+}
+
+// writeGGUF writes a minimal GGUF v3 file without tensors, holding a chat
+// template and a two-token vocabulary with BOS and EOS token IDs.
+func writeGGUF(t *testing.T, path, template string) {
+	t.Helper()
+
+	var buf ggufBuffer
+	buf.WriteString("GGUF")
+	buf.writeUint32(3) // version
+	buf.writeUint64(0) // tensor count
+	buf.writeUint64(5) // metadata count
+
+	buf.writeString("general.architecture")
+	buf.writeUint32(ggufTypeString)
+	buf.writeString("llama")
+
+	buf.writeString("tokenizer.ggml.tokens")
+	buf.writeUint32(ggufTypeArray)
+	buf.writeUint32(ggufTypeString)
+	buf.writeUint64(2)
+	buf.writeString("<s>")
+	buf.writeString("</s>")
+
+	buf.writeString("tokenizer.ggml.bos_token_id")
+	buf.writeUint32(ggufTypeUint32)
+	buf.writeUint32(0)
+
+	buf.writeString("tokenizer.ggml.eos_token_id")
+	buf.writeUint32(ggufTypeUint32)
+	buf.writeUint32(1)
+
+	buf.writeString("tokenizer.chat_template")
+	buf.writeUint32(ggufTypeString)
+	buf.writeString(template)
+
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+}
+
+// TestFetchChatTemplateFromGGUF tests reading the chat template from GGUF metadata.
+func TestFetchChatTemplateFromGGUF(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	const ggufTemplate = "{{ bos_token }}{% for message in messages %}[{{ message.role }}] {{ message.content }}" +
+		"{{ eos_token }}{% endfor %}"
+	path := filepath.Join(t.TempDir(), "model.Q4_K_M.gguf")
+	writeGGUF(t, path, ggufTemplate)
+
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       path,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	assert.Equal(t, ggufTemplate, template, "Template should be read from GGUF metadata")
+	assert.Equal(t, "<s>", templateVars["bos_token"], "BOS token should be resolved from the vocabulary")
+	assert.Equal(t, "</s>", templateVars["eos_token"], "EOS token should be resolved from the vocabulary")
+
+	t.Run("Invalid file", func(t *testing.T) {
+		notGGUF := filepath.Join(t.TempDir(), "broken.gguf")
+		require.NoError(t, os.WriteFile(notGGUF, []byte("not a gguf file"), 0o600))
+
+		_, _, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+			Model:       notGGUF,
+			IsLocalPath: true,
+		})
+		assert.Error(t, err, "FetchChatTemplate should fail for an invalid GGUF file")
+	})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "context"
+
+// GoldenCase is a conversation and the output a chat template is expected to
+// render it to, e.g. stored in a user repository for template regression
+// tests.
+type GoldenCase struct {
+	Name                string                 `json:"name"`
+	Messages            []ChatMessage          `json:"messages"`
+	AddGenerationPrompt bool                   `json:"add_generation_prompt,omitempty"`
+	ChatTemplateKWArgs  map[string]interface{} `json:"chat_template_kwargs,omitempty"`
+	Expected            string                 `json:"expected"`
+}
+
+// GoldenResult is a GoldenCase the template did not render to its expected
+// output: either Rendered differs from Expected, or the render failed with
+// Err.
+type GoldenResult struct {
+	// Index is the position of the case in the suite.
+	Index    int
+	Name     string
+	Expected string
+	Rendered string
+	Err      error
+}
+
+// RenderGoldenSuite renders each case with template and returns the cases
+// whose output differs from their expected one, in order; all cases passed
+// if it returns none. All cases are rendered in a single call into Python,
+// see RenderChatTemplateBatch. The returned error is only set when the
+// suite could not be rendered at all.
+func (w *ChatTemplatingProcessor) RenderGoldenSuite(ctx context.Context, template string,
+	cases []GoldenCase,
+) ([]GoldenResult, error) {
+	reqs := make([]*RenderJinjaTemplateRequest, len(cases))
+	for i := range cases {
+		reqs[i] = &RenderJinjaTemplateRequest{
+			Conversations:       cases[i].Messages,
+			ChatTemplate:        template,
+			AddGenerationPrompt: cases[i].AddGenerationPrompt,
+			ChatTemplateKWArgs:  cases[i].ChatTemplateKWArgs,
+		}
+	}
+
+	results, err := w.RenderChatTemplateBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []GoldenResult
+	for i, result := range results {
+		mismatch := GoldenResult{Index: i, Name: cases[i].Name, Expected: cases[i].Expected, Err: result.Err}
+		if result.Err == nil {
+			if len(result.Response.RenderedChats) > 0 {
+				mismatch.Rendered = result.Response.RenderedChats[0]
+			}
+			if mismatch.Rendered == mismatch.Expected {
+				continue
+			}
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches, nil
+}
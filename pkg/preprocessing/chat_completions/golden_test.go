@@ -0,0 +1,62 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderGoldenSuite tests reporting the golden cases a template does not render to their expected output.
+func TestRenderGoldenSuite(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	template := "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}"
+	cases := []preprocessing.GoldenCase{
+		{
+			Name:     "passing",
+			Messages: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			Expected: "user: Hello\n",
+		},
+		{
+			Name: "failing",
+			Messages: []preprocessing.ChatMessage{
+				{Role: "user", Content: "Hello"},
+				{Role: "assistant", Content: "Hi there"},
+			},
+			Expected: "user: Hello\nassistant: Hi\n",
+		},
+	}
+
+	mismatches, err := wrapper.RenderGoldenSuite(ctx, template, cases)
+	require.NoError(t, err, "RenderGoldenSuite should not return an error")
+	assert.Equal(t, []preprocessing.GoldenResult{{
+		Index:    1,
+		Name:     "failing",
+		Expected: "user: Hello\nassistant: Hi\n",
+		Rendered: "user: Hello\nassistant: Hi there\n",
+	}}, mismatches, "Only the failing golden should be reported")
+
+	mismatches, err = wrapper.RenderGoldenSuite(ctx, template, cases[:1])
+	require.NoError(t, err, "RenderGoldenSuite should not return an error")
+	assert.Empty(t, mismatches, "A passing suite should report no mismatches")
+}
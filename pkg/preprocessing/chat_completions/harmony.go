@@ -0,0 +1,38 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+// harmonyChatTemplate renders conversations in the Harmony format of OpenAI's
+// gpt-oss models: every message is framed by `<|start|>` and `<|end|>`, with
+// assistant messages on a channel such as `analysis`, `commentary` or `final`.
+// Tool calls are addressed to `functions.<name>` on the commentary channel and
+// end with `<|call|>`; tool results come back from `functions.<name>`.
+const harmonyChatTemplate = `{%- for message in messages -%}
+{%- if message.role == 'tool' -%}
+<|start|>functions.{{ message.name }} to=assistant<|channel|>{{ message.channel or 'commentary' }}<|message|>
+{{- message.content }}<|end|>
+{%- elif message.tool_calls -%}
+{%- for tool_call in message.tool_calls -%}
+<|start|>assistant<|channel|>{{ message.channel or 'commentary' }} to=functions.{{ tool_call.function.name }}
+{{- ' ' }}<|constrain|>json<|message|>{{ tool_call.function.arguments | tojson }}<|call|>
+{%- endfor -%}
+{%- else -%}
+{%- set channel = message.channel or ('final' if message.role == 'assistant' else '') -%}
+<|start|>{{ message.role }}{% if channel %}<|channel|>{{ channel }}{% endif %}<|message|>{{ message.content }}<|end|>
+{%- endif -%}
+{%- endfor -%}
+{%- if add_generation_prompt -%}<|start|>assistant{%- endif -%}`
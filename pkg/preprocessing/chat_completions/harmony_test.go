@@ -0,0 +1,79 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateHarmony tests rendering a multi-channel conversation in the Harmony format of gpt-oss models.
+func TestRenderChatTemplateHarmony(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	response, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are ChatGPT."},
+			{Role: "user", Content: "What is the weather in Paris?"},
+			{Role: "assistant", Channel: "analysis", Content: "Need to call the weather tool."},
+			{Role: "assistant", ToolCalls: []preprocessing.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: preprocessing.ToolCallFunction{Name: "get_weather", Arguments: `{"city": "Paris"}`},
+			}}},
+			{Role: "tool", Name: "get_weather", ToolCallID: "call_1", Content: "22C"},
+			{Role: "assistant", Content: "It is 22C in Paris."},
+			{Role: "user", Content: "Thanks!"},
+		},
+		Harmony:             true,
+		AddGenerationPrompt: true,
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "<|start|>system<|message|>You are ChatGPT.<|end|>"+
+		"<|start|>user<|message|>What is the weather in Paris?<|end|>"+
+		"<|start|>assistant<|channel|>analysis<|message|>Need to call the weather tool.<|end|>"+
+		`<|start|>assistant<|channel|>commentary to=functions.get_weather <|constrain|>json<|message|>{"city": "Paris"}<|call|>`+
+		"<|start|>functions.get_weather to=assistant<|channel|>commentary<|message|>22C<|end|>"+
+		"<|start|>assistant<|channel|>final<|message|>It is 22C in Paris.<|end|>"+
+		"<|start|>user<|message|>Thanks!<|end|>"+
+		"<|start|>assistant",
+		response.RenderedChats[0], "The conversation should be rendered in the Harmony format")
+	assert.True(t, response.Harmony, "Harmony formatting should be reported")
+
+	t.Run("Recognized templates", func(t *testing.T) {
+		conversation := []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}}
+		response, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: conversation,
+			ChatTemplate: "{% for message in messages %}" +
+				"<|start|>{{ message.role }}<|channel|>final<|message|>{{ message.content }}{% endfor %}",
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.True(t, response.Harmony, "Templates emitting channel tokens should be reported as Harmony")
+
+		response, err = wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: conversation,
+			ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.False(t, response.Harmony, "Other templates should not be reported as Harmony")
+	})
+}
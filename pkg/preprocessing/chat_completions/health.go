@@ -0,0 +1,84 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// canaryTemplate is rendered by Readiness. It is bundled rather than
+	// fetched, so that probes neither depend on nor load any model.
+	canaryTemplate = "{% for message in messages %}[{{ message.role }}] {{ message.content }}{% endfor %}"
+	// canaryRender is the expected render of canaryTemplate.
+	canaryRender = "[user] ping"
+)
+
+// Liveness reports whether the interpreter is up: the processor is
+// initialized and a call into Python returns. It suits a Kubernetes liveness
+// probe, and is cheap enough to call frequently. Processors managing the
+// interpreter lifecycle, see WithLazyInit, are live while it is down, as the
+// next call brings it up; Liveness does not.
+func (w *ChatTemplatingProcessor) Liveness(ctx context.Context) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	w.mu.Lock()
+	initialized := w.initialized
+	w.mu.Unlock()
+	if !initialized {
+		if w.managesLifecycle() {
+			return nil
+		}
+		return ErrNotInitialized
+	}
+
+	var resp struct{}
+	if err := callPythonFunction(ctx, "ping", struct{}{}, &resp); err != nil {
+		return fmt.Errorf("interpreter is not responsive: %w", err)
+	}
+	return nil
+}
+
+// Readiness reports whether renders succeed: on top of Liveness, a canary
+// render of a bundled template must produce the expected output. It suits a
+// Kubernetes readiness probe; the canary template stays compiled in the
+// template cache, so repeated probes are cheap. The canary is rendered as
+// is, without the options of the processor, the render hook or the render
+// metrics, so that probes are neither failed by the options nor counted.
+func (w *ChatTemplatingProcessor) Readiness(ctx context.Context) (err error) {
+	if err := w.Liveness(ctx); err != nil {
+		return err
+	}
+
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	response, err := w.renderChatTemplateJSON(ctx, &RenderJinjaTemplateRequest{
+		Conversations: []ChatMessage{{Role: "user", Content: "ping"}},
+		ChatTemplate:  canaryTemplate,
+	})
+	if err != nil {
+		return fmt.Errorf("canary render failed: %w", err)
+	}
+	if len(response.RenderedChats) != 1 || response.RenderedChats[0] != canaryRender {
+		return fmt.Errorf("canary render returned %q, expected %q", response.RenderedChats, canaryRender)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/metrics"
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHealthProbes tests that liveness and readiness fail before Initialize and pass after.
+func TestHealthProbes(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	processor := preprocessing.NewChatTemplatingProcessor()
+	assert.ErrorIs(t, processor.Liveness(ctx), preprocessing.ErrNotInitialized, "Liveness should fail before Initialize")
+	assert.ErrorIs(t, processor.Readiness(ctx), preprocessing.ErrNotInitialized, "Readiness should fail before Initialize")
+
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+	assert.NoError(t, processor.Liveness(ctx), "Liveness should pass after Initialize")
+	for range 3 {
+		assert.NoError(t, processor.Readiness(ctx), "Readiness should pass after Initialize")
+	}
+}
+
+// TestReadinessCanary tests that the readiness canary is rendered without the processor's options and render hook.
+func TestReadinessCanary(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	var hookCalls int
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithPromptHash(preprocessing.PromptHashTokens),
+		preprocessing.WithRenderHook(func(context.Context, *preprocessing.RenderJinjaTemplateRequest,
+			*preprocessing.RenderJinjaTemplateResponse, error, time.Duration,
+		) {
+			hookCalls++
+		}))
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+
+	before := counterValue(t, metrics.TemplateCompileCacheHits)
+	for range 3 {
+		assert.NoError(t, processor.Readiness(ctx), "Readiness should pass whatever the prompt hash source")
+	}
+	assert.Zero(t, hookCalls, "The canary should not run the render hook")
+	assert.Equal(t, before, counterValue(t, metrics.TemplateCompileCacheHits), "The canary should not be counted")
+}
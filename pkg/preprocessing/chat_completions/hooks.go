@@ -0,0 +1,79 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RenderHook is called after each render with its request, its outcome and
+// how long it took, e.g. to record metrics or tracing spans.
+type RenderHook func(ctx context.Context, req *RenderJinjaTemplateRequest, resp *RenderJinjaTemplateResponse,
+	err error, dur time.Duration)
+
+// FetchHook is called after each FetchChatTemplateDetails, and so after
+// every template fetch, with its request, its outcome and how long it took.
+type FetchHook func(ctx context.Context, req *FetchChatTemplateRequest, resp *FetchChatTemplateResponse,
+	err error, dur time.Duration)
+
+// WithRenderHook sets a hook called after each RenderChatTemplate, including
+// those made by RenderForModel and the other helpers. Batches, and so
+// RenderVariantsKWArgs, CountTokensBatch and RenderStream, call it once per
+// request with the duration of the batch; RenderRaw and RenderChatTemplateTo
+// pass it a response without RenderedChats. The hook runs on the caller's
+// goroutine, or on a worker of RenderStream, so it should return quickly; a
+// panicking hook is recovered and logged, and does not fail the render.
+func WithRenderHook(hook RenderHook) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.renderHook = hook
+	}
+}
+
+// WithFetchHook sets a hook called after each template fetch. Like render
+// hooks, it should return quickly, and a panicking hook is recovered and logged.
+func WithFetchHook(hook FetchHook) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.fetchHook = hook
+	}
+}
+
+// runRenderHook calls the render hook, recovering it from panics.
+func (w *ChatTemplatingProcessor) runRenderHook(ctx context.Context, req *RenderJinjaTemplateRequest,
+	resp *RenderJinjaTemplateResponse, err error, dur time.Duration,
+) {
+	defer recoverHook(ctx, "render")
+	w.renderHook(ctx, req, resp, err, dur)
+}
+
+// runFetchHook calls the fetch hook, recovering it from panics.
+func (w *ChatTemplatingProcessor) runFetchHook(ctx context.Context, req *FetchChatTemplateRequest,
+	resp *FetchChatTemplateResponse, err error, dur time.Duration,
+) {
+	defer recoverHook(ctx, "fetch")
+	w.fetchHook(ctx, req, resp, err, dur)
+}
+
+// recoverHook logs the panic of a hook, if any. It must be deferred.
+func recoverHook(ctx context.Context, hook string) {
+	if r := recover(); r != nil {
+		log.FromContext(ctx).Error(fmt.Errorf("panic: %v", r), "Hook panicked", "hook", hook)
+	}
+}
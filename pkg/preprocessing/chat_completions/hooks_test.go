@@ -0,0 +1,128 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHooks tests that render and fetch hooks observe each call, and that a panicking hook does not fail it.
+func TestHooks(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	var renders, fetches int
+	var renderErr error
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithRenderHook(func(_ context.Context, req *preprocessing.RenderJinjaTemplateRequest,
+			resp *preprocessing.RenderJinjaTemplateResponse, err error, dur time.Duration,
+		) {
+			renders++
+			renderErr = err
+			assert.Positive(t, dur, "The hook should observe the duration")
+			if err == nil {
+				assert.NotEmpty(t, req.Conversations, "The hook should observe the request")
+				assert.NotEmpty(t, resp.RenderedChats, "The hook should observe the response")
+			}
+		}),
+		preprocessing.WithFetchHook(func(_ context.Context, req *preprocessing.FetchChatTemplateRequest,
+			resp *preprocessing.FetchChatTemplateResponse, err error, _ time.Duration,
+		) {
+			fetches++
+			assert.Equal(t, "../../tokenization/testdata/test-model", req.Model, "The hook should observe the request")
+			assert.NoError(t, err)
+			assert.NotEmpty(t, resp.ChatTemplate, "The hook should observe the response")
+			panic("broken fetch hook")
+		}),
+	)
+
+	renderLocalTemplate(t, processor)
+	assert.Equal(t, 1, fetches, "The fetch hook should observe the fetch, and its panic should be recovered")
+	assert.Equal(t, 1, renders, "The render hook should observe the render")
+
+	_, err := processor.RenderChatTemplate(ctx, nil)
+	require.Error(t, err)
+	assert.Equal(t, 2, renders, "The render hook should observe failing renders")
+	assert.Equal(t, err, renderErr, "The hook should observe the error")
+}
+
+// TestRenderHookHelpers tests that the render hook observes each request rendered by the other render helpers.
+func TestRenderHookHelpers(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var renders int
+	var responses []*preprocessing.RenderJinjaTemplateResponse
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithRenderHook(func(_ context.Context, _ *preprocessing.RenderJinjaTemplateRequest,
+			resp *preprocessing.RenderJinjaTemplateResponse, err error, _ time.Duration,
+		) {
+			mu.Lock()
+			defer mu.Unlock()
+			renders++
+			responses = append(responses, resp)
+			assert.NoError(t, err)
+		}),
+	)
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+	// observed returns the number of renders observed since it was last called.
+	observed := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := renders
+		renders = 0
+		return n
+	}
+
+	request := largeRenderRequest(2)
+	_, err := processor.RenderChatTemplateBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{request, request})
+	require.NoError(t, err, "RenderChatTemplateBatch should not return an error")
+	assert.Equal(t, 2, observed(), "The hook should observe each request of a batch")
+
+	_, err = processor.RenderVariantsKWArgs(ctx, request, []map[string]interface{}{{"a": 1}, {"a": 2}, {"a": 3}})
+	require.NoError(t, err, "RenderVariantsKWArgs should not return an error")
+	assert.Equal(t, 3, observed(), "The hook should observe each variant")
+
+	reqs := make(chan *preprocessing.RenderJinjaTemplateRequest, 4)
+	for range 4 {
+		reqs <- request
+	}
+	close(reqs)
+	out := make(chan preprocessing.StreamResult, 4)
+	require.NoError(t, processor.RenderStream(ctx, reqs, out), "RenderStream should not return an error")
+	assert.Equal(t, 4, observed(), "The hook should observe each streamed request")
+
+	result, err := processor.RenderRaw(ctx, request)
+	require.NoError(t, err, "RenderRaw should not return an error")
+	result.Release()
+	require.NoError(t, processor.RenderChatTemplateTo(ctx, request, &bytes.Buffer{}))
+	assert.Equal(t, 2, observed(), "The hook should observe raw renders")
+	for _, resp := range responses[len(responses)-2:] {
+		require.NotNil(t, resp, "The hook should observe the response of raw renders")
+		assert.Nil(t, resp.RenderedChats, "Raw renders should not lend their C memory to the hook")
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+//nolint: gocritic // C and unsafe are considered dups by the linter.
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	/*
+		#include "cgo_functions.h"
+	*/
+	"C"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/metrics"
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// HotReload reinitializes the chat template module without downtime. A
+// standby instance of the module is loaded next to the active one and, once
+// it passes the same health check as Initialize, swapped in atomically; the
+// old instance is torn down when the renders in flight on it complete.
+//
+// The standby instance starts with empty caches. The filters registered with
+// RegisterJinjaFilter, on any processor, are injected into it, and the
+// templates pinned with PinTemplate are pinned again, to be cached once
+// fetched.
+func (w *ChatTemplatingProcessor) HotReload(ctx context.Context) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("HotReload")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.initialized {
+		return fmt.Errorf("%w: processor is not initialized", ErrHotReload)
+	}
+
+	var result C.int
+	var cause *C.char
+	if err := cgoThread.run(ctx, func() {
+		if result = C.Py_HotReloadChatTemplateModule(); result != 0 {
+			cause = C.Py_TakeInitError()
+		}
+	}); err != nil {
+		return err
+	}
+	if result != 0 {
+		if cause != nil {
+			defer C.free(unsafe.Pointer(cause))
+			err := fmt.Errorf("%w: %s", ErrHotReload, C.GoString(cause))
+			traceLogger.Error(err, "Standby module rejected, keeping the active one")
+			return err
+		}
+		return ErrHotReload
+	}
+	cachedTemplates.reset()
+	notFoundModels.reset()
+
+	if err := registerCustomFilters(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrHotReload, err)
+	}
+	if err := repinTemplates(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrHotReload, err)
+	}
+
+	metrics.InterpreterRestarts.Inc()
+	traceLogger.Info("Swapped to the standby chat template module")
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHotReload tests that renders keep succeeding while the module is hot reloaded.
+func TestHotReload(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	template, templateVars, err := wrapper.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	const workers = 4
+	var renders, failures atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+					Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+					ChatTemplate:       template,
+					ChatTemplateKWArgs: templateVars,
+				})
+				if err != nil {
+					failures.Add(1)
+				}
+				renders.Add(1)
+			}
+		}()
+	}
+
+	for range 3 {
+		require.NoError(t, wrapper.HotReload(ctx), "HotReload should not return an error")
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Positive(t, renders.Load(), "Renders should have run during the hot reloads")
+	assert.Zero(t, failures.Load(), "No render should fail during a hot reload")
+
+	// The swapped in module serves renders on its own.
+	renderLocalTemplate(t, wrapper)
+
+	t.Run("Not initialized", func(t *testing.T) {
+		err := preprocessing.NewChatTemplatingProcessor().HotReload(ctx)
+		assert.ErrorIs(t, err, preprocessing.ErrHotReload)
+	})
+}
+
+// TestHotReloadReinitialize tests that the hot reloaded module is kept by a later Finalize and Initialize.
+func TestHotReloadReinitialize(t *testing.T) {
+	globalWrapperMu.Lock()
+	defer globalWrapperMu.Unlock()
+
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	before, err := preprocessing.ModuleInstance(ctx)
+	require.NoError(t, err, "ModuleInstance should not return an error")
+	require.NoError(t, wrapper.HotReload(ctx), "HotReload should not return an error")
+	reloaded, err := preprocessing.ModuleInstance(ctx)
+	require.NoError(t, err, "ModuleInstance should not return an error")
+	require.NotEqual(t, before, reloaded, "HotReload should swap in a new module")
+
+	wrapper.Finalize()
+	require.Zero(t, preprocessing.InterpreterRefs(), "No processor should hold the interpreter")
+	require.NoError(t, wrapper.Initialize(), "Re-Initialize should not return an error")
+	after, err := preprocessing.ModuleInstance(ctx)
+	require.NoError(t, err, "ModuleInstance should not return an error")
+	assert.Equal(t, reloaded, after, "Initialize should keep the hot reloaded module")
+
+	// The module in use owns the compile cache templates are compiled through.
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{# reinitialized #}{% for message in messages %}{{ message.content }}{% endfor %}",
+	}
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.True(t, response.CompileCacheHit, "Renders should be served by the compile cache of the module in use")
+}
+
+// TestHotReloadCustomFilters tests that a hot reload keeps the filters registered on every processor.
+func TestHotReloadCustomFilters(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithCustomFilters())
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+	require.NoError(t, processor.RegisterJinjaFilter("reload_shout", "lambda s: s.upper() + '!'"))
+
+	require.NoError(t, wrapper.HotReload(ctx), "HotReload should not return an error")
+	response, err := processor.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content | reload_shout }}{% endfor %}",
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "HELLO!", response.RenderedChats[0], "Filters of other processors should survive a hot reload")
+}
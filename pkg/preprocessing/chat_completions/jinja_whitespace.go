@@ -0,0 +1,44 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+// JinjaWhitespace is the whitespace control of the Jinja environment chat
+// templates are compiled in. Templates written for one setting may render
+// extra or missing newlines and indentation under another.
+type JinjaWhitespace struct {
+	// TrimBlocks removes the first newline after a block tag, such as
+	// `{% if %}`.
+	TrimBlocks bool `json:"trim_blocks"`
+	// LStripBlocks strips the spaces and tabs from the start of a line up to
+	// a block tag.
+	LStripBlocks bool `json:"lstrip_blocks"`
+}
+
+// HFJinjaWhitespace is the whitespace control of transformers'
+// apply_chat_template, which vLLM and most serving stacks render with, and
+// the default of renders.
+var HFJinjaWhitespace = JinjaWhitespace{TrimBlocks: true, LStripBlocks: true}
+
+// WithJinjaWhitespace sets the whitespace control templates are compiled
+// with, for requests not setting RenderJinjaTemplateRequest.JinjaWhitespace,
+// e.g. to match a reference implementation rendering with Jinja's own
+// defaults. Defaults to HFJinjaWhitespace.
+func WithJinjaWhitespace(whitespace JinjaWhitespace) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.jinjaWhitespace = &whitespace
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indentedBlocksTemplate indents its block tags on lines of their own, as templates written for
+// transformers' whitespace control do.
+const indentedBlocksTemplate = "{% for message in messages %}\n" +
+	"  {% if message.role == 'user' %}\nU: {{ message.content }}\n  {% endif %}\n" +
+	"{% endfor %}"
+
+// TestRenderChatTemplateJinjaWhitespace tests compiling templates with a given whitespace control.
+func TestRenderChatTemplateJinjaWhitespace(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	newRequest := func(whitespace *preprocessing.JinjaWhitespace) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:   []preprocessing.ChatMessage{{Role: "user", Content: "Hi"}},
+			ChatTemplate:    indentedBlocksTemplate,
+			JinjaWhitespace: whitespace,
+		}
+	}
+
+	// The same template is rendered with each setting, so that it is compiled once per setting.
+	for _, tt := range []struct {
+		name       string
+		whitespace *preprocessing.JinjaWhitespace
+		expected   string
+	}{
+		{"Default", nil, "U: Hi\n"},
+		{"HF", &preprocessing.HFJinjaWhitespace, "U: Hi\n"},
+		{"Jinja defaults", &preprocessing.JinjaWhitespace{}, "\n  \nU: Hi\n  \n"},
+		{"Trim blocks", &preprocessing.JinjaWhitespace{TrimBlocks: true}, "  U: Hi\n  "},
+		{"Default again", nil, "U: Hi\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := wrapper.RenderChatTemplate(ctx, newRequest(tt.whitespace))
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			assert.Equal(t, tt.expected, response.RenderedChats[0])
+		})
+	}
+
+	t.Run("Processor option", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithJinjaWhitespace(preprocessing.JinjaWhitespace{}))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		response, err := processor.RenderChatTemplate(ctx, newRequest(nil))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "\n  \nU: Hi\n  \n", response.RenderedChats[0], "The processor's whitespace control should apply")
+
+		response, err = processor.RenderChatTemplate(ctx, newRequest(&preprocessing.HFJinjaWhitespace))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "U: Hi\n", response.RenderedChats[0], "The request's whitespace control should take precedence")
+	})
+}
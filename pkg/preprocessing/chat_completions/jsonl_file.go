@@ -0,0 +1,257 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultJSONLConcurrency is the number of lines RenderJSONLFile renders at
+// once unless set in JSONLOptions.
+const DefaultJSONLConcurrency = 4
+
+// jsonlWindowPerWorker bounds how many lines RenderJSONLFile reads ahead of
+// the oldest line not written yet, per worker, as results are written in
+// input order.
+const jsonlWindowPerWorker = 4
+
+// JSONLOptions configures RenderJSONLFile.
+type JSONLOptions struct {
+	// Template holds the fields shared by every line, such as ChatTemplate or
+	// Tokenizer. Each line is decoded over a copy of it, so the fields a line
+	// sets take precedence. Nil decodes each line as a whole request.
+	Template *RenderJinjaTemplateRequest
+	// OutputTokenIDs writes the token IDs of each render instead of the
+	// rendered text. It requires a Tokenizer.
+	OutputTokenIDs bool
+	// Concurrency is the number of lines rendered at once. Defaults to
+	// DefaultJSONLConcurrency.
+	Concurrency int
+}
+
+// JSONLRecord is a line written by RenderJSONLFile: the render of an input
+// line, or why it failed.
+type JSONLRecord struct {
+	// Line is the number of the input line, counting from one.
+	Line     int      `json:"line"`
+	Rendered string   `json:"rendered,omitempty"`
+	TokenIDs []uint32 `json:"token_ids,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// JSONLSummary counts the lines rendered by RenderJSONLFile.
+type JSONLSummary struct {
+	Lines  int
+	Failed int
+}
+
+// jsonlLine is an input line of RenderJSONLFile, with its position among
+// the lines rendered.
+type jsonlLine struct {
+	index  int
+	number int
+	data   []byte
+}
+
+// jsonlResult is the record of the line at index.
+type jsonlResult struct {
+	index  int
+	record JSONLRecord
+}
+
+// RenderJSONLFile renders each line of the JSONL file at inPath, a render
+// request such as `{"messages": [...]}`, and writes a JSONLRecord per line
+// to outPath, in input order, e.g. to render a dataset offline. Blank lines
+// are skipped. Lines failing to decode or render are reported in the Error
+// of their record, and counted in the summary, without stopping the others.
+//
+// Lines are rendered by opts.Concurrency workers, reading ahead of the
+// oldest line not written yet by a bounded number of lines, so that files of
+// any size render in bounded memory. The returned error is only set when
+// the files cannot be read or written, or ctx is done; outPath then holds
+// the records written so far.
+func (w *ChatTemplatingProcessor) RenderJSONLFile(ctx context.Context, inPath, outPath string,
+	opts JSONLOptions,
+) (JSONLSummary, error) {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return JSONLSummary{}, err
+	}
+	defer in.Close()
+	out, err := os.Create(outPath)
+	if err != nil {
+		return JSONLSummary{}, err
+	}
+	summary, err := w.renderJSONL(ctx, in, out, &opts)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return summary, err
+}
+
+// renderJSONL renders the JSONL lines of in to out for RenderJSONLFile.
+func (w *ChatTemplatingProcessor) renderJSONL(ctx context.Context, in io.Reader, out io.Writer,
+	opts *JSONLOptions,
+) (JSONLSummary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultJSONLConcurrency
+	}
+	// A slot is taken per line read and given back once it is written.
+	window := make(chan struct{}, workers*jsonlWindowPerWorker)
+	lines := make(chan jsonlLine)
+	results := make(chan jsonlResult)
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		readErr <- readJSONLLines(ctx, in, window, lines)
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				result := jsonlResult{index: line.index, record: w.renderJSONLLine(ctx, line, opts)}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	summary, err := writeJSONLRecords(results, window, out)
+	if err != nil {
+		// Stop the reader and the workers, which no longer have their results written.
+		cancel()
+		wg.Wait()
+		<-readErr
+		return summary, err
+	}
+	if err := <-readErr; err != nil {
+		return summary, err
+	}
+	return summary, ctx.Err()
+}
+
+// readJSONLLines sends the non-blank lines of in, taking a slot of window
+// for each, until EOF or ctx is done.
+func readJSONLLines(ctx context.Context, in io.Reader, window chan<- struct{}, lines chan<- jsonlLine) error {
+	reader := bufio.NewReader(in)
+	index := 0
+	for number := 1; ; number++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return nil
+			}
+			select {
+			case lines <- jsonlLine{index: index, number: number, data: data}:
+				index++
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read line %d: %w", number, err)
+		}
+	}
+}
+
+// renderJSONLLine decodes and renders a line of RenderJSONLFile.
+func (w *ChatTemplatingProcessor) renderJSONLLine(ctx context.Context, line jsonlLine,
+	opts *JSONLOptions,
+) JSONLRecord {
+	record := JSONLRecord{Line: line.number}
+	req := &RenderJinjaTemplateRequest{}
+	if opts.Template != nil {
+		var err error
+		if req, err = opts.Template.DeepCopy(); err != nil {
+			record.Error = err.Error()
+			return record
+		}
+	}
+	if err := w.decodeRenderRequestInto(bytes.NewReader(line.data), req); err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	if opts.OutputTokenIDs {
+		req.ReturnTokenIDs = true
+	}
+
+	response, err := w.RenderChatTemplate(ctx, req)
+	switch {
+	case err != nil:
+		record.Error = err.Error()
+	case opts.OutputTokenIDs:
+		record.TokenIDs = response.TokenIDs
+	case len(response.RenderedChats) > 0:
+		record.Rendered = response.RenderedChats[0]
+	}
+	return record
+}
+
+// writeJSONLRecords writes the records of results to out in input order,
+// giving back a slot of window per record written.
+func writeJSONLRecords(results <-chan jsonlResult, window <-chan struct{}, out io.Writer) (JSONLSummary, error) {
+	var summary JSONLSummary
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	pending := make(map[int]JSONLRecord)
+	next := 0
+	for result := range results {
+		pending[result.index] = result.record
+		for record, ok := pending[next]; ok; record, ok = pending[next] {
+			delete(pending, next)
+			next++
+			<-window
+			summary.Lines++
+			if record.Error != "" {
+				summary.Failed++
+			}
+			if err := encoder.Encode(record); err != nil {
+				return summary, err
+			}
+		}
+	}
+	return summary, writer.Flush()
+}
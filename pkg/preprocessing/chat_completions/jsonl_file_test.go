@@ -0,0 +1,125 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readJSONLRecords reads the records written by RenderJSONLFile to path.
+func readJSONLRecords(t *testing.T, path string) []preprocessing.JSONLRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []preprocessing.JSONLRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record preprocessing.JSONLRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+// TestRenderJSONLFile tests rendering the conversations of a JSONL file, in order, with per-line errors.
+func TestRenderJSONLFile(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	template := &preprocessing.RenderJinjaTemplateRequest{
+		ChatTemplate: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+	}
+
+	inPath, outPath := filepath.Join(dir, "conversations.jsonl"), filepath.Join(dir, "rendered.jsonl")
+	require.NoError(t, os.WriteFile(inPath, []byte(`{"messages": [{"role": "user", "content": "Hello"}]}
+
+{"messages": [{"role": "user", "content": "Broken"
+{"messages": [{"role": "user", "content": "Bye"}, {"role": "assistant", "content": "Goodbye"}]}`), 0o600))
+
+	summary, err := wrapper.RenderJSONLFile(ctx, inPath, outPath, preprocessing.JSONLOptions{Template: template})
+	require.NoError(t, err, "RenderJSONLFile should not return an error")
+	assert.Equal(t, preprocessing.JSONLSummary{Lines: 3, Failed: 1}, summary)
+
+	records := readJSONLRecords(t, outPath)
+	require.Len(t, records, 3, "Blank lines should be skipped")
+	assert.Equal(t, preprocessing.JSONLRecord{Line: 1, Rendered: "user: Hello\n"}, records[0])
+	assert.Equal(t, 3, records[1].Line)
+	assert.Contains(t, records[1].Error, "failed to decode request", "The malformed line should report its error")
+	assert.Equal(t, preprocessing.JSONLRecord{Line: 4, Rendered: "user: Bye\nassistant: Goodbye\n"}, records[2])
+	assert.Empty(t, template.Conversations, "The template should not be modified")
+
+	t.Run("Order", func(t *testing.T) {
+		var lines strings.Builder
+		for i := range 50 {
+			fmt.Fprintf(&lines, `{"messages": [{"role": "user", "content": "Message %d"}]}`+"\n", i)
+		}
+		require.NoError(t, os.WriteFile(inPath, []byte(lines.String()), 0o600))
+
+		summary, err := wrapper.RenderJSONLFile(ctx, inPath, outPath,
+			preprocessing.JSONLOptions{Template: template, Concurrency: 3})
+		require.NoError(t, err, "RenderJSONLFile should not return an error")
+		assert.Equal(t, preprocessing.JSONLSummary{Lines: 50}, summary)
+		records := readJSONLRecords(t, outPath)
+		require.Len(t, records, 50)
+		for i, record := range records {
+			assert.Equal(t, preprocessing.JSONLRecord{Line: i + 1, Rendered: fmt.Sprintf("user: Message %d\n", i)},
+				record, "Records should be written in input order")
+		}
+	})
+
+	t.Run("Token IDs", func(t *testing.T) {
+		withTokenizer := *template
+		withTokenizer.Tokenizer = &preprocessing.TokenizerSource{
+			Model: "../../tokenization/testdata/test-model", IsLocalPath: true,
+		}
+		require.NoError(t, os.WriteFile(inPath, []byte(`{"messages": [{"role": "user", "content": "Hello"}]}`), 0o600))
+
+		_, err := wrapper.RenderJSONLFile(ctx, inPath, outPath,
+			preprocessing.JSONLOptions{Template: &withTokenizer, OutputTokenIDs: true})
+		require.NoError(t, err, "RenderJSONLFile should not return an error")
+		records := readJSONLRecords(t, outPath)
+		require.Len(t, records, 1)
+		assert.NotEmpty(t, records[0].TokenIDs, "The token IDs should be written")
+		assert.Empty(t, records[0].Rendered, "The rendered text should not be written")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := wrapper.RenderJSONLFile(canceled, inPath, outPath, preprocessing.JSONLOptions{Template: template})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Missing input", func(t *testing.T) {
+		_, err := wrapper.RenderJSONLFile(ctx, filepath.Join(dir, "missing.jsonl"), outPath, preprocessing.JSONLOptions{})
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
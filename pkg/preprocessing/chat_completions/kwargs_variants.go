@@ -0,0 +1,59 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// RenderVariantsKWArgs renders req once per kwargs variant, e.g. with
+// thinking enabled and disabled for A/B testing, returning one response per
+// variant, in order. Each variant is merged over req.ChatTemplateKWArgs,
+// overriding its keys. All variants are rendered in a single call into
+// Python, see RenderChatTemplateBatch. It fails if any variant fails to
+// render. The request is not modified.
+func (w *ChatTemplatingProcessor) RenderVariantsKWArgs(ctx context.Context, req *RenderJinjaTemplateRequest,
+	variants []map[string]interface{},
+) ([]*RenderJinjaTemplateResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+
+	reqs := make([]*RenderJinjaTemplateRequest, len(variants))
+	for i, variant := range variants {
+		applied := *req
+		applied.ChatTemplateKWArgs = make(map[string]interface{}, len(req.ChatTemplateKWArgs)+len(variant))
+		maps.Copy(applied.ChatTemplateKWArgs, req.ChatTemplateKWArgs)
+		maps.Copy(applied.ChatTemplateKWArgs, variant)
+		reqs[i] = &applied
+	}
+
+	results, err := w.RenderChatTemplateBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*RenderJinjaTemplateResponse, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to render kwargs variant %d: %w", i, result.Err)
+		}
+		responses[i] = result.Response
+	}
+	return responses, nil
+}
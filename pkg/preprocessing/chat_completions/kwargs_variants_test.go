@@ -0,0 +1,50 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderVariantsKWArgs tests rendering one request with several kwargs variants in one call.
+func TestRenderVariantsKWArgs(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}" +
+			"{% if enable_thinking %}<think>{% endif %}{{ suffix }}",
+		ChatTemplateKWArgs: map[string]interface{}{"enable_thinking": false, "suffix": "!"},
+	}
+
+	responses, err := wrapper.RenderVariantsKWArgs(context.Background(), request, []map[string]interface{}{
+		{"enable_thinking": true},
+		{},
+	})
+	require.NoError(t, err, "RenderVariantsKWArgs should not return an error")
+	require.Len(t, responses, 2, "There should be one response per variant")
+	assert.Equal(t, "user: Hello\n<think>!", responses[0].RenderedChats[0], "The variant should override the kwargs")
+	assert.Equal(t, "user: Hello\n!", responses[1].RenderedChats[0], "The request kwargs should apply to every variant")
+
+	assert.Equal(t, map[string]interface{}{"enable_thinking": false, "suffix": "!"}, request.ChatTemplateKWArgs,
+		"The request should not be modified")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// nonTemplateErrors are render failures not caused by the template, which
+// the last good template would fail alike, or which a template reports on
+// purpose, such as ErrTemplateRaised.
+var nonTemplateErrors = []error{
+	ErrTemplateRaised, ErrTransient, ErrInternal, ErrShuttingDown, ErrNotInitialized, ErrMemoryPressure,
+	ErrTooManyMessages, ErrDuplicateSystemMessages, ErrReservedTemplateVar,
+	context.Canceled, context.DeadlineExceeded,
+}
+
+// WithLastGoodFallback keeps RenderForModel serving through broken template
+// updates: when a template fails to compile or render, e.g. after a live
+// update introducing a syntax error, the request is rendered again with the
+// last template that rendered successfully for the model, a warning is
+// logged and RenderJinjaTemplateResponse.UsedFallback is set. Failures not
+// caused by the template, such as ErrTemplateRaised or ErrTooManyMessages,
+// are returned as is, as is the original error if the fallback fails too.
+func WithLastGoodFallback() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.lastGoodTemplates = make(map[string]string)
+	}
+}
+
+// renderWithFallback renders req for model, falling back to the model's last
+// good template as set with WithLastGoodFallback.
+func (w *ChatTemplatingProcessor) renderWithFallback(ctx context.Context, model string,
+	req *RenderJinjaTemplateRequest,
+) (*RenderJinjaTemplateResponse, error) {
+	response, err := w.RenderChatTemplate(ctx, req)
+	if w.lastGoodTemplates == nil || req.ChatTemplate == "" {
+		return response, err
+	}
+	w.mu.Lock()
+	lastGood, ok := w.lastGoodTemplates[model]
+	if err == nil {
+		w.lastGoodTemplates[model] = req.ChatTemplate
+	}
+	w.mu.Unlock()
+	if err == nil || !ok || lastGood == req.ChatTemplate || !isTemplateFailure(err) {
+		return response, err
+	}
+
+	fallback := *req
+	fallback.ChatTemplate = lastGood
+	fallbackResponse, fallbackErr := w.RenderChatTemplate(ctx, &fallback)
+	if fallbackErr != nil {
+		return response, err
+	}
+	log.FromContext(ctx).WithName("RenderForModel").Info("Template failed, rendered with the last good template instead",
+		"model", model, "error", err.Error())
+	fallbackResponse.UsedFallback = true
+	return fallbackResponse, nil
+}
+
+// isTemplateFailure reports whether err may be caused by the template.
+func isTemplateFailure(err error) bool {
+	for _, target := range nonTemplateErrors {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
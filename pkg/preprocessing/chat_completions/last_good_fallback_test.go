@@ -0,0 +1,70 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLastGoodFallback tests that a broken template update falls back to the model's last good template.
+func TestLastGoodFallback(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	const model = "acme/chat-model"
+	newRequest := func(template string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:  template,
+		}
+	}
+	good := newRequest("{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}")
+	// The update lost its endfor, so it no longer compiles.
+	broken := newRequest("{% for message in messages %}{{ message.role }}: {{ message.content }}\n")
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithLastGoodFallback())
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	_, err := processor.RenderForModel(ctx, model, broken)
+	assert.Error(t, err, "A model without a last good template should fail")
+
+	expected, err := processor.RenderForModel(ctx, model, good)
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.False(t, expected.UsedFallback)
+
+	response, err := processor.RenderForModel(ctx, model, broken)
+	require.NoError(t, err, "A broken update should fall back to the last good template")
+	assert.True(t, response.UsedFallback)
+	assert.Equal(t, expected.RenderedChats, response.RenderedChats, "The fallback should render the last good template")
+
+	_, err = processor.RenderForModel(ctx, "acme/other-model", broken)
+	assert.Error(t, err, "Last good templates should not be shared across models")
+
+	plain := preprocessing.NewChatTemplatingProcessor()
+	require.NoError(t, plain.Initialize(), "Initialize should not return an error")
+	t.Cleanup(plain.Finalize)
+	_, err = plain.RenderForModel(ctx, model, good)
+	require.NoError(t, err, "RenderForModel should not return an error")
+	_, err = plain.RenderForModel(ctx, model, broken)
+	assert.Error(t, err, "Processors without WithLastGoodFallback should not fall back")
+}
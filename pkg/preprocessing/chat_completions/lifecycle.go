@@ -0,0 +1,122 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// managesLifecycle reports whether the processor initializes and finalizes
+// the interpreter itself, as set with WithLazyInit or WithIdleTimeout.
+func (w *ChatTemplatingProcessor) managesLifecycle() bool {
+	return w.lazyInit || w.idleTimeout > 0
+}
+
+// acquire marks the start of a call into the interpreter, initializing it
+// first if the processor manages its lifecycle and it is not up. The
+// returned function must be called when the call is done. Calls fail with
+// ErrShuttingDown once Shutdown started.
+func (w *ChatTemplatingProcessor) acquire() (func(), error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+	if w.managesLifecycle() && !w.initialized {
+		if err := w.initializeLocked(); err != nil {
+			return nil, err
+		}
+	}
+	w.active++
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+	}
+	return w.release, nil
+}
+
+// release marks the end of a call started with acquire, signals Shutdown
+// once no call is in flight, and arms the idle timer then.
+func (w *ChatTemplatingProcessor) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.active--
+	w.lastUsed = time.Now()
+	if w.active == 0 && w.drained != nil {
+		close(w.drained)
+		w.drained = nil
+	}
+	if w.active > 0 || w.idleTimeout <= 0 || !w.initialized {
+		return
+	}
+
+	if w.idleTimer == nil {
+		w.idleTimer = time.AfterFunc(w.idleTimeout, w.finalizeIdle)
+	} else {
+		w.idleTimer.Reset(w.idleTimeout)
+	}
+}
+
+// Shutdown gracefully stops the processor: new calls fail with
+// ErrShuttingDown, the calls in flight are waited for and the processor is
+// then finalized, which finalizes the interpreter unless other processors
+// still hold it, see Finalize. If ctx is done before the calls drain, the
+// processor is finalized anyway and the context's error is returned.
+// Initialize makes the processor accept calls again.
+func (w *ChatTemplatingProcessor) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	w.shuttingDown = true
+	var drained chan struct{}
+	if w.active > 0 {
+		if w.drained == nil {
+			w.drained = make(chan struct{})
+		}
+		drained = w.drained
+	}
+	active := w.active
+	w.mu.Unlock()
+
+	var err error
+	if drained != nil {
+		log.FromContext(ctx).V(logging.DEBUG).WithName("Shutdown").Info("Draining calls in flight", "active", active)
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			err = fmt.Errorf("calls in flight did not drain: %w", ctx.Err())
+		}
+	}
+	w.Finalize()
+	return err
+}
+
+// finalizeIdle finalizes the interpreter when the idle timer fires, unless a
+// call started or finished since it was armed.
+func (w *ChatTemplatingProcessor) finalizeIdle() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.active > 0 || time.Since(w.lastUsed) < w.idleTimeout {
+		return
+	}
+
+	log.Log.V(logging.DEBUG).WithName("finalizeIdle").Info("Finalizing idle interpreter",
+		"idleTimeout", w.idleTimeout)
+	w.finalizeLocked()
+}
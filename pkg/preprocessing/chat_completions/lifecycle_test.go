@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIdleTimeout tests that a processor finalizes the interpreter once idle, and initializes it again when used.
+func TestIdleTimeout(t *testing.T) {
+	globalWrapperMu.Lock()
+	defer globalWrapperMu.Unlock()
+
+	wrapper := getGlobalWrapper()
+	t.Cleanup(func() {
+		// The module is process-wide, restore it for the other tests.
+		require.NoError(t, wrapper.Initialize(), "Re-Initialize should not return an error")
+		renderLocalTemplate(t, wrapper)
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		other := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, other.Initialize(), "Initialize should not return an error")
+		t.Cleanup(other.Finalize)
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithIdleTimeout(50 * time.Millisecond))
+		t.Cleanup(processor.Finalize)
+
+		assert.False(t, preprocessing.IsInitialized(processor), "The interpreter should not be initialized before use")
+		require.NoError(t, processor.Liveness(context.Background()), "An idle processor should be live")
+
+		renderLocalTemplate(t, processor)
+		assert.True(t, preprocessing.IsInitialized(processor), "The first call should initialize the interpreter")
+
+		require.Eventually(t, func() bool { return !preprocessing.IsInitialized(processor) }, 5*time.Second,
+			10*time.Millisecond, "The interpreter should be finalized once idle")
+		renderLocalTemplate(t, other)
+
+		renderLocalTemplate(t, processor)
+		assert.True(t, preprocessing.IsInitialized(processor), "A call after idle shutdown should initialize the interpreter again")
+	})
+
+	t.Run("Last processor", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithIdleTimeout(50 * time.Millisecond))
+		t.Cleanup(processor.Finalize)
+		renderLocalTemplate(t, processor)
+		wrapper.Finalize()
+		require.Equal(t, 1, preprocessing.InterpreterRefs(), "Only the idle processor should hold the interpreter")
+
+		require.Eventually(t, func() bool { return preprocessing.InterpreterRefs() == 0 }, 5*time.Second,
+			10*time.Millisecond, "The interpreter should be finalized once its last processor is idle")
+		renderLocalTemplate(t, processor)
+	})
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+//nolint: gocritic // C and unsafe are considered dups by the linter.
+import (
+	"context"
+	"fmt"
+
+	/*
+		#include "cgo_functions.h"
+	*/
+	"C"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/metrics"
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PyMemStats holds memory statistics of the embedded Python interpreter.
+// Unlike runtime.MemStats, these cover the objects held by the Python side,
+// such as cached tokenizers and compiled templates.
+type PyMemStats struct {
+	// AllocatedBlocks is the number of memory blocks currently allocated by
+	// the interpreter, as reported by `sys.getallocatedblocks()`.
+	AllocatedBlocks int64
+	// GCObjects is the number of objects tracked by the garbage collector.
+	GCObjects int64
+	// TracedBytes is the memory currently traced by `tracemalloc`. It is zero
+	// unless tracing was enabled, e.g. with PYTHONTRACEMALLOC=1.
+	TracedBytes int64
+	// ResidentBytes is the resident set size of the process hosting the
+	// interpreter, Go runtime included, or -1 where it cannot be read.
+	ResidentBytes int64
+}
+
+// checkMemoryLimit returns ErrMemoryPressure if the process exceeds the
+// memory limit. It passes when no limit is set or the size is unknown.
+func (w *ChatTemplatingProcessor) checkMemoryLimit() error {
+	if w.memoryLimit <= 0 {
+		return nil
+	}
+	// Reading the resident set size does not involve the interpreter, so it
+	// does not need to wait for the locked thread.
+	if resident := int64(C.Py_ResidentBytes()); resident > w.memoryLimit {
+		return fmt.Errorf("%w: resident set of %d bytes exceeds the %d bytes limit",
+			ErrMemoryPressure, resident, w.memoryLimit)
+	}
+	return nil
+}
+
+// PythonMemoryUsage returns the memory statistics of the embedded Python
+// interpreter, and updates the corresponding gauges in the metrics package.
+func PythonMemoryUsage(ctx context.Context) (_ PyMemStats, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("PythonMemoryUsage")
+
+	var cStats C.PyMemStatsGo
+	var result C.int
+	if err := cgoThread.run(ctx, func() { result = C.Py_MemStats(&cStats) }); err != nil {
+		return PyMemStats{}, err
+	}
+	if result != 0 {
+		traceLogger.Error(nil, "C function failed")
+		return PyMemStats{}, fmt.Errorf("failed to collect python memory statistics")
+	}
+
+	stats := PyMemStats{
+		AllocatedBlocks: int64(cStats.allocated_blocks),
+		GCObjects:       int64(cStats.gc_objects),
+		TracedBytes:     int64(cStats.traced_bytes),
+		ResidentBytes:   int64(cStats.resident_bytes),
+	}
+	metrics.PythonAllocatedBlocks.Set(float64(stats.AllocatedBlocks))
+	metrics.PythonGCObjects.Set(float64(stats.GCObjects))
+
+	traceLogger.Info("Python memory usage", "allocatedBlocks", stats.AllocatedBlocks,
+		"gcObjects", stats.GCObjects, "tracedBytes", stats.TracedBytes, "residentBytes", stats.ResidentBytes)
+	return stats, nil
+}
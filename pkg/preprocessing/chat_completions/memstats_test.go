@@ -0,0 +1,85 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPythonMemoryUsage tests that interpreter memory statistics are reported after renders.
+func TestPythonMemoryUsage(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	template, templateVars, err := wrapper.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	for range 5 {
+		_, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:       template,
+			ChatTemplateKWArgs: templateVars,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+	}
+
+	stats, err := preprocessing.PythonMemoryUsage(ctx)
+	require.NoError(t, err, "PythonMemoryUsage should not return an error")
+	assert.Positive(t, stats.AllocatedBlocks, "Allocated blocks should be reported")
+	assert.Positive(t, stats.GCObjects, "GC objects should be reported")
+	assert.GreaterOrEqual(t, stats.TracedBytes, int64(0), "Traced bytes should not be negative")
+	assert.NotZero(t, stats.ResidentBytes, "The resident set size should be reported, or -1 if unknown")
+}
+
+// TestRenderChatTemplateMemoryLimit tests that renders are rejected before rendering under memory pressure.
+func TestRenderChatTemplateMemoryLimit(t *testing.T) {
+	getGlobalWrapper()
+
+	stats, err := preprocessing.PythonMemoryUsage(context.Background())
+	require.NoError(t, err, "PythonMemoryUsage should not return an error")
+	if stats.ResidentBytes < 0 {
+		t.Skip("The resident set size cannot be read on this platform")
+	}
+
+	// A template failing to render tells whether the render was attempted.
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  `{{ raise_exception("rendered") }}`,
+	}
+
+	// Any process is over a 1 byte limit, simulating memory pressure.
+	constrained := preprocessing.NewChatTemplatingProcessor(preprocessing.WithMemoryLimit(1))
+	require.NoError(t, constrained.Initialize())
+	t.Cleanup(constrained.Finalize)
+	_, err = constrained.RenderChatTemplate(context.Background(), request)
+	assert.ErrorIs(t, err, preprocessing.ErrMemoryPressure, "The render should be rejected before rendering")
+
+	unconstrained := preprocessing.NewChatTemplatingProcessor(preprocessing.WithMemoryLimit(1 << 50))
+	require.NoError(t, unconstrained.Initialize())
+	t.Cleanup(unconstrained.Finalize)
+	_, err = unconstrained.RenderChatTemplate(context.Background(), request)
+	require.Error(t, err, "The template should fail to render")
+	assert.NotErrorIs(t, err, preprocessing.ErrMemoryPressure, "The render should be attempted under the limit")
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// WithMaxMessages rejects renders of conversations with more than n
+// messages with ErrTooManyMessages, before any call into Python, so that
+// abusive requests cannot exhaust the interpreter. Unlike the request's
+// MaxMessages window, which drops old messages and renders the rest, the
+// whole request fails. A non-positive n disables the limit, the default.
+func WithMaxMessages(n int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.maxMessages = n
+	}
+}
+
+// checkMessageLimit returns ErrTooManyMessages if req has more messages than
+// the limit set with WithMaxMessages.
+func (w *ChatTemplatingProcessor) checkMessageLimit(req *RenderJinjaTemplateRequest) error {
+	if w.maxMessages > 0 && len(req.Conversations) > w.maxMessages {
+		return fmt.Errorf("%w: %d messages, limit is %d", ErrTooManyMessages, len(req.Conversations), w.maxMessages)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithMaxMessages tests that conversations over the message limit are rejected before calling into Python.
+func TestWithMaxMessages(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithMaxMessages(3))
+
+	newRequest := func(messages int) *preprocessing.RenderJinjaTemplateRequest {
+		request := &preprocessing.RenderJinjaTemplateRequest{
+			ChatTemplate: "{% for message in messages %}{{ message.content }}{% endfor %}",
+		}
+		for range messages {
+			request.Conversations = append(request.Conversations, preprocessing.ChatMessage{Role: "user", Content: "Hi"})
+		}
+		return request
+	}
+
+	var calls atomic.Int32
+	preprocessing.SetFaultHook(func() { calls.Add(1) })
+	t.Cleanup(func() { preprocessing.SetFaultHook(nil) })
+
+	_, err := processor.RenderChatTemplate(ctx, newRequest(4))
+	require.ErrorIs(t, err, preprocessing.ErrTooManyMessages, "Conversations over the limit should be rejected")
+	assert.Zero(t, calls.Load(), "Conversations over the limit should be rejected before calling into Python")
+
+	results, err := processor.RenderChatTemplateBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{
+		newRequest(4), newRequest(2),
+	})
+	require.NoError(t, err, "RenderChatTemplateBatch should not return an error")
+	assert.ErrorIs(t, results[0].Err, preprocessing.ErrTooManyMessages, "Batched requests over the limit should fail")
+	assert.NoError(t, results[1].Err, "Batched requests within the limit should render")
+
+	response, err := processor.RenderChatTemplate(ctx, newRequest(3))
+	require.NoError(t, err, "Conversations at the limit should render")
+	assert.NotEmpty(t, response.RenderedChats)
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateMessageName tests rendering named and unnamed messages with a template using message.name.
+func TestRenderChatTemplateMessageName(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Name: "alice", Content: "Hi, I am Alice."},
+			{Role: "user", Content: "And I have no name."},
+		},
+		ChatTemplate: `{% for message in messages %}{{ message.role }}` +
+			`{% if message.name is defined %} ({{ message.name }}){% endif %}: {{ message.content }}{{ "\n" }}{% endfor %}`,
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "user (alice): Hi, I am Alice.\nuser: And I have no name.\n", response.RenderedChats[0],
+		"Names should be passed to the template, and messages without one render as before")
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+const systemRole = "system"
+
+// windowMessages keeps the leading system messages and the most recent
+// maxMessages other messages, returning them along with the number of
+// dropped messages. If marker is set and messages are dropped, a system
+// message with marker is inserted in their place, after the leading system
+// messages. The input slice is not modified.
+func windowMessages(messages []ChatMessage, maxMessages int, marker string) ([]ChatMessage, int) {
+	systemPrefix := 0
+	for systemPrefix < len(messages) && messages[systemPrefix].Role == systemRole {
+		systemPrefix++
+	}
+
+	dropped := len(messages) - systemPrefix - maxMessages
+	if dropped <= 0 {
+		return messages, 0
+	}
+
+	windowed := make([]ChatMessage, 0, systemPrefix+maxMessages+1)
+	windowed = append(windowed, messages[:systemPrefix]...)
+	if marker != "" {
+		windowed = append(windowed, ChatMessage{Role: systemRole, Content: marker})
+	}
+	windowed = append(windowed, messages[systemPrefix+dropped:]...)
+	return windowed, dropped
+}
+
+// injectSystemPrompt prepends a system message with prompt to messages, unless
+// they already start with a system message. The input slice is not modified.
+func injectSystemPrompt(messages []ChatMessage, prompt string) []ChatMessage {
+	if len(messages) > 0 && messages[0].Role == systemRole {
+		return messages
+	}
+	injected := make([]ChatMessage, 0, len(messages)+1)
+	injected = append(injected, ChatMessage{Role: systemRole, Content: prompt})
+	return append(injected, messages...)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateMaxMessages tests rendering a long conversation with a small message window.
+func TestRenderChatTemplateMaxMessages(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	conversation := []preprocessing.ChatMessage{{Role: "system", Content: "You are a helpful assistant."}}
+	for i := range 20 {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		conversation = append(conversation, preprocessing.ChatMessage{Role: role, Content: fmt.Sprintf("turn %d", i)})
+	}
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations:      conversation,
+		ChatTemplate:       template,
+		ChatTemplateKWArgs: templateVars,
+		MaxMessages:        3,
+	}
+	response, err := wrapper.RenderChatTemplate(context.Background(), request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	assert.Equal(t, 17, response.DroppedMessages, "All but the system prompt and the 3 last messages should be dropped")
+	assert.Equal(t, "system: You are a helpful assistant.\nassistant: turn 17\nuser: turn 18\nassistant: turn 19\n",
+		response.RenderedChats[0], "Only the system prompt and the most recent messages should be rendered")
+	assert.Len(t, request.Conversations, 21, "The caller's request should not be modified")
+
+	// A window larger than the conversation drops nothing.
+	request.MaxMessages = 100
+	response, err = wrapper.RenderChatTemplate(context.Background(), request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Zero(t, response.DroppedMessages, "No message should be dropped")
+}
+
+// TestRenderChatTemplateTruncationMarker tests inserting a marker in place of the messages dropped by the window.
+func TestRenderChatTemplateTruncationMarker(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "turn 0"},
+			{Role: "assistant", Content: "turn 1"},
+			{Role: "user", Content: "turn 2"},
+		},
+		ChatTemplate:     "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		MaxMessages:      1,
+		TruncationMarker: "[earlier messages omitted]",
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "system: You are a helpful assistant.\nsystem: [earlier messages omitted]\nuser: turn 2\n",
+		response.RenderedChats[0], "The marker should replace the dropped messages")
+	assert.True(t, response.TruncationMarkerInserted)
+	assert.Equal(t, 2, response.DroppedMessages, "The marker should not count as a message")
+
+	// Without dropped messages, no marker is inserted.
+	request.MaxMessages = 3
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.NotContains(t, response.RenderedChats[0], "[earlier messages omitted]")
+	assert.False(t, response.TruncationMarkerInserted)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/kvcache/metrics"
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counterValue returns the current value of counter.
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, counter.Write(&m))
+	return m.GetCounter().GetValue()
+}
+
+// TestRenderCancellationsMetric tests that renders canceled by their context are counted.
+func TestRenderCancellationsMetric(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	before := counterValue(t, metrics.RenderCancellations)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+	}
+	_, err := wrapper.RenderChatTemplate(ctx, request)
+	require.ErrorIs(t, err, context.Canceled, "The render should be canceled")
+	_, err = wrapper.RenderChatTemplateBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{request})
+	require.ErrorIs(t, err, context.Canceled, "The batch should be canceled")
+	assert.Equal(t, before+2, counterValue(t, metrics.RenderCancellations), "Canceled renders should be counted")
+
+	_, err = wrapper.RenderChatTemplate(context.Background(), request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, before+2, counterValue(t, metrics.RenderCancellations), "Successful renders should not be counted")
+}
+
+// TestInterpreterRestartsMetric tests that reinitializations and hot reloads of the interpreter are counted.
+func TestInterpreterRestartsMetric(t *testing.T) {
+	globalWrapperMu.Lock()
+	defer globalWrapperMu.Unlock()
+
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	before := counterValue(t, metrics.InterpreterRestarts)
+
+	// Initializing the running interpreter again is not a restart.
+	processor := preprocessing.NewChatTemplatingProcessor()
+	require.NoError(t, processor.Initialize())
+	assert.Equal(t, before, counterValue(t, metrics.InterpreterRestarts), "Sharing the interpreter is not a restart")
+	processor.Finalize()
+
+	wrapper.Finalize()
+	require.Zero(t, preprocessing.InterpreterRefs(), "No processor should hold the interpreter")
+	require.NoError(t, wrapper.Initialize(), "Re-Initialize should not return an error")
+	renderLocalTemplate(t, wrapper)
+	assert.Equal(t, before+1, counterValue(t, metrics.InterpreterRestarts), "Reinitializations should be counted")
+
+	require.NoError(t, wrapper.HotReload(ctx), "HotReload should not return an error")
+	assert.Equal(t, before+2, counterValue(t, metrics.InterpreterRestarts), "Hot reloads should be counted")
+}
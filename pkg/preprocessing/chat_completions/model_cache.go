@@ -0,0 +1,56 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// clearModelCacheRequest is the JSON payload sent to clear_model_cache.
+type clearModelCacheRequest struct {
+	Model    string `json:"model"`
+	Revision string `json:"revision,omitempty"`
+}
+
+// clearModelCacheResponse is the JSON result of clear_model_cache.
+type clearModelCacheResponse struct {
+	templateEvictions
+	Evicted int `json:"evicted"`
+}
+
+// ClearModelCache evicts the template of model at revision, "main" if empty,
+// from the template cache, along with its compiled form, tokenizer and
+// configs, so that the next fetch reloads it, e.g. after its template
+// changed. Fetches of the model remembered as failing with ErrModelNotFound
+// are forgotten too. Unlike ClearCaches, other models stay cached. Pins are
+// kept.
+func ClearModelCache(ctx context.Context, model, revision string) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	var resp clearModelCacheResponse
+	if err := callPythonFunction(ctx, "clear_model_cache",
+		clearModelCacheRequest{Model: model, Revision: revision}, &resp); err != nil {
+		return err
+	}
+	cachedTemplates.update(model, revision, "", resp.EvictedTemplates)
+	notFoundModels.clearModel(model, revision)
+	log.FromContext(ctx).V(logging.TRACE).WithName("ClearModelCache").Info("Cleared model cache",
+		"model", model, "revision", revision, "evicted", resp.Evicted)
+	return nil
+}
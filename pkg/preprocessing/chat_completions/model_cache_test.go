@@ -0,0 +1,96 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClearModelCache tests that clearing one model's caches leaves the other models cached.
+func TestClearModelCache(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	require.NoError(t, preprocessing.ClearCaches(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+	})
+
+	// Two copies of the test model, with distinct templates.
+	cleared := filepath.Join(t.TempDir(), "cleared")
+	kept := filepath.Join(t.TempDir(), "kept")
+	for i, model := range []string{cleared, kept} {
+		require.NoError(t, os.CopyFS(model, os.DirFS(testModelPath)))
+		configPath := filepath.Join(model, "tokenizer_config.json")
+		data, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		var config map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &config))
+		config["chat_template"] = config["chat_template"].(string) + strings.Repeat(" ", i)
+		data, err = json.Marshal(config)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(configPath, data, 0o600))
+	}
+
+	// render fetches the template of model and renders it, reporting whether it was compiled from cache.
+	render := func(model string) bool {
+		template, _, err := wrapper.FetchChatTemplate(ctx,
+			preprocessing.FetchChatTemplateRequest{Model: model, IsLocalPath: true})
+		require.NoError(t, err, "FetchChatTemplate should not return an error")
+		response, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:  template,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response.CompileCacheHit
+	}
+	// cachedModels returns the models in the template cache.
+	cachedModels := func() []string {
+		data, err := wrapper.DumpCacheState(ctx)
+		require.NoError(t, err, "DumpCacheState should not return an error")
+		var state preprocessing.CacheState
+		require.NoError(t, json.Unmarshal(data, &state))
+		models := make([]string, 0, len(state.Templates))
+		for _, entry := range state.Templates {
+			models = append(models, entry.Model)
+		}
+		return models
+	}
+
+	render(cleared)
+	render(kept)
+	require.ElementsMatch(t, []string{cleared, kept}, cachedModels())
+
+	require.NoError(t, preprocessing.ClearModelCache(ctx, cleared, ""), "ClearModelCache should not return an error")
+	assert.Equal(t, []string{kept}, cachedModels(), "Only the cleared model should be evicted from the template cache")
+	assert.True(t, render(kept), "The other model's compiled template should survive")
+	assert.False(t, render(cleared), "The cleared model's compiled template should be evicted")
+
+	require.NoError(t, preprocessing.ClearModelCache(ctx, "unknown-model", "v1"),
+		"Clearing a model that is not cached should be a no-op")
+	assert.ElementsMatch(t, []string{cleared, kept}, cachedModels())
+}
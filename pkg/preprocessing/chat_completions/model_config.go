@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// ModelConfig is a model's templating configuration, as already held by
+// callers that load tokenizer configs themselves, e.g. from a model registry.
+type ModelConfig struct {
+	// ChatTemplate is the model's Jinja chat template.
+	ChatTemplate string
+	// ChatTemplateKWArgs are extra template variables, as returned by
+	// FetchChatTemplate. They take precedence over SpecialTokens.
+	ChatTemplateKWArgs map[string]interface{}
+	// SpecialTokens maps template variables such as `bos_token` and
+	// `eos_token` to the model's special tokens.
+	SpecialTokens map[string]string
+}
+
+// RenderOptions are the per-request settings of RenderFromConfig.
+type RenderOptions struct {
+	Tools                []interface{}
+	Documents            []interface{}
+	AddGenerationPrompt  bool
+	ContinueFinalMessage bool
+	TemplateVars         map[string]interface{}
+}
+
+// RenderFromConfig renders messages with the template and special tokens of
+// cfg, without fetching anything from the model.
+func (w *ChatTemplatingProcessor) RenderFromConfig(ctx context.Context, cfg ModelConfig, messages []ChatMessage,
+	opts RenderOptions,
+) (*RenderJinjaTemplateResponse, error) {
+	if cfg.ChatTemplate == "" {
+		return nil, fmt.Errorf("model config has no chat template")
+	}
+
+	kwargs := make(map[string]interface{}, len(cfg.SpecialTokens)+len(cfg.ChatTemplateKWArgs))
+	for name, token := range cfg.SpecialTokens {
+		kwargs[name] = token
+	}
+	for name, value := range cfg.ChatTemplateKWArgs {
+		kwargs[name] = value
+	}
+
+	return w.RenderChatTemplate(ctx, &RenderJinjaTemplateRequest{
+		Conversations:        messages,
+		Tools:                opts.Tools,
+		Documents:            opts.Documents,
+		ChatTemplate:         cfg.ChatTemplate,
+		ContinueFinalMessage: opts.ContinueFinalMessage,
+		AddGenerationPrompt:  opts.AddGenerationPrompt,
+		ChatTemplateKWArgs:   kwargs,
+		TemplateVars:         opts.TemplateVars,
+	})
+}
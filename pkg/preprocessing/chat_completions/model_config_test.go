@@ -0,0 +1,53 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderFromConfig tests rendering from an in-memory model config, without fetching the model.
+func TestRenderFromConfig(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	cfg := preprocessing.ModelConfig{
+		ChatTemplate: "{{ bos_token }}{% for message in messages %}[{{ message.role }}] {{ message.content }}{{ eos_token }}" +
+			"{% endfor %}{% if add_generation_prompt %}[assistant] {% endif %}",
+		SpecialTokens: map[string]string{"bos_token": "<s>", "eos_token": "</s>"},
+	}
+	messages := []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}}
+
+	response, err := wrapper.RenderFromConfig(ctx, cfg, messages, preprocessing.RenderOptions{AddGenerationPrompt: true})
+	require.NoError(t, err, "RenderFromConfig should not return an error")
+	assert.Equal(t, "<s>[user] Hello</s>[assistant] ", response.RenderedChats[0],
+		"The config's special tokens should be rendered")
+
+	// Kwargs take precedence over special tokens.
+	cfg.ChatTemplateKWArgs = map[string]interface{}{"bos_token": ""}
+	response, err = wrapper.RenderFromConfig(ctx, cfg, messages, preprocessing.RenderOptions{})
+	require.NoError(t, err, "RenderFromConfig should not return an error")
+	assert.Equal(t, "[user] Hello</s>", response.RenderedChats[0], "Kwargs should override special tokens")
+
+	_, err = wrapper.RenderFromConfig(ctx, preprocessing.ModelConfig{}, messages, preprocessing.RenderOptions{})
+	assert.Error(t, err, "A config without a chat template should fail")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// modelMaxLengthResponse is the JSON result of get_model_max_length.
+type modelMaxLengthResponse struct {
+	templateEvictions
+	ModelMaxLength int    `json:"model_max_length"`
+	CacheKey       string `json:"cache_key,omitempty"`
+	NotFound       string `json:"not_found,omitempty"`
+	RateLimited    string `json:"rate_limited,omitempty"`
+}
+
+// ModelMaxLength returns the `model_max_length` of the tokenizer of model, a
+// HuggingFace model ID or a local model path, e.g. to decide how to trim a
+// conversation before rendering. It is read when the template is fetched and
+// cached with it. Tokenizers setting none, or a sentinel for "no limit" such
+// as transformers' 1e30, report 0. Models that do not exist fail with
+// ErrModelNotFound.
+func (w *ChatTemplatingProcessor) ModelMaxLength(ctx context.Context, model string) (_ int, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	_, statErr := os.Stat(model)
+	req := FetchChatTemplateRequest{Model: model, IsLocalPath: statErr == nil}
+	req.Revision = w.revisionOrDefault("", req.IsLocalPath)
+	var resp modelMaxLengthResponse
+	payload := fetchChatTemplatePayload{FetchChatTemplateRequest: req, ProxyURL: w.httpProxy}
+	if err := callPythonFunction(ctx, "get_model_max_length", &payload, &resp); err != nil {
+		return 0, err
+	}
+	cachedTemplates.update(model, req.Revision, resp.CacheKey, resp.EvictedTemplates)
+	switch {
+	case resp.RateLimited != "":
+		return 0, fmt.Errorf("%w: %s: %s", ErrRateLimited, model, resp.RateLimited)
+	case resp.NotFound != "":
+		return 0, fmt.Errorf("%w: %s: %s", ErrModelNotFound, model, resp.NotFound)
+	}
+	return resp.ModelMaxLength, nil
+}
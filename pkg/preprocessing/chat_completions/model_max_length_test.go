@@ -0,0 +1,94 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModelMaxLength tests reading the tokenizer's model_max_length, sanitizing sentinel values.
+func TestModelMaxLength(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	maxLength, err := wrapper.ModelMaxLength(ctx, testModelPath)
+	require.NoError(t, err, "ModelMaxLength should not return an error")
+	assert.Equal(t, 512, maxLength, "The test model's tokenizer allows 512 tokens")
+
+	// A copy of the test model whose tokenizer sets transformers' "no limit" sentinel.
+	unlimited := copyModelWithMaxLength(t, testModelPath, 1e30)
+	maxLength, err = wrapper.ModelMaxLength(ctx, unlimited)
+	require.NoError(t, err, "ModelMaxLength should not return an error")
+	assert.Zero(t, maxLength, "Sentinel values should be reported as 0")
+}
+
+// TestRenderChatTemplateExceedsModelMaxLength tests flagging renders with more tokens than the model allows.
+func TestRenderChatTemplateExceedsModelMaxLength(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	small := copyModelWithMaxLength(t, "../../tokenization/testdata/test-model", 8)
+
+	render := func(content string) *preprocessing.RenderJinjaTemplateResponse {
+		response, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:  []preprocessing.ChatMessage{{Role: "user", Content: content}},
+			ChatTemplate:   "{% for message in messages %}{{ message.content }}{% endfor %}",
+			ReturnTokenIDs: true,
+			Tokenizer:      &preprocessing.TokenizerSource{Model: small, IsLocalPath: true},
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response
+	}
+
+	response := render("Hello")
+	assert.False(t, response.ExceedsModelMaxLength, "A short prompt should fit the model")
+	assert.Zero(t, response.ModelMaxLengthOverflow)
+	assert.NotContains(t, strings.Join(response.Warnings, "\n"), "model_max_length")
+
+	response = render("one two three four five six seven eight nine ten eleven twelve")
+	require.Greater(t, len(response.TokenIDs), 8)
+	assert.True(t, response.ExceedsModelMaxLength, "A long prompt should exceed the model max length")
+	assert.Equal(t, len(response.TokenIDs)-8, response.ModelMaxLengthOverflow)
+	assert.Contains(t, strings.Join(response.Warnings, "\n"), "exceeding the model_max_length of 8")
+}
+
+// copyModelWithMaxLength copies the model at path to a temporary directory, setting its tokenizer's
+// model_max_length, and returns the copy's path.
+func copyModelWithMaxLength(t *testing.T, path string, maxLength float64) string {
+	t.Helper()
+	model := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.CopyFS(model, os.DirFS(path)))
+	configPath := filepath.Join(model, "tokenizer_config.json")
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &config))
+	config["model_max_length"] = maxLength
+	data, err = json.Marshal(config)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, data, 0o600))
+	return model
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// bosTokenVar is the template variable holding the BOS token.
+const bosTokenVar = "bos_token"
+
+// ModelPolicy is the special-token handling of a model, registered with
+// WithModelPolicy and applied by RenderForModel. Serving stacks differ in
+// whether they add special tokens themselves, and prompts only share cached
+// prefixes if they are rendered the way the model server renders them.
+type ModelPolicy struct {
+	// SuppressBOS renders the template with an empty `bos_token`, for models
+	// whose serving stack adds the BOS token itself. Templates hard-coding the
+	// BOS text rather than using `bos_token` are not affected.
+	SuppressBOS bool
+	// AddSpecialTokens replaces the request's AddSpecialTokens.
+	AddSpecialTokens bool
+	// AddGenerationPrompt, if set, replaces the request's AddGenerationPrompt.
+	AddGenerationPrompt *bool
+}
+
+// WithModelPolicy registers policy for renders of model with RenderForModel.
+// Registering a model again replaces its policy.
+func WithModelPolicy(model string, policy ModelPolicy) Option {
+	return func(w *ChatTemplatingProcessor) {
+		if w.modelPolicies == nil {
+			w.modelPolicies = make(map[string]ModelPolicy)
+		}
+		w.modelPolicies[model] = policy
+	}
+}
+
+// SetModelKWArgs registers template variables, such as `enable_thinking`,
+// merged into every RenderForModel of model. Variables set by the request,
+// in ChatTemplateKWArgs or TemplateVars, take precedence. Registering a model
+// again replaces its variables, and registering nil removes them. It is safe
+// to call concurrently with renders.
+func (w *ChatTemplatingProcessor) SetModelKWArgs(model string, kwargs map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if kwargs == nil {
+		delete(w.modelKWArgs, model)
+		return
+	}
+	if w.modelKWArgs == nil {
+		w.modelKWArgs = make(map[string]map[string]interface{})
+	}
+	w.modelKWArgs[model] = maps.Clone(kwargs)
+}
+
+// RenderForModel renders a chat template like RenderChatTemplate, applying
+// the policy registered for model with WithModelPolicy and the template
+// variables registered with SetModelKWArgs. Models without either are
+// rendered as requested. The request is not modified. See
+// WithLastGoodFallback for recovering from broken template updates.
+func (w *ChatTemplatingProcessor) RenderForModel(ctx context.Context, model string,
+	req *RenderJinjaTemplateRequest,
+) (*RenderJinjaTemplateResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+
+	policy, hasPolicy := w.modelPolicies[model]
+	w.mu.Lock()
+	modelKWArgs := w.modelKWArgs[model]
+	w.mu.Unlock()
+	if !hasPolicy && modelKWArgs == nil {
+		return w.renderWithFallback(ctx, model, req)
+	}
+
+	applied := *req
+	if modelKWArgs != nil {
+		applied.ChatTemplateKWArgs = make(map[string]interface{}, len(modelKWArgs)+len(req.ChatTemplateKWArgs))
+		for name, value := range modelKWArgs {
+			if _, ok := req.TemplateVars[name]; !ok {
+				applied.ChatTemplateKWArgs[name] = value
+			}
+		}
+		maps.Copy(applied.ChatTemplateKWArgs, req.ChatTemplateKWArgs)
+	}
+	if !hasPolicy {
+		return w.renderWithFallback(ctx, model, &applied)
+	}
+
+	if policy.SuppressBOS {
+		applied.ChatTemplateKWArgs = maps.Clone(applied.ChatTemplateKWArgs)
+		if applied.ChatTemplateKWArgs == nil {
+			applied.ChatTemplateKWArgs = make(map[string]interface{}, 1)
+		}
+		applied.ChatTemplateKWArgs[bosTokenVar] = ""
+	}
+	applied.AddSpecialTokens = policy.AddSpecialTokens
+	if policy.AddGenerationPrompt != nil {
+		applied.AddGenerationPrompt = *policy.AddGenerationPrompt
+	}
+	return w.renderWithFallback(ctx, model, &applied)
+}
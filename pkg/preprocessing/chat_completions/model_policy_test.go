@@ -0,0 +1,129 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderForModel tests that the policy registered for a model is applied to its renders only.
+func TestRenderForModel(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	addGenerationPrompt := true
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithModelPolicy("served-with-bos", preprocessing.ModelPolicy{
+			SuppressBOS:         true,
+			AddGenerationPrompt: &addGenerationPrompt,
+		}))
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate: "{{ bos_token }}{% for message in messages %}{{ message.content }}{% endfor %}" +
+			"{% if add_generation_prompt %}<assistant>{% endif %}",
+		ChatTemplateKWArgs: map[string]interface{}{"bos_token": "<s>"},
+	}
+
+	response, err := processor.RenderForModel(ctx, "served-with-bos", request)
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.Equal(t, "Hello<assistant>", response.RenderedChats[0],
+		"The BOS token should be suppressed and the generation prompt added")
+	assert.Equal(t, "<s>", request.ChatTemplateKWArgs["bos_token"], "The caller's request should not be modified")
+
+	response, err = processor.RenderForModel(ctx, "other-model", request)
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.Equal(t, "<s>Hello", response.RenderedChats[0], "Models without a policy should render as requested")
+
+	t.Run("Special tokens", func(t *testing.T) {
+		testModelPath := "../../tokenization/testdata/test-model"
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithModelPolicy(testModelPath, preprocessing.ModelPolicy{AddSpecialTokens: true}))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		request := &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:  []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:   "{% for message in messages %}{{ message.content }}{% endfor %}",
+			ReturnTokenIDs: true,
+			Tokenizer: &preprocessing.TokenizerSource{
+				Model:       testModelPath,
+				IsLocalPath: true,
+			},
+		}
+		plain, err := processor.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		withSpecialTokens, err := processor.RenderForModel(ctx, testModelPath, request)
+		require.NoError(t, err, "RenderForModel should not return an error")
+
+		// The test model is a BERT tokenizer, wrapping sequences in [CLS] and [SEP].
+		assert.Equal(t, append(append([]uint32{101}, plain.TokenIDs...), 102), withSpecialTokens.TokenIDs,
+			"The tokenizer should add its special tokens")
+	})
+}
+
+// TestSetModelKWArgs tests that the template variables registered for a model apply unless the request sets them.
+func TestSetModelKWArgs(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	processor := preprocessing.NewChatTemplatingProcessor()
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+	processor.SetModelKWArgs("qwen3", map[string]interface{}{"enable_thinking": false})
+
+	newRequest := func() *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate: "{% if enable_thinking is defined and not enable_thinking %}/no_think {% endif %}" +
+				"{% for message in messages %}{{ message.content }}{% endfor %}",
+		}
+	}
+
+	response, err := processor.RenderForModel(ctx, "qwen3", newRequest())
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.Equal(t, "/no_think Hello", response.RenderedChats[0], "The registered kwarg should apply when omitted")
+
+	request := newRequest()
+	request.ChatTemplateKWArgs = map[string]interface{}{"enable_thinking": true}
+	response, err = processor.RenderForModel(ctx, "qwen3", request)
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.Equal(t, "Hello", response.RenderedChats[0], "The request's kwargs should override the registered ones")
+	assert.Len(t, request.ChatTemplateKWArgs, 1, "The caller's request should not be modified")
+
+	request = newRequest()
+	request.TemplateVars = map[string]interface{}{"enable_thinking": true}
+	response, err = processor.RenderForModel(ctx, "qwen3", request)
+	require.NoError(t, err, "Template vars should not collide with the registered kwargs")
+	assert.Equal(t, "Hello", response.RenderedChats[0], "The request's template vars should override the registered kwargs")
+
+	response, err = processor.RenderForModel(ctx, "other-model", newRequest())
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.Equal(t, "Hello", response.RenderedChats[0], "Other models should render as requested")
+
+	processor.SetModelKWArgs("qwen3", nil)
+	response, err = processor.RenderForModel(ctx, "qwen3", newRequest())
+	require.NoError(t, err, "RenderForModel should not return an error")
+	assert.Equal(t, "Hello", response.RenderedChats[0], "Removed kwargs should no longer apply")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchChatTemplateNamedTemplates tests the selection of a model's named templates based on the request's tools.
+func TestFetchChatTemplateNamedTemplates(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	const (
+		defaultTemplate = "{% for message in messages %}{{ message.content }}{% endfor %}"
+		toolUseTemplate = "{{ tools | tojson }}{% for message in messages %}{{ message.content }}{% endfor %}"
+	)
+
+	// Like tokenizer_config.json files of models shipping a separate tool-use template.
+	modelDir := filepath.Join(t.TempDir(), "named-templates-model")
+	require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+	configPath := filepath.Join(modelDir, "tokenizer_config.json")
+	configData, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(configData, &config))
+	config["chat_template"] = []map[string]string{
+		{"name": "default", "template": defaultTemplate},
+		{"name": "tool_use", "template": toolUseTemplate},
+	}
+	configData, err = json.Marshal(config)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, configData, 0o600))
+
+	tools := []interface{}{map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "get_weather"}}}
+	tests := []struct {
+		name         string
+		tools        []interface{}
+		templateName string
+		expected     string
+	}{
+		{name: "Plain request", expected: defaultTemplate},
+		{name: "Request with tools", tools: tools, expected: toolUseTemplate},
+		{name: "Overridden selection", tools: tools, templateName: "default", expected: defaultTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, _, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+				Model:        modelDir,
+				IsLocalPath:  true,
+				Tools:        tt.tools,
+				TemplateName: tt.templateName,
+			})
+			require.NoError(t, err, "FetchChatTemplate should not return an error")
+			assert.Equal(t, tt.expected, template)
+		})
+	}
+
+	t.Run("Unknown template name", func(t *testing.T) {
+		_, _, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+			Model:        modelDir,
+			IsLocalPath:  true,
+			TemplateName: "rag",
+		})
+		assert.Error(t, err, "An unknown template name should fail the fetch")
+	})
+}
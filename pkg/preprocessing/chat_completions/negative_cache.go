@@ -0,0 +1,128 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// DefaultNegativeCacheTTL is how long a fetch failing with ErrModelNotFound
+// is remembered, unless set with WithNegativeCacheTTL.
+const DefaultNegativeCacheTTL = 30 * time.Second
+
+// maxNegativeCacheEntries bounds the fetch failures remembered, so fetches of
+// ever new missing models cannot grow the negative cache without limit.
+const maxNegativeCacheEntries = 1024
+
+// notFoundModels remembers fetches that failed with ErrModelNotFound, so
+// repeated fetches fail fast instead of repeating the lookup in Python. Found
+// templates are cached by the interpreter, which is process-wide, so the
+// failures are too, and are cleared along with its caches.
+var notFoundModels negativeCache
+
+// negativeCache maps the models of fetches that failed with ErrModelNotFound
+// to their failure, evicting the oldest one once full.
+type negativeCache struct {
+	mu      sync.Mutex
+	entries map[negativeCacheKey]negativeCacheEntry
+}
+
+// negativeCacheKey keys the failures by model and revision, like the template
+// cache of the interpreter, and by the local path flag and token they were
+// fetched with, as a model missing without a token may be found with one. Only
+// the digest of the token is kept.
+type negativeCacheKey struct {
+	model       string
+	isLocalPath bool
+	token       [sha256.Size]byte
+}
+
+type negativeCacheEntry struct {
+	err   error
+	added time.Time
+}
+
+// newNegativeCacheKey keys the failures of req, once its token and revision
+// are resolved.
+//
+//nolint:gocritic // hugeParam: req is passed by value like in the fetches.
+func newNegativeCacheKey(req FetchChatTemplateRequest) negativeCacheKey {
+	key := negativeCacheKey{model: templateCacheModelKey(req.Model, req.Revision), isLocalPath: req.IsLocalPath}
+	if req.Token != "" {
+		key.token = sha256.Sum256([]byte(req.Token))
+	}
+	return key
+}
+
+// get returns the error remembered for key, or nil if there is none or it is
+// older than ttl.
+func (c *negativeCache) get(key negativeCacheKey, now time.Time, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.added.Add(ttl)) {
+		return nil
+	}
+	return entry.err
+}
+
+// add remembers err for key, evicting the oldest entry if the cache is full.
+// Expired entries are not swept, as processors remember failures for their
+// own TTL, but they are the first to be evicted.
+func (c *negativeCache) add(key negativeCacheKey, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[negativeCacheKey]negativeCacheEntry)
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxNegativeCacheEntries {
+		var oldest negativeCacheKey
+		var oldestAdded time.Time
+		for k, entry := range c.entries {
+			if oldestAdded.IsZero() || entry.added.Before(oldestAdded) {
+				oldest, oldestAdded = k, entry.added
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = negativeCacheEntry{err: err, added: now}
+}
+
+// clearModel forgets the failures of model at revision, "main" if empty,
+// whatever token they were fetched with.
+func (c *negativeCache) clearModel(model, revision string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modelKey := templateCacheModelKey(model, revision)
+	for k := range c.entries {
+		if k.model == modelKey {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// reset forgets all failures, once the caches of the interpreter are emptied.
+func (c *negativeCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
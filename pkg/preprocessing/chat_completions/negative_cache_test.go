@@ -0,0 +1,131 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchChatTemplateNegativeCache tests that fetches of a missing model fail fast, without a new lookup, within
+// the negative cache TTL.
+func TestFetchChatTemplateNegativeCache(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	// fetchAfterModelAppears fetches a missing model, then creates it, and fetches it again.
+	fetchAfterModelAppears := func(t *testing.T, processor *preprocessing.ChatTemplatingProcessor, wait time.Duration) error {
+		t.Helper()
+		modelDir := filepath.Join(t.TempDir(), "late-model")
+		request := preprocessing.FetchChatTemplateRequest{Model: modelDir, IsLocalPath: true}
+
+		_, _, err := processor.FetchChatTemplate(ctx, request)
+		require.ErrorIs(t, err, preprocessing.ErrModelNotFound, "A missing model should not be found")
+
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		time.Sleep(wait)
+		_, _, err = processor.FetchChatTemplate(ctx, request)
+		return err
+	}
+
+	t.Run("Within TTL", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithNegativeCacheTTL(time.Hour))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		// Had the second fetch reached Python, it would have found the model.
+		err := fetchAfterModelAppears(t, processor, 0)
+		assert.ErrorIs(t, err, preprocessing.ErrModelNotFound, "The failure should be served from the negative cache")
+	})
+
+	t.Run("After TTL", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithNegativeCacheTTL(10 * time.Millisecond))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		err := fetchAfterModelAppears(t, processor, 20*time.Millisecond)
+		assert.NoError(t, err, "The model should be looked up again once the failure expired")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithNegativeCacheTTL(0))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		err := fetchAfterModelAppears(t, processor, 0)
+		assert.NoError(t, err, "Failures should not be cached")
+	})
+
+	t.Run("Cleared with the model", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithNegativeCacheTTL(time.Hour))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		modelDir := filepath.Join(t.TempDir(), "cleared-model")
+		request := preprocessing.FetchChatTemplateRequest{Model: modelDir, IsLocalPath: true}
+		_, _, err := processor.FetchChatTemplate(ctx, request)
+		require.ErrorIs(t, err, preprocessing.ErrModelNotFound, "A missing model should not be found")
+
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		require.NoError(t, preprocessing.ClearModelCache(ctx, modelDir, ""))
+		_, _, err = processor.FetchChatTemplate(ctx, request)
+		assert.NoError(t, err, "Clearing the model should forget its failure")
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithNegativeCacheTTL(time.Hour))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+
+		dir := t.TempDir()
+		for i := range preprocessing.MaxNegativeCacheEntries + 10 {
+			_, _, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+				Model:       filepath.Join(dir, fmt.Sprintf("missing-%d", i)),
+				IsLocalPath: true,
+			})
+			require.ErrorIs(t, err, preprocessing.ErrModelNotFound, "A missing model should not be found")
+		}
+		assert.Equal(t, preprocessing.MaxNegativeCacheEntries, preprocessing.NegativeCacheEntries(),
+			"The oldest failures should be evicted once the negative cache is full")
+	})
+
+	t.Run("Model without chat template", func(t *testing.T) {
+		modelDir := filepath.Join(t.TempDir(), "base-model")
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		require.NoError(t, os.WriteFile(filepath.Join(modelDir, "tokenizer_config.json"),
+			[]byte(`{"tokenizer_class": "BertTokenizer"}`), 0o600))
+
+		processor := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+		_, _, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:       modelDir,
+			IsLocalPath: true,
+		})
+		assert.ErrorIs(t, err, preprocessing.ErrModelNotFound, "A model without a chat template should not be found")
+	})
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "golang.org/x/text/unicode/norm"
+
+// UnicodeNormalization is the Unicode normalization form applied to message
+// content before rendering. Clients may send canonically equivalent text in
+// different forms, e.g. "é" precomposed or as "e" and a combining accent,
+// which would otherwise render, and hash, differently.
+type UnicodeNormalization int
+
+const (
+	// NormalizationNone renders message content as is.
+	NormalizationNone UnicodeNormalization = iota
+	// NormalizationNFC applies canonical composition.
+	NormalizationNFC
+	// NormalizationNFKC applies compatibility composition, which also folds
+	// compatibility characters such as ligatures and full-width forms.
+	NormalizationNFKC
+)
+
+// String returns the name of the normalization form.
+func (n UnicodeNormalization) String() string {
+	switch n {
+	case NormalizationNone:
+		return "none"
+	case NormalizationNFC:
+		return "NFC"
+	case NormalizationNFKC:
+		return "NFKC"
+	default:
+		return "unknown"
+	}
+}
+
+// normalizeMessages returns a copy of messages with their content normalized
+// to form. The input slice is not modified.
+func normalizeMessages(messages []ChatMessage, form UnicodeNormalization) []ChatMessage {
+	var normForm norm.Form
+	switch form {
+	case NormalizationNFC:
+		normForm = norm.NFC
+	case NormalizationNFKC:
+		normForm = norm.NFKC
+	default:
+		return messages
+	}
+
+	normalized := make([]ChatMessage, len(messages))
+	for i, message := range messages {
+		message.Content = normForm.String(message.Content)
+		if len(message.ContentParts) > 0 {
+			parts := make([]ContentPart, len(message.ContentParts))
+			for j, part := range message.ContentParts {
+				part.Text = normForm.String(part.Text)
+				parts[j] = part
+			}
+			message.ContentParts = parts
+		}
+		normalized[i] = message
+	}
+	return normalized
+}
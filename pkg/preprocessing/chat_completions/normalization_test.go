@@ -0,0 +1,66 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateUnicodeNormalization tests that NFC and NFD inputs render identically once normalized.
+func TestRenderChatTemplateUnicodeNormalization(t *testing.T) {
+	getGlobalWrapper()
+
+	const (
+		nfc = "caf\u00e9"  // "é" as a single code point
+		nfd = "cafe\u0301" // "e" followed by a combining acute accent
+	)
+
+	render := func(processor *preprocessing.ChatTemplatingProcessor, content string) string {
+		t.Helper()
+		response, err := processor.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: content}},
+			ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response.RenderedChats[0]
+	}
+
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithUnicodeNormalization(preprocessing.NormalizationNFC))
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+	assert.Equal(t, nfc, render(processor, nfd), "NFD input should be composed")
+	assert.Equal(t, render(processor, nfc), render(processor, nfd), "NFC and NFD inputs should render identically")
+
+	t.Run("NFKC", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithUnicodeNormalization(preprocessing.NormalizationNFKC))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+		assert.Equal(t, "fine", render(processor, "\ufb01ne"), "Ligatures should be folded")
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		assert.NotEqual(t, render(getGlobalWrapper(), nfc), render(getGlobalWrapper(), nfd),
+			"Content should not be normalized by default")
+	})
+}
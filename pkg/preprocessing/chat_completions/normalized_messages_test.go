@@ -0,0 +1,75 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateNormalizedMessages tests echoing the messages rendered after injection and windowing.
+func TestRenderChatTemplateNormalizedMessages(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi there"},
+			{Role: "user", Content: "What is the capital of France?"},
+		},
+		ChatTemplate:             "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		DefaultSystemPrompt:      "You are a helpful assistant.",
+		MaxMessages:              1,
+		ReturnNormalizedMessages: true,
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, req)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, []preprocessing.ChatMessage{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "What is the capital of France?"},
+	}, response.NormalizedMessages, "The injected system prompt and the window should be echoed")
+	assert.Equal(t, "system: You are a helpful assistant.\nuser: What is the capital of France?\n",
+		response.RenderedChats[0])
+	assert.Equal(t, 2, response.DroppedMessages)
+	assert.Len(t, req.Conversations, 3, "The request should not be modified")
+
+	t.Run("Existing system prompt", func(t *testing.T) {
+		withSystem := *req
+		withSystem.Conversations = append([]preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a pirate."},
+		}, req.Conversations...)
+		response, err := wrapper.RenderChatTemplate(ctx, &withSystem)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		require.NotEmpty(t, response.NormalizedMessages)
+		assert.Equal(t, "You are a pirate.", response.NormalizedMessages[0].Content,
+			"An existing system prompt should not be replaced")
+		assert.Len(t, response.NormalizedMessages, 2)
+	})
+
+	t.Run("Not requested", func(t *testing.T) {
+		notRequested := *req
+		notRequested.ReturnNormalizedMessages = false
+		response, err := wrapper.RenderChatTemplate(ctx, &notRequested)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Nil(t, response.NormalizedMessages, "Messages should only be echoed on request")
+	})
+}
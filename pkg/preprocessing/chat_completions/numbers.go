@@ -0,0 +1,100 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+)
+
+// wireNumbers returns a copy of req whose free-form values (kwargs, template
+// vars, tools, documents and structured message content) hold numbers as the Python side should see them:
+// integers where they are whole, floats otherwise. JSON already writes whole
+// float64s and integer json.Numbers without a decimal point, but msgpack keeps
+// Go's types, so a float64 42 would render as `42.0` and a json.Number as a
+// string.
+func wireNumbers(req *RenderJinjaTemplateRequest) *RenderJinjaTemplateRequest {
+	converted := *req
+	if req.ChatTemplateKWArgs != nil {
+		converted.ChatTemplateKWArgs = wireNumbersMap(req.ChatTemplateKWArgs)
+	}
+	if req.TemplateVars != nil {
+		converted.TemplateVars = wireNumbersMap(req.TemplateVars)
+	}
+	if req.Tools != nil {
+		converted.Tools = wireNumbersSlice(req.Tools)
+	}
+	if req.Documents != nil {
+		converted.Documents = wireNumbersSlice(req.Documents)
+	}
+	cloned := false
+	for i, message := range req.Conversations {
+		if message.StructuredContent == nil {
+			continue
+		}
+		if !cloned {
+			converted.Conversations = slices.Clone(req.Conversations)
+			cloned = true
+		}
+		converted.Conversations[i].StructuredContent = wireNumbersValue(message.StructuredContent)
+	}
+	return &converted
+}
+
+// wireNumbersValue converts the numbers in v, recursing into the maps and
+// slices produced by decoding JSON into interface{}. Other values are
+// returned as is.
+func wireNumbersValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		if f, err := value.Float64(); err == nil {
+			return f
+		}
+		return value.String()
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return int64(value)
+		}
+		return value
+	case map[string]interface{}:
+		return wireNumbersMap(value)
+	case []interface{}:
+		return wireNumbersSlice(value)
+	default:
+		return v
+	}
+}
+
+func wireNumbersMap(m map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		converted[key] = wireNumbersValue(value)
+	}
+	return converted
+}
+
+func wireNumbersSlice(s []interface{}) []interface{} {
+	converted := make([]interface{}, len(s))
+	for i, value := range s {
+		converted[i] = wireNumbersValue(value)
+	}
+	return converted
+}
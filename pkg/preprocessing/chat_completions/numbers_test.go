@@ -0,0 +1,63 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateIntegerKWArgs tests that integer kwargs render without a decimal point in both wire formats.
+func TestRenderChatTemplateIntegerKWArgs(t *testing.T) {
+	getGlobalWrapper()
+
+	const body = `{
+		"messages": [{"role": "user", "content": "Hello"}],
+		"chat_template": "{{ max_items }} {{ ratio }} {{ big }}",
+		"chat_template_kwargs": {"max_items": 42, "ratio": 0.5, "big": 9007199254740993}
+	}`
+
+	for _, format := range []preprocessing.WireFormat{preprocessing.WireFormatJSON, preprocessing.WireFormatMsgpack} {
+		t.Run(format.String(), func(t *testing.T) {
+			processor := preprocessing.NewChatTemplatingProcessor(
+				preprocessing.WithWireFormat(format), preprocessing.WithJSONNumbers())
+			require.NoError(t, processor.Initialize())
+			t.Cleanup(processor.Finalize)
+
+			request, err := processor.DecodeRenderRequest(strings.NewReader(body))
+			require.NoError(t, err, "DecodeRenderRequest should not return an error")
+			assert.Equal(t, json.Number("42"), request.ChatTemplateKWArgs["max_items"], "Numbers should be decoded as json.Number")
+
+			response, err := processor.RenderChatTemplate(context.Background(), request)
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			assert.Equal(t, "42 0.5 9007199254740993", response.RenderedChats[0],
+				"Integers should render exactly and without a decimal point")
+
+			// Whole float64s, as produced without WithJSONNumbers, render as integers too.
+			request.ChatTemplateKWArgs = map[string]interface{}{"max_items": float64(42), "ratio": 0.5, "big": 1}
+			response, err = processor.RenderChatTemplate(context.Background(), request)
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			assert.Equal(t, "42 0.5 1", response.RenderedChats[0], "Whole floats should render as integers")
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateOffsetMapping tests that token offsets cover the full rendered string.
+func TestRenderChatTemplateOffsetMapping(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "Où est la gare?"},
+			{Role: "assistant", Content: "Tout droit, puis à gauche."},
+		},
+		ChatTemplate:        template,
+		ChatTemplateKWArgs:  templateVars,
+		ReturnOffsetMapping: true,
+		Tokenizer: &preprocessing.TokenizerSource{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		},
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.Len(t, response.OffsetMapping, len(response.TokenIDs), "There should be one offset per token")
+
+	rendered := response.RenderedChats[0]
+	covered := make([]bool, len(rendered))
+	previousStart := 0
+	for _, offset := range response.OffsetMapping {
+		start, end := offset[0], offset[1]
+		require.True(t, 0 <= start && start <= end && end <= len(rendered), "Offset %v should be within the render", offset)
+		assert.GreaterOrEqual(t, start, previousStart, "Offsets should be in order")
+		previousStart = start
+		for i := start; i < end; i++ {
+			covered[i] = true
+		}
+	}
+
+	// Every non-whitespace byte maps back to a token.
+	for i, r := range rendered {
+		if !unicode.IsSpace(r) {
+			assert.True(t, covered[i], "Byte %d (%q) should be covered by a token", i, r)
+		}
+	}
+	last := response.OffsetMapping[len(response.OffsetMapping)-1]
+	assert.Equal(t, len(strings.TrimRightFunc(rendered, unicode.IsSpace)), last[1],
+		"The last token should end the rendered text")
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToOpenAIMessages converts messages to the JSON shape of OpenAI chat
+// completions messages, e.g. for logging pipelines ingesting that format:
+// content parts become a content array of `text`, `image_url` and
+// `tool_call` parts, tool call arguments a JSON string, and structured tool
+// results the content itself. The Harmony Channel, which OpenAI messages
+// lack, is kept as `channel`, so FromOpenAIMessages gives the messages back.
+func ToOpenAIMessages(messages []ChatMessage) []map[string]interface{} {
+	converted := make([]map[string]interface{}, 0, len(messages))
+	for i := range messages {
+		message := &messages[i]
+		openAI := map[string]interface{}{"role": message.Role}
+		switch {
+		case len(message.ContentParts) > 0:
+			parts := make([]interface{}, 0, len(message.ContentParts))
+			for _, part := range message.ContentParts {
+				openAIPart := map[string]interface{}{"type": string(part.Type)}
+				if part.Type == ContentPartText {
+					openAIPart["text"] = part.Text
+				}
+				if part.ImageURL != nil {
+					openAIPart["image_url"] = map[string]interface{}{"url": part.ImageURL.URL}
+				}
+				if part.ToolCallID != "" {
+					openAIPart["tool_call_id"] = part.ToolCallID
+				}
+				parts = append(parts, openAIPart)
+			}
+			openAI["content"] = parts
+		case message.StructuredContent != nil:
+			openAI["content"] = message.StructuredContent
+		default:
+			openAI["content"] = message.Content
+		}
+		if message.Name != "" {
+			openAI["name"] = message.Name
+		}
+		if len(message.ToolCalls) > 0 {
+			toolCalls := make([]interface{}, 0, len(message.ToolCalls))
+			for _, toolCall := range message.ToolCalls {
+				openAIToolCall := map[string]interface{}{
+					"type": toolCall.Type,
+					"function": map[string]interface{}{
+						"name":      toolCall.Function.Name,
+						"arguments": toolCall.Function.Arguments,
+					},
+				}
+				if toolCall.ID != "" {
+					openAIToolCall["id"] = toolCall.ID
+				}
+				toolCalls = append(toolCalls, openAIToolCall)
+			}
+			openAI["tool_calls"] = toolCalls
+		}
+		if message.ToolCallID != "" {
+			openAI["tool_call_id"] = message.ToolCallID
+		}
+		if message.Channel != "" {
+			openAI["channel"] = message.Channel
+		}
+		converted = append(converted, openAI)
+	}
+	return converted
+}
+
+// FromOpenAIMessages converts messages in the JSON shape of OpenAI chat
+// completions messages, as decoded into maps, to ChatMessages. A content
+// array of `text`, `image_url` and `tool_call` parts, the latter as written
+// by ToOpenAIMessages, becomes ContentParts, and other non-string content,
+// such as a JSON object returned by a tool, StructuredContent. Tool call
+// arguments may be a JSON string or object.
+func FromOpenAIMessages(messages []map[string]interface{}) ([]ChatMessage, error) {
+	converted := make([]ChatMessage, 0, len(messages))
+	for i, openAI := range messages {
+		var message ChatMessage
+		var err error
+		if message.Role, err = openAIString(openAI, "role"); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if message.Name, err = openAIString(openAI, "name"); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if message.ToolCallID, err = openAIString(openAI, "tool_call_id"); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if message.Channel, err = openAIString(openAI, "channel"); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		switch content := openAI["content"].(type) {
+		case nil:
+		case string:
+			message.Content = content
+		case []interface{}:
+			if parts, ok := openAIContentParts(content); ok {
+				message.ContentParts = parts
+			} else {
+				message.StructuredContent = content
+			}
+		default:
+			message.StructuredContent = content
+		}
+
+		if toolCalls, ok := openAI["tool_calls"]; ok && toolCalls != nil {
+			// Decoding through JSON accepts arguments as a string or an object, as DecodeRenderRequest does.
+			data, err := json.Marshal(toolCalls)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: failed to marshal tool calls: %w", i, err)
+			}
+			if err := json.Unmarshal(data, &message.ToolCalls); err != nil {
+				return nil, fmt.Errorf("message %d: invalid tool calls: %w", i, err)
+			}
+		}
+		converted = append(converted, message)
+	}
+	return converted, nil
+}
+
+// openAIString returns the string field key of message, or "" if it is
+// absent or null.
+func openAIString(message map[string]interface{}, key string) (string, error) {
+	switch value := message[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	default:
+		return "", fmt.Errorf("%s is a %T, not a string", key, value)
+	}
+}
+
+// openAIContentParts converts an OpenAI content array to content parts,
+// reporting false unless all its elements are `text`, `image_url` or
+// `tool_call` parts.
+func openAIContentParts(content []interface{}) ([]ContentPart, bool) {
+	parts := make([]ContentPart, 0, len(content))
+	for _, element := range content {
+		part, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		switch part["type"] {
+		case string(ContentPartText):
+			text, ok := part["text"].(string)
+			if !ok {
+				return nil, false
+			}
+			parts = append(parts, ContentPart{Type: ContentPartText, Text: text})
+		case string(ContentPartImageURL):
+			imageURL, ok := part["image_url"].(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			url, ok := imageURL["url"].(string)
+			if !ok {
+				return nil, false
+			}
+			parts = append(parts, ContentPart{Type: ContentPartImageURL, ImageURL: &ImageURL{URL: url}})
+		case string(ContentPartToolCall):
+			id, ok := part["tool_call_id"].(string)
+			if !ok {
+				return nil, false
+			}
+			parts = append(parts, ContentPart{Type: ContentPartToolCall, ToolCallID: id})
+		default:
+			return nil, false
+		}
+	}
+	return parts, len(parts) > 0
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAIMessagesRoundTrip tests converting messages with tool calls and images to OpenAI messages and back.
+func TestOpenAIMessagesRoundTrip(t *testing.T) {
+	messages := []preprocessing.ChatMessage{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{
+			Role: "user",
+			Name: "alice",
+			ContentParts: []preprocessing.ContentPart{
+				{Type: preprocessing.ContentPartText, Text: "What is in this image?"},
+				{Type: preprocessing.ContentPartImageURL, ImageURL: &preprocessing.ImageURL{URL: "https://example.com/cat.png"}},
+			},
+		},
+		{
+			Role: "assistant",
+			ContentParts: []preprocessing.ContentPart{
+				{Type: preprocessing.ContentPartText, Text: "Let me classify it."},
+				{Type: preprocessing.ContentPartToolCall, ToolCallID: "call_1"},
+			},
+			ToolCalls: []preprocessing.ToolCall{{
+				ID:   "call_1",
+				Type: "function",
+				Function: preprocessing.ToolCallFunction{
+					Name:      "classify_image",
+					Arguments: `{"url":"https://example.com/cat.png"}`,
+				},
+			}},
+		},
+		{
+			Role:              "tool",
+			ToolCallID:        "call_1",
+			StructuredContent: map[string]interface{}{"label": "cat", "score": 0.98},
+		},
+		{Role: "assistant", Channel: "final", Content: "It is a cat."},
+	}
+
+	openAI := preprocessing.ToOpenAIMessages(messages)
+	expected := `[
+		{"role": "system", "content": "You are a helpful assistant."},
+		{"role": "user", "name": "alice", "content": [
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}
+		]},
+		{"role": "assistant", "content": [
+			{"type": "text", "text": "Let me classify it."},
+			{"type": "tool_call", "tool_call_id": "call_1"}
+		], "tool_calls": [{
+			"id": "call_1", "type": "function",
+			"function": {"name": "classify_image", "arguments": "{\"url\":\"https://example.com/cat.png\"}"}
+		}]},
+		{"role": "tool", "tool_call_id": "call_1", "content": {"label": "cat", "score": 0.98}},
+		{"role": "assistant", "channel": "final", "content": "It is a cat."}
+	]`
+	data, err := json.Marshal(openAI)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(data))
+
+	roundTripped, err := preprocessing.FromOpenAIMessages(openAI)
+	require.NoError(t, err)
+	assert.Equal(t, messages, roundTripped)
+
+	// Messages decoded from JSON, as a logging pipeline would read them, convert alike.
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	fromJSON, err := preprocessing.FromOpenAIMessages(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, messages, fromJSON)
+}
+
+// TestFromOpenAIMessages tests the OpenAI message forms FromOpenAIMessages accepts.
+func TestFromOpenAIMessages(t *testing.T) {
+	var openAI []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role": "assistant", "content": null, "tool_calls": [{
+			"id": "call_1", "type": "function",
+			"function": {"name": "get_weather", "arguments": {"city": "Paris"}}
+		}]},
+		{"role": "tool", "tool_call_id": "call_1", "content": [{"temperature": 21}]}
+	]`), &openAI))
+
+	messages, err := preprocessing.FromOpenAIMessages(openAI)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Empty(t, messages[0].Content)
+	require.Len(t, messages[0].ToolCalls, 1)
+	assert.Equal(t, `{"city":"Paris"}`, messages[0].ToolCalls[0].Function.Arguments)
+	// A content array of anything but text and image parts is a structured tool result.
+	assert.Nil(t, messages[1].ContentParts)
+	assert.Equal(t, []interface{}{map[string]interface{}{"temperature": float64(21)}}, messages[1].StructuredContent)
+
+	_, err = preprocessing.FromOpenAIMessages([]map[string]interface{}{{"role": 1}})
+	assert.Error(t, err)
+}
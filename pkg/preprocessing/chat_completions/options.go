@@ -0,0 +1,141 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "time"
+
+// Option configures a ChatTemplatingProcessor.
+type Option func(*ChatTemplatingProcessor)
+
+// WithWireFormat sets the encoding used for render requests and responses
+// across the CGO boundary. Defaults to WireFormatJSON.
+func WithWireFormat(format WireFormat) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.wireFormat = format
+	}
+}
+
+// WithCustomFilters allows registering custom Jinja filters with
+// RegisterJinjaFilter.
+//
+// WARNING: filters are Python code evaluated in-process, outside the Jinja
+// sandbox. Their code only sees basic builtins, such as len and str, and the
+// datetime, json, math, re and warnings modules, but that is no sandbox: a
+// malicious filter can still escape it. Only enable this for filter code as
+// trusted as the process itself, never for code from users or requests.
+func WithCustomFilters() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.customFiltersEnabled = true
+	}
+}
+
+// WithStrictDecoding makes DecodeRenderRequest reject requests with unknown
+// fields, such as a misspelled `add_generation_promt`, with ErrUnknownField
+// instead of silently ignoring them.
+func WithStrictDecoding() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.strictDecoding = true
+	}
+}
+
+// WithJSONNumbers makes DecodeRenderRequest decode the numbers of free-form
+// fields, such as ChatTemplateKWArgs and Tools, as json.Number rather than
+// float64, so large integers keep their exact value and integers keep
+// rendering without a decimal point.
+func WithJSONNumbers() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.jsonNumbers = true
+	}
+}
+
+// WithUnicodeNormalization normalizes message content to form before
+// rendering, so that equivalent prompts render to identical text and share
+// cache keys. Defaults to NormalizationNone.
+func WithUnicodeNormalization(form UnicodeNormalization) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.normalization = form
+	}
+}
+
+// WithPromptHash sets RenderJinjaTemplateResponse.PromptHash on every render,
+// computed over source, so the KV-cache layer can key on the rendered prompt
+// without re-hashing it. Defaults to PromptHashNone.
+func WithPromptHash(source PromptHashSource) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.promptHashSource = source
+	}
+}
+
+// WithMemoryLimit makes RenderChatTemplate reject renders with
+// ErrMemoryPressure while the resident set size of the process exceeds
+// limitBytes, rather than risk the interpreter running out of memory
+// mid-render and crashing the process. Zero, the default, disables the check.
+func WithMemoryLimit(limitBytes int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.memoryLimit = int64(limitBytes)
+	}
+}
+
+// WithNegativeCacheTTL sets how long fetches failing with ErrModelNotFound
+// keep failing fast, without a new lookup. Found templates are cached until
+// ClearCaches regardless of it. A zero ttl disables negative caching.
+// Defaults to DefaultNegativeCacheTTL.
+func WithNegativeCacheTTL(ttl time.Duration) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.negativeCacheTTL = ttl
+	}
+}
+
+// WithWarmupConcurrency sets the number of templates Warmup fetches at once,
+// so warming up many models does not flood the HuggingFace Hub with requests
+// and get rate limited. Values below one are treated as one. Defaults to
+// DefaultWarmupConcurrency.
+func WithWarmupConcurrency(n int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.warmupConcurrency = n
+	}
+}
+
+// WithWarmupRateLimit bounds the fetches Warmup starts, retries included, to
+// perSecond a second. Zero, the default, leaves them bounded by
+// WithWarmupConcurrency only.
+func WithWarmupRateLimit(perSecond float64) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.warmupLimiter = newWarmupLimiter(perSecond)
+	}
+}
+
+// WithLazyInit makes the processor initialize the interpreter on its first
+// call rather than requiring Initialize, so processors that are never used
+// do not pay for it.
+func WithLazyInit() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.lazyInit = true
+	}
+}
+
+// WithIdleTimeout makes the processor finalize the interpreter once no call
+// was made for idle, and initialize it again on the next call. It implies
+// WithLazyInit. The interpreter is process-wide, so it is only finalized once
+// no other processor holds it initialized; the memory of its caches, such as
+// tokenizers and templates, is then released. Zero, the default, keeps the
+// interpreter up until Finalize.
+func WithIdleTimeout(idle time.Duration) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.idleTimeout = idle
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+const (
+	// DefaultPipelineFetchWorkers is the number of templates ProcessRequests
+	// fetches at once unless set with WithPipelineWorkers.
+	DefaultPipelineFetchWorkers = 4
+	// DefaultPipelineRenderWorkers is the number of renders ProcessRequests
+	// runs at once unless set with WithPipelineWorkers.
+	DefaultPipelineRenderWorkers = 2
+)
+
+// ModelRenderRequest is a conversation to render with the chat template of a
+// model, for ProcessRequests.
+type ModelRenderRequest struct {
+	// Fetch identifies the model whose chat template and kwargs render Render.
+	Fetch FetchChatTemplateRequest
+	// Render is the conversation to render. Its ChatTemplate is replaced by
+	// the fetched one, and its ChatTemplateKWArgs are merged over the fetched
+	// kwargs. It is not modified.
+	Render *RenderJinjaTemplateRequest
+}
+
+// PipelineResult is the outcome of one request of ProcessRequests: either
+// Response or Err is set.
+type PipelineResult struct {
+	// Index is the position of the request in the slice given to ProcessRequests.
+	Index    int
+	Response *RenderJinjaTemplateResponse
+	Err      error
+}
+
+// WithPipelineWorkers sets the number of templates ProcessRequests fetches
+// at once, and the number of renders it runs at once. Values below one are
+// treated as one. Defaults to DefaultPipelineFetchWorkers and
+// DefaultPipelineRenderWorkers.
+func WithPipelineWorkers(fetch, render int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.pipelineFetchWorkers = fetch
+		w.pipelineRenderWorkers = render
+	}
+}
+
+// fetchedRequest is a request of ProcessRequests whose template was fetched.
+type fetchedRequest struct {
+	index    int
+	template *FetchChatTemplateResponse
+}
+
+// ProcessRequests fetches the templates of reqs concurrently while a pool of
+// render workers renders the requests whose template is fetched, emitting
+// one result per request on the returned channel as soon as it completes,
+// so results arrive out of order; PipelineResult.Index correlates them. The
+// channel is closed once every request has its result. A request failing,
+// e.g. on a model that does not exist, only fails its own result; requests
+// not started when ctx is done fail with its error.
+//
+// Calls into Python are serialized by the interpreter, so fetches and
+// renders do not run in parallel there; rather, renders start as soon as
+// their own template is fetched instead of after all fetches.
+func (w *ChatTemplatingProcessor) ProcessRequests(ctx context.Context, reqs []ModelRenderRequest,
+) <-chan PipelineResult {
+	// Buffered so that workers never block on a slow or gone consumer.
+	results := make(chan PipelineResult, len(reqs))
+	fetched := make(chan fetchedRequest, len(reqs))
+
+	go func() {
+		defer close(fetched)
+		slots := make(chan struct{}, max(w.pipelineFetchWorkers, 1))
+		var wg sync.WaitGroup
+		for i := range reqs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results <- PipelineResult{Index: i, Err: ctx.Err()}
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				template, err := w.FetchChatTemplateDetails(ctx, reqs[i].Fetch, FetchOptions{})
+				if err != nil {
+					results <- PipelineResult{Index: i, Err: err}
+					return
+				}
+				fetched <- fetchedRequest{index: i, template: template}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	var renderers sync.WaitGroup
+	for range max(w.pipelineRenderWorkers, 1) {
+		renderers.Add(1)
+		go func() {
+			defer renderers.Done()
+			for item := range fetched {
+				response, err := w.renderFetched(ctx, reqs[item.index].Render, item.template)
+				results <- PipelineResult{Index: item.index, Response: response, Err: err}
+			}
+		}()
+	}
+	go func() {
+		renderers.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// renderFetched renders req with a fetched template and its kwargs.
+func (w *ChatTemplatingProcessor) renderFetched(ctx context.Context, req *RenderJinjaTemplateRequest,
+	template *FetchChatTemplateResponse,
+) (*RenderJinjaTemplateResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+
+	applied := *req
+	applied.ChatTemplate = template.ChatTemplate
+	applied.ChatTemplateKWArgs = make(map[string]interface{}, len(template.ChatTemplateKWArgs)+len(req.ChatTemplateKWArgs))
+	maps.Copy(applied.ChatTemplateKWArgs, template.ChatTemplateKWArgs)
+	maps.Copy(applied.ChatTemplateKWArgs, req.ChatTemplateKWArgs)
+	return w.RenderChatTemplate(ctx, &applied)
+}
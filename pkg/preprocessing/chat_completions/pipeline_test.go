@@ -0,0 +1,89 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProcessRequests tests that the fetch and render pipeline emits one correlated result per request.
+func TestProcessRequests(t *testing.T) {
+	getGlobalWrapper()
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPipelineWorkers(2, 2))
+
+	testModel := preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	}
+	missingModel := preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/no-such-model",
+		IsLocalPath: true,
+	}
+	newRender := func(content string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: content}},
+		}
+	}
+
+	reqs := make([]preprocessing.ModelRenderRequest, 8)
+	for i := range reqs {
+		reqs[i] = preprocessing.ModelRenderRequest{Fetch: testModel, Render: newRender(fmt.Sprintf("message-%d", i))}
+	}
+	reqs[3].Fetch = missingModel
+	reqs[5].Render = nil
+
+	results := make(map[int]preprocessing.PipelineResult)
+	for result := range processor.ProcessRequests(context.Background(), reqs) {
+		require.NotContains(t, results, result.Index, "Each request should have a single result")
+		results[result.Index] = result
+	}
+	require.Len(t, results, len(reqs), "Every request should have a result")
+
+	for i, result := range results {
+		switch i {
+		case 3:
+			assert.ErrorIs(t, result.Err, preprocessing.ErrModelNotFound, "A failing fetch should fail its request")
+		case 5:
+			assert.Error(t, result.Err, "A nil render should fail its request")
+		default:
+			require.NoError(t, result.Err, "Request %d should render", i)
+			assert.Contains(t, result.Response.RenderedChats[0], fmt.Sprintf("message-%d", i),
+				"Result %d should be the render of its own request", i)
+		}
+	}
+}
+
+// TestProcessRequestsCanceled tests that requests of a canceled pipeline still get a result.
+func TestProcessRequestsCanceled(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	reqs := make([]preprocessing.ModelRenderRequest, 4)
+	count := 0
+	for result := range wrapper.ProcessRequests(ctx, reqs) {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+		count++
+	}
+	assert.Equal(t, len(reqs), count, "Every request should have a result")
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// prefetchQueueSize bounds the models waiting to be prefetched. Models
+	// seen while the queue is full are dropped, and prefetched when next seen.
+	prefetchQueueSize = 256
+	// maxPrefetchedModels bounds the models remembered as seen, the ones
+	// claimed first being forgotten first.
+	maxPrefetchedModels = 1024
+)
+
+// prefetcher fetches the templates of the models seen in traffic in the
+// background, see NotifyModelSeen.
+type prefetcher struct {
+	// workers is the number of fetches run at once.
+	workers int
+
+	// mu guards seen, the models whose template is fetched or being fetched
+	// with the order they were claimed in, and the running workers: queue
+	// feeds them, cancel stops them and done is closed once they returned.
+	mu     sync.Mutex
+	seen   map[string]uint64
+	claims uint64
+	queue  chan string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// WithPrefetcher enables NotifyModelSeen, fetching the templates of the
+// models seen in traffic in the background with concurrency workers.
+// Values below one are treated as one.
+func WithPrefetcher(concurrency int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.prefetcher = &prefetcher{workers: max(concurrency, 1)}
+	}
+}
+
+// claim reports whether model was not seen yet, marking it seen. Once
+// maxPrefetchedModels are seen, the one claimed first is forgotten.
+func (p *prefetcher) claim(model string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.seen[model]; ok {
+		return false
+	}
+
+	if p.seen == nil {
+		p.seen = make(map[string]uint64)
+	}
+	if len(p.seen) >= maxPrefetchedModels {
+		var oldest string
+		oldestClaim := uint64(math.MaxUint64)
+		for seen, claim := range p.seen {
+			if claim < oldestClaim {
+				oldest, oldestClaim = seen, claim
+			}
+		}
+		delete(p.seen, oldest)
+	}
+	p.claims++
+	p.seen[model] = p.claims
+	return true
+}
+
+// forget unmarks model, so that its template is fetched again when next seen.
+func (p *prefetcher) forget(model string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.seen, model)
+}
+
+// NotifyModelSeen reports a request for model, e.g. from the serving layer as
+// traffic arrives, so that its template is fetched in the background the
+// first time the model is seen and later renders are served from cache. It
+// never blocks. Fetches are low priority: they are run by the number of
+// workers set with WithPrefetcher, start no faster than the rate set with
+// WithWarmupRateLimit, and are retried like Warmup's when rate limited.
+// Models seen while too many are waiting, or whose fetch failed, are fetched
+// again when next seen. The workers are started by the first notification
+// and stopped by Finalize, which forgets the models seen; notifications are
+// ignored from Shutdown until Initialize. It is a no-op unless the processor
+// was created with WithPrefetcher.
+func (w *ChatTemplatingProcessor) NotifyModelSeen(model string) {
+	if w.prefetcher == nil || model == "" {
+		return
+	}
+	queue := w.startPrefetcher()
+	if queue == nil || !w.prefetcher.claim(model) {
+		return
+	}
+
+	select {
+	case queue <- model:
+	default:
+		w.prefetcher.forget(model)
+	}
+}
+
+// startPrefetcher starts the prefetch workers unless they run, and returns
+// the queue feeding them, or nil once Shutdown started.
+func (w *ChatTemplatingProcessor) startPrefetcher() chan<- string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shuttingDown {
+		return nil
+	}
+
+	p := w.prefetcher
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.queue != nil {
+		return p.queue
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue, done := make(chan string, prefetchQueueSize), make(chan struct{})
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.prefetchWorker(ctx, queue)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	p.queue, p.cancel, p.done = queue, cancel, done
+	return queue
+}
+
+// stopPrefetcher stops the prefetch workers, if running, waiting for the
+// fetches in flight to return, and forgets the models seen.
+func (w *ChatTemplatingProcessor) stopPrefetcher() {
+	if w.prefetcher == nil {
+		return
+	}
+
+	p := w.prefetcher
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.seen, p.queue, p.cancel, p.done = nil, nil, nil, nil
+	p.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// prefetchWorker fetches the templates of the models in queue until ctx is
+// done.
+func (w *ChatTemplatingProcessor) prefetchWorker(ctx context.Context, queue <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case model := <-queue:
+			w.prefetch(ctx, model)
+		}
+	}
+}
+
+// prefetch fetches the template of model for NotifyModelSeen.
+func (w *ChatTemplatingProcessor) prefetch(ctx context.Context, model string) {
+	if err := w.warmupOne(ctx, w.warmupFetcher(), &FetchChatTemplateRequest{Model: model}); err != nil {
+		log.FromContext(ctx).V(logging.TRACE).WithName("NotifyModelSeen").Error(err, "Failed to prefetch template",
+			"model", model)
+		w.prefetcher.forget(model)
+	}
+}
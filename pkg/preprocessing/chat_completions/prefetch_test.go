@@ -0,0 +1,153 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNotifyModelSeen tests that the template of a model seen in traffic is cached shortly after.
+func TestNotifyModelSeen(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	// An absolute path loads like a Hub model name, and is not cached by the other tests.
+	model, err := filepath.Abs("../../tokenization/testdata/test-model")
+	require.NoError(t, err)
+	prefetching := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPrefetcher(2))
+	t.Cleanup(prefetching.Finalize)
+	prefetching.NotifyModelSeen(model)
+
+	cached := func() bool {
+		data, err := wrapper.DumpCacheState(ctx)
+		require.NoError(t, err)
+		var state preprocessing.CacheState
+		require.NoError(t, json.Unmarshal(data, &state))
+		for _, entry := range state.Templates {
+			if entry.Model == model {
+				return true
+			}
+		}
+		return false
+	}
+	assert.Eventually(t, cached, 5*time.Second, 10*time.Millisecond, "The template should be prefetched")
+}
+
+// TestNotifyModelSeenFetchesOnce tests that a model is only prefetched again after its fetch failed.
+func TestNotifyModelSeenFetchesOnce(t *testing.T) {
+	wrapper := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPrefetcher(1))
+	t.Cleanup(wrapper.Finalize)
+
+	var mu sync.Mutex
+	fetches := make(map[string]int)
+	preprocessing.SetWarmupFetcher(wrapper, func(_ context.Context, req *preprocessing.FetchChatTemplateRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches[req.Model]++
+		if req.Model == "org/flaky" && fetches[req.Model] == 1 {
+			return fmt.Errorf("connection reset")
+		}
+		return nil
+	})
+	fetched := func(model string, want int) func() bool {
+		return func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return fetches[model] == want
+		}
+	}
+
+	wrapper.NotifyModelSeen("org/model")
+	wrapper.NotifyModelSeen("org/flaky")
+	assert.Eventually(t, fetched("org/model", 1), time.Second, time.Millisecond)
+	assert.Eventually(t, fetched("org/flaky", 1), time.Second, time.Millisecond)
+
+	// The failed model is only forgotten once its fetch returned, so keep notifying.
+	assert.Eventually(t, func() bool {
+		wrapper.NotifyModelSeen("org/model")
+		wrapper.NotifyModelSeen("org/flaky")
+		return fetched("org/flaky", 2)()
+	}, time.Second, time.Millisecond, "A model whose fetch failed should be fetched again")
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, fetched("org/model", 1)(), "A fetched model should not be fetched again")
+
+	// Processors without a prefetcher ignore notifications.
+	preprocessing.NewChatTemplatingProcessor().NotifyModelSeen("org/model")
+}
+
+// TestNotifyModelSeenLifecycle tests that the models remembered as seen are bounded and that Shutdown stops the
+// prefetch workers.
+func TestNotifyModelSeenLifecycle(t *testing.T) {
+	wrapper := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPrefetcher(1))
+
+	var mu sync.Mutex
+	fetches := 0
+	slowStarted := make(chan struct{})
+	preprocessing.SetWarmupFetcher(wrapper, func(ctx context.Context, req *preprocessing.FetchChatTemplateRequest) error {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		if req.Model == "org/slow" {
+			close(slowStarted)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+
+	for i := range preprocessing.MaxPrefetchedModels + 10 {
+		wrapper.NotifyModelSeen(fmt.Sprintf("org/model-%d", i))
+	}
+	assert.LessOrEqual(t, preprocessing.PrefetchSeenModels(wrapper), preprocessing.MaxPrefetchedModels,
+		"The models seen should be bounded")
+
+	// Shutdown cancels the fetch in flight and waits for it. Models dropped while the queue is full are forgotten, so
+	// keep notifying.
+	assert.Eventually(t, func() bool {
+		wrapper.NotifyModelSeen("org/slow")
+		select {
+		case <-slowStarted:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, time.Millisecond, "The slow model should be fetched")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, wrapper.Shutdown(ctx))
+	assert.Zero(t, preprocessing.PrefetchSeenModels(wrapper), "Shutdown should forget the models seen")
+
+	mu.Lock()
+	stopped := fetches
+	mu.Unlock()
+	wrapper.NotifyModelSeen("org/after-shutdown")
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, stopped, fetches, "Models seen after Shutdown should not be fetched")
+	assert.Zero(t, preprocessing.PrefetchSeenModels(wrapper), "Models seen after Shutdown should be ignored")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// PrefixCacheResult is a render split for prefix caching, as returned by
+// PrefixCacheRender.
+type PrefixCacheResult struct {
+	// PrefixTokens are the tokens of the conversation without the generation
+	// prompt, the stable prefix shared by later turns.
+	PrefixTokens []uint32
+	// PrefixHash is the sha256 hex digest of PrefixTokens, computed like
+	// PromptHashTokens.
+	PrefixHash string
+	// FullTokens are the tokens of the conversation with the generation
+	// prompt: PrefixTokens followed by GenerationTokens.
+	FullTokens []uint32
+	// GenerationTokens are the trailing tokens added by the generation prompt.
+	GenerationTokens []uint32
+}
+
+// PrefixCacheRender renders req with the generation prompt and splits its
+// tokens into the stable prefix and the generation prompt, so a KV-cache can
+// store the prefix once and share it between the turns of a conversation. A
+// single render is made: the generation prompt tokens are the ones past the
+// longest common prefix with the render without it, as for
+// GenerationPromptTokens, so a token merged across the boundary belongs to
+// GenerationTokens. req.Tokenizer must be set; AddGenerationPrompt is implied
+// and ContinueFinalMessage is not supported. req is not modified.
+func (w *ChatTemplatingProcessor) PrefixCacheRender(ctx context.Context, req *RenderJinjaTemplateRequest,
+) (*PrefixCacheResult, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+	if req.Tokenizer == nil {
+		return nil, fmt.Errorf("prefix cache rendering requires a tokenizer")
+	}
+	if req.ContinueFinalMessage || req.AppendEOS {
+		return nil, fmt.Errorf("prefix cache rendering does not support continue_final_message nor append_eos")
+	}
+
+	full := *req
+	full.AddGenerationPrompt = true
+	full.ReturnTokenIDs = true
+	full.ReturnGenerationPromptTokens = true
+	full.MaxPromptTokens = 0
+	response, err := w.RenderChatTemplate(ctx, &full)
+	if err != nil {
+		return nil, err
+	}
+
+	boundary := len(response.TokenIDs) - response.GenerationPromptTokens
+	prefix := response.TokenIDs[:boundary:boundary]
+	hash, err := promptHash(&RenderJinjaTemplateResponse{TokenIDs: prefix}, PromptHashTokens)
+	if err != nil {
+		return nil, err
+	}
+	return &PrefixCacheResult{
+		PrefixTokens:     prefix,
+		PrefixHash:       hash,
+		FullTokens:       response.TokenIDs,
+		GenerationTokens: response.TokenIDs[boundary:],
+	}, nil
+}
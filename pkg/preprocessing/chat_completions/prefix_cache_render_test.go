@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"slices"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrefixCacheRender tests that the full render splits into the prefix and generation prompt tokens.
+func TestPrefixCacheRender(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "What is the capital of France?"},
+		},
+		ChatTemplate: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}" +
+			"{% if add_generation_prompt %}assistant:{% endif %}",
+		Tokenizer: &preprocessing.TokenizerSource{Model: "../../tokenization/testdata/test-model", IsLocalPath: true},
+	}
+
+	result, err := wrapper.PrefixCacheRender(ctx, req)
+	require.NoError(t, err, "PrefixCacheRender should not return an error")
+	require.NotEmpty(t, result.GenerationTokens, "The generation prompt should have tokens")
+	assert.Equal(t, result.FullTokens, slices.Concat(result.PrefixTokens, result.GenerationTokens),
+		"The full tokens should be the prefix tokens followed by the generation tokens")
+	assert.False(t, req.AddGenerationPrompt, "The request should not be modified")
+
+	withoutPrompt := *req
+	withoutPrompt.ReturnTokenIDs = true
+	response, err := wrapper.RenderChatTemplate(ctx, &withoutPrompt)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, response.TokenIDs, result.PrefixTokens, "The prefix should be the render without generation prompt")
+
+	buf := make([]byte, 0, 4*len(result.PrefixTokens))
+	for _, id := range result.PrefixTokens {
+		buf = binary.LittleEndian.AppendUint32(buf, id)
+	}
+	digest := sha256.Sum256(buf)
+	assert.Equal(t, hex.EncodeToString(digest[:]), result.PrefixHash, "The prefix hash should digest the prefix tokens")
+
+	t.Run("Continue final message", func(t *testing.T) {
+		continued := *req
+		continued.ContinueFinalMessage = true
+		_, err := wrapper.PrefixCacheRender(ctx, &continued)
+		assert.ErrorContains(t, err, "does not support continue_final_message")
+	})
+}
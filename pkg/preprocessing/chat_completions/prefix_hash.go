@@ -0,0 +1,153 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// prefixHashCacheSize bounds the number of sessions whose prefix hash is
+// remembered by a processor.
+const prefixHashCacheSize = 1024
+
+// prefixHashCache remembers the prefix hash of sessions, keyed by the digest
+// of their prefix request, so later turns skip rendering it.
+type prefixHashCache struct {
+	mu      sync.Mutex
+	entries map[string]prefixHashEntry
+}
+
+type prefixHashEntry struct {
+	hash   string
+	tokens int
+}
+
+// get returns the entry remembered for key, if any.
+func (c *prefixHashCache) get(key string) (prefixHashEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// add remembers entry for key, evicting another session once full.
+func (c *prefixHashCache) add(key string, entry prefixHashEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]prefixHashEntry)
+	}
+	if len(c.entries) >= prefixHashCacheSize {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = entry
+}
+
+// PrefixHash returns the hash and length in tokens of the stable prefix of
+// the conversation of req: the part rendered from its leading system
+// messages, along with the tools and whatever preamble the template renders
+// before them, which stays the same as turns are appended. The hash is the
+// sha256 hex digest of the prefix tokens, computed like PromptHashTokens.
+// req.Tokenizer must be set.
+//
+// A token merged across the end of the prefix is excluded, as for
+// SystemPromptTokenSpan. The prefix is computed on the first call of a
+// session and remembered, keyed by the template, its variables, the tools,
+// the tokenizer and the system messages, so later turns do not render it.
+func (w *ChatTemplatingProcessor) PrefixHash(ctx context.Context, req *RenderJinjaTemplateRequest,
+) (string, int, error) {
+	if req == nil {
+		return "", 0, fmt.Errorf("received nil request")
+	}
+	if req.Tokenizer == nil {
+		return "", 0, fmt.Errorf("hashing a prefix requires a tokenizer")
+	}
+
+	conversation := req.Conversations
+	if req.DefaultSystemPrompt != "" {
+		conversation = injectSystemPrompt(conversation, req.DefaultSystemPrompt)
+	}
+	systemMessages := 0
+	for systemMessages < len(conversation) && conversation[systemMessages].Role == systemRole {
+		systemMessages++
+	}
+
+	prefix := *req
+	prefix.Conversations = conversation[:systemMessages]
+	prefix.DefaultSystemPrompt = ""
+	prefix.MaxMessages = 0
+	prefix.AddGenerationPrompt = false
+	prefix.ContinueFinalMessage = false
+	prefix.GenerationPromptOverride = ""
+	prefix.ReturnTokenIDs = true
+	prefix.MaxPromptTokens = 0
+	key, err := prefixHashKey(&prefix)
+	if err != nil {
+		return "", 0, err
+	}
+	if entry, ok := w.prefixHashes.get(key); ok {
+		return entry.hash, entry.tokens, nil
+	}
+
+	prefixResponse, err := w.RenderChatTemplate(ctx, &prefix)
+	if err != nil {
+		return "", 0, err
+	}
+	full := *req
+	full.ReturnTokenIDs = true
+	full.MaxPromptTokens = 0
+	fullResponse, err := w.RenderChatTemplate(ctx, &full)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tokens := 0
+	for tokens < len(prefixResponse.TokenIDs) && tokens < len(fullResponse.TokenIDs) &&
+		prefixResponse.TokenIDs[tokens] == fullResponse.TokenIDs[tokens] {
+		tokens++
+	}
+	hash, err := promptHash(&RenderJinjaTemplateResponse{TokenIDs: prefixResponse.TokenIDs[:tokens]}, PromptHashTokens)
+	if err != nil {
+		return "", 0, err
+	}
+	w.prefixHashes.add(key, prefixHashEntry{hash: hash, tokens: tokens})
+	return hash, tokens, nil
+}
+
+// prefixHashKey returns the digest of the prefix request of a session.
+func prefixHashKey(prefix *RenderJinjaTemplateRequest) (string, error) {
+	// Go-only fields affecting the render are not serialized, add them.
+	reqJSON, err := json.Marshal(struct {
+		*renderRequestWire
+		Harmony bool `json:"harmony,omitempty"`
+	}{newRenderRequestWire(prefix), prefix.Harmony})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	digest := sha256.Sum256(reqJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
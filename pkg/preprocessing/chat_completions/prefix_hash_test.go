@@ -0,0 +1,98 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrefixHash tests that the prefix hash of a session is stable, and computed once, as user turns are appended.
+func TestPrefixHash(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	renders := 0
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithPromptHash(preprocessing.PromptHashTokens),
+		preprocessing.WithRenderHook(func(context.Context, *preprocessing.RenderJinjaTemplateRequest,
+			*preprocessing.RenderJinjaTemplateResponse, error, time.Duration,
+		) {
+			renders++
+		}),
+	)
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "Hello"},
+		},
+		ChatTemplate: "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		Tokenizer:    &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+	}
+
+	hash, tokens, err := processor.PrefixHash(ctx, req)
+	require.NoError(t, err, "PrefixHash should not return an error")
+	assert.Positive(t, tokens, "The system prompt should be part of the prefix")
+
+	// The hash is that of the prefix tokens, as a token prompt hash of the system prompt alone.
+	system := *req
+	system.Conversations = req.Conversations[:1]
+	system.ReturnTokenIDs = true
+	response, err := processor.RenderChatTemplate(ctx, &system)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Len(t, response.TokenIDs, tokens, "The prefix should span the system prompt tokens")
+	assert.Equal(t, response.PromptHash, hash, "The prefix hash should be the hash of its tokens")
+
+	renders = 0
+	for _, turn := range []string{"How are you?", "What is the capital of France?"} {
+		req.Conversations = append(req.Conversations,
+			preprocessing.ChatMessage{Role: "assistant", Content: "Fine."},
+			preprocessing.ChatMessage{Role: "user", Content: turn})
+
+		turnHash, turnTokens, err := processor.PrefixHash(ctx, req)
+		require.NoError(t, err, "PrefixHash should not return an error")
+		assert.Equal(t, hash, turnHash, "The prefix hash should be stable across turns")
+		assert.Equal(t, tokens, turnTokens, "The prefix length should be stable across turns")
+	}
+	assert.Zero(t, renders, "The prefix should only be rendered on the first turn")
+
+	t.Run("Changed system prompt", func(t *testing.T) {
+		changed := *req
+		changed.Conversations = append([]preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a pirate."},
+		}, req.Conversations[1:]...)
+		changedHash, _, err := processor.PrefixHash(ctx, &changed)
+		require.NoError(t, err, "PrefixHash should not return an error")
+		assert.NotEqual(t, hash, changedHash, "Another system prompt should hash differently")
+	})
+
+	t.Run("No tokenizer", func(t *testing.T) {
+		noTokenizer := *req
+		noTokenizer.Tokenizer = nil
+		_, _, err := processor.PrefixHash(ctx, &noTokenizer)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// PromptHashSource selects what RenderJinjaTemplateResponse.PromptHash is
+// computed over.
+type PromptHashSource int
+
+const (
+	// PromptHashNone leaves PromptHash empty.
+	PromptHashNone PromptHashSource = iota
+	// PromptHashBytes hashes the UTF-8 bytes of the first rendered chat.
+	PromptHashBytes
+	// PromptHashTokens hashes the token IDs, as little-endian uint32s. Renders
+	// must set ReturnTokenIDs.
+	PromptHashTokens
+)
+
+// String returns the name of the prompt hash source.
+func (s PromptHashSource) String() string {
+	switch s {
+	case PromptHashNone:
+		return "none"
+	case PromptHashBytes:
+		return "bytes"
+	case PromptHashTokens:
+		return "tokens"
+	default:
+		return "unknown"
+	}
+}
+
+// CanonicalPromptBytes renders req and returns the bytes its PromptHash is
+// computed over, as selected by WithPromptHash: the first rendered chat, after
+// normalization and truncation, or its token IDs as little-endian uint32s.
+// Diffing them shows why prompts expected to share a cache key do not, such
+// as differing whitespace. It fails for processors not hashing prompts.
+func (w *ChatTemplatingProcessor) CanonicalPromptBytes(ctx context.Context,
+	req *RenderJinjaTemplateRequest,
+) ([]byte, error) {
+	if w.promptHashSource == PromptHashNone {
+		return nil, errors.New("prompts are not hashed, see WithPromptHash")
+	}
+	response, err := w.RenderChatTemplate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return promptHashInput(response, w.promptHashSource)
+}
+
+// promptHash returns the sha256 hex digest of the rendered prompt, computed
+// over source.
+func promptHash(resp *RenderJinjaTemplateResponse, source PromptHashSource) (string, error) {
+	if source == PromptHashNone {
+		return "", nil
+	}
+	input, err := promptHashInput(resp, source)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(input)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// promptHashInput returns the bytes of the rendered prompt hashed by
+// promptHash for source, other than PromptHashNone.
+func promptHashInput(resp *RenderJinjaTemplateResponse, source PromptHashSource) ([]byte, error) {
+	switch source {
+	case PromptHashBytes:
+		if len(resp.RenderedChats) == 0 {
+			return nil, fmt.Errorf("cannot hash prompt: no rendered chat")
+		}
+		return []byte(resp.RenderedChats[0]), nil
+	case PromptHashTokens:
+		if resp.TokenIDs == nil {
+			return nil, fmt.Errorf("cannot hash prompt tokens: render with ReturnTokenIDs set")
+		}
+		buf := make([]byte, 4*len(resp.TokenIDs))
+		for i, id := range resp.TokenIDs {
+			binary.LittleEndian.PutUint32(buf[4*i:], id)
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("unknown prompt hash source %d", source)
+	}
+}
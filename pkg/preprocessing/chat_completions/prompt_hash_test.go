@@ -0,0 +1,141 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplatePromptHash tests that identical prompts hash equally, over bytes and over tokens.
+func TestRenderChatTemplatePromptHash(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	template, templateVars, err := wrapper.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	newRequest := func(content string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: content}},
+			ChatTemplate:       template,
+			ChatTemplateKWArgs: templateVars,
+			ReturnTokenIDs:     true,
+			Tokenizer: &preprocessing.TokenizerSource{
+				Model:       testModelPath,
+				IsLocalPath: true,
+			},
+		}
+	}
+
+	for _, source := range []preprocessing.PromptHashSource{preprocessing.PromptHashBytes, preprocessing.PromptHashTokens} {
+		t.Run(source.String(), func(t *testing.T) {
+			processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPromptHash(source))
+			require.NoError(t, processor.Initialize())
+			t.Cleanup(processor.Finalize)
+
+			first, err := processor.RenderChatTemplate(ctx, newRequest("Hello"))
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			second, err := processor.RenderChatTemplate(ctx, newRequest("Hello"))
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+			other, err := processor.RenderChatTemplate(ctx, newRequest("Goodbye"))
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+			require.Len(t, first.PromptHash, sha256.Size*2, "PromptHash should be a sha256 hex digest")
+			assert.Equal(t, first.PromptHash, second.PromptHash, "Identical prompts should hash equally")
+			assert.NotEqual(t, first.PromptHash, other.PromptHash, "Different prompts should hash differently")
+		})
+	}
+
+	t.Run("Bytes digest", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPromptHash(preprocessing.PromptHashBytes))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		response, err := processor.RenderChatTemplate(ctx, newRequest("Hello"))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		sum := sha256.Sum256([]byte(response.RenderedChats[0]))
+		assert.Equal(t, hex.EncodeToString(sum[:]), response.PromptHash, "PromptHash should hash the rendered bytes")
+	})
+
+	t.Run("Tokens without token IDs", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPromptHash(preprocessing.PromptHashTokens))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		request := newRequest("Hello")
+		request.ReturnTokenIDs = false
+		_, err := processor.RenderChatTemplate(ctx, request)
+		assert.Error(t, err, "Hashing tokens should require ReturnTokenIDs")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		response, err := wrapper.RenderChatTemplate(ctx, newRequest("Hello"))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Empty(t, response.PromptHash, "PromptHash should be empty by default")
+	})
+}
+
+// TestCanonicalPromptBytes tests that CanonicalPromptBytes returns the bytes PromptHash is computed over.
+func TestCanonicalPromptBytes(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	// "é" as "e" followed by a combining acute accent, composed by NFC normalization.
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations:  []preprocessing.ChatMessage{{Role: "user", Content: "Cafe\u0301"}},
+		ChatTemplate:   "{% for message in messages %}{{ message.content }}{% endfor %}",
+		ReturnTokenIDs: true,
+		Tokenizer:      &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+	}
+
+	for _, source := range []preprocessing.PromptHashSource{preprocessing.PromptHashBytes, preprocessing.PromptHashTokens} {
+		t.Run(source.String(), func(t *testing.T) {
+			processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPromptHash(source),
+				preprocessing.WithUnicodeNormalization(preprocessing.NormalizationNFC))
+			require.NoError(t, processor.Initialize())
+			t.Cleanup(processor.Finalize)
+
+			canonical, err := processor.CanonicalPromptBytes(ctx, request)
+			require.NoError(t, err, "CanonicalPromptBytes should not return an error")
+			response, err := processor.RenderChatTemplate(ctx, request)
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+			sum := sha256.Sum256(canonical)
+			assert.Equal(t, response.PromptHash, hex.EncodeToString(sum[:]), "PromptHash should hash the canonical bytes")
+			if source == preprocessing.PromptHashBytes {
+				assert.Equal(t, "Caf\u00e9", string(canonical), "The canonical bytes should be normalized")
+			} else {
+				assert.Len(t, canonical, 4*len(response.TokenIDs), "Each token ID should take 4 bytes")
+			}
+		})
+	}
+
+	_, err := wrapper.CanonicalPromptBytes(ctx, request)
+	assert.Error(t, err, "Processors not hashing prompts should have no canonical bytes")
+}
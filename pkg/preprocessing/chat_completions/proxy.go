@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// WithHTTPProxy routes the HuggingFace requests of the fetches of the
+// processor through the given HTTP(S) proxy, e.g. in networks whose egress
+// goes through one. Initialize fails if the URL is invalid. Like
+// FetchOptions.ProxyURL, which overrides it per call, it is sent along with
+// each fetch rather than set in the interpreter's environment, so other
+// processors, and the proxy variables of the environment, are not affected.
+func WithHTTPProxy(proxyURL string) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.httpProxy = proxyURL
+	}
+}
+
+// proxyURLOrDefault returns the proxy of a fetch: proxyURL if set, the one
+// set with WithHTTPProxy otherwise.
+func (w *ChatTemplatingProcessor) proxyURLOrDefault(proxyURL string) string {
+	if proxyURL != "" {
+		return proxyURL
+	}
+	return w.httpProxy
+}
+
+// validateProxyURL checks that a proxy URL is usable by the Python HTTP
+// stack. An empty URL means no proxy and is valid.
+func validateProxyURL(proxyURL string) error {
+	if proxyURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("invalid proxy URL %q: unsupported scheme %q", proxyURL, parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchChatTemplatePerCallProxy tests that a per-call proxy is used for that fetch only.
+func TestFetchChatTemplatePerCallProxy(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	// The proxy stub rejects every request, but records that it was used.
+	var proxiedRequests atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		proxiedRequests.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	// Use a model that is not cached, so the fetch has to reach the hub.
+	request := preprocessing.FetchChatTemplateRequest{
+		Model: "llm-d/per-call-proxy-test-model",
+	}
+
+	_, _, err := wrapper.FetchChatTemplateWithOptions(context.Background(), request, preprocessing.FetchOptions{
+		ProxyURL: proxy.URL,
+	})
+	assert.Error(t, err, "Fetch through the rejecting proxy should fail")
+	assert.Positive(t, proxiedRequests.Load(), "Fetch should have been routed through the per-call proxy")
+
+	// Invalid proxies are rejected before reaching Python.
+	requestsBefore := proxiedRequests.Load()
+	_, _, err = wrapper.FetchChatTemplateWithOptions(context.Background(), request, preprocessing.FetchOptions{
+		ProxyURL: "ftp://proxy.invalid",
+	})
+	assert.Error(t, err, "Fetch with an unsupported proxy scheme should fail")
+	assert.Equal(t, requestsBefore, proxiedRequests.Load(), "Rejected proxy should not be contacted")
+}
+
+// TestWithHTTPProxy tests that fetches are routed through the proxy set on the processor.
+func TestWithHTTPProxy(t *testing.T) {
+	getGlobalWrapper()
+
+	var proxiedRequests atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		proxiedRequests.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithHTTPProxy(proxy.URL))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	// Use a model that is not cached, so the fetch has to reach the hub.
+	_, _, err := processor.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model: "llm-d/processor-proxy-test-model",
+	})
+	assert.Error(t, err, "Fetch through the rejecting proxy should fail")
+	assert.Positive(t, proxiedRequests.Load(), "Fetch should have been routed through the processor's proxy")
+
+	// The other fetches of the processor go through the proxy too.
+	requestsBefore := proxiedRequests.Load()
+	_, err = processor.FetchGenerationConfig(context.Background(), preprocessing.GenerationConfigRequest{
+		Model: "llm-d/processor-proxy-test-model",
+	})
+	assert.Error(t, err, "Fetch through the rejecting proxy should fail")
+	assert.Greater(t, proxiedRequests.Load(), requestsBefore, "Generation configs should be fetched through the proxy")
+
+	invalid := preprocessing.NewChatTemplatingProcessor(preprocessing.WithHTTPProxy("ftp://proxy.invalid"))
+	assert.ErrorIs(t, invalid.Initialize(), preprocessing.ErrInitialize, "An invalid proxy should fail Initialize")
+}
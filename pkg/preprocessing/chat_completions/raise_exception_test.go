@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateRaiseException tests surfacing the message of a template calling raise_exception.
+func TestRenderChatTemplateRaiseException(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	// Like many templates, only accept a system message at the start of the conversation.
+	template := "{% for message in messages %}" +
+		"{% if message.role == 'system' and not loop.first %}" +
+		"{{ raise_exception('System message must be at the beginning.') }}{% endif %}" +
+		"{{ message.role }}: {{ message.content }}\n{% endfor %}"
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "Hello"},
+			{Role: "system", Content: "You are a helpful assistant."},
+		},
+		ChatTemplate: template,
+	}
+	_, err := wrapper.RenderChatTemplate(ctx, req)
+	require.ErrorIs(t, err, preprocessing.ErrTemplateRaised, "The template's exception should be surfaced")
+	assert.Contains(t, err.Error(), "System message must be at the beginning.",
+		"The error should carry the template's message")
+
+	t.Run("Batch", func(t *testing.T) {
+		valid := &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:  template,
+		}
+		results, err := wrapper.RenderChatTemplateBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{req, valid})
+		require.NoError(t, err, "RenderChatTemplateBatch should not return an error")
+		require.Len(t, results, 2)
+		assert.ErrorIs(t, results[0].Err, preprocessing.ErrTemplateRaised)
+		require.NoError(t, results[1].Err, "A valid conversation should render")
+		assert.Equal(t, "user: Hello\n", results[1].Response.RenderedChats[0])
+	})
+
+	t.Run("Template error", func(t *testing.T) {
+		undefined := *req
+		undefined.ChatTemplate = "{{ messages[0].content.missing.field }}"
+		_, err := wrapper.RenderChatTemplate(ctx, &undefined)
+		require.Error(t, err, "An undefined variable should fail the render")
+		assert.NotErrorIs(t, err, preprocessing.ErrTemplateRaised,
+			"Only raise_exception should be reported as raised by the template")
+	})
+}
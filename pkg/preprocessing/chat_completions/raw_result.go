@@ -0,0 +1,178 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+//nolint: gocritic // C and unsafe are considered dups by the linter.
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	/*
+		#include "cgo_functions.h"
+	*/
+	"C"
+)
+
+// CResult is a rendered conversation held in the C memory Python returned it
+// in, for integrations keeping results in their own buffers without an
+// intermediate Go copy.
+//
+// The caller owns the CResult and must call Release exactly once when done
+// with it; until then the C memory is not reclaimed. The slice returned by
+// Bytes aliases that memory: it must not be used, nor retained, after
+// Release, and must not be modified. A CResult must not be released while
+// another goroutine uses its bytes.
+type CResult struct {
+	ptr *C.char
+	// The rendered conversation follows the response header in the buffer.
+	offset int
+	length int
+}
+
+// Bytes returns the rendered conversation, valid until Release. It is nil
+// once released.
+func (r *CResult) Bytes() []byte {
+	if r.ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(r.ptr), r.offset)), r.length)
+}
+
+// Len returns the length of the rendered conversation in bytes.
+func (r *CResult) Len() int {
+	return r.length
+}
+
+// Release frees the C memory of the result. Releasing it again is a no-op.
+func (r *CResult) Release() {
+	if r.ptr == nil {
+		return
+	}
+	freeC(unsafe.Pointer(r.ptr))
+	r.ptr = nil
+	r.offset, r.length = 0, 0
+}
+
+// RenderRaw renders a chat template like RenderChatTemplate, returning the
+// rendered conversation in the C memory it was produced in rather than
+// copying it into a Go string. The caller must Release the result, see
+// CResult. The render is checked like by RenderChatTemplate, but only the
+// text is returned: the fields of RenderJinjaTemplateResponse other than
+// RenderedChats are not available. The render hook is passed them, but not
+// the rendered conversation.
+func (w *ChatTemplatingProcessor) RenderRaw(ctx context.Context, req *RenderJinjaTemplateRequest,
+) (*CResult, error) {
+	if w.renderHook == nil {
+		result, _, err := w.renderRaw(ctx, req)
+		return result, err
+	}
+
+	start := time.Now()
+	result, response, err := w.renderRaw(ctx, req)
+	w.runRenderHook(ctx, req, response, err, time.Since(start))
+	return result, err
+}
+
+// renderRaw renders a chat template for RenderRaw, without running the render
+// hook. It also returns the response of the render, without RenderedChats.
+func (w *ChatTemplatingProcessor) renderRaw(ctx context.Context, req *RenderJinjaTemplateRequest,
+) (_ *CResult, _ *RenderJinjaTemplateResponse, err error) {
+	defer func() { countRenderCancellation(err) }()
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("RenderRaw")
+	if req == nil {
+		traceLogger.Error(nil, "Received nil request")
+		return nil, nil, fmt.Errorf("received nil request")
+	}
+
+	req, droppedMessages, err := w.prepareRender(req)
+	if err != nil {
+		traceLogger.Error(err, "Invalid request")
+		return nil, nil, err
+	}
+	if err := w.checkMemoryLimit(); err != nil {
+		traceLogger.Error(err, "Rejecting render under memory pressure")
+		return nil, nil, err
+	}
+
+	reqJSON, err := json.Marshal(newRenderRequestWire(req))
+	if err != nil {
+		traceLogger.Error(err, "Failed to marshal request")
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	// Note: cString allocates C memory that must be freed to avoid memory leaks
+	cFuncName := cString("render_jinja_template_text")
+	defer freeC(unsafe.Pointer(cFuncName))
+	cReqJSON := cString(string(reqJSON))
+	defer freeC(unsafe.Pointer(cReqJSON))
+	var cResult *C.char
+	if err := cgoThread.run(ctx, func() { cResult = C.Py_CallChatTemplateFunction(cFuncName, cReqJSON) }); err != nil {
+		return nil, nil, err
+	}
+	if cResult == nil {
+		traceLogger.Error(nil, "C function returned nil")
+		return nil, nil, fmt.Errorf("python render_jinja_template_text failed")
+	}
+
+	// The result is released with freeC, so it is accounted like our own allocations.
+	liveCAllocations.Add(1)
+	result := &CResult{ptr: cResult, length: int(C.strlen(cResult))}
+	response, err := w.finishRawRender(req, result, droppedMessages)
+	if err != nil {
+		result.Release()
+		traceLogger.Error(err, "Failed to finish the render")
+		return nil, nil, err
+	}
+	return result, response, nil
+}
+
+// finishRawRender splits result into the response header and the rendered
+// conversation following it, and finishes the render like RenderChatTemplate,
+// lending the conversation to finishRender without copying it. The response
+// is returned without the conversation, which only lives as long as result.
+func (w *ChatTemplatingProcessor) finishRawRender(req *RenderJinjaTemplateRequest, result *CResult,
+	droppedMessages int,
+) (*RenderJinjaTemplateResponse, error) {
+	buf := result.Bytes()
+	header, _, ok := bytes.Cut(buf, []byte("\n"))
+	if !ok {
+		return nil, fmt.Errorf("python render_jinja_template_text returned no response header")
+	}
+	var response RenderJinjaTemplateResponse
+	if err := json.Unmarshal(header, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	result.offset = len(header) + 1
+	result.length -= result.offset
+	rendered := result.Bytes()
+	response.RenderedChats = []string{unsafe.String(unsafe.SliceData(rendered), len(rendered))}
+	err := w.finishRender(req, &response, droppedMessages)
+	response.RenderedChats = nil
+	return &response, err
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderRaw tests acquiring, using and releasing a render result held in C memory.
+func TestRenderRaw(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	request := largeRenderRequest(16)
+	expected, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	live := preprocessing.LiveCAllocations()
+	result, err := wrapper.RenderRaw(ctx, request)
+	require.NoError(t, err, "RenderRaw should not return an error")
+	assert.Equal(t, live+1, preprocessing.LiveCAllocations(), "The result should be held until released")
+
+	assert.Equal(t, len(expected.RenderedChats[0]), result.Len())
+	assert.Equal(t, expected.RenderedChats[0], string(result.Bytes()), "The result should hold the rendered chat")
+
+	result.Release()
+	assert.Equal(t, live, preprocessing.LiveCAllocations(), "Releasing the result should free its C memory")
+	assert.Nil(t, result.Bytes(), "A released result should not expose its memory")
+	assert.Zero(t, result.Len())
+	result.Release()
+	assert.Equal(t, live, preprocessing.LiveCAllocations(), "Releasing a result again should be a no-op")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// rawTemplateRequest is the JSON payload sent to get_raw_chat_template.
+type rawTemplateRequest struct {
+	Model    string `json:"model"`
+	Revision string `json:"revision,omitempty"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+}
+
+// rawTemplateResponse is the JSON result of get_raw_chat_template.
+type rawTemplateResponse struct {
+	Template []byte `json:"template"`
+	NotFound string `json:"not_found,omitempty"`
+}
+
+// FetchTemplateRaw returns the chat template of a model exactly as stored,
+// e.g. to archive the template each stored prompt was rendered with, along
+// with its sha256 hex digest, as returned by TemplateDigest. The template is
+// read from the model's `chat_template.jinja`, or else from the
+// `chat_template` of its tokenizer_config.json; models with named templates
+// return their `default` one. Unlike FetchChatTemplate, no tokenizer is
+// loaded and nothing is selected or overridden per request.
+//
+// model is a HuggingFace model ID, or a local model directory.
+func (w *ChatTemplatingProcessor) FetchTemplateRaw(ctx context.Context, model, revision string,
+) (_ []byte, _ string, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	var resp rawTemplateResponse
+	if err := callPythonFunction(ctx, "get_raw_chat_template",
+		rawTemplateRequest{Model: model, Revision: revision, ProxyURL: w.httpProxy}, &resp); err != nil {
+		return nil, "", err
+	}
+	if resp.NotFound != "" {
+		return nil, "", fmt.Errorf("%w: %s: %s", ErrModelNotFound, model, resp.NotFound)
+	}
+	return resp.Template, TemplateDigest(string(resp.Template)), nil
+}
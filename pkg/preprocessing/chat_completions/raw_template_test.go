@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchTemplateRaw tests that templates are returned byte for byte as stored, with their digest.
+func TestFetchTemplateRaw(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	t.Run("Template file", func(t *testing.T) {
+		modelDir := filepath.Join(t.TempDir(), "jinja-model")
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		// Line endings, trailing whitespace and non-ASCII text must survive as is.
+		stored := []byte("{%- for message in messages -%}\r\n  {{ message.content }} — \t\n{%- endfor -%}\n\n")
+		require.NoError(t, os.WriteFile(filepath.Join(modelDir, "chat_template.jinja"), stored, 0o600))
+
+		template, digest, err := wrapper.FetchTemplateRaw(ctx, modelDir, "")
+		require.NoError(t, err, "FetchTemplateRaw should not return an error")
+		assert.Equal(t, stored, template, "The template should match the file exactly")
+		sum := sha256.Sum256(stored)
+		assert.Equal(t, hex.EncodeToString(sum[:]), digest, "The digest should be the sha256 of the file")
+	})
+
+	t.Run("Tokenizer config", func(t *testing.T) {
+		config, err := os.ReadFile(filepath.Join(testModelPath, "tokenizer_config.json"))
+		require.NoError(t, err)
+		var tokenizerConfig struct {
+			ChatTemplate string `json:"chat_template"`
+		}
+		require.NoError(t, json.Unmarshal(config, &tokenizerConfig))
+
+		template, digest, err := wrapper.FetchTemplateRaw(ctx, testModelPath, "")
+		require.NoError(t, err, "FetchTemplateRaw should not return an error")
+		assert.Equal(t, []byte(tokenizerConfig.ChatTemplate), template, "The template should match the tokenizer config")
+		assert.Equal(t, preprocessing.TemplateDigest(tokenizerConfig.ChatTemplate), digest)
+	})
+
+	t.Run("Missing model", func(t *testing.T) {
+		_, _, err := wrapper.FetchTemplateRaw(ctx, t.TempDir(), "")
+		assert.ErrorIs(t, err, preprocessing.ErrModelNotFound, "A directory without a template should not be found")
+	})
+}
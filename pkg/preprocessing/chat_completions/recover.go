@@ -0,0 +1,50 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// faultHook, if set, runs on the locked thread before every call into
+// Python, for tests to inject faults.
+var faultHook atomic.Pointer[func()]
+
+// internalError wraps a recovered panic value in ErrInternal, along with the
+// stack of the panicking goroutine. A panic value that is an error stays in
+// the chain, so that e.g. an ErrTransient panic is classified as such.
+func internalError(recovered interface{}) error {
+	if cause, ok := recovered.(error); ok {
+		return fmt.Errorf("%w: panic: %w\n%s", ErrInternal, cause, debug.Stack())
+	}
+	return fmt.Errorf("%w: panic: %v\n%s", ErrInternal, recovered, debug.Stack())
+}
+
+// recoverInternal returns the error of a method given the value it
+// recovered: an ErrInternal error if it panicked, err otherwise. Methods
+// crossing into C recover with it in a deferred call, so that one bad call
+// fails alone instead of crashing the process:
+//
+//	defer func() { err = recoverInternal(recover(), err) }()
+func recoverInternal(recovered interface{}, err error) error {
+	if recovered != nil {
+		return internalError(recovered)
+	}
+	return err
+}
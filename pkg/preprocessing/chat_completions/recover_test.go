@@ -0,0 +1,49 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPanicRecovery tests that a panic while calling into Python fails the call with ErrInternal instead of
+// crashing the process, and that later calls are served normally.
+func TestPanicRecovery(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+	}
+
+	preprocessing.SetFaultHook(func() { panic("injected fault") })
+	_, err := wrapper.RenderChatTemplate(ctx, request)
+	preprocessing.SetFaultHook(nil)
+	require.ErrorIs(t, err, preprocessing.ErrInternal, "A panic should be returned as ErrInternal")
+	assert.Contains(t, err.Error(), "injected fault", "The error should carry the panic value")
+	assert.Contains(t, err.Error(), "recover_test.go", "The error should carry the stack of the panic")
+
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "Calls after a recovered panic should succeed")
+	assert.NotEmpty(t, response.RenderedChats, "Calls after a recovered panic should render")
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// WithConsistencyChecks verifies renders requesting both ReturnTokenIDs and
+// ReturnPerTurnSegments, failing them with ErrInconsistentRender when the
+// tokens of their turn segments do not add up to TokenIDs. This happens when
+// a template ends turns mid-token for the model's tokenizer, so per-turn
+// token spans would not line up with the prompt, and is meant for debugging
+// and testing new templates.
+func WithConsistencyChecks() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.consistencyChecks = true
+	}
+}
+
+// checkTurnTokenCounts checks that the turn token counts of response, if any,
+// add up to its tokens before truncation.
+func checkTurnTokenCounts(response *RenderJinjaTemplateResponse) error {
+	if response.TurnTokenCounts == nil {
+		return nil
+	}
+	sum := 0
+	for _, count := range response.TurnTokenCounts {
+		sum += count
+	}
+	if tokens := len(response.TokenIDs) + response.TruncatedTokens; sum != tokens {
+		return fmt.Errorf("%w: turn segments tokenize to %d tokens, the rendered chat to %d",
+			ErrInconsistentRender, sum, tokens)
+	}
+	return nil
+}
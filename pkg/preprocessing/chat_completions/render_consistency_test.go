@@ -0,0 +1,75 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateConsistencyChecks tests that turn token counts must add up to the rendered tokens.
+func TestRenderChatTemplateConsistencyChecks(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithConsistencyChecks())
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	newRequest := func(template string, messages ...string) *preprocessing.RenderJinjaTemplateRequest {
+		req := &preprocessing.RenderJinjaTemplateRequest{
+			ChatTemplate:          template,
+			ReturnTokenIDs:        true,
+			ReturnPerTurnSegments: true,
+			Tokenizer:             &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+		}
+		for _, message := range messages {
+			req.Conversations = append(req.Conversations, preprocessing.ChatMessage{Role: "user", Content: message})
+		}
+		return req
+	}
+
+	t.Run("Consistent", func(t *testing.T) {
+		response, err := processor.RenderChatTemplate(ctx, newRequest(
+			"{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			"Hello", "How are you?"))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		require.Len(t, response.TurnTokenCounts, 2, "There should be a count per message")
+		assert.Equal(t, len(response.TokenIDs), response.TurnTokenCounts[0]+response.TurnTokenCounts[1],
+			"Turn token counts should add up to the rendered tokens")
+	})
+
+	t.Run("Mismatched", func(t *testing.T) {
+		// Without a separator, "Hel" and "lo" render to the single token "hello".
+		response, err := processor.RenderChatTemplate(ctx, newRequest(
+			"{% for message in messages %}{{ message.content }}{% endfor %}",
+			"Hel", "lo"))
+		require.ErrorIs(t, err, preprocessing.ErrInconsistentRender, "Turns ending mid-token should be reported")
+		assert.Nil(t, response)
+
+		// Without the checks, the same render succeeds.
+		_, err = getGlobalWrapper().RenderChatTemplate(ctx, newRequest(
+			"{% for message in messages %}{{ message.content }}{% endfor %}",
+			"Hel", "lo"))
+		assert.NoError(t, err, "RenderChatTemplate should not check consistency by default")
+	})
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxFuzzNesting bounds the nesting of the fuzzed kwargs, past the recursion limit of the Python JSON decoder.
+const maxFuzzNesting = 1200
+
+// nestedKWArg nests value in depth alternating maps and lists.
+func nestedKWArg(value interface{}, depth int) interface{} {
+	for i := range depth {
+		if i%2 == 0 {
+			value = map[string]interface{}{"nested": value}
+		} else {
+			value = []interface{}{value}
+		}
+	}
+	return value
+}
+
+// FuzzRenderChatTemplate tests that odd requests, with arbitrary strings, tools and deeply nested kwargs, only ever
+// fail with an error: neither the process nor the interpreter may crash, and no panic may be recovered.
+func FuzzRenderChatTemplate(f *testing.F) {
+	f.Add("user", "Hello", "", `{"type":"function","function":{"name":"get_weather"}}`, `{"enable_thinking":true}`, uint16(0))
+	f.Add("assistant", "\u202eevil\u202c \U0001F600 \u0000 \xff\xfe", "agent\u200d1", `[]`, `{"x":1e308}`, uint16(3))
+	f.Add("", "", "", ``, ``, uint16(64))
+	f.Add("tool", "{{ messages }}{% raw %}", "\n", `{"function":null}`, `{"messages":"shadowed"}`, uint16(maxFuzzNesting))
+	f.Add("system", "\xc3\x28 \xed\xa0\x80 \ufffd", "", `"not an object"`, `[1,2,3]`, uint16(1))
+
+	wrapper := getGlobalWrapper()
+	f.Fuzz(func(t *testing.T, role, content, name, toolJSON, kwargsJSON string, depth uint16) {
+		var tool interface{}
+		if json.Unmarshal([]byte(toolJSON), &tool) != nil {
+			tool = toolJSON
+		}
+		var kwargs map[string]interface{}
+		if json.Unmarshal([]byte(kwargsJSON), &kwargs) != nil {
+			kwargs = map[string]interface{}{"raw": kwargsJSON}
+		}
+		if kwargs == nil {
+			kwargs = map[string]interface{}{}
+		}
+		kwargs["deep"] = nestedKWArg(content, int(depth%(maxFuzzNesting+1)))
+
+		response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: role, Content: content, Name: name},
+				{Role: "user", Content: content},
+			},
+			Tools:              []interface{}{tool},
+			ChatTemplate:       preprocessing.BundledChatMLTemplate,
+			ChatTemplateKWArgs: kwargs,
+		})
+		if err != nil {
+			assert.NotErrorIs(t, err, preprocessing.ErrInternal, "Odd requests should fail without panicking")
+			return
+		}
+		require.Len(t, response.RenderedChats, 1, "A rendered request should have one rendered chat")
+	})
+}
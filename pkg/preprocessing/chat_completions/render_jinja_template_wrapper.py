@@ -17,17 +17,32 @@
 Standalone wrapper for render_jinja_template function from transformers.
 """
 
+import base64
+import builtins
+import contextlib
+import gc
+import hashlib
 import json
 import logging
+import math
+import os
+import re
+import secrets
 import sys
+import threading
+import time
+import warnings
+from collections import OrderedDict
+from datetime import datetime
 from typing import Optional, Union
 
 # Import core functions from transformers - moved to function level to avoid import errors
 TRANSFORMERS_AVAILABLE = None  # Will be set when first needed
+TRANSFORMERS_IMPORT_ERROR = None  # The ImportError raised when transformers is not available
 
 def _ensure_transformers_available():
     """Ensure transformers is available, importing it if needed."""
-    global TRANSFORMERS_AVAILABLE
+    global TRANSFORMERS_AVAILABLE, TRANSFORMERS_IMPORT_ERROR
     if TRANSFORMERS_AVAILABLE is None:
         try:
             print("[Python] Attempting to import transformers...")
@@ -40,91 +55,1585 @@ def _ensure_transformers_available():
             print(f"[Python] Failed to import transformers: {e}")
             print("[Python] Ensure the 'transformers' library is installed in the Python environment.")
             TRANSFORMERS_AVAILABLE = False
+            TRANSFORMERS_IMPORT_ERROR = e
             return False
     return TRANSFORMERS_AVAILABLE
 
+
+def check_transformers_available():
+    """
+    Raise an ImportError describing why transformers cannot be imported, if so.
+    Called by the C layer at initialization so the cause is reported to Go.
+    """
+    if _ensure_transformers_available():
+        return None
+
+    try:
+        import transformers
+        installed = f"version {transformers.__version__} is installed"
+    except ImportError:
+        installed = "it is not installed"
+    raise ImportError(
+        f"transformers library is not importable ({installed}): {TRANSFORMERS_IMPORT_ERROR}"
+    ) from TRANSFORMERS_IMPORT_ERROR
+
 # Basic logging setup
 logger = logging.getLogger(__name__)
 
-# Module-level cache for templates
-_template_cache = {}
-_cache_lock = None
+# Module-level LRU cache for templates, bounded to _template_cache_size entries besides the pinned ones
+_template_cache = OrderedDict()
+_template_cache_size = 256
+# Cache keys of the templates never evicted from _template_cache
+_pinned_templates = set()
+# Metadata of the entries of _template_cache, by cache key, reported by dump_template_cache
+_template_cache_stats = {}
+# Module-level cache for loaded tokenizers, used when token IDs are requested
+_tokenizer_cache = {}
+# LRU cache of the copies of cached tokenizers with additional or overridden special tokens, by cache key and
+# tokens. Each holds a full copy of its tokenizer, so only the _extended_tokenizer_cache_size most recently used
+# are kept.
+_extended_tokenizer_cache = OrderedDict()
+_extended_tokenizer_cache_size = 16
+# Tokenizers loaded by load_tokenizer, by handle, kept until release_tokenizer even if the caches are cleared
+_tokenizer_handles = {}
+# Number of tokenizers loaded by _load_tokenizer, reported by tokenizer_load_count
+_tokenizer_loads = 0
+# Identifies this instance of the module, as hot reloads load new ones next to it
+_module_instance = secrets.token_hex(8)
+# Module-level cache for parsed generation configs
+_generation_config_cache = {}
+# Module-level cache of whether models are encoder-decoder, read from their config.json
+_encoder_decoder_cache = {}
+_cache_lock = None
+
+def _get_cache_lock():
+    """Get or create a threading lock for cache access."""
+    global _cache_lock
+    if _cache_lock is None:
+        import threading
+        _cache_lock = threading.Lock()
+    return _cache_lock
+
+
+def _collect_template_vars(tokenizer):
+    """Collect extra rendering variables from a tokenizer."""
+    kwargs = {}
+    for k in ["bos_token", "eos_token", "eot_token", "pad_token", "unk_token", "sep_token", "additional_special_tokens"]:
+        v = getattr(tokenizer, k, None)
+        if v is not None:
+            kwargs[k] = v
+    return kwargs
+
+
+def _cache_key(model_name, revision, token, is_local_path):
+    """Build the cache key shared by the template and tokenizer caches."""
+    return f"{model_name}:{revision or 'main'}:{token or 'none'}:{is_local_path}"
+
+
+def _local_model_dir(model_name):
+    """Return the directory of a local model path.
+
+    For local paths, model_name can be either a directory containing tokenizer files
+    or a path to a specific tokenizer file. Ensure we extract the directory if needed.
+    """
+    import os
+
+    if os.path.isfile(model_name):
+        # If it's a file path (tokenizer.json), get the directory
+        return os.path.dirname(model_name)
+    # If it's already a directory, use it directly
+    return model_name
+
+
+def _load_eos_token_ids(tokenizer, model_name, revision=None, token=None, is_local_path=False, proxy_url=None):
+    """Return the token IDs ending generation, and their strings.
+
+    The generation config may list several EOS tokens (e.g. Llama-3's `<|end_of_text|>`
+    and `<|eot_id|>`), the tokenizer only knows one, so it is only used as a fallback.
+    """
+    from transformers import GenerationConfig
+
+    eos_token_ids = None
+    try:
+        if is_local_path:
+            generation_config = GenerationConfig.from_pretrained(_local_model_dir(model_name), local_files_only=True)
+        else:
+            proxies = {"http": proxy_url, "https": proxy_url} if proxy_url else None
+            generation_config = GenerationConfig.from_pretrained(model_name, revision=revision, token=token,
+                                                                 proxies=proxies)
+        eos_token_ids = generation_config.eos_token_id
+    except (OSError, ValueError):
+        pass  # Not every model ships a generation config
+
+    if eos_token_ids is None:
+        eos_token_ids = getattr(tokenizer, "eos_token_id", None)
+    if eos_token_ids is None:
+        return [], []
+    if isinstance(eos_token_ids, int):
+        eos_token_ids = [eos_token_ids]
+    eos_token_ids = list(eos_token_ids)
+    return eos_token_ids, list(tokenizer.convert_ids_to_tokens(eos_token_ids))
+
+
+def _load_tokenizer(model_name, revision=None, token=None, is_local_path=False, proxy_url=None):
+    """Load a tokenizer from a local path or from Hugging Face.
+
+    The proxy is passed per call instead of through the environment, so that
+    concurrent loads with different proxies do not interfere.
+    """
+    from transformers import AutoTokenizer
+
+    global _tokenizer_loads
+    with _get_cache_lock():
+        _tokenizer_loads += 1
+
+    # Determine if we're loading from local path or HuggingFace
+    if is_local_path:
+        tokenizer_dir = _local_model_dir(model_name)
+        print(f"[Python] Loading tokenizer from local path: {tokenizer_dir}")
+        return AutoTokenizer.from_pretrained(tokenizer_dir, local_files_only=True, trust_remote_code=True)
+
+    # Load from Hugging Face
+    print(f"[Python] Loading tokenizer from HuggingFace: {model_name}")
+    proxies = {"http": proxy_url, "https": proxy_url} if proxy_url else None
+    return AutoTokenizer.from_pretrained(model_name, revision=revision, token=token, proxies=proxies,
+                                         trust_remote_code=True)
+
+
+# GGUF metadata value types, see https://github.com/ggml-org/ggml/blob/master/docs/gguf.md
+_GGUF_MAGIC = b"GGUF"
+_GGUF_TYPE_STRING = 8
+_GGUF_TYPE_ARRAY = 9
+_GGUF_SCALAR_FORMATS = {
+    0: "<B", 1: "<b", 2: "<H", 3: "<h", 4: "<I", 5: "<i", 6: "<f", 7: "<?", 10: "<Q", 11: "<q", 12: "<d",
+}
+# GGUF metadata keys read by _read_gguf_chat_template
+_GGUF_TEMPLATE_KEYS = {
+    "tokenizer.chat_template",
+    "tokenizer.ggml.tokens",
+    "tokenizer.ggml.bos_token_id",
+    "tokenizer.ggml.eos_token_id",
+    "tokenizer.ggml.padding_token_id",
+    "tokenizer.ggml.unknown_token_id",
+}
+
+
+def _is_gguf_path(model_name):
+    """Whether a local model path points at a GGUF file."""
+    return model_name.lower().endswith(".gguf")
+
+
+def _read_gguf_metadata(path, keys):
+    """Read the given metadata keys from a GGUF file header, skipping the others.
+
+    Only the header is read, tensor data is never loaded.
+    """
+    import struct
+
+    def read(f, fmt):
+        size = struct.calcsize(fmt)
+        data = f.read(size)
+        if len(data) != size:
+            raise ValueError(f"truncated GGUF file: {path}")
+        return struct.unpack(fmt, data)[0]
+
+    def read_string(f):
+        return f.read(read(f, "<Q")).decode("utf-8")
+
+    def read_value(f, value_type, keep):
+        if value_type == _GGUF_TYPE_STRING:
+            return read_string(f)
+        if value_type == _GGUF_TYPE_ARRAY:
+            item_type = read(f, "<I")
+            count = read(f, "<Q")
+            if not keep and item_type in _GGUF_SCALAR_FORMATS:
+                f.seek(count * struct.calcsize(_GGUF_SCALAR_FORMATS[item_type]), 1)
+                return None
+            return [read_value(f, item_type, keep) for _ in range(count)]
+        if value_type in _GGUF_SCALAR_FORMATS:
+            return read(f, _GGUF_SCALAR_FORMATS[value_type])
+        raise ValueError(f"unsupported GGUF metadata type {value_type} in {path}")
+
+    metadata = {}
+    with open(path, "rb") as f:
+        if f.read(4) != _GGUF_MAGIC:
+            raise ValueError(f"not a GGUF file: {path}")
+        version = read(f, "<I")
+        # Version 1 used 32-bit counts, later versions 64-bit ones.
+        count_format = "<I" if version == 1 else "<Q"
+        read(f, count_format)  # tensor count
+        kv_count = read(f, count_format)
+        for _ in range(kv_count):
+            key = read_string(f)
+            value = read_value(f, read(f, "<I"), key in keys)
+            if key in keys:
+                metadata[key] = value
+    return metadata
+
+
+def _read_gguf_chat_template(path):
+    """Return the chat template, special tokens and EOS token IDs stored in a GGUF file's metadata."""
+    metadata = _read_gguf_metadata(path, _GGUF_TEMPLATE_KEYS)
+    template = metadata.get("tokenizer.chat_template")
+    if template is None:
+        raise ValueError(f"GGUF file has no tokenizer.chat_template metadata: {path}")
+
+    template_vars = {}
+    eos_token_ids = []
+    tokens = metadata.get("tokenizer.ggml.tokens") or []
+    for name, key in [("bos_token", "tokenizer.ggml.bos_token_id"),
+                      ("eos_token", "tokenizer.ggml.eos_token_id"),
+                      ("pad_token", "tokenizer.ggml.padding_token_id"),
+                      ("unk_token", "tokenizer.ggml.unknown_token_id")]:
+        token_id = metadata.get(key)
+        if token_id is not None and 0 <= token_id < len(tokens):
+            template_vars[name] = tokens[token_id]
+            if name == "eos_token":
+                eos_token_ids = [token_id]
+    return template, template_vars, eos_token_ids
+
+
+def _get_tokenizer(source, additional_special_tokens=None, special_token_overrides=None):
+    """Return a cached tokenizer for the given source, loading it on first use.
+
+    Tokenizers with additional or overridden special tokens, such as {"bos_token": "<s>"}, are copies cached
+    apart, so the tokenizer of the source, shared with other requests, is left unchanged. Overrides of
+    special tokens the tokenizer has no attribute for, such as eot_token, are registered as additional ones.
+    """
+    model_name = source.get("model")
+    if not model_name:
+        raise ValueError("tokenizer.model is required when return_token_ids is set")
+
+    handle = source.get("handle")
+    if handle and not additional_special_tokens and not special_token_overrides:
+        # Released or unknown handles, e.g. of a hot reloaded module, are looked up by source
+        with _get_cache_lock():
+            tokenizer = _tokenizer_handles.get(handle)
+        if tokenizer is not None:
+            return tokenizer
+
+    revision = source.get("revision")
+    token = source.get("token")
+    is_local_path = source.get("is_local_path", False)
+    cache_key = _cache_key(model_name, revision, token, is_local_path)
+
+    lock = _get_cache_lock()
+    with lock:
+        tokenizer = _tokenizer_cache.get(cache_key)
+    if tokenizer is None:
+        tokenizer = _load_tokenizer(model_name, revision, token, is_local_path)
+        with lock:
+            _tokenizer_cache[cache_key] = tokenizer
+    if not additional_special_tokens and not special_token_overrides:
+        return tokenizer
+
+    extended_key = (f"{cache_key}:{json.dumps(sorted(set(additional_special_tokens or [])))}"
+                    f":{json.dumps(special_token_overrides or {}, sort_keys=True)}")
+    with lock:
+        extended = _extended_tokenizer_cache.get(extended_key)
+        if extended is not None:
+            _extended_tokenizer_cache.move_to_end(extended_key)
+    if extended is None:
+        import copy
+
+        extended = copy.deepcopy(tokenizer)
+        attributes = getattr(extended, "SPECIAL_TOKENS_ATTRIBUTES", [])
+        special_tokens = {name: token for name, token in (special_token_overrides or {}).items()
+                          if name in attributes and name != "additional_special_tokens"}
+        special_tokens["additional_special_tokens"] = list(additional_special_tokens or []) + [
+            token for name, token in (special_token_overrides or {}).items() if name not in special_tokens]
+        extended.add_special_tokens(special_tokens, replace_additional_special_tokens=False)
+        with lock:
+            _extended_tokenizer_cache[extended_key] = extended
+            while len(_extended_tokenizer_cache) > _extended_tokenizer_cache_size:
+                _extended_tokenizer_cache.popitem(last=False)
+    return extended
+
+
+# Bounded LRU cache of compiled chat templates, keyed by template hash
+_COMPILE_CACHE_SIZE = 128
+_compile_cache = OrderedDict()
+# Whether the last compile on this thread was served from the cache, and the
+# (trim_blocks, lstrip_blocks) templates are compiled with on this thread, if not transformers'
+_compile_cache_local = threading.local()
+_compile_cache_installed = False
+
+
+def _install_compile_cache():
+    """Serve transformers' template compilation from _compile_cache.
+
+    transformers looks `_compile_jinja_template` up at render time, so replacing
+    it routes every render through the cache. The original is called unwrapped,
+    so that _compile_cache is the only cache and is bounded and cleared by us.
+    """
+    global _compile_cache_installed
+    if _compile_cache_installed:
+        return
+
+    from transformers.utils import chat_template_utils
+
+    compile_template = getattr(chat_template_utils, "_compile_jinja_template", None)
+    if compile_template is None:
+        return  # Nothing to cache with this transformers version
+    compile_template = getattr(compile_template, "__wrapped__", compile_template)
+
+    def cached_compile_template(chat_template):
+        key = hashlib.sha256(chat_template.encode("utf-8")).hexdigest()
+        whitespace = getattr(_compile_cache_local, "whitespace", None)
+        if whitespace is not None:
+            key += ":trim_blocks=%d:lstrip_blocks=%d" % whitespace
+        lock = _get_cache_lock()
+        with lock:
+            compiled = _compile_cache.get(key)
+            if compiled is not None:
+                _compile_cache.move_to_end(key)
+        _compile_cache_local.hit = compiled is not None
+        if compiled is not None:
+            return compiled
+
+        compiled = compile_template(chat_template)
+        with lock:
+            _compile_cache[key] = compiled
+            while len(_compile_cache) > _COMPILE_CACHE_SIZE:
+                _compile_cache.popitem(last=False)
+        return compiled
+
+    # Lets a hot reloaded instance of this module unwrap to the original
+    cached_compile_template.__wrapped__ = compile_template
+    chat_template_utils._compile_jinja_template = cached_compile_template
+    _compile_cache_installed = True
+
+
+# Custom Jinja filters, added to every rendering environment once installed
+_custom_filters = {}
+
+
+def _install_custom_filters():
+    """Add _custom_filters to the environments transformers compiles templates with.
+
+    Filters are resolved when a template is compiled, so they cannot be added to an
+    already compiled template; instead transformers' environment class is replaced by
+    a subclass adding them. The subclass also applies the whitespace control set by
+    _jinja_whitespace, which is likewise fixed at compile time.
+    """
+    from transformers.utils import chat_template_utils
+
+    environment_class = chat_template_utils.ImmutableSandboxedEnvironment
+    if getattr(environment_class, "_custom_filters", None) is _custom_filters:
+        return
+    # A hot reloaded instance of this module replaces the subclass of the old one
+    environment_class = getattr(environment_class, "_base_environment", environment_class)
+
+    class CustomFiltersEnvironment(environment_class):
+        _base_environment = environment_class
+        _custom_filters = _custom_filters
+
+        def __init__(self, *args, **kwargs):
+            super().__init__(*args, **kwargs)
+            self.filters.update(_custom_filters)
+            whitespace = getattr(_compile_cache_local, "whitespace", None)
+            if whitespace is not None:
+                self.trim_blocks, self.lstrip_blocks = whitespace
+
+    chat_template_utils.ImmutableSandboxedEnvironment = CustomFiltersEnvironment
+
+
+# The builtins filter code can use: no imports, file or attribute access, or
+# evaluation of further code. This limits accidents, it is no sandbox, so filter
+# code must still be trusted.
+_FILTER_BUILTINS = {
+    name: getattr(builtins, name)
+    for name in (
+        "abs", "all", "any", "bool", "bytes", "callable", "chr", "dict", "divmod", "enumerate",
+        "filter", "float", "format", "frozenset", "hash", "hex", "int", "isinstance", "iter", "len",
+        "list", "map", "max", "min", "next", "oct", "ord", "pow", "range", "repr", "reversed",
+        "round", "set", "slice", "sorted", "str", "sum", "tuple", "zip",
+        "Exception", "IndexError", "KeyError", "TypeError", "ValueError",
+        "DeprecationWarning", "UserWarning",
+    )
+}
+
+# The modules filter code can use by name, without importing them.
+_FILTER_MODULES = {"datetime": datetime, "json": json, "math": math, "re": re, "warnings": warnings}
+
+
+def register_jinja_filter(request_json):
+    """
+    Register a custom Jinja filter for all subsequent renders.
+    Args:
+        request_json (str): JSON string containing:
+            - name (str): The filter name used in templates.
+            - code (str): A Python expression evaluating to the filter callable. It is
+              evaluated with _FILTER_BUILTINS and _FILTER_MODULES only.
+    Returns:
+        str: An empty JSON object.
+    """
+    if not _ensure_transformers_available():
+        raise ImportError("transformers library is required for register_jinja_filter")
+
+    request = json.loads(request_json)
+    name = request.get("name")
+    code = request.get("code")
+    if not name or not name.isidentifier():
+        raise ValueError(f"invalid filter name: {name!r}")
+
+    jinja_filter = eval(code, {"__builtins__": _FILTER_BUILTINS, **_FILTER_MODULES})
+    if not callable(jinja_filter):
+        raise TypeError(f"filter {name!r} code does not evaluate to a callable")
+
+    _install_compile_cache()
+    _install_custom_filters()
+    lock = _get_cache_lock()
+    with lock:
+        _custom_filters[name] = jinja_filter
+        # Templates compiled before may have resolved a previous version of the filter.
+        _compile_cache.clear()
+    return json.dumps({})
+
+
+def ping(request_json):
+    """
+    Answer a liveness probe, proving that the interpreter runs Python code.
+    Returns:
+        str: An empty JSON object.
+    """
+    return json.dumps({})
+
+
+# Why the render stack cannot run in an isolated subinterpreter, "" if it can, None until probed
+_subinterpreter_error = None
+
+
+def _probe_subinterpreters():
+    """Return why the render stack cannot run in an isolated subinterpreter, or "" if it can. Subinterpreters
+    with their own GIL need Python 3.12, and extension modules such as tokenizers may refuse to load in them."""
+    if sys.version_info < (3, 12):
+        return "Python %d.%d lacks subinterpreters with their own GIL, added in 3.12" % sys.version_info[:2]
+    try:
+        import _interpreters as interpreters  # Python 3.13+
+    except ImportError:
+        try:
+            import _xxsubinterpreters as interpreters  # Python 3.12
+        except ImportError as e:
+            return "subinterpreters are unavailable: %s" % e
+    interpreter = interpreters.create()
+    try:
+        # Python 3.12 raises the failure, 3.13 returns it.
+        failure = interpreters.run_string(interpreter, "import jinja2, tokenizers, transformers")
+    except Exception as e:
+        failure = e
+    finally:
+        interpreters.destroy(interpreter)
+    if failure:
+        return "the render stack cannot be imported in an isolated subinterpreter: %s" % failure
+    return ""
+
+
+def get_runtime_info(request_json):
+    """
+    Describe the Python runtime, probing once whether subinterpreters could isolate renders.
+    Returns:
+        str: JSON string with the Python version and, if subinterpreters are unsupported, why.
+    """
+    global _subinterpreter_error
+    with _get_cache_lock():
+        subinterpreter_error = _subinterpreter_error
+    if subinterpreter_error is None:
+        # Importing the render stack in a subinterpreter is slow, so it is not probed under the cache lock, which
+        # renders take. Concurrent first calls may probe twice, to the same result.
+        subinterpreter_error = _probe_subinterpreters()
+        with _get_cache_lock():
+            _subinterpreter_error = subinterpreter_error
+    return json.dumps({
+        "python_version": "%d.%d.%d" % sys.version_info[:3],
+        "subinterpreter_error": subinterpreter_error,
+    })
+
+
+def _evict_templates():
+    """Evict the least recently used templates that are not pinned, until at most
+    _template_cache_size unpinned ones remain, and return the evicted cache keys. The cache lock must be held."""
+    unpinned = [key for key in _template_cache if key not in _pinned_templates]
+    evicted = unpinned[:max(len(unpinned) - _template_cache_size, 0)]
+    for key in evicted:
+        del _template_cache[key]
+        _template_cache_stats.pop(key, None)
+    return evicted
+
+
+def set_template_cache_size(request_json):
+    """
+    Bound the template cache, evicting the least recently used templates beyond the new size.
+    Args:
+        request_json (str): JSON string containing:
+            - size (int): The number of templates cached besides the pinned ones.
+    Returns:
+        str: JSON string containing the 'evicted_templates' cache keys.
+    """
+    global _template_cache_size
+    with _get_cache_lock():
+        _template_cache_size = json.loads(request_json)["size"]
+        evicted = _evict_templates()
+    return json.dumps({"evicted_templates": evicted})
+
+
+def set_template_pinned(request_json):
+    """
+    Pin a template so it is never evicted from the template cache, or unpin it. Pins apply to the cache
+    key, so a template pinned before it is fetched stays cached once fetched.
+    Args:
+        request_json (str): JSON string containing:
+            - model (str), revision (str, optional), token (str, optional), is_local_path (bool, optional):
+              The template's source, as passed to get_model_chat_template.
+            - pinned (bool): Whether to pin or unpin the template.
+    Returns:
+        str: JSON string containing the 'evicted_templates' cache keys, once unpinned.
+    """
+    request = json.loads(request_json)
+    cache_key = _cache_key(request["model"], request.get("revision"), request.get("token"),
+                           request.get("is_local_path", False))
+    evicted = []
+    with _get_cache_lock():
+        if request.get("pinned"):
+            _pinned_templates.add(cache_key)
+        else:
+            _pinned_templates.discard(cache_key)
+            evicted = _evict_templates()
+    return json.dumps({"evicted_templates": evicted})
+
+
+def dump_template_cache(request_json):
+    """
+    Describe the entries of the template cache, from the least to the most recently used. Tokens of
+    private models are part of the cache keys, but never reported.
+    Returns:
+        str: JSON string containing 'templates', each with the 'model', 'revision', 'source' ("hub",
+        "local" or "gguf"), 'size' of its templates in characters, whether it is 'pinned', the unix
+        time of its 'last_access' and its number of cache 'hits'.
+    """
+    with _get_cache_lock():
+        templates = [
+            {**_template_cache_stats[key], "pinned": key in _pinned_templates}
+            for key in _template_cache if key in _template_cache_stats
+        ]
+    return json.dumps({"templates": templates})
+
+
+def clear_caches():
+    """Clear all caches for testing purposes."""
+    lock = _get_cache_lock()
+    with lock:
+        global _template_cache
+        _template_cache.clear()
+        _pinned_templates.clear()
+        _template_cache_stats.clear()
+        _tokenizer_cache.clear()
+        _extended_tokenizer_cache.clear()
+        _generation_config_cache.clear()
+        _encoder_decoder_cache.clear()
+        _compile_cache.clear()
+    return "Caches cleared"
+
+
+def release_caches(request_json):
+    """
+    Release the memory held by the caches before the module is finalized, e.g. by an idle timeout.
+    Returns:
+        str: An empty JSON object.
+    """
+    clear_caches()
+    gc.collect()
+    return json.dumps({})
+
+
+def _template_strings(chat_template):
+    """Return the template strings of a cached chat template, either a single template or named ones."""
+    if isinstance(chat_template, str):
+        return [chat_template]
+    if isinstance(chat_template, dict):
+        return [t for t in chat_template.values() if isinstance(t, str)]
+    return []
+
+
+def render_completion(request_json):
+    """
+    Apply a tokenizer's special tokens, such as BOS, to a completion prompt, without any chat template,
+    as completion requests are tokenized.
+    Args:
+        request_json (str): JSON string containing:
+            - tokenizer (dict): The tokenizer source, as for render_jinja_template.
+            - prompt (str): The raw prompt.
+            - return_token_ids (bool, optional): Whether to return the token IDs.
+    Returns:
+        str: JSON string containing the 'text' of the prompt with the special tokens added, and its
+        'token_ids' when return_token_ids is set.
+    """
+    request = json.loads(request_json)
+    tokenizer = _get_tokenizer(request.get("tokenizer") or {})
+    prompt = request.get("prompt", "")
+
+    token_ids = tokenizer.encode(prompt, add_special_tokens=True)
+    prompt_ids = tokenizer.encode(prompt, add_special_tokens=False)
+    # The special tokens surround the prompt's own tokens; render them back around the raw prompt.
+    for start in range(len(token_ids) - len(prompt_ids) + 1):
+        if token_ids[start:start + len(prompt_ids)] == prompt_ids:
+            break
+    else:
+        raise ValueError("tokenizer special tokens do not surround the prompt tokens")
+    text = (tokenizer.decode(token_ids[:start]) + prompt +
+            tokenizer.decode(token_ids[start + len(prompt_ids):]))
+
+    response = {"text": text}
+    if request.get("return_token_ids"):
+        response["token_ids"] = token_ids
+    return json.dumps(response)
+
+
+def preload_tokenizer(request_json):
+    """
+    Load and cache a tokenizer ahead of the first renders requesting token IDs.
+    Args:
+        request_json (str): JSON string containing the tokenizer source: model (str), revision (str, optional),
+            token (str, optional) and is_local_path (bool, optional).
+    Returns:
+        str: An empty JSON object, or only a 'not_found' reason.
+    """
+    try:
+        _get_tokenizer(json.loads(request_json))
+    except OSError as e:
+        if not _is_not_found_error(e):
+            raise
+        return json.dumps({"not_found": str(e)})
+    return json.dumps({})
+
+
+def load_tokenizer(request_json):
+    """
+    Load a tokenizer and return a handle to it, which tokenizer sources pass as 'handle' to use the tokenizer
+    without looking it up. It is kept until release_tokenizer, even if the caches are cleared.
+    Args:
+        request_json (str): JSON string containing the tokenizer source, as for preload_tokenizer.
+    Returns:
+        str: JSON string containing the 'handle' (int), or only a 'not_found' reason.
+    """
+    try:
+        tokenizer = _get_tokenizer(json.loads(request_json))
+    except OSError as e:
+        if not _is_not_found_error(e):
+            raise
+        return json.dumps({"not_found": str(e)})
+
+    # Random rather than sequential, so that handles of a hot reloaded module do not collide with older ones
+    with _get_cache_lock():
+        handle = 0
+        while not handle or handle in _tokenizer_handles:
+            handle = secrets.randbits(63)
+        _tokenizer_handles[handle] = tokenizer
+    return json.dumps({"handle": handle})
+
+
+def release_tokenizer(request_json):
+    """
+    Release a handle returned by load_tokenizer. Sources passing it look the tokenizer up again.
+    Args:
+        request_json (str): JSON string containing the 'handle' (int).
+    Returns:
+        str: An empty JSON object.
+    """
+    handle = json.loads(request_json).get("handle")
+    with _get_cache_lock():
+        _tokenizer_handles.pop(handle, None)
+    return json.dumps({})
+
+
+def tokenizer_load_count(request_json):
+    """
+    Report the number of tokenizers loaded since the module was imported, for testing purposes.
+    Returns:
+        str: JSON string containing the number of 'loads'.
+    """
+    with _get_cache_lock():
+        return json.dumps({"loads": _tokenizer_loads})
+
+
+def module_instance(request_json):
+    """
+    Identify the instance of this module serving calls, for testing purposes.
+    Returns:
+        str: JSON string containing the 'instance' ID.
+    """
+    return json.dumps({"instance": _module_instance})
+
+
+def clear_model_cache(request_json):
+    """
+    Evict one model from the caches, e.g. after its template changed, leaving the other models cached.
+    Pins are kept, so a pinned template is pinned again once fetched. Compiled templates are evicted
+    unless another cached model still uses them.
+    Args:
+        request_json (str): JSON string containing:
+            - model (str): The model ID or local path.
+            - revision (str, optional): The revision, "main" if empty.
+    Returns:
+        str: JSON string containing the number of 'evicted' template cache entries and their
+        'evicted_templates' cache keys.
+    """
+    request = json.loads(request_json)
+    model_name = request.get("model")
+    if not model_name:
+        raise ValueError("model is required in request")
+    prefix = f"{model_name}:{request.get('revision') or 'main'}:"
+
+    with _get_cache_lock():
+        evicted = [key for key in _template_cache if key.startswith(prefix)]
+        evicted_templates = set()
+        for key in evicted:
+            evicted_templates.update(_template_strings(_template_cache.pop(key).get("chat_template")))
+            _template_cache_stats.pop(key, None)
+        for template in _template_cache.values():
+            evicted_templates.difference_update(_template_strings(template.get("chat_template")))
+        evicted_digests = {hashlib.sha256(template.encode("utf-8")).hexdigest() for template in evicted_templates}
+        # Templates compiled with other whitespace control are keyed by the digest and a suffix
+        for key in [key for key in _compile_cache if key.split(":", 1)[0] in evicted_digests]:
+            del _compile_cache[key]
+
+        for cache in (_tokenizer_cache, _extended_tokenizer_cache, _generation_config_cache, _encoder_decoder_cache):
+            for key in [key for key in cache if key.startswith(prefix)]:
+                del cache[key]
+    return json.dumps({"evicted": len(evicted), "evicted_templates": evicted})
+
+
+def _trim_trailing_whitespace(rendered_chats, generation_indices):
+    """
+    Strip trailing whitespace from each rendered chat, clamping the generation
+    indices so that they stay within the trimmed text.
+    """
+    trimmed_chats = [chat.rstrip() for chat in rendered_chats]
+    trimmed_indices = []
+    for chat, indices in zip(trimmed_chats, generation_indices or [[] for _ in rendered_chats]):
+        trimmed_indices.append([[start, min(end, len(chat))] for start, end in indices if start < len(chat)])
+    return trimmed_chats, trimmed_indices
+
+
+def _byte_offsets(text, offsets):
+    """Convert character offsets into text to UTF-8 byte offsets, which Go strings are indexed by."""
+    byte_positions = [0]
+    for char in text:
+        byte_positions.append(byte_positions[-1] + len(char.encode("utf-8")))
+    return [[byte_positions[start], byte_positions[end]] for start, end in offsets]
+
+
+def _decode_tool_call_arguments(conversation):
+    """Decode the JSON-encoded arguments of tool calls in place, as vLLM does.
+
+    The OpenAI API encodes tool call arguments as a JSON string, while templates
+    (e.g. Llama-3 and Mistral) iterate over them or pass them to `tojson`.
+    """
+    for message in conversation:
+        for tool_call in message.get('tool_calls') or []:
+            function = tool_call.get('function') or {}
+            arguments = function.get('arguments')
+            if isinstance(arguments, str):
+                try:
+                    function['arguments'] = json.loads(arguments)
+                except json.JSONDecodeError:
+                    pass  # Leave arguments that are not JSON as is
+
+
+def _expand_content_parts(conversation):
+    """Replace the content of messages with content parts by the parts, in place, in the format of
+    transformers' vision templates, which render image parts as their image placeholder. Tool call
+    parts carry the message's tool call they reference, validated by Go to exist."""
+    for message in conversation:
+        parts = message.pop('content_parts', None)
+        if not parts:
+            continue
+        tool_calls = {call.get("id"): call for call in message.get("tool_calls") or []}
+        content = []
+        for part in parts:
+            if part.get("type") == "image_url":
+                content.append({"type": "image", "url": part["image_url"]["url"]})
+            elif part.get("type") == "tool_call":
+                content.append({"type": "tool_call", "tool_call": tool_calls[part["tool_call_id"]]})
+            else:
+                content.append({"type": "text", "text": part.get("text", "")})
+        message['content'] = content
+
+
+def _expand_structured_content(conversation):
+    """Replace the content of tool messages with structured content by the structured value, in place,
+    as templates such as Llama-3's render non-string tool results with `tojson`."""
+    for message in conversation:
+        if 'structured_content' in message:
+            message['content'] = message.pop('structured_content')
+
+
+def _count_images(conversation):
+    """Return the number of image parts in a conversation expanded by _expand_content_parts."""
+    return sum(
+        1
+        for message in conversation if isinstance(message.get('content'), list)
+        for part in message['content'] if part.get("type") == "image"
+    )
+
+
+def _image_token_spans(tokenizer, rendered, placeholder, images, add_special_tokens):
+    """Return the [start, end) token spans of the image placeholders in a rendered chat.
+
+    Each placeholder maps to the tokens its characters were encoded into. The template
+    must render one placeholder per image part, so embeddings can be matched to images.
+    """
+    starts = []
+    start = rendered.find(placeholder)
+    while start >= 0:
+        starts.append(start)
+        start = rendered.find(placeholder, start + len(placeholder))
+    if len(starts) != images:
+        raise ValueError(f"chat template rendered {len(starts)} image placeholders {placeholder!r} "
+                         f"for {images} images")
+
+    offsets = tokenizer(rendered, add_special_tokens=add_special_tokens, return_offsets_mapping=True)["offset_mapping"]
+    spans = []
+    for start in starts:
+        end = start + len(placeholder)
+        # Special tokens added by the tokenizer have empty offsets, and never overlap.
+        tokens = [i for i, (token_start, token_end) in enumerate(offsets)
+                  if token_start < end and token_end > start]
+        spans.append([tokens[0], tokens[-1] + 1])
+    return spans
+
+
+def _render_prefix(render, request, conversation, end):
+    """Render the first `end` messages of a conversation, without any generation prompt."""
+    partial_chats, _ = render(**{
+        **request,
+        'conversations': [conversation[:end]],
+        'add_generation_prompt': False,
+        'continue_final_message': False,
+        'return_assistant_tokens_mask': False,
+    })
+    return partial_chats[0]
+
+
+def _is_encoder_decoder(source):
+    """Whether the model of a tokenizer source is an encoder-decoder (seq2seq) model, e.g. T5.
+
+    Read from `is_encoder_decoder` in the model's config.json, and cached like templates.
+    """
+    model_name = source.get("model")
+    if not model_name:
+        raise ValueError("tokenizer.model is required when return_encoder_decoder_inputs is set")
+
+    revision = source.get("revision")
+    cache_key = _cache_key(model_name, revision, source.get("token"), source.get("is_local_path", False))
+    lock = _get_cache_lock()
+    with lock:
+        if cache_key in _encoder_decoder_cache:
+            return _encoder_decoder_cache[cache_key]
+
+    config_path = _model_file(model_name, revision, "config.json")
+    is_encoder_decoder = False
+    if config_path is not None:
+        with open(config_path, encoding="utf-8") as f:
+            is_encoder_decoder = bool(json.load(f).get("is_encoder_decoder", False))
+
+    with lock:
+        _encoder_decoder_cache[cache_key] = is_encoder_decoder
+    return is_encoder_decoder
+
+
+def _encoder_decoder_inputs(render, request, conversation, rendered):
+    """Split a render into the input of an encoder-decoder model's encoder and decoder.
+
+    The encoder reads the conversation's context: the messages before the reply being
+    generated, without a generation prompt. The decoder starts from the rest of the render,
+    the generation prompt or the continued final message.
+    """
+    end = len(conversation)
+    if request.get('continue_final_message', False):
+        end -= 1
+    encoder_input = _render_prefix(render, request, conversation, end)
+    if not rendered.startswith(encoder_input):
+        raise ValueError("chat template is not prefix-stable, cannot split the conversation's context from the reply")
+    return encoder_input, rendered[len(encoder_input):]
+
+
+def _system_prompt_token_span(render, request, conversation, token_ids, tokenizer, add_special_tokens):
+    """Return the [start, end) token span of the leading system messages of a conversation.
+
+    The span is the longest prefix of token_ids shared with the tokens of the system
+    messages rendered alone, so that a token merged across the boundary is excluded.
+    Returns [0, 0] if the conversation does not start with a system message.
+    """
+    system_messages = 0
+    while system_messages < len(conversation) and conversation[system_messages].get('role') == 'system':
+        system_messages += 1
+    if system_messages == 0:
+        return [0, 0]
+
+    system_ids = tokenizer.encode(_render_prefix(render, request, conversation, system_messages),
+                                  add_special_tokens=add_special_tokens)
+    end = 0
+    for system_id, token_id in zip(system_ids, token_ids):
+        if system_id != token_id:
+            break
+        end += 1
+    return [0, end]
+
+
+def _generation_prompt_tokens(tokenizer, rendered, without_prompt):
+    """Count the tokens of a render added by its generation prompt.
+
+    They are the tokens past the longest common prefix with the render without generation prompt, so a
+    token merged across the boundary is counted. Special tokens added by the tokenizer are not.
+    """
+    token_ids = tokenizer.encode(rendered, add_special_tokens=False)
+    common = 0
+    for token_id, without_id in zip(token_ids, tokenizer.encode(without_prompt, add_special_tokens=False)):
+        if token_id != without_id:
+            break
+        common += 1
+    return len(token_ids) - common
+
+
+def _truncate_tokens(response, max_tokens, side):
+    """Truncate the tokens of a response to max_tokens, removing the excess from side, "left" or "right"."""
+    excess = len(response["token_ids"]) - max_tokens
+    if excess <= 0:
+        return
+
+    kept = slice(excess, None) if side == "left" else slice(None, max_tokens)
+    response["token_ids"] = response["token_ids"][kept]
+    if "offset_mapping" in response:
+        response["offset_mapping"] = response["offset_mapping"][kept]
+    response["truncated_tokens"] = excess
+
+    generation_prompt_tokens = response.get("generation_prompt_tokens")
+    if generation_prompt_tokens:
+        # The generation prompt ends the tokens, so right truncation removes it first.
+        if side == "left":
+            response["generation_prompt_tokens"] = min(generation_prompt_tokens, max_tokens)
+        else:
+            response["generation_prompt_tokens"] = max(generation_prompt_tokens - excess, 0)
+
+    if "system_prompt_token_span" in response:
+        start, end = response["system_prompt_token_span"]
+        if side == "left":
+            start, end = max(start - excess, 0), max(end - excess, 0)
+        else:
+            start, end = min(start, max_tokens), min(end, max_tokens)
+        response["system_prompt_token_span"] = [start, end] if start < end else [0, 0]
+
+    if "image_token_spans" in response:
+        # Images cut by the truncation are partially kept, those removed are dropped.
+        if side == "left":
+            spans = [[max(start - excess, 0), end - excess] for start, end in response["image_token_spans"]]
+        else:
+            spans = [[start, min(end, max_tokens)] for start, end in response["image_token_spans"]]
+        response["image_token_spans"] = [[start, end] for start, end in spans if start < end]
+
+    if "role_boundaries" in response:
+        # Messages cut by the truncation are partially kept, those removed are dropped.
+        boundaries = []
+        for boundary in response["role_boundaries"]:
+            if side == "left":
+                start, end = max(boundary["start_token"] - excess, 0), boundary["end_token"] - excess
+            else:
+                start, end = boundary["start_token"], min(boundary["end_token"], max_tokens)
+            if start < end:
+                boundaries.append({**boundary, "start_token": start, "end_token": end})
+        response["role_boundaries"] = boundaries
+
+
+def _check_generation_prompt_support(chat_template):
+    """Raise a ValueError unless the chat template renders a generation prompt, i.e. uses `add_generation_prompt`."""
+    from jinja2 import meta
+
+    if "add_generation_prompt" not in meta.find_undeclared_variables(_parse_template(chat_template or "")):
+        raise ValueError("generation_prompt_override requires a template supporting add_generation_prompt")
+
+
+def _generation_prompt_applied(render, request, rendered_chats, variants):
+    """Return whether add_generation_prompt changed the rendered chats of the request, which sets it.
+
+    Templates not mentioning add_generation_prompt are skipped without rendering; otherwise the variants
+    already rendered are reused, or the chats are rendered again without the generation prompt.
+    """
+    if "add_generation_prompt" not in (request.get('chat_template') or ''):
+        return False
+    if variants is not None:
+        return variants["with_generation_prompt"] != variants["without_generation_prompt"]
+    without_prompt, _ = render(**{**request, 'add_generation_prompt': False})
+    return without_prompt != rendered_chats
+
+
+def _assistant_stop_strings(render, request):
+    """Return the strings ending an assistant turn of the template, e.g. `<|eot_id|>` for Llama-3.
+
+    A conversation ending with an assistant message is rendered, and the text the template emits after
+    its content, stripped of whitespace, is the first stop string. The `eos_token` follows, if different,
+    as models may end their turn with it too.
+    """
+    rendered, _ = render(**{
+        **request,
+        'conversations': [[{"role": "user", "content": "Hello"},
+                           {"role": "assistant", "content": _ASSISTANT_STOP_SENTINEL}]],
+        'add_generation_prompt': False,
+        'continue_final_message': False,
+        'return_assistant_tokens_mask': False,
+    })
+    index = rendered[0].rfind(_ASSISTANT_STOP_SENTINEL)
+    stop_strings = []
+    if index >= 0:
+        stop = rendered[0][index + len(_ASSISTANT_STOP_SENTINEL):].strip()
+        if stop:
+            stop_strings.append(stop)
+    eos_token = request.get('eos_token')
+    if eos_token and eos_token not in stop_strings:
+        stop_strings.append(eos_token)
+    return stop_strings
+
+
+def _append_eos(rendered_chats, request):
+    """Append the EOS token to the renders of conversations ending with an assistant message.
+
+    Inference templates usually leave the final assistant turn open, training data needs it closed.
+    Renders already ending with the EOS token are left as is.
+    """
+    eos_token = request.get('eos_token')
+    if not eos_token:
+        raise ValueError("append_eos requires the eos_token template variable")
+
+    appended = []
+    for conversation, rendered in zip(request['conversations'], rendered_chats):
+        if conversation and conversation[-1].get('role') == 'assistant' and not rendered.endswith(eos_token):
+            rendered += eos_token
+        appended.append(rendered)
+    return appended
+
+
+def _turn_segments(render, request, conversation, rendered):
+    """Split a rendered conversation into the text contributed by each of its messages.
+
+    Generation indices only cover assistant turns of templates with generation tags, so
+    instead each message ends where the render of the conversation up to that message
+    ends. Text before the first message (e.g. a BOS token) belongs to the first segment
+    and text after the last one (e.g. the generation prompt) to the last segment, so the
+    segments always join back into the render.
+    """
+    if not conversation:
+        return []
+
+    boundaries = [0]
+    for end in range(1, len(conversation)):
+        partial = _render_prefix(render, request, conversation, end)
+        boundary = len(partial)
+        if not rendered.startswith(partial) or boundary < boundaries[-1]:
+            raise ValueError(f"chat template is not prefix-stable, cannot split the conversation at message {end}")
+        boundaries.append(boundary)
+    boundaries.append(len(rendered))
+
+    return [rendered[start:end] for start, end in zip(boundaries, boundaries[1:])]
+
+
+def _role_boundaries(render, request, conversation, rendered, token_ids, tokenizer, add_special_tokens):
+    """Return the role and [start, end) token range of each message of a rendered conversation.
+
+    A message's tokens end where the tokens of the conversation rendered up to it stop matching
+    the tokens of the whole render, so a token merged across a boundary belongs to the next
+    message. As for turn segments, tokens before the first message belong to it and the
+    generation prompt to the last message.
+    """
+    if not conversation:
+        return []
+
+    boundaries = [0]
+    for end in range(1, len(conversation)):
+        partial = _render_prefix(render, request, conversation, end)
+        if not rendered.startswith(partial):
+            raise ValueError(f"chat template is not prefix-stable, cannot split the conversation at message {end}")
+        common = 0
+        for partial_id, token_id in zip(tokenizer.encode(partial, add_special_tokens=add_special_tokens), token_ids):
+            if partial_id != token_id:
+                break
+            common += 1
+        boundaries.append(max(common, boundaries[-1]))
+    boundaries.append(len(token_ids))
+
+    return [
+        {"role": message.get('role'), "start_token": start, "end_token": end}
+        for message, start, end in zip(conversation, boundaries, boundaries[1:])
+    ]
+
+
+def _per_message_tokens(tokenizer, conversation, role_boundaries):
+    """Return the number of tokens attributable to the content of each message of a conversation.
+
+    A message's content is tokenized on its own, text parts joined and structured content as JSON, and
+    its count capped to the tokens of its role boundary, so the rest of the boundary, such as role
+    headers and separators, is template overhead.
+    """
+    counts = []
+    for message, boundary in zip(conversation, role_boundaries):
+        content = message.get('content')
+        if isinstance(content, list):
+            content = "".join(part.get("text", "") for part in content if part.get("type") == "text")
+        elif content is not None and not isinstance(content, str):
+            content = json.dumps(content)
+        tokens = len(tokenizer.encode(content, add_special_tokens=False)) if content else 0
+        counts.append(min(tokens, boundary["end_token"] - boundary["start_token"]))
+    return counts
+
+
+def _turn_token_counts(tokenizer, segments, add_special_tokens):
+    """Return the number of tokens each turn segment tokenizes to on its own.
+
+    Tokens the tokenizer adds count towards the first segment. The counts only add up to the
+    tokens of the whole render when the template's turn boundaries are token boundaries too.
+    """
+    counts = [len(tokenizer.encode(segment, add_special_tokens=False)) for segment in segments]
+    if counts and add_special_tokens:
+        counts[0] += len(tokenizer.encode("", add_special_tokens=True))
+    return counts
+
+
+# huggingface_hub errors of models, revisions and files that do not exist, by name, since
+# huggingface_hub is only imported through transformers.
+_NOT_FOUND_ERRORS = {"RepositoryNotFoundError", "RevisionNotFoundError", "EntryNotFoundError"}
+
+# HTTP status of Hub responses throttling the caller, which may succeed when retried later.
+_RATE_LIMITED_STATUS = 429
+
+# Text rendered for each image part by the common vision templates, e.g. Llava's
+_DEFAULT_IMAGE_PLACEHOLDER = "<image>"
+
+# Content of the assistant message rendered to find the text a template closes assistant turns with.
+_ASSISTANT_STOP_SENTINEL = "assistant-stop-sentinel-7f3a"
+
+# Templates emitting this token render the Harmony format of gpt-oss models.
+_HARMONY_CHANNEL_TOKEN = "<|channel|>"
+
 
-def _get_cache_lock():
-    """Get or create a threading lock for cache access."""
-    global _cache_lock
-    if _cache_lock is None:
-        import threading
-        _cache_lock = threading.Lock()
-    return _cache_lock
+def _fixed_strftime_now(fixed_date_time):
+    """Return a `strftime_now` template function formatting the RFC 3339 time fixed_date_time."""
+    # datetime.fromisoformat only accepts the "Z" suffix from Python 3.11
+    now = datetime.fromisoformat(fixed_date_time.replace("Z", "+00:00"))
 
+    def strftime_now(format):
+        return now.strftime(format)
 
-def _collect_template_vars(tokenizer):
-    """Collect extra rendering variables from a tokenizer."""
-    kwargs = {}
-    for k in ["bos_token", "eos_token", "eot_token", "pad_token", "unk_token", "sep_token", "additional_special_tokens"]:
-        v = getattr(tokenizer, k, None)
-        if v is not None:
-            kwargs[k] = v
-    return kwargs
+    return strftime_now
 
 
-def clear_caches():
-    """Clear all caches for testing purposes."""
-    lock = _get_cache_lock()
-    with lock:
-        global _template_cache
-        _template_cache.clear()
-    return "Caches cleared"
+def _render(request):
+    """
+    Render a chat template from a decoded request, see render_jinja_template.
+    Shared by the JSON and msgpack entry points.
 
+    Python warnings raised while rendering, e.g. by deprecated constructs, are
+    returned under 'warnings' rather than printed or swallowed.
 
-def render_jinja_template(request_json):
+    With a 'traceparent', the render is logged with it, and the time spent in the
+    interpreter is returned under 'interpreter_duration_ns' along with it, so
+    Python-side timing can be correlated with the caller's trace.
     """
-    Render a chat template using the transformers library.
-    This function is aligned with the Go cgo_functions.go structs.
+    start = time.perf_counter_ns()
+    # The traceparent identifies the caller's span, it is not a template variable.
+    traceparent = request.pop("traceparent", None)
+    with warnings.catch_warnings(record=True) as caught, _jinja_whitespace(request.pop("jinja_whitespace", None)):
+        warnings.simplefilter("always")
+        try:
+            response = _render_request(request)
+        except Exception as e:
+            message = _template_raised_message(e)
+            if message is None:
+                raise
+            response = {"rendered_chats": [], "generation_indices": [], "template_raised": message}
 
-    Args:
-        request_json (str): JSON string containing the request parameters:
-            - conversations (list): List of conversation lists
-            - chat_template (str, optional): The template to use
-            - tools (list, optional): Tool schemas
-            - documents (list, optional): Document schemas
-            - return_assistant_tokens_mask (bool, optional): Whether to return assistant tokens mask
-            - continue_final_message (bool, optional): Whether to continue final message
-            - add_generation_prompt (bool, optional): Whether to add generation prompt
-            - kwargs (dict, optional): Additional rendering variables
-    Returns:
-        str: JSON string containing 'rendered_chats' and 'generation_indices' keys.
+    # Renders such as turn segments render the template several times, report each warning once.
+    reported = list(dict.fromkeys(f"{w.category.__name__}: {w.message}" for w in caught))
+    if reported:
+        response["warnings"] = reported
+
+    if traceparent:
+        duration = time.perf_counter_ns() - start
+        logger.debug("Rendered chat template in %d ns, traceparent %s", duration, traceparent)
+        response["traceparent"] = traceparent
+        response["interpreter_duration_ns"] = duration
+    return response
+
+
+@contextlib.contextmanager
+def _jinja_whitespace(whitespace):
     """
+    Compile the templates rendered in this context with the trim_blocks and lstrip_blocks of
+    whitespace, a dict, rather than transformers' (both enabled). None keeps transformers'.
+    """
+    if whitespace is None:
+        yield
+        return
+    _install_compile_cache()
+    _install_custom_filters()
+    _compile_cache_local.whitespace = (bool(whitespace.get("trim_blocks")), bool(whitespace.get("lstrip_blocks")))
+    try:
+        yield
+    finally:
+        _compile_cache_local.whitespace = None
+
+
+def _template_raised_message(error):
+    """Return the message of an error raised by a template calling raise_exception, or None for other errors."""
+    try:
+        from jinja2.exceptions import TemplateError
+    except ImportError:
+        return None
+    # transformers' raise_exception raises a bare TemplateError, while Jinja's own errors are subclasses of it.
+    if type(error) is not TemplateError:
+        return None
+    return str(error) or "raise_exception called without a message"
+
+
+def _render_request(request):
+    """Render a chat template from a decoded request, see _render."""
     if not _ensure_transformers_available():
         raise ImportError("transformers library is required for render_jinja_template")
 
     # Import the modules we need
     from transformers.utils.chat_template_utils import render_jinja_template as transformers_render_jinja_template
 
-    # Parse the JSON request
-    request = json.loads(request_json)
-
     # Align Go's `messages` field with transformers' `conversations` parameter.
     if 'messages' in request:
         request['conversations'] = [request.pop('messages')] # wrap to match expected format
 
+    tool_args_format = request.pop('tool_args_format', None) or "object"
+    for conversation in request.get('conversations') or []:
+        if tool_args_format == "object":
+            _decode_tool_call_arguments(conversation)
+        _expand_content_parts(conversation)
+        _expand_structured_content(conversation)
+
+    # Pop the fields that are not parameters of transformers' render_jinja_template,
+    # otherwise they would leak into the template context.
+    return_token_ids = request.pop('return_token_ids', False)
+    return_offset_mapping = request.pop('return_offset_mapping', False)
+    return_system_prompt_token_span = request.pop('return_system_prompt_token_span', False)
+    return_generation_prompt_tokens = request.pop('return_generation_prompt_tokens', False)
+    return_generation_prompt_applied = request.pop('return_generation_prompt_applied', False)
+    tokenizer_source = request.pop('tokenizer', None) or {}
+    render_variants = request.pop('render_variants', False)
+    trim_trailing_whitespace = request.pop('trim_trailing_whitespace', False)
+    return_turn_segments = request.pop('return_per_turn_segments', False)
+    return_role_boundaries = request.pop('return_role_boundaries', False)
+    return_per_message_tokens = request.pop('return_per_message_tokens', False)
+    fixed_date_time = request.pop('fixed_date_time', None)
+    add_special_tokens = request.pop('add_special_tokens', False)
+    append_eos = request.pop('append_eos', False)
+    max_prompt_tokens = request.pop('max_prompt_tokens', 0)
+    truncation_side = request.pop('truncation_side', None)
+    return_assistant_stop_strings = request.pop('return_assistant_stop_strings', False)
+    return_encoder_decoder_inputs = request.pop('return_encoder_decoder_inputs', False)
+    image_placeholder = request.pop('image_placeholder', None) or _DEFAULT_IMAGE_PLACEHOLDER
+    additional_special_tokens = request.pop('additional_special_tokens', None)
+    # Go also sets them in chat_template_kwargs for the template, so they only configure the tokenizer here.
+    special_token_overrides = request.pop('special_token_overrides', None)
+    generation_prompt_override = request.pop('generation_prompt_override', None)
+    if generation_prompt_override:
+        _check_generation_prompt_support(request.get('chat_template'))
+        # The template renders without its own generation prompt, the override takes its place.
+        request['add_generation_prompt'] = False
+
     try:
         # Get template_vars and spread them as individual arguments
         template_vars = request.pop('chat_template_kwargs', {})
         request.update(template_vars)
+        # Typed template vars are validated by Go not to collide with the above
+        request.update(request.pop('template_vars', None) or {})
+        if fixed_date_time:
+            # Render parameters take precedence over the environment's globals
+            request['strftime_now'] = _fixed_strftime_now(fixed_date_time)
 
+        _install_compile_cache()
+        _compile_cache_local.hit = None
         rendered_chats, generation_indices = transformers_render_jinja_template(**request)
+        compile_cache_hit = _compile_cache_local.hit
+
+        variants = None
+        if generation_prompt_override:
+            if render_variants:
+                variants = {
+                    "with_generation_prompt": rendered_chats[0] + generation_prompt_override,
+                    "without_generation_prompt": rendered_chats[0],
+                }
+            rendered_chats = [chat + generation_prompt_override for chat in rendered_chats]
+        elif render_variants:
+            # Render the other variant here, so callers get both in a single CGO call.
+            add_generation_prompt = bool(request.get('add_generation_prompt', False))
+            other_chats, _ = transformers_render_jinja_template(
+                **{**request, 'add_generation_prompt': not add_generation_prompt})
+            with_prompt, without_prompt = rendered_chats[0], other_chats[0]
+            if not add_generation_prompt:
+                with_prompt, without_prompt = without_prompt, with_prompt
+            variants = {
+                "with_generation_prompt": with_prompt,
+                "without_generation_prompt": without_prompt,
+            }
+
+        if not return_generation_prompt_applied:
+            generation_prompt_applied = False
+        elif generation_prompt_override:
+            generation_prompt_applied = True
+        elif request.get('add_generation_prompt', False):
+            generation_prompt_applied = _generation_prompt_applied(transformers_render_jinja_template, request,
+                                                                   rendered_chats, variants)
+        else:
+            generation_prompt_applied = False
 
     except Exception as e:
         raise
 
-    # Return as JSON string, aligning with the Go response struct.
-    result = json.dumps({
+    if trim_trailing_whitespace:
+        # Whitespace ending a generation prompt (e.g. "<|assistant|>\n") is part of it, so only
+        # renders without one are trimmed.
+        if not request.get('add_generation_prompt', False) and not generation_prompt_override:
+            rendered_chats, generation_indices = _trim_trailing_whitespace(rendered_chats, generation_indices)
+        if variants is not None:
+            variants["without_generation_prompt"] = variants["without_generation_prompt"].rstrip()
+
+    if append_eos and not request.get('continue_final_message', False):
+        rendered_chats = _append_eos(rendered_chats, request)
+
+    response = {
         "rendered_chats": rendered_chats,
         "generation_indices": generation_indices
+    }
+    if variants is not None:
+        response["variants"] = variants
+    if compile_cache_hit:
+        response["compile_cache_hit"] = True
+    if generation_prompt_applied:
+        response["generation_prompt_applied"] = True
+    if _HARMONY_CHANNEL_TOKEN in (request.get('chat_template') or ''):
+        response["harmony"] = True
+
+    if return_assistant_stop_strings:
+        response["assistant_stop_strings"] = _assistant_stop_strings(transformers_render_jinja_template, request)
+
+    if return_encoder_decoder_inputs and _is_encoder_decoder(tokenizer_source):
+        response["encoder_input"], response["decoder_input"] = _encoder_decoder_inputs(
+            transformers_render_jinja_template, request, request['conversations'][0], rendered_chats[0])
+
+    if return_turn_segments:
+        response["turn_segments"] = [
+            _turn_segments(transformers_render_jinja_template, request, conversation, rendered)
+            for conversation, rendered in zip(request['conversations'], rendered_chats)
+        ]
+
+    if return_token_ids or return_offset_mapping:
+        # Chat templates usually emit their special tokens, so the tokenizer only adds them again on request.
+        tokenizer = _get_tokenizer(tokenizer_source, additional_special_tokens, special_token_overrides)
+        if return_offset_mapping:
+            encoding = tokenizer(rendered_chats[0], add_special_tokens=add_special_tokens, return_offsets_mapping=True)
+            response["token_ids"] = list(encoding["input_ids"])
+            response["offset_mapping"] = _byte_offsets(rendered_chats[0], encoding["offset_mapping"])
+        else:
+            response["token_ids"] = tokenizer.encode(rendered_chats[0], add_special_tokens=add_special_tokens)
+        if return_system_prompt_token_span:
+            response["system_prompt_token_span"] = _system_prompt_token_span(
+                transformers_render_jinja_template, request, request['conversations'][0], response["token_ids"],
+                tokenizer, add_special_tokens)
+        images = _count_images(request['conversations'][0])
+        if images:
+            response["image_token_spans"] = _image_token_spans(tokenizer, rendered_chats[0], image_placeholder, images,
+                                                               add_special_tokens)
+        if return_generation_prompt_tokens and (request.get('add_generation_prompt', False)
+                                                or generation_prompt_override):
+            if generation_prompt_override:
+                without_prompt = rendered_chats[0][:len(rendered_chats[0]) - len(generation_prompt_override)]
+            else:
+                conversation = request['conversations'][0]
+                without_prompt = _render_prefix(transformers_render_jinja_template, request, conversation,
+                                                len(conversation))
+            response["generation_prompt_tokens"] = _generation_prompt_tokens(tokenizer, rendered_chats[0],
+                                                                             without_prompt)
+        if return_role_boundaries or return_per_message_tokens:
+            role_boundaries = _role_boundaries(
+                transformers_render_jinja_template, request, request['conversations'][0], rendered_chats[0],
+                response["token_ids"], tokenizer, add_special_tokens)
+            if return_role_boundaries:
+                response["role_boundaries"] = role_boundaries
+            if return_per_message_tokens:
+                per_message_tokens = _per_message_tokens(tokenizer, request['conversations'][0], role_boundaries)
+                response["per_message_tokens"] = per_message_tokens
+                response["template_overhead_tokens"] = len(response["token_ids"]) - sum(per_message_tokens)
+        if "turn_segments" in response:
+            response["turn_token_counts"] = _turn_token_counts(tokenizer, response["turn_segments"][0],
+                                                               add_special_tokens)
+        if max_prompt_tokens > 0:
+            # Without a side requested, follow the model's convention.
+            side = truncation_side or getattr(tokenizer, "truncation_side", "right")
+            _truncate_tokens(response, max_prompt_tokens, side)
+        model_max_length = _sane_model_max_length(tokenizer)
+        overflow = len(response["token_ids"]) - model_max_length
+        if model_max_length and overflow > 0:
+            # Engines reject such prompts or silently truncate them, so callers learn about it before sending them.
+            response["exceeds_model_max_length"] = True
+            response["model_max_length_overflow"] = overflow
+            warnings.warn(f"rendered {len(response['token_ids'])} tokens, exceeding the model_max_length of "
+                          f"{model_max_length} by {overflow}")
+
+    return response
+
+
+def render_jinja_template(request_json):
+    """
+    Render a chat template using the transformers library.
+    This function is aligned with the Go cgo_functions.go structs.
+
+    Args:
+        request_json (str): JSON string containing the request parameters:
+            - conversations (list): List of conversation lists
+            - chat_template (str, optional): The template to use
+            - tools (list, optional): Tool schemas
+            - documents (list, optional): Document schemas
+            - return_assistant_tokens_mask (bool, optional): Whether to return assistant tokens mask
+            - continue_final_message (bool, optional): Whether to continue final message
+            - add_generation_prompt (bool, optional): Whether to add generation prompt
+            - kwargs (dict, optional): Additional rendering variables
+            - return_token_ids (bool, optional): Whether to tokenize the rendered chat
+            - return_offset_mapping (bool, optional): Whether to also return the byte range of each token
+            - tokenizer (dict, optional): Tokenizer source used when return_token_ids is set, and whose model
+              config is read when return_encoder_decoder_inputs is set
+    Returns:
+        str: JSON string containing 'rendered_chats' and 'generation_indices' keys,
+        'token_ids' when return_token_ids is set, and 'offset_mapping' when return_offset_mapping is set.
+    """
+    # Parse the JSON request
+    request = json.loads(request_json)
+
+    # Return as JSON string, aligning with the Go response struct.
+    return json.dumps(_render(request))
+
+
+def render_jinja_template_text(request_json):
+    """
+    Render a chat template like render_jinja_template, returning the rendered text of the
+    conversation after the rest of the response, so large renders cross into Go without
+    being escaped into a JSON envelope.
+
+    Args:
+        request_json (str): JSON string with the same fields as render_jinja_template.
+    Returns:
+        str: The JSON response of render_jinja_template without 'rendered_chats', on a
+        single line, followed by a newline and the rendered conversation, empty if the
+        template raised.
+    """
+    request = json.loads(request_json)
+    response = _render(request)
+    rendered_chats = response.pop("rendered_chats", None) or [""]
+    return json.dumps(response) + "\n" + rendered_chats[0]
+
+
+def tokenize_text(request_json):
+    """
+    Tokenize a piece of text, e.g. a chunk of a long render tokenized incrementally.
+
+    Args:
+        request_json (str): JSON string containing the request parameters:
+            - tokenizer (dict): Tokenizer source, as in render_jinja_template
+            - text (str): The text to tokenize, without adding special tokens
+            - additional_special_tokens (list, optional): Special tokens registered with the tokenizer first
+    Returns:
+        str: JSON string containing 'token_ids' and their UTF-8 byte ranges in 'offset_mapping'.
+    """
+    request = json.loads(request_json)
+    tokenizer = _get_tokenizer(request.get('tokenizer') or {}, request.get('additional_special_tokens'))
+    text = request.get('text', '')
+    encoding = tokenizer(text, add_special_tokens=False, return_offsets_mapping=True)
+    return json.dumps({
+        "token_ids": list(encoding["input_ids"]),
+        "offset_mapping": _byte_offsets(text, encoding["offset_mapping"]),
     })
-    return result
+
+
+def render_jinja_template_batch(request_json):
+    """
+    Render a batch of chat templates in a single call. Each request may carry its
+    own chat_template: templates are compiled once per distinct template, through
+    the compile cache. A failing request does not fail the others.
+
+    Args:
+        request_json (str): JSON string containing:
+            - requests (list): Render requests, with the same fields as render_jinja_template.
+    Returns:
+        str: JSON string containing 'results', one per request and in the same order,
+        each holding either a 'response' or an 'error'.
+    """
+    request = json.loads(request_json)
+
+    results = []
+    for item in request.get("requests") or []:
+        try:
+            results.append({"response": _render(item)})
+        except Exception as e:
+            results.append({"error": f"{type(e).__name__}: {e}"})
+    return json.dumps({"results": results})
+
+
+def render_jinja_template_msgpack(request_msgpack):
+    """
+    Render a chat template like render_jinja_template, exchanging msgpack-encoded
+    bytes instead of JSON strings to reduce the encoding overhead of large requests.
+
+    Args:
+        request_msgpack (bytes): msgpack-encoded request, with the same fields as render_jinja_template.
+    Returns:
+        bytes: msgpack-encoded response, with the same fields as render_jinja_template.
+    """
+    import msgpack
+
+    request = msgpack.unpackb(request_msgpack, raw=False)
+    return msgpack.packb(_render(request), use_bin_type=True)
+
+
+# Larger model_max_length values are sentinels for "no limit", such as transformers' VERY_LARGE_INTEGER (1e30)
+_MAX_SANE_MODEL_MAX_LENGTH = 1 << 30
+
+
+def _sane_model_max_length(tokenizer):
+    """Return the model_max_length of a tokenizer, or 0 when it has none or a sentinel value."""
+    value = getattr(tokenizer, "model_max_length", None)
+    if isinstance(value, float) and value.is_integer():
+        value = int(value)
+    if not isinstance(value, int) or isinstance(value, bool) or not 0 < value <= _MAX_SANE_MODEL_MAX_LENGTH:
+        return 0
+    return value
+
+
+def get_model_max_length(request_json):
+    """
+    Return the model_max_length of a model's tokenizer, cached along with its chat template.
+    Args:
+        request_json (str): JSON string with the same fields as get_model_chat_template.
+    Returns:
+        str: JSON string containing 'model_max_length', 0 when the tokenizer sets none or a sentinel value,
+             and the 'cache_key' of the model, or a 'not_found' or only a 'rate_limited' reason as
+             get_model_chat_template. Both list the 'evicted_templates' as get_model_chat_template.
+    """
+    request = json.loads(request_json)
+    # Fetching the template caches the tokenizer's result, even for models without a chat template.
+    response = json.loads(get_model_chat_template(request_json))
+    if "rate_limited" in response:
+        return json.dumps(response)
+
+    cache_key = _cache_key(request.get("model"), request.get("revision"), request.get("token"),
+                           request.get("is_local_path", False))
+    with _get_cache_lock():
+        cached = _template_cache.get(cache_key)
+    if cached is None:
+        return json.dumps({"not_found": response.get("not_found") or "model is not cached",
+                           "evicted_templates": response.get("evicted_templates", [])})
+    return json.dumps({"model_max_length": cached.get("model_max_length", 0), "cache_key": cache_key,
+                       "evicted_templates": response.get("evicted_templates", [])})
 
 
 def get_model_chat_template(request_json):
@@ -132,14 +1641,22 @@ def get_model_chat_template(request_json):
     Load a tokenizer from Hugging Face Hub or local path and return its chat template string and required variables.
     Args:
         request_json (str): JSON string containing the request parameters:
-            - model (str): The model ID or path (HF model ID, local directory path, or path to tokenizer file).
-            - chat_template (str, optional): The template name or string to use.
+            - model (str): The model ID or path (HF model ID, local directory path, path to tokenizer file,
+              or path to a .gguf file whose metadata holds the chat template).
+            - chat_template (str, optional): The template string to use instead of the model's.
+            - template_name (str, optional): The named template of the model to use, overriding the
+              automatic selection of the `tool_use` template for requests with tools, and `default` otherwise.
             - tools (list[dict], optional): Tool schemas to pass.
             - revision (str, optional): Model revision.
             - token (str, optional): Hugging Face token for private models.
             - is_local_path (bool, optional): Whether the model is a local path (default: False).
+            - proxy_url (str, optional): Proxy used for the Hugging Face requests of this call only.
     Returns:
-        str: JSON string containing 'template' and 'kwargs' keys, aligning with the Go response struct.
+        str: JSON string containing 'chat_template', 'chat_template_kwargs', 'eos_token_ids', 'stop_tokens' and
+             'model_max_length' keys, aligning with the Go response struct, and the 'cache_key' of the template,
+             or a 'not_found' reason when the model or its chat template does not exist, or only a 'rate_limited'
+             reason when the Hub throttled the request. Templates evicted to cache this one are listed in
+             'evicted_templates'.
     """
     if not _ensure_transformers_available():
         print("[Python] get_model_chat_template ERROR - Transformers not available")
@@ -150,63 +1667,365 @@ def get_model_chat_template(request_json):
 
     model_name = request.get("model")
     chat_template = request.get("chat_template")
+    template_name = request.get("template_name")
     tools = request.get("tools")
     revision = request.get("revision")
     token = request.get("token")
     is_local_path = request.get("is_local_path", False)
+    proxy_url = request.get("proxy_url")
 
     if not model_name:
         print("[Python] get_model_chat_template ERROR - model_name is required")
         raise ValueError("model_name is required in request")
 
     # Create cache key
-    cache_key = f"{model_name}:{revision or 'main'}:{token or 'none'}:{is_local_path}"
+    cache_key = _cache_key(model_name, revision, token, is_local_path)
 
     # Check cache first
     lock = _get_cache_lock()
     with lock:
         if cache_key in _template_cache:
-            cached_result = _template_cache[cache_key]
-            # If a specific chat_template was requested, override the cached template
-            if chat_template is not None:
-                cached_result["template"] = chat_template
-            return json.dumps(cached_result)
-
-    # Import the modules we need
-    from transformers import AutoTokenizer
-    import os
+            _template_cache.move_to_end(cache_key)
+            stats = _template_cache_stats[cache_key]
+            stats["hits"] += 1
+            stats["last_access"] = time.time()
+            return json.dumps(_chat_template_response(_template_cache[cache_key], chat_template, template_name, tools,
+                                                      cache_key))
 
-    # Determine if we're loading from local path or HuggingFace
-    if is_local_path:
-        # For local paths, model_name can be either a directory containing tokenizer files
-        # or a path to a specific tokenizer file. Ensure we extract the directory if needed.
-        if os.path.isfile(model_name):
-            # If it's a file path (tokenizer.json), get the directory
-            tokenizer_dir = os.path.dirname(model_name)
-        else:
-            # If it's already a directory, use it directly
-            tokenizer_dir = model_name
+    if is_local_path and not os.path.exists(model_name):
+        return json.dumps({"not_found": "no such local path"})
 
-        print(f"[Python] Loading tokenizer from local path: {tokenizer_dir}")
-        tokenizer = AutoTokenizer.from_pretrained(tokenizer_dir, local_files_only=True, trust_remote_code=True)
+    tokenizer = None
+    if is_local_path and _is_gguf_path(model_name):
+        # GGUF files carry the template in their metadata, no tokenizer config is needed.
+        print(f"[Python] Loading chat template from GGUF metadata: {model_name}")
+        template, template_vars, eos_token_ids = _read_gguf_chat_template(model_name)
+        stop_tokens = [template_vars["eos_token"]] if eos_token_ids else []
     else:
-        # Load from Hugging Face
-        print(f"[Python] Loading tokenizer from HuggingFace: {model_name}")
-        tokenizer = AutoTokenizer.from_pretrained(model_name, revision=revision, token=token, trust_remote_code=True)
+        try:
+            tokenizer = _load_tokenizer(model_name, revision, token, is_local_path, proxy_url)
+        except OSError as e:
+            if _is_rate_limited_error(e):
+                return json.dumps({"rate_limited": str(e)})
+            if not _is_not_found_error(e):
+                raise
+            return json.dumps({"not_found": str(e)})
 
-    template = tokenizer.chat_template if chat_template is None else chat_template
+        template = tokenizer.chat_template
 
-    # Collect special tokens
-    template_vars = _collect_template_vars(tokenizer)
+        # Collect special tokens
+        template_vars = _collect_template_vars(tokenizer)
+        eos_token_ids, stop_tokens = _load_eos_token_ids(tokenizer, model_name, revision, token, is_local_path,
+                                                         proxy_url)
 
-    # Cache the result, aligning with the Go response struct.
-    result = {"chat_template": template, "chat_template_kwargs": template_vars}
+    # Cache the model's own templates, which may be named, and select one per request.
+    result = {
+        "chat_template": template,
+        "chat_template_kwargs": template_vars,
+        "eos_token_ids": eos_token_ids,
+        "stop_tokens": stop_tokens,
+        "model_max_length": _sane_model_max_length(tokenizer),
+    }
     with lock:
         _template_cache[cache_key] = result.copy()  # Cache a copy to avoid reference issues
+        _template_cache_stats[cache_key] = {
+            "model": model_name,
+            "revision": revision,
+            "source": "gguf" if tokenizer is None else "local" if is_local_path else "hub",
+            "size": len(json.dumps(template)) if template is not None else 0,
+            "hits": 0,
+            "last_access": time.time(),
+        }
+        evicted = _evict_templates()
+        if tokenizer is not None:
+            _tokenizer_cache.setdefault(cache_key, tokenizer)  # Reuse the loaded tokenizer for token IDs
+
+    response = _chat_template_response(result, chat_template, template_name, tools, cache_key)
+    response["evicted_templates"] = evicted
+    return json.dumps(response)
+
+
+def _is_not_found_error(error):
+    """Whether a tokenizer load failed because the model or revision does not exist on the Hub.
+
+    transformers reports them as OSError, raised from the huggingface_hub error.
+    """
+    while error is not None:
+        if type(error).__name__ in _NOT_FOUND_ERRORS:
+            return True
+        error = error.__cause__ or error.__context__
+    return False
+
+
+def _is_rate_limited_error(error):
+    """Whether a tokenizer load failed because the Hub rate limited the request."""
+    while error is not None:
+        response = getattr(error, "response", None)
+        if getattr(response, "status_code", None) == _RATE_LIMITED_STATUS:
+            return True
+        error = error.__cause__ or error.__context__
+    return False
+
+
+def _model_file(model_name, revision, filename, token=None, proxy_url=None):
+    """Return the local path of a model file, downloading it from the Hub if needed, or None if it does not exist.
+
+    The proxy is passed per call instead of through the environment, like in _load_tokenizer.
+    """
+    if os.path.isdir(model_name):
+        path = os.path.join(model_name, filename)
+        return path if os.path.isfile(path) else None
+
+    from huggingface_hub import hf_hub_download
+
+    proxies = {"http": proxy_url, "https": proxy_url} if proxy_url else None
+    try:
+        return hf_hub_download(model_name, filename, revision=revision, token=token, proxies=proxies)
+    except Exception as e:
+        if not _is_not_found_error(e):
+            raise
+        return None
+
+
+def get_raw_chat_template(request_json):
+    """
+    Return a model's chat template exactly as stored, for archiving: the bytes of its
+    `chat_template.jinja`, or else the `chat_template` of its tokenizer_config.json. Named
+    templates are not selected per request, the `default` one is returned.
+
+    Args:
+        request_json (str): JSON string containing:
+            - model (str): The model ID or local directory.
+            - revision (str, optional): Model revision.
+            - proxy_url (str, optional): Proxy used for the Hugging Face requests of this call only.
+    Returns:
+        str: JSON string containing the base64-encoded 'template', or only a 'not_found' reason.
+    """
+    request = json.loads(request_json)
+    model_name = request.get("model")
+    revision = request.get("revision")
+    proxy_url = request.get("proxy_url")
+    if not model_name:
+        raise ValueError("model_name is required in request")
+
+    template_path = _model_file(model_name, revision, "chat_template.jinja", proxy_url=proxy_url)
+    if template_path is not None:
+        with open(template_path, "rb") as f:
+            raw = f.read()
+    else:
+        config_path = _model_file(model_name, revision, "tokenizer_config.json", proxy_url=proxy_url)
+        if config_path is None:
+            return json.dumps({"not_found": "no chat_template.jinja or tokenizer_config.json"})
+        with open(config_path, encoding="utf-8") as f:
+            template = json.load(f).get("chat_template")
+        if template is None:
+            return json.dumps({"not_found": "model has no chat template"})
+        raw = _select_named_template(template, None, None).encode("utf-8")
+
+    return json.dumps({"template": base64.b64encode(raw).decode("ascii")})
+
+
+def get_generation_config(request_json):
+    """
+    Return the serving defaults of a model's generation_config.json, cached per model, revision and token like
+    templates.
+
+    Args:
+        request_json (str): JSON string containing:
+            - model (str): The model ID or local directory.
+            - revision (str, optional): Model revision.
+            - token (str, optional): Hugging Face token for private models.
+            - proxy_url (str, optional): Proxy used for the Hugging Face requests of this call only.
+    Returns:
+        str: JSON string containing 'eos_token_ids' and, when set, 'max_new_tokens', 'max_length',
+        'temperature', 'top_p', 'top_k', 'repetition_penalty' and 'stop_strings', or only a
+        'not_found' reason.
+    """
+    request = json.loads(request_json)
+    model_name = request.get("model")
+    revision = request.get("revision")
+    token = request.get("token")
+    proxy_url = request.get("proxy_url")
+    if not model_name:
+        raise ValueError("model_name is required in request")
+
+    cache_key = _cache_key(model_name, revision, token, os.path.isdir(model_name))
+    lock = _get_cache_lock()
+    with lock:
+        if cache_key in _generation_config_cache:
+            return json.dumps(_generation_config_cache[cache_key])
+
+    config_path = _model_file(model_name, revision, "generation_config.json", token, proxy_url)
+    if config_path is None:
+        return json.dumps({"not_found": "model has no generation_config.json"})
+    with open(config_path, encoding="utf-8") as f:
+        config = json.load(f)
+
+    # Both fields may hold a single value or a list.
+    eos_token_ids = config.get("eos_token_id")
+    if isinstance(eos_token_ids, int):
+        eos_token_ids = [eos_token_ids]
+    stop_strings = config.get("stop_strings")
+    if isinstance(stop_strings, str):
+        stop_strings = [stop_strings]
+
+    result = {"eos_token_ids": eos_token_ids or [], "stop_strings": stop_strings}
+    for key in ["max_new_tokens", "max_length", "temperature", "top_p", "top_k", "repetition_penalty"]:
+        result[key] = config.get(key)
+    result = {key: value for key, value in result.items() if value is not None}
 
+    with lock:
+        _generation_config_cache[cache_key] = result
     return json.dumps(result)
 
 
+def _select_named_template(templates, template_name, tools):
+    """Select the template to render from a model's templates, like transformers' get_chat_template.
+
+    Models may ship several named templates, as a mapping or, in tokenizer_config.json, as a list of
+    {"name", "template"} entries. template_name selects one explicitly; otherwise requests with tools
+    use the `tool_use` template when there is one, and the others the `default` template.
+    """
+    if isinstance(templates, list):
+        templates = {entry["name"]: entry["template"] for entry in templates}
+    if not isinstance(templates, dict):
+        # A single template is the model's default one.
+        if template_name not in (None, "", "default"):
+            raise ValueError(f"model has no chat template named {template_name!r}, it has a single template")
+        return templates
+
+    if template_name:
+        if template_name not in templates:
+            raise ValueError(f"model has no chat template named {template_name!r}, "
+                             f"available templates: {sorted(templates)}")
+        return templates[template_name]
+    if tools and "tool_use" in templates:
+        return templates["tool_use"]
+    if "default" in templates:
+        return templates["default"]
+    raise ValueError(f"model has no default chat template, set the template name to one of {sorted(templates)}")
+
+
+def _chat_template_response(result, chat_template, template_name, tools, cache_key):
+    """Build a get_model_chat_template response from a result cached under cache_key, selecting the template
+    to use."""
+    response = dict(result)
+    if chat_template is not None:
+        response["chat_template"] = chat_template
+    elif result["chat_template"] is None:
+        # Common for base models, which are not meant for chat.
+        return {"not_found": "model has no chat template"}
+    else:
+        response["chat_template"] = _select_named_template(result["chat_template"], template_name, tools)
+    response["cache_key"] = cache_key
+    return response
+
+
+def _parse_template(template):
+    """Compile a chat template with transformers' environment and return its AST.
+
+    Compiling first validates the template with the same extensions and sandbox
+    transformers renders with.
+    """
+    from transformers.utils.chat_template_utils import _compile_jinja_template
+
+    compiled = _compile_jinja_template(template)
+    return compiled.environment.parse(template)
+
+
+def compile_chat_template(request_json):
+    """
+    Compile a chat template without rendering it, to check its syntax. The compiled template is cached
+    for later renders.
+    Args:
+        request_json (str): JSON string containing the request parameters:
+            - chat_template (str): The template to compile.
+    Returns:
+        str: JSON string, empty on success or containing the 'syntax_error' message and its 'line'.
+    """
+    if not _ensure_transformers_available():
+        raise ImportError("transformers library is required for compile_chat_template")
+
+    from jinja2 import TemplateSyntaxError
+    from transformers.utils.chat_template_utils import _compile_jinja_template
+
+    request = json.loads(request_json)
+    template = request.get("chat_template")
+    if not template:
+        raise ValueError("chat_template is required in request")
+
+    try:
+        _compile_jinja_template(template)
+    except TemplateSyntaxError as e:
+        return json.dumps({"syntax_error": e.message, "line": e.lineno})
+    return json.dumps({})
+
+
+def get_template_capabilities(request_json):
+    """
+    Statically analyze a chat template and report which inputs it makes use of.
+    Args:
+        request_json (str): JSON string containing the request parameters:
+            - chat_template (str): The template to analyze.
+    Returns:
+        str: JSON string containing the boolean capabilities, aligning with the Go Capabilities struct.
+    """
+    if not _ensure_transformers_available():
+        raise ImportError("transformers library is required for get_template_capabilities")
+
+    from jinja2 import meta, nodes
+
+    request = json.loads(request_json)
+    template = request.get("chat_template")
+    if not template:
+        raise ValueError("chat_template is required in request")
+
+    ast = _parse_template(template)
+    variables = meta.find_undeclared_variables(ast)
+    constants = {node.value for node in ast.find_all(nodes.Const) if isinstance(node.value, str)}
+
+    return json.dumps({
+        "supports_tools": "tools" in variables,
+        "supports_documents": "documents" in variables,
+        "supports_system_role": "system" in constants,
+        "requires_generation_prompt": "add_generation_prompt" in variables,
+    })
+
+
+# Text shaped like a special token: <|im_start|>, <s> and </s>, or [INST] and [/INST].
+_SPECIAL_TOKEN_PATTERN = re.compile(r"<\|[^<>|\s]+\|>|</?[A-Za-z_][^<>\s]*>|\[/?[A-Z][A-Z_]*\]")
+
+
+def check_template_tokenizer_compat(request_json):
+    """
+    Find the special tokens a chat template hard-codes that the tokenizer's vocabulary lacks, e.g. in a
+    broken model upload. Such tokens are split into several tokens, so renders misbehave subtly.
+    Args:
+        request_json (str): JSON string containing the request parameters:
+            - chat_template (str): The template to check.
+            - tokenizer (dict): Tokenizer source, as in render_jinja_template.
+    Returns:
+        str: JSON string containing the 'missing_tokens', each once.
+    """
+    if not _ensure_transformers_available():
+        raise ImportError("transformers library is required for check_template_tokenizer_compat")
+
+    from jinja2 import nodes
+
+    request = json.loads(request_json)
+    template = request.get("chat_template")
+    if not template:
+        raise ValueError("chat_template is required in request")
+
+    ast = _parse_template(template)
+    texts = [node.value for node in ast.find_all(nodes.Const) if isinstance(node.value, str)]
+    texts += [node.data for node in ast.find_all(nodes.TemplateData)]
+    referenced = dict.fromkeys(token for text in texts for token in _SPECIAL_TOKEN_PATTERN.findall(text))
+
+    vocab = _get_tokenizer(request.get("tokenizer") or {}).get_vocab()
+    return json.dumps({"missing_tokens": [token for token in referenced if token not in vocab]})
+
+
 def main():
     """Example usage and testing function."""
     if not _ensure_transformers_available():
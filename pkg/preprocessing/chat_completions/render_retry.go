@@ -0,0 +1,82 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// MaxRenderAttempts bounds the attempts set with WithRenderRetry, so that
+	// retries cannot mask a call failing for good.
+	MaxRenderAttempts = 5
+	// RenderRetryBackoff is the wait before the first retry of a transient
+	// render failure, doubled before every further retry, giving the
+	// interpreter time to release memory.
+	RenderRetryBackoff = 10 * time.Millisecond
+)
+
+// WithRenderRetry makes RenderChatTemplate retry renders failing with
+// ErrTransient, such as on a MemoryError raised in the interpreter, up to
+// maxAttempts attempts in total, backing off from RenderRetryBackoff. Other
+// errors, such as template or syntax errors, are returned without retrying.
+// Values below one are treated as one, and values above MaxRenderAttempts as
+// MaxRenderAttempts.
+func WithRenderRetry(maxAttempts int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.renderAttempts = min(max(maxAttempts, 1), MaxRenderAttempts)
+	}
+}
+
+// renderChatTemplateWithRetry renders like renderChatTemplate, retrying
+// transient failures as set with WithRenderRetry. Every attempt calls into
+// Python anew, reacquiring the GIL.
+func (w *ChatTemplatingProcessor) renderChatTemplateWithRetry(ctx context.Context,
+	req *RenderJinjaTemplateRequest,
+) (*RenderJinjaTemplateResponse, error) {
+	backoff := RenderRetryBackoff
+	for attempt := 1; ; attempt++ {
+		response, err := w.renderChatTemplate(ctx, req)
+		if !errors.Is(err, ErrTransient) || attempt >= w.renderAttempts || ctx.Err() != nil {
+			return response, err
+		}
+		log.FromContext(ctx).V(logging.DEBUG).WithName("RenderChatTemplate").Info("Retrying transient render failure",
+			"attempt", attempt, "backoff", backoff, "error", err.Error())
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return response, err
+		}
+		backoff *= 2
+	}
+}
+
+// renderCallError is the error of a render call into Python that failed,
+// wrapping ErrTransient if the C side classified the failure as transient.
+func renderCallError(funcName string, transient bool) error {
+	if transient {
+		return fmt.Errorf("%w: python %s failed", ErrTransient, funcName)
+	}
+	return fmt.Errorf("python %s failed", funcName)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithRenderRetry tests that renders are retried on ErrTransient only, and no more than the attempts set.
+func TestWithRenderRetry(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	wrapper := preprocessing.NewChatTemplatingProcessor(preprocessing.WithRenderRetry(3))
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+	}
+
+	// failFor makes the next n calls into Python fail with fault, counting all calls.
+	var calls atomic.Int32
+	failFor := func(n int32, fault error) {
+		calls.Store(0)
+		preprocessing.SetFaultHook(func() {
+			if calls.Add(1) <= n {
+				panic(fault)
+			}
+		})
+	}
+	t.Cleanup(func() { preprocessing.SetFaultHook(nil) })
+
+	failFor(1, preprocessing.ErrTransient)
+	start := time.Now()
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "A render failing once with ErrTransient should succeed when retried")
+	assert.NotEmpty(t, response.RenderedChats)
+	assert.EqualValues(t, 2, calls.Load(), "The render should be attempted twice")
+	assert.GreaterOrEqual(t, time.Since(start), preprocessing.RenderRetryBackoff, "The retry should back off")
+
+	// A context done while backing off stops the retries.
+	failFor(10, preprocessing.ErrTransient)
+	canceled, cancel := context.WithTimeout(ctx, preprocessing.RenderRetryBackoff/2)
+	defer cancel()
+	_, err = wrapper.RenderChatTemplate(canceled, request)
+	require.ErrorIs(t, err, preprocessing.ErrTransient)
+	assert.EqualValues(t, 1, calls.Load(), "The render should not be retried once the context is done")
+
+	failFor(10, preprocessing.ErrTransient)
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	require.ErrorIs(t, err, preprocessing.ErrTransient, "Retries should stop after the attempts set")
+	assert.EqualValues(t, 3, calls.Load(), "The render should be attempted three times")
+
+	failFor(1, preprocessing.ErrTemplateSyntax)
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	require.ErrorIs(t, err, preprocessing.ErrTemplateSyntax, "Non-transient errors should be returned")
+	assert.EqualValues(t, 1, calls.Load(), "Non-transient errors should not be retried")
+
+	failFor(1, preprocessing.ErrTransient)
+	_, err = preprocessing.NewChatTemplatingProcessor().RenderChatTemplate(ctx, request)
+	require.ErrorIs(t, err, preprocessing.ErrTransient, "Renders should not be retried without WithRenderRetry")
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+// TestWithRenderRetryRecursionError tests that a render exhausting the interpreter's stack is not classified as
+// transient by the call into Python, since the same input recurses as deep again, and is not retried.
+func TestWithRenderRetryRecursionError(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	wrapper := preprocessing.NewChatTemplatingProcessor(preprocessing.WithRenderRetry(3))
+
+	// A tool nested deeper than Python's recursion limit fails to decode with a RecursionError.
+	var tool interface{} = map[string]interface{}{}
+	for range 9000 {
+		tool = []interface{}{tool}
+	}
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		Tools:         []interface{}{tool},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+	}
+
+	var calls atomic.Int32
+	preprocessing.SetFaultHook(func() { calls.Add(1) })
+	t.Cleanup(func() { preprocessing.SetFaultHook(nil) })
+
+	_, err := wrapper.RenderChatTemplate(ctx, request)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, preprocessing.ErrTransient, "A RecursionError should not be classified as transient")
+	assert.EqualValues(t, 1, calls.Load(), "The render should not be retried")
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// DefaultStreamWorkers is the number of batches RenderStream renders at
+	// once unless set with WithStreamWorkers.
+	DefaultStreamWorkers = 2
+	// DefaultStreamBatchSize is the largest number of requests RenderStream
+	// renders in a single call into Python unless set with WithStreamWorkers.
+	DefaultStreamBatchSize = 16
+)
+
+// StreamResult is the outcome of one request of RenderStream: either
+// Response or Err is set.
+type StreamResult struct {
+	// Index is the position of the request on the channel given to
+	// RenderStream, counting from zero.
+	Index    int
+	Response *RenderJinjaTemplateResponse
+	Err      error
+}
+
+// WithStreamWorkers sets the number of batches RenderStream renders at once,
+// and the largest number of requests of a batch. Values below one are
+// treated as one. Defaults to DefaultStreamWorkers and
+// DefaultStreamBatchSize.
+func WithStreamWorkers(workers, batchSize int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.streamWorkers = workers
+		w.streamBatchSize = batchSize
+	}
+}
+
+// indexedRequest is a request of RenderStream along with its position.
+type indexedRequest struct {
+	index int
+	req   *RenderJinjaTemplateRequest
+}
+
+// RenderStream renders the requests received on reqs until it is closed,
+// sending one result per request on out, e.g. to generate datasets of more
+// prompts than fit in memory. Results are sent as soon as their batch is
+// rendered, so they arrive out of order; StreamResult.Index correlates them.
+//
+// A pool of workers, sized with WithStreamWorkers, takes the requests
+// already received, up to the batch size, and renders them in a single call
+// into Python, see RenderChatTemplateBatch. A worker only takes new requests
+// once out accepted its results, so a slow consumer holds back the producer
+// and no more than the workers times the batch size requests are in flight.
+//
+// RenderStream returns once every request received has its result, or once
+// ctx is done; it then stops receiving, waits for the batches in flight,
+// whose results are dropped unless out accepts them right away, and returns
+// the context's error. It closes out before returning.
+func (w *ChatTemplatingProcessor) RenderStream(ctx context.Context, reqs <-chan *RenderJinjaTemplateRequest,
+	out chan<- StreamResult,
+) error {
+	defer close(out)
+
+	// Unbuffered, so that requests are only received as workers take them.
+	indexed := make(chan indexedRequest)
+	go func() {
+		defer close(indexed)
+		for index := 0; ; index++ {
+			var req *RenderJinjaTemplateRequest
+			select {
+			case r, ok := <-reqs:
+				if !ok {
+					return
+				}
+				req = r
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case indexed <- indexedRequest{index: index, req: req}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for range max(w.streamWorkers, 1) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			w.renderStreamBatches(ctx, indexed, out)
+		}()
+	}
+	workers.Wait()
+	return ctx.Err()
+}
+
+// renderStreamBatches renders batches of the requests received on indexed
+// for RenderStream, until it is closed or ctx is done.
+func (w *ChatTemplatingProcessor) renderStreamBatches(ctx context.Context, indexed <-chan indexedRequest,
+	out chan<- StreamResult,
+) {
+	batchSize := max(w.streamBatchSize, 1)
+	batch := make([]indexedRequest, 0, batchSize)
+	reqs := make([]*RenderJinjaTemplateRequest, 0, batchSize)
+	for first := range indexed {
+		// Batch the requests already waiting, without waiting for more.
+		batch = append(batch[:0], first)
+	gather:
+		for len(batch) < batchSize {
+			select {
+			case item, ok := <-indexed:
+				if !ok {
+					break gather
+				}
+				batch = append(batch, item)
+			default:
+				break gather
+			}
+		}
+
+		reqs = reqs[:0]
+		for _, item := range batch {
+			reqs = append(reqs, item.req)
+		}
+		results, err := w.RenderChatTemplateBatch(ctx, reqs)
+		for i, item := range batch {
+			result := StreamResult{Index: item.index, Err: err}
+			if err == nil {
+				result.Response, result.Err = results[i].Response, results[i].Err
+			}
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
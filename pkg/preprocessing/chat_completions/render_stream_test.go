@@ -0,0 +1,110 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamRequest returns the i-th request fed to RenderStream.
+func streamRequest(i int) *preprocessing.RenderJinjaTemplateRequest {
+	return &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: fmt.Sprintf("prompt %d", i)}},
+		ChatTemplate:  "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+	}
+}
+
+// TestRenderStream tests that RenderStream renders every request fed, many more than it holds at once.
+func TestRenderStream(t *testing.T) {
+	getGlobalWrapper()
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithStreamWorkers(2, 4))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	const count = 200
+	reqs := make(chan *preprocessing.RenderJinjaTemplateRequest)
+	go func() {
+		defer close(reqs)
+		for i := range count {
+			reqs <- streamRequest(i)
+		}
+	}()
+
+	out := make(chan preprocessing.StreamResult, 1)
+	done := make(chan error, 1)
+	go func() { done <- processor.RenderStream(context.Background(), reqs, out) }()
+
+	rendered := make(map[int]string, count)
+	for result := range out {
+		require.NoError(t, result.Err, "Every request should render")
+		assert.NotContains(t, rendered, result.Index, "Every request should have a single result")
+		rendered[result.Index] = result.Response.RenderedChats[0]
+	}
+	require.NoError(t, <-done, "RenderStream should not return an error")
+	require.Len(t, rendered, count, "Every request should have a result")
+	for i := range count {
+		assert.Equal(t, fmt.Sprintf("user: prompt %d\n", i), rendered[i])
+	}
+}
+
+// TestRenderStreamCancel tests that RenderStream stops and closes its output once its context is canceled.
+func TestRenderStreamCancel(t *testing.T) {
+	getGlobalWrapper()
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithStreamWorkers(2, 4))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The producer never closes the channel, so only the cancellation ends the stream.
+	reqs := make(chan *preprocessing.RenderJinjaTemplateRequest)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case reqs <- streamRequest(i):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	out := make(chan preprocessing.StreamResult)
+	done := make(chan error, 1)
+	go func() { done <- processor.RenderStream(ctx, reqs, out) }()
+
+	for range 10 {
+		result := <-out
+		require.NoError(t, result.Err, "Requests should render until canceled")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RenderStream should return once canceled")
+	}
+	_, open := <-out
+	assert.False(t, open, "The output should be closed")
+}
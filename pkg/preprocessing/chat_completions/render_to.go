@@ -0,0 +1,45 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// RenderChatTemplateTo renders a chat template like RenderChatTemplate and
+// writes the rendered conversation to out, straight from the C buffer Python
+// returned it in, without building a Go string. It suits offline generation
+// of many large prompts. The render is checked like by RenderChatTemplate,
+// but only the text is written: the fields of RenderJinjaTemplateResponse
+// other than RenderedChats are not available.
+func (w *ChatTemplatingProcessor) RenderChatTemplateTo(ctx context.Context, req *RenderJinjaTemplateRequest,
+	out io.Writer,
+) error {
+	result, err := w.RenderRaw(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer result.Release()
+
+	// io.Writer implementations must not retain the slice, so lending them C memory is safe.
+	if _, err := out.Write(result.Bytes()); err != nil {
+		return fmt.Errorf("failed to write rendered chat: %w", err)
+	}
+	return nil
+}
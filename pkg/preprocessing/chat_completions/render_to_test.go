@@ -0,0 +1,61 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateTo tests that rendering to a writer writes the same bytes as a normal render.
+func TestRenderChatTemplateTo(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	for _, messages := range []int{1, 256} {
+		request := largeRenderRequest(messages)
+		expected, err := wrapper.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+		var buf bytes.Buffer
+		require.NoError(t, wrapper.RenderChatTemplateTo(ctx, request, &buf), "RenderChatTemplateTo should not return an error")
+		assert.Equal(t, expected.RenderedChats[0], buf.String(), "The written render should match, for %d messages", messages)
+	}
+
+	err := wrapper.RenderChatTemplateTo(ctx, &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{{ raise_exception('broken') }}",
+	}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, preprocessing.ErrTemplateRaised, "A failing render should return an error")
+
+	// The render is checked like by RenderChatTemplate.
+	processor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithEmptyRenderPolicy(preprocessing.EmptyRenderError))
+	buf := &bytes.Buffer{}
+	err = processor.RenderChatTemplateTo(ctx, &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "system", Content: "You are a helpful assistant."}},
+		ChatTemplate: "{% for message in messages %}{% if message.role == 'user' %}" +
+			"{{ message.content }}\n{% endif %}{% endfor %}",
+	}, buf)
+	assert.ErrorIs(t, err, preprocessing.ErrEmptyRender, "The empty render should fail")
+	assert.Zero(t, buf.Len(), "Nothing should be written for a failing render")
+}
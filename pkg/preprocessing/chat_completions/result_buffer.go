@@ -0,0 +1,164 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+//nolint: gocritic // C and unsafe are considered dups by the linter.
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"slices"
+	"sync"
+	"unsafe"
+
+	/*
+		#include "cgo_functions.h"
+	*/
+	"C"
+)
+
+const (
+	// minResultBufferSize is the initial size of a reused result buffer.
+	minResultBufferSize = 64 << 10
+	// adaptiveBufferWindow is the number of recent result sizes an adaptive
+	// buffer is sized from.
+	adaptiveBufferWindow = 256
+	// adaptiveBufferInterval is the number of renders between resizes of an
+	// adaptive buffer.
+	adaptiveBufferInterval = 32
+	// adaptiveBufferPercentile is the share of recent results an adaptive
+	// buffer is sized to fit.
+	adaptiveBufferPercentile = 0.95
+)
+
+// resultBuffer is the reusable buffer JSON render results are copied into
+// with WithResultBufferReuse. It is Go memory, only lent to C for the
+// duration of a call, and grows to fit the largest result seen so far,
+// unless adaptive.
+type resultBuffer struct {
+	// mu guards the fields from the render call until its result is unmarshaled.
+	mu  sync.Mutex
+	buf []byte
+
+	// adaptive buffers are sized to fit adaptiveBufferPercentile of the
+	// recent results, recorded in sizes from next on, and sorted into
+	// sorted to resize. Larger results are unmarshaled from their own C
+	// allocation instead.
+	adaptive bool
+	sizes    []int
+	sorted   []int
+	next     int
+	renders  int
+}
+
+// WithAdaptiveBuffer makes JSON renders reuse a result buffer like
+// WithResultBufferReuse, sized to fit most of the recent results instead of
+// the largest: every few renders, it is resized to a percentile of the
+// recent result sizes, rounded up to a power of two so that it only
+// reallocates when the workload changes. Larger results are unmarshaled from
+// their own allocation, so an occasional large render does not pin memory.
+func WithAdaptiveBuffer() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.resultBuffer = &resultBuffer{adaptive: true}
+	}
+}
+
+// WithResultBufferReuse makes JSON renders copy their result into a buffer
+// reused across calls, rather than a fresh C allocation per call that is
+// copied again into Go memory. The buffer only grows when a result does not
+// fit. Renders of the processor then hold the buffer until their result is
+// unmarshaled, so they no longer overlap with each other.
+func WithResultBufferReuse() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.resultBuffer = &resultBuffer{}
+	}
+}
+
+// render calls render_jinja_template with the JSON request, unmarshaling
+// the result from the buffer into response.
+func (b *resultBuffer) render(ctx context.Context, cReqJSON *C.char, response *RenderJinjaTemplateResponse) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf == nil {
+		b.buf = make([]byte, minResultBufferSize)
+	}
+	cBuf := (*C.char)(unsafe.Pointer(&b.buf[0]))
+	var cResultLen C.size_t
+	var cResult *C.char
+	var transient bool
+	if err := cgoThread.run(ctx, func() {
+		cResult = C.Py_CallRenderJinjaTemplateInto(cReqJSON, cBuf, C.size_t(len(b.buf)), &cResultLen)
+		transient = C.Py_TakeCallTransient() != 0
+	}); err != nil {
+		return err
+	}
+	if cResult == nil {
+		return renderCallError("render_jinja_template", transient)
+	}
+
+	resultLen := int(cResultLen)
+	result := b.buf[:min(resultLen, len(b.buf))]
+	if cResult != cBuf {
+		defer C.free(unsafe.Pointer(cResult))
+		if b.adaptive {
+			result = unsafe.Slice((*byte)(unsafe.Pointer(cResult)), resultLen)
+		} else {
+			// The result did not fit: grow the buffer for the next renders.
+			b.buf = make([]byte, max(resultLen, 2*len(b.buf)))
+			copy(b.buf, unsafe.Slice((*byte)(unsafe.Pointer(cResult)), resultLen))
+			result = b.buf[:resultLen]
+		}
+	}
+
+	err := json.Unmarshal(result, response)
+	if b.adaptive {
+		b.resize(resultLen)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// resize records the size of a result of an adaptive buffer, resizing the
+// buffer every adaptiveBufferInterval renders. b.mu must be held, and the
+// buffer no longer in use.
+func (b *resultBuffer) resize(resultLen int) {
+	if len(b.sizes) < adaptiveBufferWindow {
+		b.sizes = append(b.sizes, resultLen)
+	} else {
+		b.sizes[b.next] = resultLen
+		b.next = (b.next + 1) % adaptiveBufferWindow
+	}
+	b.renders++
+	if b.renders%adaptiveBufferInterval != 0 {
+		return
+	}
+
+	b.sorted = append(b.sorted[:0], b.sizes...)
+	slices.Sort(b.sorted)
+	percentile := b.sorted[int(adaptiveBufferPercentile*float64(len(b.sorted)-1))]
+	size := minResultBufferSize
+	if percentile > size {
+		size = 1 << bits.Len(uint(percentile-1))
+	}
+	if size != len(b.buf) {
+		b.buf = make([]byte, size)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateResultBufferReuse tests that renders through a reused result buffer, including results
+// outgrowing it, match renders through fresh allocations.
+func TestRenderChatTemplateResultBufferReuse(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithResultBufferReuse())
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+
+	// The larger requests render results beyond the initial buffer size, which then grows.
+	for _, messages := range []int{1, 4, 256, 1024, 4} {
+		request := largeRenderRequest(messages)
+		expected, err := wrapper.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+		response, err := processor.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate with a reused buffer should not return an error")
+		assert.Equal(t, expected.RenderedChats, response.RenderedChats,
+			"Renders should not depend on the result buffer, for %d messages", messages)
+	}
+}
+
+// BenchmarkRenderChatTemplateResultBuffer compares renders of same-sized requests into fresh result allocations
+// with renders into a reused result buffer.
+func BenchmarkRenderChatTemplateResultBuffer(b *testing.B) {
+	getGlobalWrapper()
+
+	request := largeRenderRequest(64)
+	for name, opts := range map[string][]preprocessing.Option{
+		"fresh":  nil,
+		"reused": {preprocessing.WithResultBufferReuse()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			processor := preprocessing.NewChatTemplatingProcessor(opts...)
+			require.NoError(b, processor.Initialize())
+			b.Cleanup(processor.Finalize)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := processor.RenderChatTemplate(context.Background(), request)
+				require.NoError(b, err, "Benchmark should not return errors")
+			}
+		})
+	}
+}
+
+// TestRenderChatTemplateAdaptiveBuffer tests that an adaptive result buffer renders results of any size, and is sized
+// to the recent results rather than the largest one.
+func TestRenderChatTemplateAdaptiveBuffer(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithAdaptiveBuffer())
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+
+	// A result beyond the buffer is rendered without growing it.
+	large := largeRenderRequest(1024)
+	expected, err := wrapper.RenderChatTemplate(ctx, large)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	response, err := processor.RenderChatTemplate(ctx, large)
+	require.NoError(t, err, "RenderChatTemplate with an adaptive buffer should not return an error")
+	assert.Equal(t, expected.RenderedChats, response.RenderedChats, "Renders should not depend on the result buffer")
+	assert.Equal(t, preprocessing.MinResultBufferSize, preprocessing.ResultBufferSize(processor),
+		"A single large result should not grow the buffer")
+
+	// Once most results are large, the buffer grows to fit them.
+	for range 64 {
+		_, err := processor.RenderChatTemplate(ctx, large)
+		require.NoError(t, err, "RenderChatTemplate with an adaptive buffer should not return an error")
+	}
+	assert.GreaterOrEqual(t, preprocessing.ResultBufferSize(processor), len(response.RenderedChats[0]),
+		"The buffer should fit the recent results")
+
+	// And shrinks back once they are small again.
+	small := largeRenderRequest(4)
+	for range 256 {
+		_, err := processor.RenderChatTemplate(ctx, small)
+		require.NoError(t, err, "RenderChatTemplate with an adaptive buffer should not return an error")
+	}
+	assert.Equal(t, preprocessing.MinResultBufferSize, preprocessing.ResultBufferSize(processor),
+		"The buffer should shrink to the recent results")
+}
+
+// BenchmarkRenderChatTemplateAdaptiveBuffer renders requests of varied sizes through a result buffer growing to fit
+// the largest result and through an adaptive one, after a warmup sizing the adaptive buffer to the workload, so
+// allocations per render are stable.
+func BenchmarkRenderChatTemplateAdaptiveBuffer(b *testing.B) {
+	getGlobalWrapper()
+
+	// Mostly small renders, with an occasional large one.
+	var requests []*preprocessing.RenderJinjaTemplateRequest
+	for range 15 {
+		requests = append(requests, largeRenderRequest(4), largeRenderRequest(16), largeRenderRequest(64))
+	}
+	requests = append(requests, largeRenderRequest(1024))
+
+	for name, opt := range map[string]preprocessing.Option{
+		"grow":     preprocessing.WithResultBufferReuse(),
+		"adaptive": preprocessing.WithAdaptiveBuffer(),
+	} {
+		b.Run(name, func(b *testing.B) {
+			processor := preprocessing.NewChatTemplatingProcessor(opt)
+			require.NoError(b, processor.Initialize())
+			b.Cleanup(processor.Finalize)
+			for i := range 512 {
+				_, err := processor.RenderChatTemplate(context.Background(), requests[i%len(requests)])
+				require.NoError(b, err, "Warmup should not return errors")
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := processor.RenderChatTemplate(context.Background(), requests[i%len(requests)])
+				require.NoError(b, err, "Benchmark should not return errors")
+			}
+			b.ReportMetric(float64(preprocessing.ResultBufferSize(processor)), "buffer-bytes")
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// RoleBoundary is the range of the tokens of a rendered chat contributed by
+// one message, see RenderJinjaTemplateRequest.ReturnRoleBoundaries.
+type RoleBoundary struct {
+	Role string `json:"role"`
+	// StartToken and EndToken are the [start, end) range of the message's
+	// tokens in RenderJinjaTemplateResponse.TokenIDs.
+	StartToken int `json:"start_token"`
+	EndToken   int `json:"end_token"`
+}
+
+// validateRoleBoundaries checks that role boundaries, and the per-message
+// tokens derived from them, can be reported for req.
+func validateRoleBoundaries(req *RenderJinjaTemplateRequest) error {
+	if req.ReturnRoleBoundaries && !req.ReturnTokenIDs && !req.ReturnOffsetMapping {
+		return fmt.Errorf("return_role_boundaries requires return_token_ids")
+	}
+	if req.ReturnPerMessageTokens && !req.ReturnTokenIDs && !req.ReturnOffsetMapping {
+		return fmt.Errorf("return_per_message_tokens requires return_token_ids")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+)
+
+// InterpreterMode is how a processor's renders are isolated in Python.
+type InterpreterMode string
+
+const (
+	// InterpreterModeShared renders in the process-wide interpreter, whose
+	// caches, such as compiled templates, all processors share.
+	InterpreterModeShared InterpreterMode = "shared"
+)
+
+// RuntimeInfo describes the Python runtime a processor renders in.
+type RuntimeInfo struct {
+	// PythonVersion is the version of the embedded interpreter, e.g. "3.12.4".
+	PythonVersion string `json:"python_version"`
+	// SubinterpretersSupported is true if the interpreter has subinterpreters
+	// with their own GIL, from Python 3.12, and the render stack, including
+	// extension modules such as tokenizers, imports in an isolated one.
+	SubinterpretersSupported bool `json:"subinterpreters_supported"`
+	// SubinterpretersUnsupportedReason is why subinterpreters are not
+	// supported, empty if they are.
+	SubinterpretersUnsupportedReason string `json:"subinterpreters_unsupported_reason,omitempty"`
+	// InterpreterMode is the mode the processor renders in. The chat template
+	// module and its caches are loaded once per process, so processors always
+	// render in the shared interpreter.
+	InterpreterMode InterpreterMode `json:"interpreter_mode"`
+}
+
+// runtimeInfoResponse is the JSON result of get_runtime_info.
+type runtimeInfoResponse struct {
+	PythonVersion       string `json:"python_version"`
+	SubinterpreterError string `json:"subinterpreter_error,omitempty"`
+}
+
+// RuntimeInfo reports the Python version, whether subinterpreters could
+// isolate renders, and the interpreter mode the processor renders in. The
+// support of subinterpreters is probed once per process, by importing the
+// render stack in a throwaway subinterpreter. Extension modules built with
+// PyO3, such as tokenizers, refuse to load in isolated subinterpreters.
+func (w *ChatTemplatingProcessor) RuntimeInfo(ctx context.Context) (_ RuntimeInfo, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	defer release()
+
+	var resp runtimeInfoResponse
+	if err := callPythonFunction(ctx, "get_runtime_info", struct{}{}, &resp); err != nil {
+		return RuntimeInfo{}, err
+	}
+	return RuntimeInfo{
+		PythonVersion:                    resp.PythonVersion,
+		SubinterpretersSupported:         resp.SubinterpreterError == "",
+		SubinterpretersUnsupportedReason: resp.SubinterpreterError,
+		InterpreterMode:                  InterpreterModeShared,
+	}, nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRuntimeInfo tests that RuntimeInfo reports the embedded interpreter and explains missing subinterpreter support.
+func TestRuntimeInfo(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	info, err := wrapper.RuntimeInfo(ctx)
+	require.NoError(t, err, "RuntimeInfo should not return an error")
+	assert.Regexp(t, `^3\.\d+\.\d+$`, info.PythonVersion)
+	assert.Equal(t, preprocessing.InterpreterModeShared, info.InterpreterMode)
+	assert.Equal(t, info.SubinterpretersSupported, info.SubinterpretersUnsupportedReason == "",
+		"Missing support should be explained, and only then")
+
+	// Python before 3.12 has no subinterpreters with their own GIL.
+	var minor int
+	_, err = fmt.Sscanf(strings.TrimPrefix(info.PythonVersion, "3."), "%d", &minor)
+	require.NoError(t, err)
+	if minor < 12 {
+		assert.False(t, info.SubinterpretersSupported)
+		assert.Contains(t, info.SubinterpretersUnsupportedReason, "3.12")
+	}
+
+	again, err := wrapper.RuntimeInfo(ctx)
+	require.NoError(t, err, "RuntimeInfo should not return an error")
+	assert.Equal(t, info, again, "The support of subinterpreters should be probed once")
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SchemaDialect is the JSON Schema dialect the `parameters` of Tools are
+// rewritten to before rendering. Tools are authored for different dialects,
+// and templates walking their schemas, e.g. to render `$ref`s or tuple
+// items, only understand the keywords of one.
+type SchemaDialect int
+
+const (
+	// SchemaDialectAsIs passes tool parameters as sent.
+	SchemaDialectAsIs SchemaDialect = iota
+	// SchemaDialectDraft07 rewrites 2020-12 keywords to their draft-07
+	// equivalents: `$defs` to `definitions`, `prefixItems` to an `items`
+	// array, with `items` then moved to `additionalItems`, and
+	// `dependentRequired` and `dependentSchemas` to `dependencies`.
+	SchemaDialectDraft07
+	// SchemaDialect202012 rewrites draft-07 keywords to their 2020-12
+	// equivalents, the reverse of SchemaDialectDraft07.
+	SchemaDialect202012
+)
+
+// String returns the name of the schema dialect.
+func (d SchemaDialect) String() string {
+	switch d {
+	case SchemaDialectAsIs:
+		return "as-is"
+	case SchemaDialectDraft07:
+		return "draft-07"
+	case SchemaDialect202012:
+		return "2020-12"
+	default:
+		return "unknown"
+	}
+}
+
+// WithSchemaDialect sets the JSON Schema dialect the parameters of tools are
+// rewritten to, for templates expecting one. `$ref`s to the rewritten
+// definitions and a `$schema` naming either dialect follow. Defaults to
+// SchemaDialectAsIs.
+func WithSchemaDialect(dialect SchemaDialect) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.schemaDialect = dialect
+	}
+}
+
+const (
+	draft07SchemaURI = "http://json-schema.org/draft-07/schema#"
+	schema202012URI  = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// draft07DefsRef and defs202012Ref prefix the `$ref`s to the definitions of
+// the root schema in each dialect.
+const (
+	draft07DefsRef = "#/definitions/"
+	defs202012Ref  = "#/$defs/"
+)
+
+// Keywords whose value is a schema, a list of schemas or a map of schemas,
+// in either dialect. Other keywords, such as `enum` or `default`, hold data
+// that is not rewritten.
+var (
+	schemaKeywords = []string{
+		"items", "additionalItems", "additionalProperties", "contains", "propertyNames", "not",
+		"if", "then", "else", "unevaluatedItems", "unevaluatedProperties",
+	}
+	schemaListKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	schemaMapKeywords  = []string{
+		"properties", "patternProperties", "definitions", "$defs", "dependencies", "dependentSchemas",
+	}
+)
+
+// coerceToolSchemas returns tools with the parameters of each rewritten to
+// dialect. Both the OpenAI form, with the parameters under "function", and
+// the flat form are handled. tools is not modified.
+func coerceToolSchemas(tools []interface{}, dialect SchemaDialect) []interface{} {
+	out := slices.Clone(tools)
+	for i, tool := range tools {
+		schema, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		function, nested := schema["function"].(map[string]interface{})
+		if !nested {
+			function = schema
+		}
+		parameters, ok := function["parameters"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		coerced := maps.Clone(function)
+		coerced["parameters"] = coerceSchema(parameters, dialect)
+		if nested {
+			wrapped := maps.Clone(schema)
+			wrapped["function"] = coerced
+			coerced = wrapped
+		}
+		out[i] = coerced
+	}
+	return out
+}
+
+// coerceSchema returns a copy of schema and its subschemas rewritten to
+// dialect. Values that are not schemas, e.g. booleans, are returned as is.
+func coerceSchema(schema interface{}, dialect SchemaDialect) interface{} {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		return schema
+	}
+
+	out := maps.Clone(object)
+	for _, keyword := range schemaKeywords {
+		if value, ok := out[keyword]; ok {
+			if list, isList := value.([]interface{}); isList {
+				// A tuple `items` of draft-07
+				out[keyword] = coerceSchemaList(list, dialect)
+			} else {
+				out[keyword] = coerceSchema(value, dialect)
+			}
+		}
+	}
+	for _, keyword := range schemaListKeywords {
+		if list, ok := out[keyword].([]interface{}); ok {
+			out[keyword] = coerceSchemaList(list, dialect)
+		}
+	}
+	for _, keyword := range schemaMapKeywords {
+		if schemas, ok := out[keyword].(map[string]interface{}); ok {
+			coerced := make(map[string]interface{}, len(schemas))
+			for name, value := range schemas {
+				coerced[name] = coerceSchema(value, dialect)
+			}
+			out[keyword] = coerced
+		}
+	}
+
+	switch dialect {
+	case SchemaDialectDraft07:
+		toDraft07(out)
+	case SchemaDialect202012:
+		to202012(out)
+	}
+	return out
+}
+
+// coerceSchemaList returns a copy of schemas rewritten to dialect.
+func coerceSchemaList(schemas []interface{}, dialect SchemaDialect) []interface{} {
+	out := make([]interface{}, len(schemas))
+	for i, value := range schemas {
+		out[i] = coerceSchema(value, dialect)
+	}
+	return out
+}
+
+// to202012 rewrites the draft-07 keywords of schema, whose subschemas are
+// already rewritten, in place.
+func to202012(schema map[string]interface{}) {
+	renameKeyword(schema, "definitions", "$defs")
+	if items, ok := schema["items"].([]interface{}); ok {
+		delete(schema, "items")
+		schema["prefixItems"] = items
+		renameKeyword(schema, "additionalItems", "items")
+	}
+	if dependencies, ok := schema["dependencies"].(map[string]interface{}); ok {
+		delete(schema, "dependencies")
+		required, schemas := map[string]interface{}{}, map[string]interface{}{}
+		for name, dependency := range dependencies {
+			if _, isList := dependency.([]interface{}); isList {
+				required[name] = dependency
+			} else {
+				schemas[name] = dependency
+			}
+		}
+		mergeKeyword(schema, "dependentRequired", required)
+		mergeKeyword(schema, "dependentSchemas", schemas)
+	}
+	rewriteDialectRefs(schema, draft07SchemaURI, schema202012URI, draft07DefsRef, defs202012Ref)
+}
+
+// toDraft07 rewrites the 2020-12 keywords of schema, whose subschemas are
+// already rewritten, in place.
+func toDraft07(schema map[string]interface{}) {
+	renameKeyword(schema, "$defs", "definitions")
+	if prefixItems, ok := schema["prefixItems"].([]interface{}); ok {
+		delete(schema, "prefixItems")
+		renameKeyword(schema, "items", "additionalItems")
+		schema["items"] = prefixItems
+	}
+	for _, keyword := range []string{"dependentRequired", "dependentSchemas"} {
+		if dependencies, ok := schema[keyword].(map[string]interface{}); ok {
+			delete(schema, keyword)
+			mergeKeyword(schema, "dependencies", dependencies)
+		}
+	}
+	rewriteDialectRefs(schema, schema202012URI, draft07SchemaURI, defs202012Ref, draft07DefsRef)
+}
+
+// renameKeyword moves the value of from to to, unless schema already has to.
+func renameKeyword(schema map[string]interface{}, from, to string) {
+	value, ok := schema[from]
+	if !ok {
+		return
+	}
+	if _, taken := schema[to]; taken {
+		return
+	}
+	delete(schema, from)
+	schema[to] = value
+}
+
+// mergeKeyword adds the entries of values to the map under keyword, keeping
+// those schema already has.
+func mergeKeyword(schema map[string]interface{}, keyword string, values map[string]interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	merged, _ := schema[keyword].(map[string]interface{})
+	merged = maps.Clone(merged)
+	if merged == nil {
+		merged = make(map[string]interface{}, len(values))
+	}
+	for name, value := range values {
+		if _, taken := merged[name]; !taken {
+			merged[name] = value
+		}
+	}
+	schema[keyword] = merged
+}
+
+// rewriteDialectRefs rewrites a `$schema` of the fromURI dialect to toURI,
+// and a `$ref` to the definitions under fromDefs to toDefs.
+func rewriteDialectRefs(schema map[string]interface{}, fromURI, toURI, fromDefs, toDefs string) {
+	if uri, ok := schema["$schema"].(string); ok && strings.TrimSuffix(uri, "#") == strings.TrimSuffix(fromURI, "#") {
+		schema["$schema"] = toURI
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if name, found := strings.CutPrefix(ref, fromDefs); found {
+			schema["$ref"] = toDefs + name
+		}
+	}
+}
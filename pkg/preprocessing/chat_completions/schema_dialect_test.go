@@ -0,0 +1,81 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// draft07Tool is a tool whose parameters use draft-07 definitions, tuple items and dependencies. Its "definitions"
+// property is a property name, not a keyword.
+const draft07Tool = `{"type": "function", "function": {"name": "move", "parameters": {
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"definitions": {"point": {"type": "array", "items": [{"type": "number"}, {"type": "number"}], "additionalItems": false}},
+	"properties": {"origin": {"$ref": "#/definitions/point"}, "definitions": {"type": "string"}},
+	"dependencies": {"origin": ["unit"]}
+}}}`
+
+// TestSchemaDialect tests rewriting the parameters of tools to the JSON Schema dialect of the template.
+func TestSchemaDialect(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	var tool interface{}
+	require.NoError(t, json.Unmarshal([]byte(draft07Tool), &tool))
+	newRequest := func(template string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Move to the origin."}},
+			Tools:         []interface{}{tool},
+			ChatTemplate:  template,
+		}
+	}
+	refTemplate := "{{ tools[0].function.parameters.properties.origin['$ref'] }}"
+
+	t.Run("As is", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+		response, err := processor.RenderChatTemplate(ctx, newRequest(refTemplate))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "#/definitions/point", response.RenderedChats[0], "Parameters should be passed as sent")
+	})
+
+	t.Run("2020-12", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithSchemaDialect(preprocessing.SchemaDialect202012))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+		request := newRequest("{% set p = tools[0].function.parameters %}" +
+			"{{ p['$schema'] }} {{ p['$defs'].point.prefixItems | length }} {{ p['$defs'].point['items'] }} " +
+			"{{ p.properties.origin['$ref'] }} {{ p.dependentRequired.origin | join(',') }} " +
+			"{{ 'definitions' in p }} {{ 'definitions' in p.properties }}")
+		response, err := processor.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "https://json-schema.org/draft/2020-12/schema 2 False #/$defs/point unit False True",
+			response.RenderedChats[0], "The draft-07 keywords should be rewritten")
+
+		parameters := request.Tools[0].(map[string]interface{})["function"].(map[string]interface{})["parameters"]
+		assert.Contains(t, parameters, "definitions", "The request's tools should not be modified")
+	})
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestShutdown tests that Shutdown rejects new renders and waits for those in flight before finalizing.
+func TestShutdown(t *testing.T) {
+	globalWrapperMu.Lock()
+	defer globalWrapperMu.Unlock()
+
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	t.Cleanup(func() {
+		preprocessing.SetFaultHook(nil)
+		// The module is process-wide, restore it for the other tests.
+		require.NoError(t, wrapper.Initialize(), "Re-Initialize should not return an error")
+		renderLocalTemplate(t, wrapper)
+	})
+
+	// blockRenders holds calls into Python until the returned function is called.
+	blockRenders := func() func() {
+		unblock := make(chan struct{})
+		preprocessing.SetFaultHook(func() { <-unblock })
+		return func() {
+			close(unblock)
+			preprocessing.SetFaultHook(nil)
+		}
+	}
+
+	t.Run("Drain", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+		t.Cleanup(processor.Finalize)
+
+		unblock := blockRenders()
+		const inFlight = 4
+		var wg sync.WaitGroup
+		errs := make([]error, inFlight)
+		for i := range inFlight {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = processor.RenderChatTemplate(ctx, largeRenderRequest(100))
+			}()
+		}
+		require.Eventually(t, func() bool { return preprocessing.ActiveCalls(processor) == inFlight },
+			5*time.Second, time.Millisecond, "The renders should be in flight")
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- processor.Shutdown(ctx) }()
+		require.Eventually(t, func() bool { return preprocessing.IsShuttingDown(processor) },
+			5*time.Second, time.Millisecond, "The processor should be shutting down")
+		_, err := processor.RenderChatTemplate(ctx, largeRenderRequest(1))
+		require.ErrorIs(t, err, preprocessing.ErrShuttingDown, "New renders should be rejected once shutting down")
+		select {
+		case <-shutdown:
+			t.Fatal("Shutdown should wait for the renders in flight")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		unblock()
+		require.NoError(t, <-shutdown, "Shutdown should not return an error once drained")
+		wg.Wait()
+		for i, err := range errs {
+			assert.NoError(t, err, "Render %d in flight should complete", i)
+		}
+		assert.False(t, preprocessing.IsInitialized(processor), "Shutdown should finalize the interpreter")
+
+		require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+		_, err = processor.RenderChatTemplate(ctx, largeRenderRequest(1))
+		assert.NoError(t, err, "Initialize should make the processor accept renders again")
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+		t.Cleanup(processor.Finalize)
+
+		unblock := blockRenders()
+		rendered := make(chan error, 1)
+		go func() {
+			_, err := processor.RenderChatTemplate(ctx, largeRenderRequest(1))
+			rendered <- err
+		}()
+		require.Eventually(t, func() bool { return preprocessing.ActiveCalls(processor) == 1 },
+			5*time.Second, time.Millisecond, "The render should be in flight")
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- processor.Shutdown(shutdownCtx) }()
+		<-shutdownCtx.Done()
+		// Finalizing runs after the call in flight on the interpreter's thread.
+		unblock()
+		assert.ErrorIs(t, <-shutdown, context.DeadlineExceeded, "Shutdown should report the calls not drained")
+		assert.NoError(t, <-rendered)
+		assert.False(t, preprocessing.IsInitialized(processor), "Shutdown should finalize the interpreter past its deadline")
+	})
+
+	t.Run("Other processors", func(t *testing.T) {
+		other := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, other.Initialize(), "Initialize should not return an error")
+		t.Cleanup(other.Finalize)
+		processor := preprocessing.NewChatTemplatingProcessor()
+		require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+		t.Cleanup(processor.Finalize)
+		refs := preprocessing.InterpreterRefs()
+
+		require.NoError(t, processor.Shutdown(ctx), "Shutdown should not return an error")
+		assert.Equal(t, refs-1, preprocessing.InterpreterRefs(), "Shutdown should only release the processor's reference")
+		_, err := other.RenderChatTemplate(ctx, largeRenderRequest(1))
+		assert.NoError(t, err, "Other processors should keep rendering")
+	})
+}
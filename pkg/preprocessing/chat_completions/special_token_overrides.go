@@ -0,0 +1,57 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// overridableSpecialTokens are the special tokens of the tokenizer config
+// that RenderJinjaTemplateRequest.SpecialTokenOverrides may replace, the
+// template variables collected from tokenizers by the Python side.
+var overridableSpecialTokens = []string{
+	"bos_token", "eos_token", "eot_token", "pad_token", "unk_token", "sep_token",
+}
+
+// applySpecialTokenOverrides returns req with its SpecialTokenOverrides set
+// in ChatTemplateKWArgs, the Python side applying them to the tokenizer.
+// Names other than overridableSpecialTokens, or also in TemplateVars, fail,
+// as does AddSpecialTokens. The input request is not modified.
+func applySpecialTokenOverrides(req *RenderJinjaTemplateRequest) (*RenderJinjaTemplateRequest, error) {
+	if req.AddSpecialTokens {
+		return nil, fmt.Errorf("special_token_overrides cannot be combined with add_special_tokens, " +
+			"the tokenizer would add its own special tokens")
+	}
+	for name := range req.SpecialTokenOverrides {
+		if !slices.Contains(overridableSpecialTokens, name) {
+			return nil, fmt.Errorf("cannot override special token %q, expected one of %v", name, overridableSpecialTokens)
+		}
+		if _, ok := req.TemplateVars[name]; ok {
+			return nil, fmt.Errorf("%w: %q is a special token override", ErrReservedTemplateVar, name)
+		}
+	}
+	overridden := *req
+	overridden.ChatTemplateKWArgs = make(map[string]interface{},
+		len(req.ChatTemplateKWArgs)+len(req.SpecialTokenOverrides))
+	maps.Copy(overridden.ChatTemplateKWArgs, req.ChatTemplateKWArgs)
+	for name, token := range req.SpecialTokenOverrides {
+		overridden.ChatTemplateKWArgs[name] = token
+	}
+	return &overridden, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSpecialTokenOverrides tests that special tokens can be overridden for a single render.
+func TestSpecialTokenOverrides(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	newRequest := func() *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:       "{{ bos_token }}{% for message in messages %}{{ message.content }}{% endfor %}{{ eos_token }}",
+			ChatTemplateKWArgs: map[string]interface{}{"bos_token": "<s>", "eos_token": "</s>"},
+		}
+	}
+
+	request := newRequest()
+	request.SpecialTokenOverrides = map[string]string{"bos_token": "<|begin_of_text|>"}
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "<|begin_of_text|>Hello</s>", response.RenderedChats[0], "The render should use the overridden BOS")
+	assert.Equal(t, "<s>", request.ChatTemplateKWArgs["bos_token"], "The request's kwargs should not be modified")
+
+	response, err = wrapper.RenderChatTemplate(ctx, newRequest())
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "<s>Hello</s>", response.RenderedChats[0], "Overrides should not outlive their render")
+
+	request = newRequest()
+	request.SpecialTokenOverrides = map[string]string{"chat_template": "{{ 'injected' }}"}
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	assert.Error(t, err, "Only special tokens should be overridable")
+
+	request = newRequest()
+	request.SpecialTokenOverrides = map[string]string{"eos_token": "<|eot|>"}
+	request.TemplateVars = map[string]interface{}{"eos_token": "<|end|>"}
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	assert.ErrorIs(t, err, preprocessing.ErrReservedTemplateVar, "Overrides should not collide with template vars")
+
+	request = newRequest()
+	request.SpecialTokenOverrides = map[string]string{"bos_token": "<|begin_of_text|>"}
+	request.AddSpecialTokens = true
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	assert.Error(t, err, "Overrides should not be combined with the tokenizer's own special tokens")
+}
+
+// TestSpecialTokenOverridesTokenIDs tests that overridden special tokens are applied to the tokenizer, encoding as
+// single special token IDs, for that render only.
+func TestSpecialTokenOverridesTokenIDs(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	const bos, eot = "<|begin_of_text|>", "<|eot_id|>"
+	newRequest := func(overrides map[string]string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:         []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:          "{{ bos_token }}{% for message in messages %}{{ message.content }}{% endfor %}",
+			ChatTemplateKWArgs:    map[string]interface{}{"bos_token": bos},
+			ReturnOffsetMapping:   true,
+			Tokenizer:             &preprocessing.TokenizerSource{Model: "../../tokenization/testdata/test-model", IsLocalPath: true},
+			SpecialTokenOverrides: overrides,
+		}
+	}
+	// tokensOf returns the texts of the tokens of a response.
+	tokensOf := func(response *preprocessing.RenderJinjaTemplateResponse) []string {
+		tokens := make([]string, len(response.OffsetMapping))
+		for i, offsets := range response.OffsetMapping {
+			tokens[i] = response.RenderedChats[0][offsets[0]:offsets[1]]
+		}
+		return tokens
+	}
+
+	response, err := wrapper.RenderChatTemplate(ctx, newRequest(map[string]string{"bos_token": bos, "eot_token": eot}))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.NotEmpty(t, response.TokenIDs)
+	assert.Equal(t, bos, tokensOf(response)[0], "The overridden BOS should be a single token")
+	overriddenID := response.TokenIDs[0]
+
+	withEOT := newRequest(map[string]string{"bos_token": bos, "eot_token": eot})
+	withEOT.Conversations[0].Content = "Hello" + eot
+	response, err = wrapper.RenderChatTemplate(ctx, withEOT)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, overriddenID, response.TokenIDs[0], "The overridden BOS should have the same ID across renders")
+	assert.Contains(t, tokensOf(response), eot, "Overrides without a tokenizer attribute should be single tokens too")
+
+	// The shared tokenizer is left unchanged.
+	response, err = wrapper.RenderChatTemplate(ctx, newRequest(nil))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.NotEqual(t, bos, tokensOf(response)[0], "Other renders should not see the overridden BOS")
+	assert.NotContains(t, response.TokenIDs, overriddenID, "Other renders should not see the overridden BOS")
+}
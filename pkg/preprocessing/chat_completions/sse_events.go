@@ -0,0 +1,89 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// SSEDone is the server-sent event ending an OpenAI-style stream.
+const SSEDone = "data: [DONE]\n\n"
+
+// SSEEvent is a chat completions delta event replaying part of a render.
+type SSEEvent struct {
+	// Role is the role of the message the content was rendered from, empty
+	// when unknown.
+	Role string `json:"role,omitempty"`
+	// Content is the rendered text of the event.
+	Content string `json:"content"`
+}
+
+// sseChunk is the JSON data of an SSEEvent, shaped like a chat completions
+// chunk.
+type sseChunk struct {
+	Object  string      `json:"object"`
+	Choices []sseChoice `json:"choices"`
+}
+
+type sseChoice struct {
+	Index int      `json:"index"`
+	Delta SSEEvent `json:"delta"`
+}
+
+// Format returns e as a server-sent event holding a chat completions chunk,
+// `data: {"object":"chat.completion.chunk","choices":[{"index":0,"delta":{...}}]}`.
+func (e SSEEvent) Format() string {
+	//nolint:errcheck // a chunk of strings always encodes
+	data, _ := json.Marshal(sseChunk{Object: "chat.completion.chunk", Choices: []sseChoice{{Delta: e}}})
+	return "data: " + string(data) + "\n\n"
+}
+
+// RenderToSSEEvents chunks the first rendered chat of resp into delta
+// events, one per message, so tools can preview the prompt's structure as a
+// stream. It is not model generation: the events replay the render, and their
+// contents joined give it back. Messages are split with the TurnSegments of
+// ReturnPerTurnSegments, and their roles taken from RoleBoundaries or
+// NormalizedMessages when returned. Without TurnSegments, the whole render is
+// a single event.
+func RenderToSSEEvents(resp *RenderJinjaTemplateResponse) []SSEEvent {
+	if resp == nil || len(resp.RenderedChats) == 0 {
+		return nil
+	}
+	if len(resp.TurnSegments) == 0 || strings.Join(resp.TurnSegments[0], "") != resp.RenderedChats[0] {
+		return []SSEEvent{{Content: resp.RenderedChats[0]}}
+	}
+
+	segments := resp.TurnSegments[0]
+	events := make([]SSEEvent, len(segments))
+	for i, segment := range segments {
+		events[i] = SSEEvent{Role: segmentRole(resp, i, len(segments)), Content: segment}
+	}
+	return events
+}
+
+// segmentRole returns the role of the message of segment i of the n turn
+// segments of resp, or "" if resp does not report roles for them.
+func segmentRole(resp *RenderJinjaTemplateResponse, i, n int) string {
+	if len(resp.RoleBoundaries) == n {
+		return resp.RoleBoundaries[i].Role
+	}
+	if len(resp.NormalizedMessages) == n {
+		return resp.NormalizedMessages[i].Role
+	}
+	return ""
+}
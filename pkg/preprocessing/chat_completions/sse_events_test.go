@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderToSSEEvents tests that the delta events of a render follow its messages and reconstruct it.
+func TestRenderToSSEEvents(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi there"},
+		},
+		ChatTemplate:             "{% for message in messages %}<{{ message.role }}>{{ message.content }}\n{% endfor %}",
+		ReturnPerTurnSegments:    true,
+		ReturnNormalizedMessages: true,
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	events := preprocessing.RenderToSSEEvents(response)
+	require.Len(t, events, 3, "There should be one event per message")
+	var replayed strings.Builder
+	for i, event := range events {
+		assert.Equal(t, request.Conversations[i].Role, event.Role, "Each event should carry its message's role")
+		assert.Contains(t, event.Content, request.Conversations[i].Content)
+
+		// The events are OpenAI-style chunks.
+		data, found := strings.CutPrefix(event.Format(), "data: ")
+		require.True(t, found, "Events should be formatted as server-sent events")
+		var chunk struct {
+			Choices []struct {
+				Delta preprocessing.SSEEvent `json:"delta"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk), "Events should hold JSON chunks")
+		require.Len(t, chunk.Choices, 1)
+		replayed.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, response.RenderedChats[0], replayed.String(), "The events should reconstruct the full render")
+
+	request.ReturnPerTurnSegments = false
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, []preprocessing.SSEEvent{{Content: response.RenderedChats[0]}},
+		preprocessing.RenderToSSEEvents(response), "Without turn segments the render should be a single event")
+}
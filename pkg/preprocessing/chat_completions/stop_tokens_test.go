@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFetchChatTemplateStopTokens tests that EOS token IDs and stop tokens are returned with the template.
+func TestFetchChatTemplateStopTokens(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	t.Run("Tokenizer EOS token", func(t *testing.T) {
+		response, err := wrapper.FetchChatTemplateDetails(context.Background(), preprocessing.FetchChatTemplateRequest{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		}, preprocessing.FetchOptions{})
+		require.NoError(t, err, "FetchChatTemplateDetails should not return an error")
+		assert.Equal(t, []int{102}, response.EOSTokenIDs, "EOS token ID should fall back to the tokenizer's")
+		assert.Equal(t, []string{"[SEP]"}, response.StopTokens, "Stop token should fall back to the tokenizer's")
+	})
+
+	t.Run("Multiple EOS tokens", func(t *testing.T) {
+		// Like Llama-3's generation config, which lists both `<|end_of_text|>` and `<|eot_id|>`.
+		modelDir := filepath.Join(t.TempDir(), "multi-eos-model")
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		require.NoError(t, os.WriteFile(filepath.Join(modelDir, "generation_config.json"),
+			[]byte(`{"bos_token_id": 101, "eos_token_id": [102, 0]}`), 0o600))
+
+		response, err := wrapper.FetchChatTemplateDetails(context.Background(), preprocessing.FetchChatTemplateRequest{
+			Model:       modelDir,
+			IsLocalPath: true,
+		}, preprocessing.FetchOptions{})
+		require.NoError(t, err, "FetchChatTemplateDetails should not return an error")
+		assert.Equal(t, []int{102, 0}, response.EOSTokenIDs, "All EOS token IDs should be returned")
+		assert.Equal(t, []string{"[SEP]", "[PAD]"}, response.StopTokens, "Stop tokens should match the EOS token IDs")
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// RenderSuffixResponse is the part of a rendered conversation beyond the
+// prefix already held in a session's KV-cache.
+type RenderSuffixResponse struct {
+	// Text is the rendered text following the cached prefix.
+	Text string
+	// TokenIDs are the token IDs following the cached prefix.
+	TokenIDs []uint32
+	// PrefixTokens is the number of cached tokens the suffix follows.
+	PrefixTokens int
+}
+
+// RenderSuffix renders req like RenderChatTemplate and returns only what
+// follows the first cachedPrefixTokens tokens, e.g. to prefill just the new
+// turn of a session whose KV-cache holds the earlier ones. req.Tokenizer must
+// be set, and must provide offset mappings.
+//
+// The cached prefix is expected to come from the conversation's previous
+// render, i.e. without its last message. It is checked against a render of
+// that shorter conversation, failing with ErrPrefixMismatch if the cached
+// tokens exceed it or if the full conversation renders different tokens
+// within the prefix, as templates rewriting earlier turns do. Edits to
+// earlier messages made by the client cannot be told apart from the
+// original conversation, since only the number of cached tokens is known.
+func (w *ChatTemplatingProcessor) RenderSuffix(ctx context.Context, req *RenderJinjaTemplateRequest,
+	cachedPrefixTokens int,
+) (*RenderSuffixResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+	if req.Tokenizer == nil {
+		return nil, fmt.Errorf("rendering a suffix requires a tokenizer")
+	}
+	if cachedPrefixTokens < 0 {
+		return nil, fmt.Errorf("invalid number of cached prefix tokens %d", cachedPrefixTokens)
+	}
+
+	full := *req
+	full.ReturnTokenIDs = true
+	full.ReturnOffsetMapping = true
+	response, err := w.RenderChatTemplate(ctx, &full)
+	if err != nil {
+		return nil, err
+	}
+	if cachedPrefixTokens == 0 {
+		return &RenderSuffixResponse{Text: response.RenderedChats[0], TokenIDs: response.TokenIDs}, nil
+	}
+
+	if len(req.Conversations) == 0 {
+		return nil, fmt.Errorf("%w: empty conversation", ErrPrefixMismatch)
+	}
+	previous := *req
+	previous.Conversations = req.Conversations[:len(req.Conversations)-1]
+	previous.AddGenerationPrompt = false
+	previous.ContinueFinalMessage = false
+	previous.ReturnTokenIDs = true
+	previous.ReturnOffsetMapping = false
+	previousResponse, err := w.RenderChatTemplate(ctx, &previous)
+	if err != nil {
+		return nil, err
+	}
+
+	if cachedPrefixTokens > len(previousResponse.TokenIDs) || cachedPrefixTokens > len(response.TokenIDs) {
+		return nil, fmt.Errorf("%w: %d cached tokens, but the previous conversation renders to %d",
+			ErrPrefixMismatch, cachedPrefixTokens, len(previousResponse.TokenIDs))
+	}
+	if !slices.Equal(previousResponse.TokenIDs[:cachedPrefixTokens], response.TokenIDs[:cachedPrefixTokens]) {
+		return nil, fmt.Errorf("%w: the conversation renders different tokens within the first %d",
+			ErrPrefixMismatch, cachedPrefixTokens)
+	}
+
+	// The suffix starts right after the last cached token, keeping the
+	// whitespace between it and the next token.
+	prefixEnd := response.OffsetMapping[cachedPrefixTokens-1][1]
+	return &RenderSuffixResponse{
+		Text:         response.RenderedChats[0][prefixEnd:],
+		TokenIDs:     response.TokenIDs[cachedPrefixTokens:],
+		PrefixTokens: cachedPrefixTokens,
+	}, nil
+}
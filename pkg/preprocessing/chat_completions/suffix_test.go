@@ -0,0 +1,81 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderSuffix tests that only the tokens beyond a session's cached prefix are returned, and that
+// conversations no longer matching the prefix are rejected.
+func TestRenderSuffix(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	tokenizer := &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true}
+	history := []preprocessing.ChatMessage{
+		{Role: "user", Content: "What is the capital of France?"},
+		{Role: "assistant", Content: "Paris."},
+	}
+	conversation := append(append([]preprocessing.ChatMessage{}, history...),
+		preprocessing.ChatMessage{Role: "user", Content: "And of Italy?"})
+
+	newRequest := func(template string, messages []preprocessing.ChatMessage) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:  messages,
+			ChatTemplate:   template,
+			ReturnTokenIDs: true,
+			Tokenizer:      tokenizer,
+		}
+	}
+	stableTemplate := "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}"
+
+	// The session cached the previous turn's render.
+	cached, err := wrapper.RenderChatTemplate(ctx, newRequest(stableTemplate, history))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	full, err := wrapper.RenderChatTemplate(ctx, newRequest(stableTemplate, conversation))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	t.Run("Matching prefix", func(t *testing.T) {
+		suffix, err := wrapper.RenderSuffix(ctx, newRequest(stableTemplate, conversation), len(cached.TokenIDs))
+		require.NoError(t, err, "RenderSuffix should not return an error")
+		assert.Equal(t, full.TokenIDs[len(cached.TokenIDs):], suffix.TokenIDs, "Only the new tokens should be returned")
+		assert.Equal(t, "\nuser: And of Italy?\n", suffix.Text, "Only the new text should be returned")
+		assert.Equal(t, len(cached.TokenIDs), suffix.PrefixTokens)
+
+		suffix, err = wrapper.RenderSuffix(ctx, newRequest(stableTemplate, conversation), 0)
+		require.NoError(t, err, "RenderSuffix should not return an error")
+		assert.Equal(t, full.TokenIDs, suffix.TokenIDs, "Without a cached prefix, the full render should be returned")
+	})
+
+	t.Run("Diverged prefix", func(t *testing.T) {
+		// Templates rendering the message count change the history's tokens on every turn.
+		countingTemplate := "{{ messages | length }} messages\n" + stableTemplate
+		_, err := wrapper.RenderSuffix(ctx, newRequest(countingTemplate, conversation), len(cached.TokenIDs))
+		assert.ErrorIs(t, err, preprocessing.ErrPrefixMismatch, "A rewritten history should not match the cached prefix")
+
+		_, err = wrapper.RenderSuffix(ctx, newRequest(stableTemplate, conversation), len(cached.TokenIDs)+1)
+		assert.ErrorIs(t, err, preprocessing.ErrPrefixMismatch,
+			"More cached tokens than the previous conversation renders should not match")
+	})
+}
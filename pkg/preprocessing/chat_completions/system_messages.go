@@ -0,0 +1,127 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SystemMergePolicy selects how conversations with more than one system
+// message are rendered. Some clients send several, e.g. a platform prompt and
+// a user-supplied one, and templates differ in handling them: some render
+// each, others only the first or raise.
+type SystemMergePolicy int
+
+const (
+	// SystemMergeKeepAll renders all system messages as sent.
+	SystemMergeKeepAll SystemMergePolicy = iota
+	// SystemMergeIntoFirst merges the content of all system messages, in
+	// order and separated by a blank line, into the first one and drops the
+	// others.
+	SystemMergeIntoFirst
+	// SystemMergeError fails conversations with more than one system message
+	// with ErrDuplicateSystemMessages.
+	SystemMergeError
+)
+
+// String returns the name of the system merge policy.
+func (p SystemMergePolicy) String() string {
+	switch p {
+	case SystemMergeKeepAll:
+		return "keep-all"
+	case SystemMergeIntoFirst:
+		return "merge-into-first"
+	case SystemMergeError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// WithSystemMergePolicy sets how conversations with more than one system
+// message are rendered. Defaults to SystemMergeKeepAll.
+func WithSystemMergePolicy(policy SystemMergePolicy) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.systemMergePolicy = policy
+	}
+}
+
+// mergedContentSeparator separates the content of merged messages.
+const mergedContentSeparator = "\n\n"
+
+// mergeSystemMessages applies policy to the system messages of messages. The
+// input slice is not modified.
+func mergeSystemMessages(messages []ChatMessage, policy SystemMergePolicy) ([]ChatMessage, error) {
+	first, count := -1, 0
+	for i := range messages {
+		if messages[i].Role == systemRole {
+			if first < 0 {
+				first = i
+			}
+			count++
+		}
+	}
+	if count < 2 || policy == SystemMergeKeepAll {
+		return messages, nil
+	}
+	if policy == SystemMergeError {
+		return nil, fmt.Errorf("%w: %d system messages", ErrDuplicateSystemMessages, count)
+	}
+
+	merged := make([]ChatMessage, 0, len(messages)-count+1)
+	system := messages[first]
+	for i := first + 1; i < len(messages); i++ {
+		if messages[i].Role == systemRole {
+			system = mergeMessageContent(system, &messages[i])
+		}
+	}
+	for i := range messages {
+		switch {
+		case i == first:
+			merged = append(merged, system)
+		case messages[i].Role != systemRole:
+			merged = append(merged, messages[i])
+		}
+	}
+	return merged, nil
+}
+
+// mergeMessageContent appends the content of next to message. If either has
+// content parts, so does the result, with string content as a text part.
+func mergeMessageContent(message ChatMessage, next *ChatMessage) ChatMessage {
+	if len(message.ContentParts) == 0 && len(next.ContentParts) == 0 {
+		message.Content = strings.Join([]string{message.Content, next.Content}, mergedContentSeparator)
+		return message
+	}
+
+	parts := make([]ContentPart, 0, len(message.ContentParts)+len(next.ContentParts)+2)
+	parts = append(parts, messageContentParts(&message)...)
+	parts = append(parts, ContentPart{Type: ContentPartText, Text: mergedContentSeparator})
+	parts = append(parts, messageContentParts(next)...)
+	message.Content = ""
+	message.ContentParts = parts
+	return message
+}
+
+// messageContentParts returns the content of message as content parts.
+func messageContentParts(message *ChatMessage) []ContentPart {
+	if len(message.ContentParts) > 0 {
+		return message.ContentParts
+	}
+	return []ContentPart{{Type: ContentPartText, Text: message.Content}}
+}
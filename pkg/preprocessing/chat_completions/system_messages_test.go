@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSystemMergePolicy tests rendering a conversation with two system messages under each policy.
+func TestSystemMergePolicy(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	newRequest := func() *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "system", Content: "You are a helpful assistant."},
+				{Role: "user", Content: "Hello"},
+				{Role: "system", Content: "Answer in French."},
+			},
+			ChatTemplate:             "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			ReturnNormalizedMessages: true,
+		}
+	}
+
+	t.Run("Keep all", func(t *testing.T) {
+		response, err := preprocessing.NewChatTemplatingProcessor().RenderChatTemplate(ctx, newRequest())
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, newRequest().Conversations, response.NormalizedMessages,
+			"All system messages should be kept by default")
+		assert.Equal(t, "system: You are a helpful assistant.\nuser: Hello\nsystem: Answer in French.\n",
+			response.RenderedChats[0])
+	})
+
+	t.Run("Merge into first", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithSystemMergePolicy(preprocessing.SystemMergeIntoFirst))
+		request := newRequest()
+		response, err := processor.RenderChatTemplate(ctx, request)
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant.\n\nAnswer in French."},
+			{Role: "user", Content: "Hello"},
+		}, response.NormalizedMessages, "The system messages should be merged into the first")
+		assert.Equal(t, "system: You are a helpful assistant.\n\nAnswer in French.\nuser: Hello\n",
+			response.RenderedChats[0])
+		assert.Len(t, request.Conversations, 3, "The request should not be modified")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		processor := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithSystemMergePolicy(preprocessing.SystemMergeError))
+		_, err := processor.RenderChatTemplate(ctx, newRequest())
+		require.ErrorIs(t, err, preprocessing.ErrDuplicateSystemMessages)
+
+		single := newRequest()
+		single.Conversations = single.Conversations[:2]
+		_, err = processor.RenderChatTemplate(ctx, single)
+		require.NoError(t, err, "A single system message should be accepted")
+	})
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSystemPromptTokenSpan tests that the system prompt span covers exactly the system prompt tokens.
+func TestSystemPromptTokenSpan(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	systemPrompt := preprocessing.ChatMessage{Role: "system", Content: "You are a helpful assistant."}
+	request := func(conversation ...preprocessing.ChatMessage) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:               conversation,
+			ChatTemplate:                template,
+			ChatTemplateKWArgs:          templateVars,
+			ReturnTokenIDs:              true,
+			ReturnSystemPromptTokenSpan: true,
+			Tokenizer: &preprocessing.TokenizerSource{
+				Model:       testModelPath,
+				IsLocalPath: true,
+			},
+		}
+	}
+	render := func(conversation ...preprocessing.ChatMessage) *preprocessing.RenderJinjaTemplateResponse {
+		t.Helper()
+		response, err := wrapper.RenderChatTemplate(context.Background(), request(conversation...))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response
+	}
+
+	systemOnly := render(systemPrompt)
+	full := render(systemPrompt, preprocessing.ChatMessage{Role: "user", Content: "What is the capital of France?"})
+
+	span := full.SystemPromptTokenSpan
+	assert.Equal(t, [2]int{0, len(systemOnly.TokenIDs)}, span, "Span should cover the system prompt tokens")
+	assert.Equal(t, systemOnly.TokenIDs, full.TokenIDs[span[0]:span[1]], "Span should map to the system prompt tokens")
+	assert.Less(t, span[1], len(full.TokenIDs), "Span should not cover the user message")
+
+	noSystem := render(preprocessing.ChatMessage{Role: "user", Content: "Hello"})
+	assert.Equal(t, [2]int{0, 0}, noSystem.SystemPromptTokenSpan, "Span should be empty without a system prompt")
+
+	unrequested := request(systemPrompt, preprocessing.ChatMessage{Role: "user", Content: "Hello"})
+	unrequested.ReturnSystemPromptTokenSpan = false
+	response, err := wrapper.RenderChatTemplate(context.Background(), unrequested)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, [2]int{0, 0}, response.SystemPromptTokenSpan, "Span should only be computed on request")
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "sync"
+
+// cachedTemplates mirrors the keys of the template cache of the interpreter,
+// which is process-wide, so that IsTemplateCached never calls into Python.
+// Fetches add the key the interpreter cached the template under, and every
+// call that may evict templates removes the keys the interpreter reports.
+var cachedTemplates templateCacheIndex
+
+// templateCacheIndex maps the template cache keys of the interpreter to the
+// model and revision they were fetched for, counting the keys per model, as
+// each token and local path flag of a model is cached apart.
+type templateCacheIndex struct {
+	mu     sync.RWMutex
+	keys   map[string]string
+	models map[string]int
+}
+
+// IsTemplateCached reports whether the template of model at revision, "main"
+// if empty, is in the template cache, so that fetching it is served without
+// loading the model, e.g. for a router to prefer models that are warm. It
+// only reads an in-process index of the cache: it neither calls into Python
+// nor touches the network, nor does it count as an access of the template.
+func IsTemplateCached(model, revision string) bool {
+	cachedTemplates.mu.RLock()
+	defer cachedTemplates.mu.RUnlock()
+	return cachedTemplates.models[templateCacheModelKey(model, revision)] > 0
+}
+
+// templateCacheModelKey keys the models of the template cache, defaulting the
+// revision like the interpreter.
+func templateCacheModelKey(model, revision string) string {
+	if revision == "" {
+		revision = "main"
+	}
+	return model + "@" + revision
+}
+
+// update records that the template of model at revision was cached under
+// key, unless key is empty, then forgets the evicted keys.
+func (i *templateCacheIndex) update(model, revision, key string, evicted []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if key != "" {
+		if _, ok := i.keys[key]; !ok {
+			if i.keys == nil {
+				i.keys = make(map[string]string)
+				i.models = make(map[string]int)
+			}
+			modelKey := templateCacheModelKey(model, revision)
+			i.keys[key] = modelKey
+			i.models[modelKey]++
+		}
+	}
+	for _, evictedKey := range evicted {
+		modelKey, ok := i.keys[evictedKey]
+		if !ok {
+			continue
+		}
+		delete(i.keys, evictedKey)
+		if i.models[modelKey]--; i.models[modelKey] == 0 {
+			delete(i.models, modelKey)
+		}
+	}
+}
+
+// templateEvictions lists the template cache keys evicted by a call into
+// the interpreter, as returned by the calls that may evict templates.
+type templateEvictions struct {
+	EvictedTemplates []string `json:"evicted_templates,omitempty"`
+}
+
+// reset forgets all keys, once the cache of the interpreter is emptied.
+func (i *templateCacheIndex) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys, i.models = nil, nil
+}
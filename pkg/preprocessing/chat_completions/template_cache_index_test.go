@@ -0,0 +1,56 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsTemplateCached tests that the template cache index follows fetches and evictions.
+func TestIsTemplateCached(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+	require.NoError(t, preprocessing.ClearCaches(ctx), "ClearCaches should not return an error")
+
+	assert.False(t, preprocessing.IsTemplateCached(testModelPath, ""), "The template should not be cached before a fetch")
+
+	_, _, err := wrapper.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	assert.True(t, preprocessing.IsTemplateCached(testModelPath, ""), "The template should be cached after a fetch")
+	assert.True(t, preprocessing.IsTemplateCached(testModelPath, "main"), "The revision should default to main")
+	assert.False(t, preprocessing.IsTemplateCached(testModelPath, "v2"), "Other revisions should not be cached")
+
+	require.NoError(t, preprocessing.ClearModelCache(ctx, testModelPath, ""), "ClearModelCache should not return an error")
+	assert.False(t, preprocessing.IsTemplateCached(testModelPath, ""), "Evicted templates should not be cached")
+
+	_, _, err = wrapper.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	require.NoError(t, preprocessing.ClearCaches(ctx), "ClearCaches should not return an error")
+	assert.False(t, preprocessing.IsTemplateCached(testModelPath, ""), "Clearing the caches should clear the index")
+}
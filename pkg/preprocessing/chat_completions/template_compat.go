@@ -0,0 +1,90 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// templateCompatRequest is the request sent to check_template_tokenizer_compat.
+type templateCompatRequest struct {
+	ChatTemplate string           `json:"chat_template"`
+	Tokenizer    *TokenizerSource `json:"tokenizer"`
+}
+
+// templateCompatResponse is the response of check_template_tokenizer_compat.
+type templateCompatResponse struct {
+	MissingTokens []string `json:"missing_tokens"`
+}
+
+// CheckTemplateTokenizerCompat fetches the chat template of the model of req
+// and returns a warning for each special token the template hard-codes, such
+// as `<|im_start|>` or `[INST]`, that the model's tokenizer vocabulary lacks.
+// Such tokens are split into several tokens, so renders misbehave subtly;
+// this catches broken model uploads, e.g. before serving a model. No
+// warnings are returned for compatible models.
+//
+//nolint:gocritic // hugeParam: req is passed by value intentionally for immutability, but can consider using pointer.
+func (w *ChatTemplatingProcessor) CheckTemplateTokenizerCompat(ctx context.Context,
+	req FetchChatTemplateRequest,
+) ([]string, error) {
+	template, err := w.FetchChatTemplateDetails(ctx, req, FetchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if template.ChatTemplate == "" {
+		return nil, fmt.Errorf("model %s has no chat template", req.Model)
+	}
+
+	missing, err := w.missingTemplateTokens(ctx, &templateCompatRequest{
+		ChatTemplate: template.ChatTemplate,
+		Tokenizer: &TokenizerSource{
+			Model:       req.Model,
+			Revision:    req.Revision,
+			Token:       req.Token,
+			IsLocalPath: req.IsLocalPath,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	warnings := make([]string, 0, len(missing))
+	for _, token := range missing {
+		warnings = append(warnings, fmt.Sprintf("chat template references %q, which is not in the tokenizer vocabulary",
+			token))
+	}
+	return warnings, nil
+}
+
+// missingTemplateTokens returns the special tokens of the template of req
+// missing from the vocabulary of its tokenizer.
+func (w *ChatTemplatingProcessor) missingTemplateTokens(ctx context.Context, req *templateCompatRequest,
+) (_ []string, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var resp templateCompatResponse
+	if err := callPythonFunction(ctx, "check_template_tokenizer_compat", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.MissingTokens, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckTemplateTokenizerCompat tests warning about special tokens of a template missing from the tokenizer.
+func TestCheckTemplateTokenizerCompat(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	req := preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+		// The test model is a BERT tokenizer, which has [CLS] and [SEP] but no ChatML tokens.
+		ChatTemplate: "[CLS]{% for message in messages %}{{ '<|im_start|>' + message.role }}\n" +
+			"{{ message.content }}<|im_end|>[SEP]{% endfor %}",
+	}
+	warnings, err := wrapper.CheckTemplateTokenizerCompat(ctx, req)
+	require.NoError(t, err, "CheckTemplateTokenizerCompat should not return an error")
+	require.Len(t, warnings, 2, "Only the tokens missing from the vocabulary should be reported")
+	assert.Contains(t, warnings[0], `"<|im_start|>"`)
+	assert.Contains(t, warnings[1], `"<|im_end|>"`)
+
+	req.ChatTemplate = "{% for message in messages %}[CLS]{{ message.content }}[SEP]{% endfor %}"
+	warnings, err = wrapper.CheckTemplateTokenizerCompat(ctx, req)
+	require.NoError(t, err, "CheckTemplateTokenizerCompat should not return an error")
+	assert.Empty(t, warnings, "A template using only tokens of the vocabulary should be compatible")
+}
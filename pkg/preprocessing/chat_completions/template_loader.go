@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TemplateLoader loads chat templates from a source of the caller's, such as
+// an object store, instead of HuggingFace.
+type TemplateLoader interface {
+	// Load returns the chat template of model at revision and its template
+	// kwargs, such as the special tokens it refers to. It returns an error
+	// wrapping ErrModelNotFound for models it does not hold, which are then
+	// fetched from HuggingFace; any other error fails the fetch.
+	Load(ctx context.Context, model, revision string) ([]byte, map[string]interface{}, error)
+}
+
+// WithTemplateLoader sets a loader that FetchChatTemplate, and so the renders
+// fetching templates, consult before falling back to HuggingFace or the local
+// model directory. Loaded templates are not cached by the processor, so the
+// loader should cache them itself if loading is expensive.
+func WithTemplateLoader(loader TemplateLoader) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.templateLoader = loader
+	}
+}
+
+// loadTemplate fetches the template of req from the template loader. It
+// reports false when the fetch should fall back to the Python module: there
+// is no loader, or it does not hold the model.
+func (w *ChatTemplatingProcessor) loadTemplate(
+	ctx context.Context,
+	req *FetchChatTemplateRequest,
+) (*FetchChatTemplateResponse, bool, error) {
+	if w.templateLoader == nil {
+		return nil, false, nil
+	}
+	template, kwargs, err := w.templateLoader.Load(ctx, req.Model, req.Revision)
+	if errors.Is(err, ErrModelNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load chat template of %s: %w", req.Model, err)
+	}
+	response := &FetchChatTemplateResponse{
+		ChatTemplate:       string(template),
+		ChatTemplateKWArgs: kwargs,
+	}
+	// As with fetched templates, the request's template overrides the model's.
+	if req.ChatTemplate != "" {
+		response.ChatTemplate = req.ChatTemplate
+	}
+	return response, true, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTemplateLoader is a TemplateLoader serving templates from memory.
+type memoryTemplateLoader struct {
+	templates map[string]string
+	kwargs    map[string]interface{}
+	err       error
+}
+
+func (l *memoryTemplateLoader) Load(_ context.Context, model, revision string) ([]byte, map[string]interface{}, error) {
+	if l.err != nil {
+		return nil, nil, l.err
+	}
+	template, ok := l.templates[model+"@"+revision]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s@%s", preprocessing.ErrModelNotFound, model, revision)
+	}
+	return []byte(template), l.kwargs, nil
+}
+
+// TestTemplateLoader tests that FetchChatTemplate consults the template loader before HuggingFace.
+func TestTemplateLoader(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	const template = "{% for message in messages %}{{ message['role'] }}: {{ message['content'] }}\n{% endfor %}"
+	loader := &memoryTemplateLoader{
+		templates: map[string]string{"acme/chat-model@v1": template},
+		kwargs:    map[string]interface{}{"bos_token": "<s>"},
+	}
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTemplateLoader(loader))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	t.Run("Loaded template", func(t *testing.T) {
+		fetched, vars, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:    "acme/chat-model",
+			Revision: "v1",
+		})
+		require.NoError(t, err, "FetchChatTemplate should not return an error")
+		assert.Equal(t, template, fetched, "Template should be the loaded one")
+		assert.Equal(t, loader.kwargs, vars, "Template vars should be the loaded ones")
+
+		response, err := processor.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "Hello"},
+			},
+			ChatTemplate: fetched,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "user: Hello\n", response.RenderedChats[0], "Loaded template should render")
+	})
+
+	t.Run("Digest verification", func(t *testing.T) {
+		_, _, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:          "acme/chat-model",
+			Revision:       "v1",
+			ExpectedDigest: preprocessing.TemplateDigest(template + "changed"),
+		})
+		assert.ErrorIs(t, err, preprocessing.ErrDigestMismatch, "Loaded templates should be verified")
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		expected, _, err := getGlobalWrapper().FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		})
+		require.NoError(t, err)
+
+		fetched, _, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		})
+		require.NoError(t, err, "Models the loader does not hold should be fetched from their source")
+		assert.Equal(t, expected, fetched)
+	})
+
+	t.Run("Loader error", func(t *testing.T) {
+		errUnavailable := errors.New("object store unavailable")
+		failing := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTemplateLoader(&memoryTemplateLoader{
+			err: errUnavailable,
+		}))
+		require.NoError(t, failing.Initialize(), "Initialize should not return an error")
+		t.Cleanup(failing.Finalize)
+
+		_, _, err := failing.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		})
+		assert.ErrorIs(t, err, errUnavailable, "Loader errors should fail the fetch")
+	})
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// DefaultTemplateCacheSize is the default number of fetched templates cached,
+// besides the pinned ones.
+const DefaultTemplateCacheSize = 256
+
+// templateCacheSizeRequest is the JSON payload sent to set_template_cache_size.
+type templateCacheSizeRequest struct {
+	Size int `json:"size"`
+}
+
+// templatePin identifies a pinned template like the template cache of the
+// interpreter keys it: by model, revision, token and local path flag.
+type templatePin struct {
+	Model       string `json:"model"`
+	Revision    string `json:"revision,omitempty"`
+	Token       string `json:"token,omitempty"`
+	IsLocalPath bool   `json:"is_local_path,omitempty"`
+}
+
+// templatePinnedRequest is the JSON payload sent to set_template_pinned.
+type templatePinnedRequest struct {
+	templatePin
+	Pinned bool `json:"pinned"`
+}
+
+// pinnedTemplates are the templates pinned with PinTemplate, by any
+// processor. The template cache is process-wide, so HotReload pins them again
+// in the standby module, and they are dropped along with the cache.
+var pinnedTemplates struct {
+	sync.Mutex
+	pins map[templatePin]struct{}
+}
+
+// WithTemplateCacheSize bounds the cache of fetched templates to size
+// entries, evicting the least recently used ones beyond it. Pinned templates
+// are not counted. The cache is process-wide, so the size set at Initialize
+// applies to every processor. Defaults to DefaultTemplateCacheSize.
+func WithTemplateCacheSize(size int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.templateCacheSize = size
+	}
+}
+
+// PinTemplate fetches the template of req like FetchChatTemplate, and keeps
+// it cached until UnpinTemplate, regardless of the cache size, so hot models
+// never pay a re-fetch. Templates are pinned per model, revision, token and
+// local path flag, like they are cached. The cache is process-wide, so pins
+// are shared by all processors: HotReload keeps them, while ClearCaches and
+// the Finalize of the last processor drop them along with the cache.
+//
+//nolint:gocritic // hugeParam: req is passed by value like in FetchChatTemplate.
+func (w *ChatTemplatingProcessor) PinTemplate(ctx context.Context, req FetchChatTemplateRequest) error {
+	req.Revision = w.revisionOrDefault(req.Revision, req.IsLocalPath)
+	pin := templatePin{Model: req.Model, Revision: req.Revision, Token: req.Token, IsLocalPath: req.IsLocalPath}
+	// Pin before fetching, so the template cannot be evicted in between.
+	if err := w.setTemplatePinned(ctx, pin, true); err != nil {
+		return err
+	}
+	if _, err := w.FetchChatTemplateDetails(ctx, req, FetchOptions{}); err != nil {
+		if unpinErr := w.setTemplatePinned(ctx, pin, false); unpinErr != nil {
+			return fmt.Errorf("%w (unpinning failed: %w)", err, unpinErr)
+		}
+		return err
+	}
+
+	pinnedTemplates.Lock()
+	defer pinnedTemplates.Unlock()
+	if pinnedTemplates.pins == nil {
+		pinnedTemplates.pins = make(map[templatePin]struct{})
+	}
+	pinnedTemplates.pins[pin] = struct{}{}
+	return nil
+}
+
+// UnpinTemplate makes the template pinned by PinTemplate with the same
+// model, revision, token and local path flag evictable again. Unpinning a
+// template that is not pinned is a no-op.
+//
+//nolint:gocritic // hugeParam: req is passed by value like in FetchChatTemplate.
+func (w *ChatTemplatingProcessor) UnpinTemplate(ctx context.Context, req FetchChatTemplateRequest) error {
+	req.Revision = w.revisionOrDefault(req.Revision, req.IsLocalPath)
+	pin := templatePin{Model: req.Model, Revision: req.Revision, Token: req.Token, IsLocalPath: req.IsLocalPath}
+	pinnedTemplates.Lock()
+	delete(pinnedTemplates.pins, pin)
+	pinnedTemplates.Unlock()
+	return w.setTemplatePinned(ctx, pin, false)
+}
+
+// setTemplatePinned pins or unpins the template of pin.
+func (w *ChatTemplatingProcessor) setTemplatePinned(ctx context.Context, pin templatePin, pinned bool) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if pin.Model == "" {
+		return fmt.Errorf("model cannot be empty")
+	}
+	return pinTemplate(ctx, pin, pinned)
+}
+
+// pinTemplate pins or unpins the template of pin in the interpreter.
+func pinTemplate(ctx context.Context, pin templatePin, pinned bool) error {
+	var resp templateEvictions
+	if err := callPythonFunction(ctx, "set_template_pinned",
+		templatePinnedRequest{templatePin: pin, Pinned: pinned}, &resp); err != nil {
+		return err
+	}
+	cachedTemplates.update("", "", "", resp.EvictedTemplates)
+	return nil
+}
+
+// repinTemplates pins the templates pinned with PinTemplate again, once
+// HotReload swapped in a module with empty caches. They are cached again
+// once fetched.
+func repinTemplates(ctx context.Context) error {
+	pinnedTemplates.Lock()
+	pins := slices.Collect(maps.Keys(pinnedTemplates.pins))
+	pinnedTemplates.Unlock()
+	for _, pin := range pins {
+		if err := pinTemplate(ctx, pin, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetTemplatePins forgets the pins, once the caches of the interpreter are
+// emptied.
+func resetTemplatePins() {
+	pinnedTemplates.Lock()
+	defer pinnedTemplates.Unlock()
+	pinnedTemplates.pins = nil
+}
+
+// setTemplateCacheSize bounds the template cache of the interpreter.
+func setTemplateCacheSize(ctx context.Context, size int) error {
+	var resp templateEvictions
+	if err := callPythonFunction(ctx, "set_template_cache_size", templateCacheSizeRequest{Size: size}, &resp); err != nil {
+		return err
+	}
+	cachedTemplates.update("", "", "", resp.EvictedTemplates)
+	return nil
+}
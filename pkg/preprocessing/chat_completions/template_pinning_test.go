@@ -0,0 +1,135 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPinTemplate tests that pinned templates survive eviction from a full template cache.
+func TestPinTemplate(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	require.NoError(t, preprocessing.ClearCaches(ctx))
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTemplateCacheSize(2))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+	// The cache is process-wide, restore it for the other tests.
+	t.Cleanup(func() {
+		require.NoError(t, preprocessing.ResetTemplateCacheSize(ctx))
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+	})
+
+	// Each copy of the test model is a distinct cache entry. Models are
+	// deleted once fetched, so only cached templates can still be fetched.
+	copyModel := func(t *testing.T) string {
+		t.Helper()
+		modelDir := filepath.Join(t.TempDir(), "model")
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		return modelDir
+	}
+	fetch := func(model string) error {
+		_, _, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:       model,
+			IsLocalPath: true,
+		})
+		return err
+	}
+
+	pinned := copyModel(t)
+	pinRequest := preprocessing.FetchChatTemplateRequest{Model: pinned, IsLocalPath: true}
+	require.NoError(t, processor.PinTemplate(ctx, pinRequest), "PinTemplate should not return an error")
+	require.NoError(t, os.RemoveAll(pinned))
+
+	// Fill the cache past its capacity.
+	evicted := copyModel(t)
+	require.NoError(t, fetch(evicted))
+	require.NoError(t, os.RemoveAll(evicted))
+	for range 2 {
+		require.NoError(t, fetch(copyModel(t)))
+	}
+
+	assert.NoError(t, fetch(pinned), "Pinned template should survive eviction")
+	assert.ErrorIs(t, fetch(evicted), preprocessing.ErrModelNotFound, "Unpinned template should be evicted")
+
+	// Once unpinned, the template is evicted like any other.
+	require.NoError(t, processor.UnpinTemplate(ctx, pinRequest), "UnpinTemplate should not return an error")
+	for range 2 {
+		require.NoError(t, fetch(copyModel(t)))
+	}
+	assert.ErrorIs(t, fetch(pinned), preprocessing.ErrModelNotFound, "Unpinned template should be evicted")
+
+	// Pinning a missing model fails like its fetch.
+	assert.Error(t, processor.PinTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+		Model: "llm-d/pin-template-missing-model",
+	}), "PinTemplate of a missing model should fail")
+}
+
+// TestPinTemplateKeys tests that pins apply to the token they were made with, and that they are kept by hot
+// reloads and dropped by ClearCaches.
+func TestPinTemplateKeys(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	require.NoError(t, preprocessing.ClearCaches(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+	})
+
+	withToken := preprocessing.FetchChatTemplateRequest{Model: testModelPath, Token: "hf_pinned", IsLocalPath: true}
+	withoutToken := preprocessing.FetchChatTemplateRequest{Model: testModelPath, IsLocalPath: true}
+	// pinned returns whether each cached template is pinned, from the least to the most recently used.
+	pinned := func() []bool {
+		t.Helper()
+		data, err := wrapper.DumpCacheState(ctx)
+		require.NoError(t, err, "DumpCacheState should not return an error")
+		var state preprocessing.CacheState
+		require.NoError(t, json.Unmarshal(data, &state))
+		var pins []bool
+		for _, entry := range state.Templates {
+			pins = append(pins, entry.Pinned)
+		}
+		return pins
+	}
+
+	require.NoError(t, wrapper.PinTemplate(ctx, withToken), "PinTemplate should not return an error")
+	_, _, err := wrapper.FetchChatTemplate(ctx, withoutToken)
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	assert.Equal(t, []bool{true, false}, pinned(), "Only the template fetched with the pinned token should be pinned")
+
+	require.NoError(t, wrapper.HotReload(ctx), "HotReload should not return an error")
+	_, _, err = wrapper.FetchChatTemplate(ctx, withToken)
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	assert.Equal(t, []bool{true}, pinned(), "The pin should be kept by a hot reload")
+
+	require.NoError(t, preprocessing.ClearCaches(ctx))
+	require.NoError(t, wrapper.HotReload(ctx), "HotReload should not return an error")
+	_, _, err = wrapper.FetchChatTemplate(ctx, withToken)
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	assert.Equal(t, []bool{false}, pinned(), "The pin should be dropped by ClearCaches")
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "context"
+
+// TemplateSelector decides the chat template of a fetch at runtime, e.g. per
+// tenant or feature flag carried by ctx. It returns the template and its
+// kwargs, and true to use them instead of fetching the model's template, or
+// false to fetch it as usual.
+type TemplateSelector func(ctx context.Context, req *FetchChatTemplateRequest) (string, map[string]interface{}, bool)
+
+// WithTemplateSelector sets a selector that FetchChatTemplate, and so the
+// renders fetching templates, invoke before fetching, ahead of the loader set
+// with WithTemplateLoader. Selected templates are not cached by the
+// processor.
+func WithTemplateSelector(selector TemplateSelector) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.templateSelector = selector
+	}
+}
+
+// selectTemplate returns the template selected for req, reporting false when
+// there is no selector or it selects none.
+func (w *ChatTemplatingProcessor) selectTemplate(ctx context.Context,
+	req *FetchChatTemplateRequest,
+) (*FetchChatTemplateResponse, bool) {
+	if w.templateSelector == nil {
+		return nil, false
+	}
+	template, kwargs, ok := w.templateSelector(ctx, req)
+	if !ok {
+		return nil, false
+	}
+	return &FetchChatTemplateResponse{
+		ChatTemplate:       template,
+		ChatTemplateKWArgs: kwargs,
+	}, true
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantKey is the context key of the tenant of a request.
+type tenantKey struct{}
+
+// TestTemplateSelector tests routing the fetches of different tenants to different templates.
+func TestTemplateSelector(t *testing.T) {
+	getGlobalWrapper()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	templates := map[string]string{
+		"acme":   "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		"globex": "{% for message in messages %}<{{ message.role }}>{{ message.content }}</{{ message.role }}>{% endfor %}",
+	}
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTemplateSelector(
+		func(ctx context.Context, _ *preprocessing.FetchChatTemplateRequest) (string, map[string]interface{}, bool) {
+			tenant, ok := ctx.Value(tenantKey{}).(string)
+			if !ok {
+				return "", nil, false
+			}
+			template, ok := templates[tenant]
+			return template, map[string]interface{}{"tenant": tenant}, ok
+		}))
+	require.NoError(t, processor.Initialize(), "Initialize should not return an error")
+	t.Cleanup(processor.Finalize)
+
+	render := func(ctx context.Context) string {
+		template, kwargs, err := processor.FetchChatTemplate(ctx, preprocessing.FetchChatTemplateRequest{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		})
+		require.NoError(t, err, "FetchChatTemplate should not return an error")
+		assert.Equal(t, ctx.Value(tenantKey{}), kwargs["tenant"], "The selected kwargs should be returned")
+		response, err := processor.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:       template,
+			ChatTemplateKWArgs: kwargs,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response.RenderedChats[0]
+	}
+
+	assert.Equal(t, "user: Hello\n", render(context.WithValue(context.Background(), tenantKey{}, "acme")))
+	assert.Equal(t, "<user>Hello</user>", render(context.WithValue(context.Background(), tenantKey{}, "globex")))
+
+	// Requests the selector does not route fetch the model's template.
+	fetchModel := preprocessing.FetchChatTemplateRequest{Model: testModelPath, IsLocalPath: true}
+	template, _, err := processor.FetchChatTemplate(context.Background(), fetchModel)
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	modelTemplate, _, err := getGlobalWrapper().FetchChatTemplate(context.Background(), fetchModel)
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+	assert.Equal(t, modelTemplate, template, "Unrouted requests should get the model's template")
+}
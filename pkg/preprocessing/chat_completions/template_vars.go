@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateStringVar is the template variable holding the current date, as used
+// by the Llama-3.x templates.
+const DateStringVar = "date_string"
+
+// dateStringLayout matches the `strftime_now("%d %b %Y")` format the
+// Llama-3.x templates fall back to when DateStringVar is not set.
+const dateStringLayout = "02 Jan 2006"
+
+// reservedTemplateVars are the names transformers binds in the template
+// context, or uses as render parameters, which TemplateVars may not shadow.
+var reservedTemplateVars = map[string]struct{}{
+	"messages":                     {},
+	"conversations":                {},
+	"tools":                        {},
+	"documents":                    {},
+	"chat_template":                {},
+	"add_generation_prompt":        {},
+	"continue_final_message":       {},
+	"return_assistant_tokens_mask": {},
+	"raise_exception":              {},
+	"strftime_now":                 {},
+}
+
+// DateString formats t as the `date_string` template variable.
+func DateString(t time.Time) string {
+	return t.Format(dateStringLayout)
+}
+
+// DateTemplateVars returns template vars setting DateStringVar to the date of t.
+func DateTemplateVars(t time.Time) map[string]interface{} {
+	return map[string]interface{}{DateStringVar: DateString(t)}
+}
+
+// validateTemplateVars checks that the request's TemplateVars collide neither
+// with reserved template names nor with its ChatTemplateKWArgs.
+func validateTemplateVars(req *RenderJinjaTemplateRequest) error {
+	for name := range req.TemplateVars {
+		if _, reserved := reservedTemplateVars[name]; reserved {
+			return fmt.Errorf("%w: %q", ErrReservedTemplateVar, name)
+		}
+		if _, ok := req.ChatTemplateKWArgs[name]; ok {
+			return fmt.Errorf("%w: %q is also set in chat_template_kwargs", ErrReservedTemplateVar, name)
+		}
+	}
+	return nil
+}
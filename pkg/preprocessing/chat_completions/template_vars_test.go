@@ -0,0 +1,77 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// llama31SystemTemplate is the system header of the Llama-3.1 chat template,
+// which falls back to a fixed date when `date_string` is not provided.
+const llama31SystemTemplate = `{{- bos_token }}
+{%- if not date_string is defined %}
+    {%- set date_string = "26 Jul 2024" %}
+{%- endif %}
+{{- "<|start_header_id|>system<|end_header_id|>\n\n" }}
+{{- "Cutting Knowledge Date: December 2023\n" }}
+{{- "Today Date: " + date_string + "\n\n" }}
+{{- "<|eot_id|>" }}
+{%- for message in messages %}
+    {{- '<|start_header_id|>' + message['role'] + '<|end_header_id|>\n\n'+ message['content'] | trim + '<|eot_id|>' }}
+{%- endfor %}`
+
+// TestRenderChatTemplateWithTemplateVars tests that typed template vars reach the template context.
+func TestRenderChatTemplateWithTemplateVars(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	date := time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "What day is it?"},
+		},
+		ChatTemplate:       llama31SystemTemplate,
+		ChatTemplateKWArgs: map[string]interface{}{"bos_token": "<|begin_of_text|>"},
+		TemplateVars:       preprocessing.DateTemplateVars(date),
+	}
+
+	response, err := wrapper.RenderChatTemplate(context.Background(), request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Contains(t, response.RenderedChats[0], "Today Date: 15 Oct 2026\n",
+		"Rendered chat should use the provided date_string")
+
+	t.Run("Reserved name", func(t *testing.T) {
+		reserved := *request
+		reserved.TemplateVars = map[string]interface{}{"messages": []interface{}{}}
+
+		_, err := wrapper.RenderChatTemplate(context.Background(), &reserved)
+		assert.ErrorIs(t, err, preprocessing.ErrReservedTemplateVar)
+	})
+
+	t.Run("Collision with kwargs", func(t *testing.T) {
+		colliding := *request
+		colliding.TemplateVars = map[string]interface{}{"bos_token": "<s>"}
+
+		_, err := wrapper.RenderChatTemplate(context.Background(), &colliding)
+		assert.ErrorIs(t, err, preprocessing.ErrReservedTemplateVar)
+	})
+}
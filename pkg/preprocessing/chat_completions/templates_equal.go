@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// TemplatesEqual reports whether models A and B, HuggingFace model IDs or
+// local model directories, render conversations identically: whether their
+// chat templates match up to line endings, and so do their template kwargs,
+// such as the special tokens. The KV-cache layer can then share prefix
+// blocks between them. Templates are fetched like FetchChatTemplate, so
+// they are served from cache when already fetched.
+func (w *ChatTemplatingProcessor) TemplatesEqual(ctx context.Context, modelA, modelB string) (bool, error) {
+	a, err := w.fetchModelTemplate(ctx, modelA)
+	if err != nil {
+		return false, err
+	}
+	b, err := w.fetchModelTemplate(ctx, modelB)
+	if err != nil {
+		return false, err
+	}
+
+	return normalizeTemplate(a.ChatTemplate) == normalizeTemplate(b.ChatTemplate) &&
+		reflect.DeepEqual(a.ChatTemplateKWArgs, b.ChatTemplateKWArgs), nil
+}
+
+// fetchModelTemplate fetches the template of model, loading it from disk
+// when it names a local directory.
+func (w *ChatTemplatingProcessor) fetchModelTemplate(ctx context.Context, model string,
+) (*FetchChatTemplateResponse, error) {
+	return w.FetchChatTemplateDetails(ctx, modelTemplateRequest(model, ""), FetchOptions{})
+}
+
+// modelTemplateRequest returns the request fetching the template of model at
+// revision, loading it from disk when model names a local directory.
+func modelTemplateRequest(model, revision string) FetchChatTemplateRequest {
+	info, err := os.Stat(model)
+	return FetchChatTemplateRequest{
+		Model:       model,
+		Revision:    revision,
+		IsLocalPath: err == nil && info.IsDir(),
+	}
+}
+
+// normalizeTemplate strips the line ending differences between templates,
+// from files saved on Windows. Other whitespace is kept: surrounding
+// whitespace is rendered as is, so it changes the prompt.
+func normalizeTemplate(template string) string {
+	return strings.ReplaceAll(template, "\r\n", "\n")
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTemplatesEqual tests comparing the templates of two models.
+func TestTemplatesEqual(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	// copyModel copies the test model, rewriting its tokenizer config with edit.
+	copyModel := func(t *testing.T, edit func(string) string) string {
+		t.Helper()
+		modelDir := filepath.Join(t.TempDir(), "model")
+		require.NoError(t, os.CopyFS(modelDir, os.DirFS(testModelPath)))
+		configPath := filepath.Join(modelDir, "tokenizer_config.json")
+		config, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(configPath, []byte(edit(string(config))), 0o600))
+		return modelDir
+	}
+
+	t.Run("Same template", func(t *testing.T) {
+		modelA := copyModel(t, func(config string) string { return config })
+		modelB := copyModel(t, func(config string) string { return config })
+
+		equal, err := wrapper.TemplatesEqual(ctx, modelA, modelB)
+		require.NoError(t, err, "TemplatesEqual should not return an error")
+		assert.True(t, equal, "Models with the same template should be equal")
+	})
+
+	t.Run("Different template", func(t *testing.T) {
+		modelB := copyModel(t, func(config string) string {
+			return strings.Replace(config, "{{ message.role }}: ", "{{ message.role }} | ", 1)
+		})
+
+		equal, err := wrapper.TemplatesEqual(ctx, testModelPath, modelB)
+		require.NoError(t, err, "TemplatesEqual should not return an error")
+		assert.False(t, equal, "Models with different templates should not be equal")
+	})
+
+	t.Run("Line endings", func(t *testing.T) {
+		modelA := copyModel(t, func(config string) string {
+			return strings.Replace(config, `{% endfor %}"`, `{% endfor %}\n"`, 1)
+		})
+		modelB := copyModel(t, func(config string) string {
+			return strings.Replace(config, `{% endfor %}"`, `{% endfor %}\r\n"`, 1)
+		})
+
+		equal, err := wrapper.TemplatesEqual(ctx, modelA, modelB)
+		require.NoError(t, err, "TemplatesEqual should not return an error")
+		assert.True(t, equal, "Models whose templates only differ in line endings should be equal")
+	})
+
+	t.Run("Trailing whitespace", func(t *testing.T) {
+		modelB := copyModel(t, func(config string) string {
+			return strings.Replace(config, `{% endfor %}"`, `{% endfor %}\n"`, 1)
+		})
+
+		equal, err := wrapper.TemplatesEqual(ctx, testModelPath, modelB)
+		require.NoError(t, err, "TemplatesEqual should not return an error")
+		assert.False(t, equal, "Models whose templates render different whitespace should not be equal")
+	})
+
+	t.Run("Different special tokens", func(t *testing.T) {
+		modelB := copyModel(t, func(config string) string {
+			return strings.Replace(config, `"eos_token": "[SEP]"`, `"eos_token": "[PAD]"`, 1)
+		})
+
+		equal, err := wrapper.TemplatesEqual(ctx, testModelPath, modelB)
+		require.NoError(t, err, "TemplatesEqual should not return an error")
+		assert.False(t, equal, "Models with different special tokens should not be equal")
+	})
+}
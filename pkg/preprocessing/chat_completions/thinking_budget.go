@@ -0,0 +1,48 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"maps"
+	"strings"
+)
+
+// thinkingBudgetKWArgs are the template variables taking the thinking token
+// budget of the known model families, in the order they are looked up in
+// templates.
+var thinkingBudgetKWArgs = []string{
+	// Seed-OSS, where -1 leaves the reasoning unbounded.
+	"thinking_budget",
+}
+
+// applyThinkingBudget sets the thinking budget variable of req's template to
+// req.ThinkingBudget. Templates without a known one are rendered as is. The
+// input request is not modified.
+func applyThinkingBudget(req *RenderJinjaTemplateRequest) *RenderJinjaTemplateRequest {
+	for _, name := range thinkingBudgetKWArgs {
+		if !strings.Contains(req.ChatTemplate, name) {
+			continue
+		}
+		budgeted := *req
+		budgeted.ChatTemplateKWArgs = make(map[string]interface{}, len(req.ChatTemplateKWArgs)+1)
+		maps.Copy(budgeted.ChatTemplateKWArgs, req.ChatTemplateKWArgs)
+		budgeted.ChatTemplateKWArgs[name] = *req.ThinkingBudget
+		budgeted.thinkingBudgetApplied = true
+		return &budgeted
+	}
+	return req
+}
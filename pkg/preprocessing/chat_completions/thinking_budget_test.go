@@ -0,0 +1,61 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedOSSThinkingTemplate announces the thinking budget like Seed-OSS's template, unless it is unset.
+const seedOSSThinkingTemplate = "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}" +
+	"{% if thinking_budget is defined %}[budget: {{ thinking_budget }} tokens]{% endif %}"
+
+// TestRenderChatTemplateThinkingBudget tests passing a thinking budget to templates supporting one.
+func TestRenderChatTemplateThinkingBudget(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	budget := 512
+	newRequest := func(template string) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:      []preprocessing.ChatMessage{{Role: "user", Content: "Prove Fermat's little theorem."}},
+			ChatTemplate:       template,
+			ChatTemplateKWArgs: map[string]interface{}{"bos_token": "<s>"},
+			ThinkingBudget:     &budget,
+		}
+	}
+
+	request := newRequest(seedOSSThinkingTemplate)
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.True(t, response.ThinkingBudgetApplied, "The template should consume the thinking budget")
+	assert.Equal(t, "user: Prove Fermat's little theorem.\n[budget: 512 tokens]", response.RenderedChats[0])
+	assert.NotContains(t, request.ChatTemplateKWArgs, "thinking_budget", "The request should not be modified")
+
+	t.Run("Unsupported template", func(t *testing.T) {
+		response, err := wrapper.RenderChatTemplate(ctx,
+			newRequest("{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}"))
+		require.NoError(t, err, "Templates without a thinking budget should render")
+		assert.False(t, response.ThinkingBudgetApplied, "The thinking budget should not be reported as applied")
+		assert.Equal(t, "user: Prove Fermat's little theorem.\n", response.RenderedChats[0])
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"os"
+
+	"C"
+)
+
+// Bounds of WithPythonThreadStackSize.
+const (
+	// MinPythonThreadStackSize is the smallest stack the interpreter thread
+	// may be given, in bytes.
+	MinPythonThreadStackSize = 1 << 20
+	// MaxPythonThreadStackSize is the largest stack the interpreter thread
+	// may be given, in bytes.
+	MaxPythonThreadStackSize = 1 << 30
+)
+
+// WithPythonThreadStackSize sets the stack size, in bytes, of the thread
+// running the interpreter, e.g. to raise it for deeply recursive templates
+// that would otherwise overflow the stack and crash the process. Every call
+// into Python runs on that one thread, so CPython's own threading.stack_size,
+// which only applies to threads started by Python, would not help.
+//
+// The thread is process-wide and started by the first call into Python, so
+// the size must be set on the first processor initialized: initializing one
+// with another size fails with ErrInitialize. The size must lie within
+// [MinPythonThreadStackSize, MaxPythonThreadStackSize], and is rounded up to
+// a whole number of pages. Zero, the default, keeps the default stack of Go
+// threads.
+func WithPythonThreadStackSize(bytes int) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.pythonThreadStackSize = bytes
+	}
+}
+
+// startInterpreterThread starts the interpreter thread with the stack size
+// of w, before the interpreter is initialized.
+func (w *ChatTemplatingProcessor) startInterpreterThread() error {
+	if w.pythonThreadStackSize == 0 {
+		return nil
+	}
+	if w.pythonThreadStackSize < MinPythonThreadStackSize || w.pythonThreadStackSize > MaxPythonThreadStackSize {
+		return fmt.Errorf("python thread stack size %d is out of bounds [%d, %d]",
+			w.pythonThreadStackSize, MinPythonThreadStackSize, MaxPythonThreadStackSize)
+	}
+	pageSize := os.Getpagesize()
+	return cgoThread.startWithStackSize((w.pythonThreadStackSize + pageSize - 1) / pageSize * pageSize)
+}
+
+// goServeExecutorCalls serves the calls of cgoThread on the thread started
+// by Py_StartExecutorThread.
+//
+//export goServeExecutorCalls
+func goServeExecutorCalls() {
+	cgoThread.serve()
+}
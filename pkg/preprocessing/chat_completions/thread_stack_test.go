@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pythonThreadStackSizeEnv is the interpreter thread stack size TestMain starts helper processes with. The thread is
+// process-wide, and already started by the time tests run, so it can only be tested in a process of its own.
+const pythonThreadStackSizeEnv = "PREPROCESSING_TEST_PYTHON_THREAD_STACK_SIZE"
+
+// TestPythonThreadStackSize tests the bounds of the interpreter thread stack size, and rendering a recursion-heavy
+// template on a raised stack.
+func TestPythonThreadStackSize(t *testing.T) {
+	getGlobalWrapper()
+	helper := os.Getenv(pythonThreadStackSizeEnv) != ""
+
+	t.Run("Out of bounds", func(t *testing.T) {
+		for _, size := range []int{4 << 10, 2 * preprocessing.MaxPythonThreadStackSize} {
+			err := preprocessing.NewChatTemplatingProcessor(preprocessing.WithPythonThreadStackSize(size)).Initialize()
+			require.ErrorIs(t, err, preprocessing.ErrInitialize, "Size %d should be rejected", size)
+			assert.ErrorContains(t, err, "out of bounds")
+		}
+	})
+
+	t.Run("Already started", func(t *testing.T) {
+		if helper {
+			t.Skip("The helper process starts the thread with a stack size")
+		}
+		err := preprocessing.NewChatTemplatingProcessor(
+			preprocessing.WithPythonThreadStackSize(64 << 20)).Initialize()
+		require.ErrorIs(t, err, preprocessing.ErrInitialize, "The running thread's stack size cannot change")
+		assert.ErrorContains(t, err, "already runs")
+	})
+
+	t.Run("Recursion-heavy template", func(t *testing.T) {
+		if !helper {
+			// #nosec G204 -- the test binary runs itself.
+			cmd := exec.Command(os.Args[0], "-test.run=^TestPythonThreadStackSize$/^Recursion-heavy_template$",
+				"-test.count=1")
+			cmd.Env = append(os.Environ(), pythonThreadStackSizeEnv+"="+strconv.Itoa(256<<20))
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, "The helper process should render without crashing:\n%s", output)
+			return
+		}
+
+		// Each level nests a macro call and the generator rendering it, deep enough to overflow small thread
+		// stacks, such as musl's 128 KiB default.
+		const depth = 150
+		response, err := getGlobalWrapper().RenderChatTemplate(context.Background(),
+			&preprocessing.RenderJinjaTemplateRequest{
+				Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+				ChatTemplate: "{% macro nest(n) %}{% if n > 0 %}({{ nest(n - 1) }}){% endif %}{% endmacro %}" +
+					"{{ nest(" + strconv.Itoa(depth) + ") }}",
+			})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, depth, strings.Count(response.RenderedChats[0], "("), "Every level should be rendered")
+	})
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTokenStreamChunkSize is the default size, in bytes, of the chunks of
+// the rendered chat RenderTokenStream tokenizes at once.
+const DefaultTokenStreamChunkSize = 64 << 10
+
+// tokenizeTextRequest is the JSON payload sent to tokenize_text.
+type tokenizeTextRequest struct {
+	Tokenizer               *TokenizerSource `json:"tokenizer"`
+	Text                    string           `json:"text"`
+	AdditionalSpecialTokens []string         `json:"additional_special_tokens,omitempty"`
+}
+
+// tokenizeTextResponse is the JSON result of tokenize_text.
+type tokenizeTextResponse struct {
+	TokenIDs      []uint32 `json:"token_ids"`
+	OffsetMapping [][2]int `json:"offset_mapping"`
+}
+
+// RenderTokenStream renders req like RenderChatTemplate and tokenizes the
+// rendered chat in chunks of about chunkSize bytes, calling emit with the
+// token IDs of each chunk in order, so the tokens of very long prompts can be
+// processed incrementally rather than encoded at once. Together, the emitted
+// tokens are those ReturnTokenIDs would return. The response holds the
+// rendered chat, without TokenIDs. A chunkSize of zero uses
+// DefaultTokenStreamChunkSize.
+//
+// Chunks end at whitespace, and start with the last token of the previous
+// chunk, so that tokens never straddle chunks; text without whitespace is
+// tokenized at once. req.Tokenizer must be set, and
+// must provide offset mappings. Special tokens added by the tokenizer and
+// truncation are not supported. An error returned by emit stops the stream
+// and is returned.
+func (w *ChatTemplatingProcessor) RenderTokenStream(ctx context.Context, req *RenderJinjaTemplateRequest,
+	chunkSize int, emit func(tokenIDs []uint32) error,
+) (*RenderJinjaTemplateResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+	if req.Tokenizer == nil {
+		return nil, fmt.Errorf("streaming tokens requires a tokenizer")
+	}
+	if req.AddSpecialTokens || req.MaxPromptTokens > 0 {
+		return nil, fmt.Errorf("streaming tokens does not support add_special_tokens nor max_prompt_tokens")
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultTokenStreamChunkSize
+	}
+
+	untokenized := *req
+	untokenized.ReturnTokenIDs = false
+	untokenized.ReturnOffsetMapping = false
+	response, err := w.RenderChatTemplate(ctx, &untokenized)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.streamTokens(ctx, req, response.RenderedChats[0], chunkSize, emit); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// streamTokens tokenizes text in chunks of about chunkSize bytes, calling
+// emit with the tokens of each chunk that the text following it cannot alter.
+func (w *ChatTemplatingProcessor) streamTokens(ctx context.Context, req *RenderJinjaTemplateRequest, text string,
+	chunkSize int, emit func(tokenIDs []uint32) error,
+) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Tokens up to boundary were emitted, the last of them starting at anchor.
+	anchor, boundary, end := 0, 0, 0
+	for boundary < len(text) {
+		end = min(end+chunkSize, len(text))
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		final := end == len(text)
+
+		var resp tokenizeTextResponse
+		if err := callPythonFunction(ctx, "tokenize_text", tokenizeTextRequest{
+			Tokenizer:               req.Tokenizer,
+			Text:                    text[anchor:end],
+			AdditionalSpecialTokens: req.AdditionalSpecialTokens,
+		}, &resp); err != nil {
+			return err
+		}
+		if len(resp.OffsetMapping) != len(resp.TokenIDs) {
+			return fmt.Errorf("tokenizer returned %d offsets for %d tokens", len(resp.OffsetMapping), len(resp.TokenIDs))
+		}
+
+		// The tokens of a word cut by the end of the chunk may change once it
+		// is complete, as may those of a whitespace run, so only the tokens
+		// before the last run of whitespace are kept. Chunks without
+		// whitespace grow until they reach some.
+		cut := len(text)
+		if !final {
+			cut = -1
+			if space := strings.LastIndexFunc(text[boundary:end], unicode.IsSpace); space >= 0 {
+				cut = boundary + len(strings.TrimRightFunc(text[boundary:boundary+space], unicode.IsSpace))
+			}
+		}
+
+		var tokens []uint32
+		chunkStart := anchor
+		for i, id := range resp.TokenIDs {
+			start, stop := chunkStart+resp.OffsetMapping[i][0], chunkStart+resp.OffsetMapping[i][1]
+			if boundary > 0 && stop <= boundary {
+				// Emitted with the previous chunk.
+				continue
+			}
+			if start < boundary {
+				return fmt.Errorf("streamed tokens diverge at byte %d of the render, use larger chunks", boundary)
+			}
+			if stop > cut {
+				break
+			}
+			tokens = append(tokens, id)
+			anchor, boundary = start, stop
+		}
+		if final {
+			boundary = len(text)
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		if err := emit(tokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
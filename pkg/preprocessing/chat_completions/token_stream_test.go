@@ -0,0 +1,97 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderTokenStream tests that the tokens streamed chunk by chunk are those of tokenizing the whole render.
+func TestRenderTokenStream(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	newRequest := func() *preprocessing.RenderJinjaTemplateRequest {
+		conversation := make([]preprocessing.ChatMessage, 0, 20)
+		for i := range 20 {
+			conversation = append(conversation, preprocessing.ChatMessage{
+				Role: "user",
+				// Multi-byte characters and long words make chunks end mid-rune and mid-word.
+				Content: fmt.Sprintf("Message %d: %s %s", i, strings.Repeat("héllo wörld 日本語 ", 5),
+					strings.Repeat("x", 50)),
+			})
+		}
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: conversation,
+			ChatTemplate:  "{% for message in messages %}{{ message.role }}: {{ message.content }}\n\n{% endfor %}",
+			Tokenizer:     &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+		}
+	}
+
+	batch := newRequest()
+	batch.ReturnTokenIDs = true
+	expected, err := wrapper.RenderChatTemplate(ctx, batch)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.NotEmpty(t, expected.TokenIDs)
+
+	for _, chunkSize := range []int{7, 64, 1000, 0} {
+		t.Run(fmt.Sprintf("Chunk size %d", chunkSize), func(t *testing.T) {
+			var streamed []uint32
+			chunks := 0
+			response, err := wrapper.RenderTokenStream(ctx, newRequest(), chunkSize, func(tokenIDs []uint32) error {
+				assert.NotEmpty(t, tokenIDs, "Chunks should not be empty")
+				streamed = append(streamed, tokenIDs...)
+				chunks++
+				return nil
+			})
+			require.NoError(t, err, "RenderTokenStream should not return an error")
+			assert.Equal(t, expected.RenderedChats, response.RenderedChats, "The render should be returned")
+			assert.Nil(t, response.TokenIDs, "Streamed tokens should not be returned at once")
+			assert.Equal(t, expected.TokenIDs, streamed, "Streamed tokens should match the batch tokens")
+			if chunkSize > 0 && chunkSize < 100 {
+				assert.Greater(t, chunks, 1, "Small chunks should stream the tokens in several calls")
+			}
+		})
+	}
+
+	t.Run("Emit error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		calls := 0
+		_, err := wrapper.RenderTokenStream(ctx, newRequest(), 64, func([]uint32) error {
+			calls++
+			return errStop
+		})
+		assert.ErrorIs(t, err, errStop, "An emit error should stop the stream")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("No tokenizer", func(t *testing.T) {
+		req := newRequest()
+		req.Tokenizer = nil
+		_, err := wrapper.RenderTokenStream(ctx, req, 0, func([]uint32) error { return nil })
+		assert.Error(t, err)
+	})
+}
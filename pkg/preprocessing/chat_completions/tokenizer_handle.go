@@ -0,0 +1,104 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// loadTokenizerResponse is the JSON result of load_tokenizer.
+type loadTokenizerResponse struct {
+	Handle   uint64 `json:"handle"`
+	NotFound string `json:"not_found,omitempty"`
+}
+
+// releaseTokenizerRequest is the JSON request of release_tokenizer.
+type releaseTokenizerRequest struct {
+	Handle uint64 `json:"handle"`
+}
+
+// TokenizerHandle is a tokenizer loaded by LoadTokenizer. Renders whose
+// Tokenizer is its Source use it directly rather than looking it up by
+// model, e.g. in tight loops counting tokens. It stays loaded, even across
+// ClearCaches, until released.
+type TokenizerHandle struct {
+	processor *ChatTemplatingProcessor
+	source    TokenizerSource
+	released  atomic.Bool
+}
+
+// LoadTokenizer loads the tokenizer of model, a HuggingFace model ID or a
+// local model path, and returns a handle to it. Models that do not exist
+// fail with ErrModelNotFound.
+func (w *ChatTemplatingProcessor) LoadTokenizer(ctx context.Context, model string) (_ *TokenizerHandle, err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	_, statErr := os.Stat(model)
+	source := TokenizerSource{Model: model, IsLocalPath: statErr == nil}
+	source.Revision = w.revisionOrDefault("", source.IsLocalPath)
+	var resp loadTokenizerResponse
+	if err := callPythonFunction(ctx, "load_tokenizer", &source, &resp); err != nil {
+		return nil, err
+	}
+	if resp.NotFound != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrModelNotFound, model, resp.NotFound)
+	}
+	source.Handle = resp.Handle
+	return &TokenizerHandle{processor: w, source: source}, nil
+}
+
+// Source returns the TokenizerSource of h, to set as
+// RenderJinjaTemplateRequest.Tokenizer. Once h is released, it looks the
+// tokenizer up by model.
+func (h *TokenizerHandle) Source() *TokenizerSource {
+	source := h.source
+	if h.released.Load() {
+		source.Handle = 0
+	}
+	return &source
+}
+
+// Release frees the tokenizer of h, unless it is also cached by model.
+// Sources returned by Source before keep working, looking the tokenizer up
+// by model. Releasing h again is a no-op.
+func (h *TokenizerHandle) Release() (err error) {
+	if h.released.Load() {
+		return nil
+	}
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := h.processor.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var resp struct{}
+	req := releaseTokenizerRequest{Handle: h.source.Handle}
+	if err := callPythonFunction(context.Background(), "release_tokenizer", &req, &resp); err != nil {
+		return err
+	}
+	h.released.Store(true)
+	return nil
+}
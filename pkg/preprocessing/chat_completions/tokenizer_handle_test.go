@@ -0,0 +1,113 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenizerHandle tests rendering with the tokenizer of a handle, across cache clears and after its release.
+func TestTokenizerHandle(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	handle, err := wrapper.LoadTokenizer(ctx, testModelPath)
+	require.NoError(t, err, "LoadTokenizer should not return an error")
+	t.Cleanup(func() { assert.NoError(t, handle.Release()) })
+	require.NotZero(t, handle.Source().Handle)
+
+	// countTokens renders with token IDs and the tokenizer of source, returning the number of tokens and
+	// of tokenizers loaded by the render.
+	countTokens := func(source *preprocessing.TokenizerSource) (tokens, loads int) {
+		before, err := preprocessing.TokenizerLoads(ctx)
+		require.NoError(t, err)
+		request := newCountRequest("Hello, how are you doing today?")
+		request.Tokenizer = source
+		counts, err := wrapper.CountTokensBatch(ctx, []*preprocessing.RenderJinjaTemplateRequest{request})
+		require.NoError(t, err, "Counting tokens should not return an error")
+		after, err := preprocessing.TokenizerLoads(ctx)
+		require.NoError(t, err)
+		return counts[0], after - before
+	}
+
+	expected, _ := countTokens(&preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true})
+	tokens, loads := countTokens(handle.Source())
+	assert.Equal(t, expected, tokens, "The handle should tokenize like its model")
+	assert.Zero(t, loads)
+
+	require.NoError(t, preprocessing.ClearCaches(ctx))
+	tokens, loads = countTokens(handle.Source())
+	assert.Equal(t, expected, tokens)
+	assert.Zero(t, loads, "The handle should keep its tokenizer loaded across cache clears")
+
+	plain, err := preprocessing.CanonicalKey(newCountRequest("Hi"))
+	require.NoError(t, err)
+	withHandle := newCountRequest("Hi")
+	withHandle.Tokenizer = handle.Source()
+	key, err := preprocessing.CanonicalKey(withHandle)
+	require.NoError(t, err)
+	assert.Equal(t, plain, key, "The handle should not be part of the canonical key")
+
+	t.Run("Release", func(t *testing.T) {
+		released, err := wrapper.LoadTokenizer(ctx, testModelPath)
+		require.NoError(t, err, "LoadTokenizer should not return an error")
+		source := released.Source()
+		require.NoError(t, released.Release(), "Release should not return an error")
+		require.NoError(t, released.Release(), "Releasing again should be a no-op")
+		assert.Zero(t, released.Source().Handle, "A released handle should look the tokenizer up by model")
+
+		tokens, _ := countTokens(source)
+		assert.Equal(t, expected, tokens, "Sources of a released handle should keep working")
+	})
+}
+
+// BenchmarkTokenizerHandle compares the per-call overhead of counting tokens with a handle and by model.
+func BenchmarkTokenizerHandle(b *testing.B) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	handle, err := wrapper.LoadTokenizer(ctx, "../../tokenization/testdata/test-model")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer handle.Release() //nolint:errcheck // The benchmark is over.
+
+	for _, bm := range []struct {
+		name   string
+		source *preprocessing.TokenizerSource
+	}{
+		{"Handle", handle.Source()},
+		{"Model", &preprocessing.TokenizerSource{Model: "../../tokenization/testdata/test-model", IsLocalPath: true}},
+	} {
+		request := newCountRequest("Hello")
+		request.Tokenizer = bm.source
+		reqs := []*preprocessing.RenderJinjaTemplateRequest{request}
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := wrapper.CountTokensBatch(ctx, reqs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
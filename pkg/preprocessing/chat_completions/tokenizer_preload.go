@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// preloadTokenizerResponse is the JSON result of preload_tokenizer.
+type preloadTokenizerResponse struct {
+	NotFound string `json:"not_found,omitempty"`
+}
+
+// WithWarmupTokenizers makes Warmup also load the tokenizer of each model
+// after fetching its template, like PreloadTokenizer, so that renders
+// requesting token IDs are fast immediately, e.g. for templates served by a
+// TemplateLoader, whose fetch loads no tokenizer.
+func WithWarmupTokenizers() Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.warmupTokenizers = true
+	}
+}
+
+// PreloadTokenizer loads and caches the tokenizer of model, a HuggingFace
+// model ID or a local model path, ahead of the first render requesting
+// token IDs with a TokenizerSource for it, which would otherwise pay the
+// load. Models that do not exist fail with ErrModelNotFound.
+func (w *ChatTemplatingProcessor) PreloadTokenizer(ctx context.Context, model string) error {
+	_, statErr := os.Stat(model)
+	isLocalPath := statErr == nil
+	return w.preloadTokenizer(ctx, &TokenizerSource{
+		Model: model, Revision: w.revisionOrDefault("", isLocalPath), IsLocalPath: isLocalPath,
+	})
+}
+
+// preloadTokenizer loads and caches the tokenizer of source.
+func (w *ChatTemplatingProcessor) preloadTokenizer(ctx context.Context, source *TokenizerSource) (err error) {
+	defer func() { err = recoverInternal(recover(), err) }()
+	release, err := w.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var resp preloadTokenizerResponse
+	if err := callPythonFunction(ctx, "preload_tokenizer", source, &resp); err != nil {
+		return err
+	}
+	if resp.NotFound != "" {
+		return fmt.Errorf("%w: %s: %s", ErrModelNotFound, source.Model, resp.NotFound)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPreloadTokenizer tests that token-ID renders after PreloadTokenizer do not load the tokenizer again.
+func TestPreloadTokenizer(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	// renderLoads renders with token IDs, returning the number of tokenizers loaded by the render.
+	renderLoads := func() int {
+		before, err := preprocessing.TokenizerLoads(ctx)
+		require.NoError(t, err)
+		response, err := wrapper.RenderChatTemplate(ctx, &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:  []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:   "{% for message in messages %}{{ message.content }}{% endfor %}",
+			ReturnTokenIDs: true,
+			Tokenizer:      &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		require.NotEmpty(t, response.TokenIDs)
+		after, err := preprocessing.TokenizerLoads(ctx)
+		require.NoError(t, err)
+		return after - before
+	}
+
+	t.Run("PreloadTokenizer", func(t *testing.T) {
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+		before, err := preprocessing.TokenizerLoads(ctx)
+		require.NoError(t, err)
+		require.NoError(t, wrapper.PreloadTokenizer(ctx, testModelPath), "PreloadTokenizer should not return an error")
+		after, err := preprocessing.TokenizerLoads(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, after-before, "PreloadTokenizer should load the tokenizer")
+		assert.Zero(t, renderLoads(), "A render after PreloadTokenizer should not load the tokenizer again")
+	})
+
+	t.Run("Warmup", func(t *testing.T) {
+		require.NoError(t, preprocessing.ClearCaches(ctx))
+		loader := &memoryTemplateLoader{templates: map[string]string{
+			testModelPath + "@": "{% for message in messages %}{{ message.content }}{% endfor %}",
+		}}
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTemplateLoader(loader),
+			preprocessing.WithWarmupTokenizers())
+		require.NoError(t, processor.Warmup(ctx, []preprocessing.FetchChatTemplateRequest{
+			{Model: testModelPath, IsLocalPath: true},
+		}), "Warmup should not return an error")
+		assert.Zero(t, renderLoads(), "A render after Warmup should not load the tokenizer again")
+	})
+}
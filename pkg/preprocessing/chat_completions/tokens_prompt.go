@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"fmt"
+)
+
+// imageModality is the vLLM modality of image placeholders.
+const imageModality = "image"
+
+// TokensPrompt is a render shaped like vLLM's `TokensPrompt`, as returned by
+// RenderToTokensPrompt, so its JSON can be handed to vLLM's offline API
+// without reshaping.
+type TokensPrompt struct {
+	PromptTokenIDs []uint32 `json:"prompt_token_ids"`
+	// MultiModalPlaceholders are the positions of the placeholder tokens of
+	// each modality, in order, as in vLLM's `multi_modal_placeholders`. Only
+	// set for conversations with images.
+	MultiModalPlaceholders map[string][]PlaceholderRange `json:"multi_modal_placeholders,omitempty"`
+}
+
+// PlaceholderRange is the position of the placeholder tokens of a
+// multimodal item, as in vLLM's `PlaceholderRange`.
+type PlaceholderRange struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
+
+// RenderToTokensPrompt renders req and returns its tokens as a TokensPrompt,
+// with the positions of its image placeholders, see
+// RenderJinjaTemplateResponse.ImageTokenSpans. req.Tokenizer must be set;
+// ReturnTokenIDs is implied. req is not modified.
+func (w *ChatTemplatingProcessor) RenderToTokensPrompt(ctx context.Context, req *RenderJinjaTemplateRequest,
+) (*TokensPrompt, error) {
+	if req == nil {
+		return nil, fmt.Errorf("received nil request")
+	}
+	if req.Tokenizer == nil {
+		return nil, fmt.Errorf("rendering a tokens prompt requires a tokenizer")
+	}
+
+	withTokens := *req
+	withTokens.ReturnTokenIDs = true
+	response, err := w.RenderChatTemplate(ctx, &withTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := &TokensPrompt{PromptTokenIDs: response.TokenIDs}
+	if prompt.PromptTokenIDs == nil {
+		// vLLM requires the field, even for an empty prompt.
+		prompt.PromptTokenIDs = []uint32{}
+	}
+	if len(response.ImageTokenSpans) > 0 {
+		images := make([]PlaceholderRange, len(response.ImageTokenSpans))
+		for i, span := range response.ImageTokenSpans {
+			images[i] = PlaceholderRange{Offset: span[0], Length: span[1] - span[0]}
+		}
+		prompt.MultiModalPlaceholders = map[string][]PlaceholderRange{imageModality: images}
+	}
+	return prompt, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderToTokensPrompt tests that renders are returned in the JSON shape of vLLM's TokensPrompt.
+func TestRenderToTokensPrompt(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	request := newCountRequest("Hello, how are you doing today?")
+	prompt, err := wrapper.RenderToTokensPrompt(ctx, request)
+	require.NoError(t, err, "RenderToTokensPrompt should not return an error")
+	withTokens := *request
+	withTokens.ReturnTokenIDs = true
+	response, err := wrapper.RenderChatTemplate(ctx, &withTokens)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, response.TokenIDs, prompt.PromptTokenIDs)
+	assert.False(t, request.ReturnTokenIDs, "The request should not be modified")
+
+	data, err := json.Marshal(prompt)
+	require.NoError(t, err)
+	var shape map[string][]int
+	require.NoError(t, json.Unmarshal(data, &shape), "prompt_token_ids should be a list of ints")
+	assert.Len(t, shape, 1, "Text-only prompts should only have token IDs")
+	assert.Len(t, shape["prompt_token_ids"], len(response.TokenIDs))
+
+	t.Run("Images", func(t *testing.T) {
+		image := preprocessing.ContentPart{
+			Type:     preprocessing.ContentPartImageURL,
+			ImageURL: &preprocessing.ImageURL{URL: "https://example.com/cat.png"},
+		}
+		request := &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{{
+				Role: "user",
+				ContentParts: []preprocessing.ContentPart{
+					image, image, {Type: preprocessing.ContentPartText, Text: "What differs between these images?"},
+				},
+			}},
+			ChatTemplate: llavaTemplate,
+			Tokenizer:    &preprocessing.TokenizerSource{Model: "../../tokenization/testdata/test-model", IsLocalPath: true},
+		}
+		prompt, err := wrapper.RenderToTokensPrompt(ctx, request)
+		require.NoError(t, err, "RenderToTokensPrompt should not return an error")
+
+		data, err := json.Marshal(prompt)
+		require.NoError(t, err)
+		var shape struct {
+			PromptTokenIDs         []int `json:"prompt_token_ids"`
+			MultiModalPlaceholders map[string][]struct {
+				Offset int `json:"offset"`
+				Length int `json:"length"`
+			} `json:"multi_modal_placeholders"`
+		}
+		require.NoError(t, json.Unmarshal(data, &shape))
+		require.Len(t, shape.MultiModalPlaceholders["image"], 2, "Each image should have a placeholder range")
+		previousEnd := 0
+		for _, placeholder := range shape.MultiModalPlaceholders["image"] {
+			assert.Positive(t, placeholder.Length)
+			assert.GreaterOrEqual(t, placeholder.Offset, previousEnd, "Placeholder ranges should be ordered")
+			previousEnd = placeholder.Offset + placeholder.Length
+		}
+		assert.LessOrEqual(t, previousEnd, len(shape.PromptTokenIDs))
+	})
+
+	t.Run("No tokenizer", func(t *testing.T) {
+		request := newCountRequest("Hello")
+		request.Tokenizer = nil
+		_, err := wrapper.RenderToTokensPrompt(ctx, request)
+		assert.Error(t, err, "Rendering a tokens prompt without a tokenizer should fail")
+	})
+}
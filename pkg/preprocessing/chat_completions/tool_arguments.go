@@ -0,0 +1,101 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ToolArguments are the JSON-encoded arguments of a tool call. They decode
+// from a JSON string, as in the OpenAI API, or from a JSON object, as some
+// clients send them, kept as its compact encoding, so that both forms render
+// alike.
+type ToolArguments string
+
+// UnmarshalJSON decodes arguments sent as a JSON string or as any other JSON
+// value.
+func (a *ToolArguments) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var arguments string
+		if err := json.Unmarshal(data, &arguments); err != nil {
+			return err
+		}
+		*a = ToolArguments(arguments)
+		return nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	*a = ToolArguments(compact.String())
+	return nil
+}
+
+// UnmarshalJSON decodes a tool call function, accepting Arguments sent as a
+// JSON string or as any other JSON value.
+func (f *ToolCallFunction) UnmarshalJSON(data []byte) error {
+	type plainFunction ToolCallFunction
+	var decoded struct {
+		plainFunction
+		Arguments ToolArguments `json:"arguments"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*f = ToolCallFunction(decoded.plainFunction)
+	f.Arguments = string(decoded.Arguments)
+	return nil
+}
+
+// ToolArgsFormat is the form tool call arguments are passed to templates in.
+type ToolArgsFormat string
+
+const (
+	// ToolArgsObject decodes arguments into a mapping, as vLLM does, for
+	// templates iterating over them or passing them to `tojson`, such as
+	// Llama-3's and Mistral's. Arguments that are not valid JSON are passed
+	// as is.
+	ToolArgsObject ToolArgsFormat = "object"
+	// ToolArgsString passes arguments as their JSON text, for templates
+	// rendering them verbatim.
+	ToolArgsString ToolArgsFormat = "string"
+)
+
+// WithToolArgsFormat sets the form tool call arguments are passed to
+// templates in, for requests not setting
+// RenderJinjaTemplateRequest.ToolArgsFormat. Defaults to ToolArgsObject.
+func WithToolArgsFormat(format ToolArgsFormat) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.toolArgsFormat = format
+	}
+}
+
+// validate checks that format is empty or a known tool arguments format.
+func (format ToolArgsFormat) validate() error {
+	switch format {
+	case "", ToolArgsObject, ToolArgsString:
+		return nil
+	default:
+		return fmt.Errorf("invalid tool arguments format %q, expected %q or %q", format, ToolArgsObject, ToolArgsString)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolCallsTestTemplate renders tool calls and tool results the way tool-use templates do.
+const toolCallsTestTemplate = `{% for message in messages %}
+{%- if message.tool_calls %}{% for tool_call in message.tool_calls %}[CALL {{ tool_call.function.name }} ` +
+	`{{ tool_call.function.arguments | tojson }}]{% endfor %}
+{%- elif message.role == 'tool' %}[RESULT {{ message.tool_call_id }}: {{ message.content }}]
+{%- else %}{{ message.role }}: {{ message.content }}{% endif %}{{ "\n" }}
+{%- endfor %}`
+
+// TestRenderChatTemplateToolCalls tests rendering an assistant tool call followed by its tool result.
+func TestRenderChatTemplateToolCalls(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "What is the weather in Paris?"},
+			{Role: "assistant", ToolCalls: []preprocessing.ToolCall{{
+				ID:   "call_1",
+				Type: "function",
+				Function: preprocessing.ToolCallFunction{
+					Name:      "get_weather",
+					Arguments: `{"city": "Paris"}`,
+				},
+			}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "22C"},
+		},
+		ChatTemplate: toolCallsTestTemplate,
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	rendered := response.RenderedChats[0]
+	assert.Contains(t, rendered, "user: What is the weather in Paris?\n", "String-only messages should render as before")
+	assert.Contains(t, rendered, `[CALL get_weather {"city": "Paris"}]`, "Tool call arguments should render as a mapping")
+	assert.Contains(t, rendered, "[RESULT call_1: 22C]", "Tool results should render with their call ID")
+}
+
+// TestChatMessageJSON tests that messages with tool calls round-trip through JSON, and string-only ones are unchanged.
+func TestChatMessageJSON(t *testing.T) {
+	message := preprocessing.ChatMessage{
+		Role: "assistant",
+		ToolCalls: []preprocessing.ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: preprocessing.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}},
+	}
+	data, err := json.Marshal(message)
+	require.NoError(t, err)
+	var decoded preprocessing.ChatMessage
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, message, decoded, "Tool calls should round-trip through JSON")
+
+	data, err = json.Marshal(preprocessing.ChatMessage{Role: "user", Content: "Hello"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role": "user", "content": "Hello"}`, string(data), "String-only messages should be unchanged")
+}
+
+// TestRenderChatTemplateToolArgsFormat tests that tool call arguments sent as a JSON string or as an object render
+// alike, in the form requested by ToolArgsFormat.
+func TestRenderChatTemplateToolArgsFormat(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	const body = `{"messages": [
+		{"role": "user", "content": "What is the weather in Paris?"},
+		{"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function",
+			"function": {"name": "get_weather", "arguments": %s}}]}
+	], "chat_template": %q}`
+	const template = "{% for message in messages %}{% for tool_call in message.tool_calls or [] %}" +
+		"{% if tool_call.function.arguments is string %}[TEXT {{ tool_call.function.arguments }}]" +
+		"{% else %}[OBJECT {{ tool_call.function.arguments | tojson }}]{% endif %}{% endfor %}{% endfor %}"
+	stringArguments, err := wrapper.DecodeRenderRequest(strings.NewReader(
+		fmt.Sprintf(body, `"{\"city\": \"Paris\"}"`, template)))
+	require.NoError(t, err, "DecodeRenderRequest should accept string arguments")
+	objectArguments, err := wrapper.DecodeRenderRequest(strings.NewReader(
+		fmt.Sprintf(body, `{"city": "Paris"}`, template)))
+	require.NoError(t, err, "DecodeRenderRequest should accept object arguments")
+
+	for _, tt := range []struct {
+		format   preprocessing.ToolArgsFormat
+		expected string
+	}{
+		{preprocessing.ToolArgsObject, `[OBJECT {"city": "Paris"}]`},
+		{preprocessing.ToolArgsString, `[TEXT {"city":"Paris"}]`},
+	} {
+		t.Run(string(tt.format), func(t *testing.T) {
+			processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithToolArgsFormat(tt.format))
+			require.NoError(t, processor.Initialize())
+			t.Cleanup(processor.Finalize)
+
+			for _, request := range []*preprocessing.RenderJinjaTemplateRequest{stringArguments, objectArguments} {
+				response, err := processor.RenderChatTemplate(ctx, request)
+				require.NoError(t, err, "RenderChatTemplate should not return an error")
+				if tt.format == preprocessing.ToolArgsString && request == stringArguments {
+					// String arguments are passed as sent.
+					assert.Equal(t, `[TEXT {"city": "Paris"}]`, response.RenderedChats[0])
+					continue
+				}
+				assert.Equal(t, tt.expected, response.RenderedChats[0])
+			}
+		})
+	}
+
+	invalid := *objectArguments
+	invalid.ToolArgsFormat = "yaml"
+	_, err = wrapper.RenderChatTemplate(ctx, &invalid)
+	assert.Error(t, err, "An unknown tool arguments format should be rejected")
+}
+
+// TestToolArgumentsJSON tests decoding tool call arguments sent as a JSON string or as another JSON value.
+func TestToolArgumentsJSON(t *testing.T) {
+	for input, expected := range map[string]preprocessing.ToolArguments{
+		`"{\"city\": \"Paris\"}"`:           `{"city": "Paris"}`,
+		`{"city": "Paris", "days": [1, 2]}`: `{"city":"Paris","days":[1,2]}`,
+		`null`:                              ``,
+	} {
+		var arguments preprocessing.ToolArguments
+		require.NoError(t, json.Unmarshal([]byte(input), &arguments), "Arguments %s should decode", input)
+		assert.Equal(t, expected, arguments, "Arguments %s should decode", input)
+
+		var function preprocessing.ToolCallFunction
+		data := []byte(`{"name": "get_weather", "arguments": ` + input + `}`)
+		require.NoError(t, json.Unmarshal(data, &function), "Function with arguments %s should decode", input)
+		assert.Equal(t, preprocessing.ToolCallFunction{Name: "get_weather", Arguments: string(expected)}, function,
+			"Function with arguments %s should decode", input)
+	}
+}
+
+// TestRenderChatTemplateToolMessages tests rendering tool results with string and structured content, answering an
+// earlier tool call.
+func TestRenderChatTemplateToolMessages(t *testing.T) {
+	getGlobalWrapper()
+	ctx := context.Background()
+
+	const template = "{% for message in messages %}{% if message.role == 'tool' %}" +
+		"[RESULT {{ message.tool_call_id }}: {% if message.content is string %}{{ message.content }}" +
+		"{% else %}{{ message.content | tojson }}{% endif %}]{% endif %}{% endfor %}"
+	newRequest := func(result preprocessing.ChatMessage) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "What is the weather in Paris?"},
+				{Role: "assistant", ToolCalls: []preprocessing.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: preprocessing.ToolCallFunction{Name: "get_weather", Arguments: `{"city": "Paris"}`},
+				}}},
+				result,
+			},
+			ChatTemplate: template,
+		}
+	}
+
+	for _, format := range []preprocessing.WireFormat{preprocessing.WireFormatJSON, preprocessing.WireFormatMsgpack} {
+		processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithWireFormat(format))
+		require.NoError(t, processor.Initialize())
+		t.Cleanup(processor.Finalize)
+
+		response, err := processor.RenderChatTemplate(ctx, newRequest(
+			preprocessing.ChatMessage{Role: "tool", ToolCallID: "call_1", Content: "22C"}))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, "[RESULT call_1: 22C]", response.RenderedChats[0], "String results should render as is")
+
+		response, err = processor.RenderChatTemplate(ctx, newRequest(preprocessing.ChatMessage{
+			Role:              "tool",
+			ToolCallID:        "call_1",
+			StructuredContent: map[string]interface{}{"temperature": float64(22), "unit": "C"},
+		}))
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		assert.Equal(t, `[RESULT call_1: {"temperature": 22, "unit": "C"}]`, response.RenderedChats[0],
+			"Structured results should render as a mapping")
+	}
+
+	wrapper := getGlobalWrapper()
+	_, err := wrapper.RenderChatTemplate(ctx, newRequest(
+		preprocessing.ChatMessage{Role: "tool", ToolCallID: "call_2", Content: "22C"}))
+	assert.Error(t, err, "A tool message answering an unknown tool call should be rejected")
+
+	_, err = wrapper.RenderChatTemplate(ctx, newRequest(
+		preprocessing.ChatMessage{Role: "user", StructuredContent: map[string]interface{}{"city": "Paris"}}))
+	assert.Error(t, err, "Structured content should be rejected outside tool messages")
+
+	_, err = wrapper.RenderChatTemplate(ctx, newRequest(preprocessing.ChatMessage{
+		Role:              "tool",
+		ToolCallID:        "call_1",
+		Content:           "22C",
+		StructuredContent: map[string]interface{}{"temperature": 22},
+	}))
+	assert.Error(t, err, "A tool message should not have both content and structured content")
+}
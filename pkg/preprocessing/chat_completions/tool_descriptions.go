@@ -0,0 +1,81 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"maps"
+	"slices"
+	"unicode/utf8"
+)
+
+// toolDescriptionEllipsis marks the end of a truncated tool description.
+const toolDescriptionEllipsis = "..."
+
+// truncateToolDescriptions returns tools with each description longer than
+// maxBytes cut to at most maxBytes bytes, ending with toolDescriptionEllipsis,
+// along with the names of the tools truncated. Both the OpenAI form, with the
+// description under "function", and the flat form are handled. tools is not
+// modified.
+func truncateToolDescriptions(tools []interface{}, maxBytes int) ([]interface{}, []string) {
+	var out []interface{}
+	var truncated []string
+	for i, tool := range tools {
+		schema, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		function, nested := schema["function"].(map[string]interface{})
+		if !nested {
+			function = schema
+		}
+		description, ok := function["description"].(string)
+		if !ok || len(description) <= maxBytes {
+			continue
+		}
+
+		shortened := maps.Clone(function)
+		shortened["description"] = truncateDescription(description, maxBytes)
+		if nested {
+			wrapped := maps.Clone(schema)
+			wrapped["function"] = shortened
+			shortened = wrapped
+		}
+		if out == nil {
+			out = slices.Clone(tools)
+		}
+		out[i] = shortened
+		var name string
+		if n, ok := function["name"].(string); ok {
+			name = n
+		}
+		truncated = append(truncated, name)
+	}
+	if out == nil {
+		return tools, nil
+	}
+	return out, truncated
+}
+
+// truncateDescription cuts description to at most maxBytes bytes, including
+// toolDescriptionEllipsis, without splitting a UTF-8 sequence.
+func truncateDescription(description string, maxBytes int) string {
+	cut := max(maxBytes-len(toolDescriptionEllipsis), 0)
+	for cut > 0 && !utf8.RuneStart(description[cut]) {
+		cut--
+	}
+	return description[:cut] + toolDescriptionEllipsis
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMaxToolDescriptionBytes tests that oversized tool descriptions are truncated before rendering and reported.
+func TestMaxToolDescriptionBytes(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	oversized := strings.Repeat("Searches the web. ", 100)
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		Tools: []interface{}{
+			map[string]interface{}{
+				"type":     "function",
+				"function": map[string]interface{}{"name": "search", "description": oversized},
+			},
+			map[string]interface{}{
+				"type":     "function",
+				"function": map[string]interface{}{"name": "clock", "description": "Tells the time."},
+			},
+		},
+		ChatTemplate: "{% for tool in tools %}[{{ tool.function.description }}]{% endfor %}" +
+			"{% for message in messages %}{{ message.content }}{% endfor %}",
+		MaxToolDescriptionBytes: 32,
+	}
+
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, []string{"search"}, response.TruncatedTools, "Only the oversized tool should be truncated")
+	require.Len(t, response.RenderedChats, 1)
+	assert.Equal(t, "[Searches the web. Searches th...][Tells the time.]Hello", response.RenderedChats[0],
+		"The oversized description should be cut to 32 bytes, ending with an ellipsis")
+
+	function := request.Tools[0].(map[string]interface{})["function"].(map[string]interface{})
+	assert.Equal(t, oversized, function["description"], "The request's tools should not be modified")
+
+	request.MaxToolDescriptionBytes = 2
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	assert.Error(t, err, "A bound leaving no room for the description should be rejected")
+
+	request.MaxToolDescriptionBytes = 3
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "[...][Tells the time.]Hello", response.RenderedChats[0],
+		"A bound of 3 bytes should keep only the ellipsis")
+
+	request.MaxToolDescriptionBytes = 0
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Empty(t, response.TruncatedTools, "Descriptions should not be truncated by default")
+	assert.Contains(t, response.RenderedChats[0], oversized)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+const toolRole = "tool"
+
+// validateToolMessages checks that the tool messages of req answer a tool
+// call of an earlier message, and that only they have structured content.
+func validateToolMessages(req *RenderJinjaTemplateRequest) error {
+	var toolCallIDs map[string]bool
+	for i := range req.Conversations {
+		message := &req.Conversations[i]
+		if message.StructuredContent != nil {
+			if message.Role != toolRole {
+				return fmt.Errorf("message %d has structured content, which only tool messages may have", i)
+			}
+			if message.Content != "" {
+				return fmt.Errorf("message %d has both content and structured content", i)
+			}
+		}
+		if message.Role == toolRole && message.ToolCallID != "" && !toolCallIDs[message.ToolCallID] {
+			return fmt.Errorf("tool message %d answers unknown tool call %q", i, message.ToolCallID)
+		}
+		for _, toolCall := range message.ToolCalls {
+			if toolCall.ID == "" {
+				continue
+			}
+			if toolCallIDs == nil {
+				toolCallIDs = make(map[string]bool)
+			}
+			toolCallIDs[toolCall.ID] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// toolPlacementKWArg is a template variable selecting where a model family's
+// template renders the tool definitions.
+type toolPlacementKWArg struct {
+	name string
+	// inUserMessage is the value of the variable placing the tools in the
+	// first user message rather than in the system message.
+	inUserMessage bool
+}
+
+// toolPlacementKWArgs are the tool placement variables of the known model
+// families, in the order they are looked up in templates.
+var toolPlacementKWArgs = []toolPlacementKWArg{
+	// Llama-3.1 and later, defaulting to the first user message.
+	{name: "tools_in_user_message", inUserMessage: true},
+}
+
+// applyToolPlacement sets the tool placement variable of req's template to
+// req.ToolsInUserMessage. It fails for templates without a known one. The
+// input request is not modified.
+func applyToolPlacement(req *RenderJinjaTemplateRequest) (*RenderJinjaTemplateRequest, error) {
+	for _, kwarg := range toolPlacementKWArgs {
+		if !strings.Contains(req.ChatTemplate, kwarg.name) {
+			continue
+		}
+		placed := *req
+		placed.ChatTemplateKWArgs = make(map[string]interface{}, len(req.ChatTemplateKWArgs)+1)
+		maps.Copy(placed.ChatTemplateKWArgs, req.ChatTemplateKWArgs)
+		placed.ChatTemplateKWArgs[kwarg.name] = *req.ToolsInUserMessage == kwarg.inUserMessage
+		return &placed, nil
+	}
+	return nil, fmt.Errorf("tools_in_user_message is set, but the chat template does not support configuring tool placement")
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// llama31ToolsTemplate places tools like Llama-3.1's template, in the first user message unless
+// tools_in_user_message is false.
+const llama31ToolsTemplate = "{% if tools_in_user_message is not defined %}{% set tools_in_user_message = true %}{% endif %}" +
+	"{% for message in messages %}{{ message.role }}: " +
+	"{% if tools and message.role == ('user' if tools_in_user_message else 'system') %}" +
+	"[tools: {{ tools | map(attribute='function.name') | join(', ') }}] {% endif %}" +
+	"{{ message.content }}\n{% endfor %}"
+
+// TestRenderChatTemplateToolsInUserMessage tests placing tools in the system or the first user message.
+func TestRenderChatTemplateToolsInUserMessage(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	newRequest := func(toolsInUserMessage bool) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "system", Content: "You are a helpful assistant."},
+				{Role: "user", Content: "What is the weather in Paris?"},
+			},
+			Tools: []interface{}{map[string]interface{}{
+				"type":     "function",
+				"function": map[string]interface{}{"name": "get_weather"},
+			}},
+			ChatTemplate:       llama31ToolsTemplate,
+			ToolsInUserMessage: &toolsInUserMessage,
+		}
+	}
+
+	inUser, err := wrapper.RenderChatTemplate(ctx, newRequest(true))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "system: You are a helpful assistant.\nuser: [tools: get_weather] What is the weather in Paris?\n",
+		inUser.RenderedChats[0])
+
+	inSystem, err := wrapper.RenderChatTemplate(ctx, newRequest(false))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, "system: [tools: get_weather] You are a helpful assistant.\nuser: What is the weather in Paris?\n",
+		inSystem.RenderedChats[0])
+
+	t.Run("Unsupported template", func(t *testing.T) {
+		req := newRequest(true)
+		req.ChatTemplate = "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}"
+		_, err := wrapper.RenderChatTemplate(ctx, req)
+		assert.ErrorContains(t, err, "does not support configuring tool placement")
+	})
+}
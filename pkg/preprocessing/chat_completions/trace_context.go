@@ -0,0 +1,35 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// traceParentPattern matches a W3C Trace Context `traceparent`: version,
+// trace ID, parent span ID and flags, in lowercase hex.
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// validateTraceParent checks that traceParent, if set, is a well-formed
+// `traceparent`, so that malformed IDs are not propagated into traces.
+func validateTraceParent(traceParent string) error {
+	if traceParent != "" && !traceParentPattern.MatchString(traceParent) {
+		return fmt.Errorf("invalid traceparent %q", traceParent)
+	}
+	return nil
+}
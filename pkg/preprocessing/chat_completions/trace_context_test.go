@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateTraceParent tests that the traceparent of a render is echoed with the interpreter-side duration.
+func TestRenderChatTemplateTraceParent(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+		TraceParent:   traceParent,
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, traceParent, response.TraceParent, "The traceparent should be echoed in the response")
+	assert.Positive(t, response.InterpreterDuration, "The interpreter-side duration should be reported")
+
+	request.TraceParent = ""
+	response, err = wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Empty(t, response.TraceParent)
+	assert.Zero(t, response.InterpreterDuration, "The duration should only be reported with a traceparent")
+
+	request.TraceParent = "not-a-traceparent"
+	_, err = wrapper.RenderChatTemplate(ctx, request)
+	assert.Error(t, err, "A malformed traceparent should be rejected")
+}
+
+// TestRenderChatTemplateTraceParentHidden tests that the traceparent of a render is not a template variable.
+func TestRenderChatTemplateTraceParentHidden(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{% if traceparent is defined %}{{ traceparent }}{% else %}hidden{% endif %}",
+		TraceParent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, request)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.Len(t, response.RenderedChats, 1)
+	assert.Equal(t, "hidden", response.RenderedChats[0], "The template should not see the traceparent")
+}
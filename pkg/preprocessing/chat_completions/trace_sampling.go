@@ -0,0 +1,89 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// WithTraceSampling instruments a fraction rate, between 0 and 1, of
+// renders in detail: their duration and the interpreter's memory statistics
+// before and after, logged at debug verbosity. Reading the statistics takes
+// extra calls into Python, which is too costly for every render in
+// production. Defaults to 0, no sampling.
+func WithTraceSampling(rate float64) Option {
+	return func(w *ChatTemplatingProcessor) {
+		w.traceSampleRate = min(max(rate, 0), 1)
+	}
+}
+
+// traceSample is the instrumentation of a sampled render.
+type traceSample struct {
+	start  time.Time
+	before PyMemStats
+}
+
+// startTraceSample decides whether the render starting is sampled, and if so
+// starts instrumenting it. It returns nil for renders not sampled.
+func (w *ChatTemplatingProcessor) startTraceSample(ctx context.Context) *traceSample {
+	//nolint:gosec // Sampling does not need a cryptographic source.
+	if w.traceSampleRate <= 0 || rand.Float64() >= w.traceSampleRate {
+		return nil
+	}
+
+	before, err := PythonMemoryUsage(ctx)
+	if err != nil {
+		log.FromContext(ctx).V(logging.DEBUG).Error(err, "Failed to sample Python memory usage")
+	}
+	return &traceSample{start: time.Now(), before: before}
+}
+
+// finish logs the instrumentation of a sampled render. It is a no-op on the
+// nil sample of renders not sampled.
+func (s *traceSample) finish(ctx context.Context, response *RenderJinjaTemplateResponse, renderErr error) {
+	if s == nil {
+		return
+	}
+	duration := time.Since(s.start)
+	logger := log.FromContext(ctx).V(logging.DEBUG).WithName("RenderChatTemplate")
+
+	after, err := PythonMemoryUsage(ctx)
+	if err != nil {
+		logger.Error(err, "Failed to sample Python memory usage")
+	}
+	keysAndValues := []interface{}{
+		"duration", duration,
+		"allocatedBlocksDelta", after.AllocatedBlocks - s.before.AllocatedBlocks,
+		"gcObjectsDelta", after.GCObjects - s.before.GCObjects,
+		"residentBytes", after.ResidentBytes,
+		"residentBytesDelta", after.ResidentBytes - s.before.ResidentBytes,
+	}
+	if renderErr != nil {
+		logger.Error(renderErr, "Sampled render failed", keysAndValues...)
+		return
+	}
+	keysAndValues = append(keysAndValues,
+		"renderedBytes", len(response.RenderedChats[0]),
+		"tokens", len(response.TokenIDs),
+		"compileCacheHit", response.CompileCacheHit)
+	logger.Info("Sampled render", keysAndValues...)
+}
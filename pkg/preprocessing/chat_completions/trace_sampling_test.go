@@ -0,0 +1,66 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TestTraceSampling tests that roughly the configured fraction of renders is traced in detail.
+func TestTraceSampling(t *testing.T) {
+	getGlobalWrapper()
+
+	sampled := 0
+	logger := funcr.New(func(_, args string) {
+		if strings.Contains(args, `"Sampled render"`) {
+			sampled++
+		}
+	}, funcr.Options{Verbosity: logging.TRACE})
+	ctx := log.IntoContext(context.Background(), logger)
+
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+	}
+	render := func(t *testing.T, processor *preprocessing.ChatTemplatingProcessor, calls int) {
+		t.Helper()
+		for range calls {
+			_, err := processor.RenderChatTemplate(ctx, request)
+			require.NoError(t, err, "RenderChatTemplate should not return an error")
+		}
+	}
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithTraceSampling(0.2))
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+	render(t, processor, 1000)
+	// The expected 200 samples have a standard deviation of about 13.
+	assert.InDelta(t, 200, sampled, 60, "About a fifth of the renders should be sampled")
+
+	sampled = 0
+	render(t, preprocessing.NewChatTemplatingProcessor(), 100)
+	assert.Zero(t, sampled, "Renders should not be sampled by default")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTrimTrailingWhitespace tests trimmed vs untrimmed renders.
+func TestTrimTrailingWhitespace(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	// The test model's template ends every turn with a newline.
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	render := func(addGenerationPrompt, trim bool) string {
+		t.Helper()
+		response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+			Conversations:          []preprocessing.ChatMessage{{Role: "user", Content: "Hello"}},
+			ChatTemplate:           template,
+			ChatTemplateKWArgs:     templateVars,
+			AddGenerationPrompt:    addGenerationPrompt,
+			TrimTrailingWhitespace: trim,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		return response.RenderedChats[0]
+	}
+
+	untrimmed := render(false, false)
+	trimmed := render(false, true)
+	assert.True(t, strings.HasSuffix(untrimmed, "\n"), "Untrimmed render should keep its trailing newline")
+	assert.Equal(t, strings.TrimRight(untrimmed, " \t\r\n"), trimmed, "Trimmed render should only lose trailing whitespace")
+
+	// The generation prompt, including its trailing whitespace, is never trimmed.
+	assert.Equal(t, render(true, false), render(true, true), "Renders with a generation prompt should not be trimmed")
+}
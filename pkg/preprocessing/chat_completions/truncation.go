@@ -0,0 +1,41 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// TruncationSide is the side of the tokenized chat tokens are removed from
+// to fit RenderJinjaTemplateRequest.MaxPromptTokens, named as transformers'
+// `truncation_side`.
+type TruncationSide string
+
+const (
+	// TruncationLeft removes the earliest tokens, keeping the most recent turns.
+	TruncationLeft TruncationSide = "left"
+	// TruncationRight removes the latest tokens, keeping the earliest turns.
+	TruncationRight TruncationSide = "right"
+)
+
+// validate checks that side is empty or a known truncation side.
+func (side TruncationSide) validate() error {
+	switch side {
+	case "", TruncationLeft, TruncationRight:
+		return nil
+	default:
+		return fmt.Errorf("invalid truncation side %q, expected %q or %q", side, TruncationLeft, TruncationRight)
+	}
+}
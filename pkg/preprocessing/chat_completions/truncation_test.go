@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateTruncationSide tests that left and right truncation keep different ends of the tokens.
+func TestRenderChatTemplateTruncationSide(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	newRequest := func(maxTokens int, side preprocessing.TruncationSide) *preprocessing.RenderJinjaTemplateRequest {
+		return &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "What is the capital of France?"},
+				{Role: "assistant", Content: "The capital of France is Paris."},
+				{Role: "user", Content: "And of Italy?"},
+			},
+			ChatTemplate:        "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+			ReturnOffsetMapping: true,
+			Tokenizer:           &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+			MaxPromptTokens:     maxTokens,
+			TruncationSide:      side,
+		}
+	}
+
+	full, err := wrapper.RenderChatTemplate(ctx, newRequest(0, ""))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	const maxTokens = 5
+	require.Greater(t, len(full.TokenIDs), maxTokens, "The conversation should exceed the budget")
+	excess := len(full.TokenIDs) - maxTokens
+
+	left, err := wrapper.RenderChatTemplate(ctx, newRequest(maxTokens, preprocessing.TruncationLeft))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, full.TokenIDs[excess:], left.TokenIDs, "Left truncation should keep the most recent tokens")
+	assert.Equal(t, full.OffsetMapping[excess:], left.OffsetMapping, "Offsets should be truncated with the tokens")
+	assert.Equal(t, excess, left.TruncatedTokens)
+
+	right, err := wrapper.RenderChatTemplate(ctx, newRequest(maxTokens, preprocessing.TruncationRight))
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Equal(t, full.TokenIDs[:maxTokens], right.TokenIDs, "Right truncation should keep the earliest tokens")
+	assert.Equal(t, full.OffsetMapping[:maxTokens], right.OffsetMapping, "Offsets should be truncated with the tokens")
+	assert.Equal(t, excess, right.TruncatedTokens)
+
+	assert.Equal(t, full.RenderedChats, left.RenderedChats, "The rendered chat should not be truncated")
+
+	_, err = wrapper.RenderChatTemplate(ctx, newRequest(maxTokens, "middle"))
+	assert.Error(t, err, "An unknown truncation side should be rejected")
+}
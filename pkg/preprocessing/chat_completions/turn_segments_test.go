@@ -0,0 +1,142 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateTurnSegments tests splitting a three-message conversation into three segments.
+func TestRenderChatTemplateTurnSegments(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi there"},
+		},
+		ChatTemplate:          template,
+		ChatTemplateKWArgs:    templateVars,
+		ReturnPerTurnSegments: true,
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.Len(t, response.TurnSegments, 1, "There should be segments for the single conversation")
+
+	segments := response.TurnSegments[0]
+	assert.Equal(t, []string{
+		"system: You are a helpful assistant.\n",
+		"user: Hello\n",
+		"assistant: Hi there\n",
+	}, segments, "Each message should map to its own segment")
+	assert.Equal(t, response.RenderedChats[0], strings.Join(segments, ""), "Segments should join into the rendered chat")
+}
+
+// TestRenderChatTemplateRoleBoundaries tests mapping a three-message conversation to three token ranges.
+func TestRenderChatTemplateRoleBoundaries(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi there"},
+		},
+		ChatTemplate:          "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		ReturnTokenIDs:        true,
+		ReturnPerTurnSegments: true,
+		ReturnRoleBoundaries:  true,
+		Tokenizer:             &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, req)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	boundaries := response.RoleBoundaries
+	require.Len(t, boundaries, 3, "Each message should have its boundary")
+	assert.Equal(t, []string{"system", "user", "assistant"},
+		[]string{boundaries[0].Role, boundaries[1].Role, boundaries[2].Role})
+	assert.Zero(t, boundaries[0].StartToken, "The first message should start the tokens")
+	assert.Equal(t, len(response.TokenIDs), boundaries[2].EndToken, "The last message should end the tokens")
+	for i, boundary := range boundaries {
+		if i > 0 {
+			assert.Equal(t, boundaries[i-1].EndToken, boundary.StartToken, "Boundaries should be contiguous")
+		}
+		// The template ends each turn with a newline, so turns tokenize on their own as in the whole render.
+		assert.Equal(t, response.TurnTokenCounts[i], boundary.EndToken-boundary.StartToken,
+			"The %s message should span the tokens of its segment", boundary.Role)
+	}
+
+	noTokens := *req
+	noTokens.ReturnTokenIDs = false
+	_, err = wrapper.RenderChatTemplate(ctx, &noTokens)
+	assert.Error(t, err, "Role boundaries should require token IDs")
+}
+
+// TestRenderChatTemplatePerMessageTokens tests that the per-message tokens and the template overhead add up to the
+// tokens of the render.
+func TestRenderChatTemplatePerMessageTokens(t *testing.T) {
+	wrapper := getGlobalWrapper()
+	ctx := context.Background()
+	testModelPath := "../../tokenization/testdata/test-model"
+
+	req := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi there"},
+		},
+		ChatTemplate:           "{% for message in messages %}{{ message.role }}: {{ message.content }}\n{% endfor %}",
+		ReturnTokenIDs:         true,
+		ReturnRoleBoundaries:   true,
+		ReturnPerMessageTokens: true,
+		Tokenizer:              &preprocessing.TokenizerSource{Model: testModelPath, IsLocalPath: true},
+	}
+	response, err := wrapper.RenderChatTemplate(ctx, req)
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+
+	require.Len(t, response.PerMessageTokens, 3, "Each message should have its count")
+	total := response.TemplateOverheadTokens
+	for i, tokens := range response.PerMessageTokens {
+		boundary := response.RoleBoundaries[i]
+		assert.Positive(t, tokens, "The %s message should have tokens", boundary.Role)
+		assert.Less(t, tokens, boundary.EndToken-boundary.StartToken,
+			"The role header of the %s message should not be attributed to it", boundary.Role)
+		total += tokens
+	}
+	assert.Positive(t, response.TemplateOverheadTokens, "The role headers should be template overhead")
+	assert.Equal(t, len(response.TokenIDs), total, "The per-message counts and the overhead should add up to the total")
+
+	noTokens := *req
+	noTokens.ReturnTokenIDs = false
+	noTokens.ReturnRoleBoundaries = false
+	_, err = wrapper.RenderChatTemplate(ctx, &noTokens)
+	assert.ErrorContains(t, err, "return_per_message_tokens requires return_token_ids")
+}
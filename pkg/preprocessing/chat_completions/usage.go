@@ -0,0 +1,53 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import "fmt"
+
+// Usage mirrors the `usage` object of OpenAI-compatible API responses.
+// Only the prompt side is known at templating time, so CompletionTokens is
+// zero and TotalTokens equals PromptTokens until the caller adds generated
+// tokens.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// PromptTokensDetails is nil unless the caller knows how many prompt
+	// tokens were served from the KV-cache.
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails mirrors OpenAI's `usage.prompt_tokens_details`.
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// PromptUsage computes the OpenAI-compatible usage of a rendered prompt from
+// its token IDs. The response must come from a render with ReturnTokenIDs set.
+func PromptUsage(resp *RenderJinjaTemplateResponse) (Usage, error) {
+	if resp == nil {
+		return Usage{}, fmt.Errorf("received nil response")
+	}
+	if resp.TokenIDs == nil {
+		return Usage{}, fmt.Errorf("response has no token IDs, render with ReturnTokenIDs set")
+	}
+
+	return Usage{
+		PromptTokens: len(resp.TokenIDs),
+		TotalTokens:  len(resp.TokenIDs),
+	}, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPromptUsage tests that the prompt usage is computed from the rendered token IDs.
+func TestPromptUsage(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	testModelPath := "../../tokenization/testdata/test-model"
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       testModelPath,
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{
+			{Role: "user", Content: "How many tokens is this?"},
+		},
+		ChatTemplate:       template,
+		ChatTemplateKWArgs: templateVars,
+		ReturnTokenIDs:     true,
+		Tokenizer: &preprocessing.TokenizerSource{
+			Model:       testModelPath,
+			IsLocalPath: true,
+		},
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	require.NotEmpty(t, response.TokenIDs, "Token IDs should be returned")
+
+	usage, err := preprocessing.PromptUsage(response)
+	require.NoError(t, err, "PromptUsage should not return an error")
+	assert.Equal(t, len(response.TokenIDs), usage.PromptTokens, "Prompt tokens should equal the number of token IDs")
+	assert.Equal(t, usage.PromptTokens, usage.TotalTokens, "Total tokens should equal prompt tokens")
+	assert.Zero(t, usage.CompletionTokens, "Completion tokens should be zero")
+
+	// Responses rendered without token IDs cannot be accounted.
+	_, err = preprocessing.PromptUsage(&preprocessing.RenderJinjaTemplateResponse{RenderedChats: []string{"text"}})
+	assert.Error(t, err, "PromptUsage should fail without token IDs")
+}
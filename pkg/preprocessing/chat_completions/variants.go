@@ -0,0 +1,26 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+// Keys of RenderJinjaTemplateResponse.Variants.
+const (
+	// VariantWithGenerationPrompt is the conversation rendered with the generation prompt.
+	VariantWithGenerationPrompt = "with_generation_prompt"
+	// VariantWithoutGenerationPrompt is the conversation rendered without the
+	// generation prompt, the stable prefix shared by later turns.
+	VariantWithoutGenerationPrompt = "without_generation_prompt"
+)
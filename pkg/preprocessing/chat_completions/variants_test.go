@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderVariants tests that both variants are returned and differ only by the generation prompt suffix.
+func TestRenderVariants(t *testing.T) {
+	wrapper := getGlobalWrapper()
+
+	template, templateVars, err := wrapper.FetchChatTemplate(context.Background(), preprocessing.FetchChatTemplateRequest{
+		Model:       "../../tokenization/testdata/test-model",
+		IsLocalPath: true,
+	})
+	require.NoError(t, err, "FetchChatTemplate should not return an error")
+
+	for _, addGenerationPrompt := range []bool{true, false} {
+		response, err := wrapper.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+			Conversations: []preprocessing.ChatMessage{
+				{Role: "user", Content: "Hello"},
+				{Role: "assistant", Content: "Hi there"},
+				{Role: "user", Content: "How are you?"},
+			},
+			ChatTemplate:        template,
+			ChatTemplateKWArgs:  templateVars,
+			AddGenerationPrompt: addGenerationPrompt,
+			RenderVariants:      true,
+		})
+		require.NoError(t, err, "RenderChatTemplate should not return an error")
+		require.Len(t, response.Variants, 2, "Both variants should be returned")
+
+		with := response.Variants[preprocessing.VariantWithGenerationPrompt]
+		without := response.Variants[preprocessing.VariantWithoutGenerationPrompt]
+		assert.NotEqual(t, with, without, "Variants should differ")
+		assert.True(t, strings.HasPrefix(with, without),
+			"The variant without generation prompt should be a prefix of the one with it")
+
+		// The primary rendering follows the request's AddGenerationPrompt.
+		if addGenerationPrompt {
+			assert.Equal(t, with, response.RenderedChats[0])
+		} else {
+			assert.Equal(t, without, response.RenderedChats[0])
+		}
+	}
+}
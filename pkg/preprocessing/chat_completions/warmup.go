@@ -0,0 +1,136 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/llm-d/llm-d-kv-cache/pkg/utils/logging"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultWarmupConcurrency is the number of templates Warmup fetches at
+	// once unless set with WithWarmupConcurrency.
+	DefaultWarmupConcurrency = 4
+
+	// warmupMaxAttempts bounds the fetches of a template rate limited by the Hub.
+	warmupMaxAttempts = 4
+	// warmupRetryBackoff is the wait before the first retry of a rate limited
+	// fetch, doubled before every further retry.
+	warmupRetryBackoff = 250 * time.Millisecond
+)
+
+// warmupFetcher fetches a template during Warmup.
+type warmupFetcher func(ctx context.Context, req *FetchChatTemplateRequest) error
+
+// Warmup fetches the chat templates of reqs ahead of the first renders, so
+// they are served from cache. At most the number of templates set with
+// WithWarmupConcurrency are fetched at once, starting no faster than the
+// rate set with WithWarmupRateLimit, and fetches rate limited by the Hub are
+// retried with backoff. It returns the joined errors of the templates that
+// could not be fetched; the others are cached regardless.
+func (w *ChatTemplatingProcessor) Warmup(ctx context.Context, reqs []FetchChatTemplateRequest) error {
+	traceLogger := log.FromContext(ctx).V(logging.TRACE).WithName("Warmup")
+	fetch := w.warmupFetcher()
+
+	errs := make([]error, len(reqs))
+	slots := make(chan struct{}, max(w.warmupConcurrency, 1))
+	var wg sync.WaitGroup
+	for i := range reqs {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			wg.Wait()
+			return errors.Join(errs...)
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := w.warmupOne(ctx, fetch, &reqs[i]); err != nil {
+				traceLogger.Error(err, "Failed to warm up template", "model", reqs[i].Model)
+				errs[i] = fmt.Errorf("failed to warm up %s: %w", reqs[i].Model, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// warmupFetcher returns the function fetching templates during Warmup.
+func (w *ChatTemplatingProcessor) warmupFetcher() warmupFetcher {
+	if w.warmupFetch != nil {
+		return w.warmupFetch
+	}
+	return func(ctx context.Context, req *FetchChatTemplateRequest) error {
+		if _, err := w.FetchChatTemplateDetails(ctx, *req, FetchOptions{}); err != nil {
+			return err
+		}
+		if !w.warmupTokenizers {
+			return nil
+		}
+		return w.preloadTokenizer(ctx, &TokenizerSource{
+			Model:       req.Model,
+			Revision:    w.revisionOrDefault(req.Revision, req.IsLocalPath),
+			Token:       req.Token,
+			IsLocalPath: req.IsLocalPath,
+		})
+	}
+}
+
+// warmupOne fetches the template of req, retrying while the Hub rate limits it.
+func (w *ChatTemplatingProcessor) warmupOne(ctx context.Context, fetch warmupFetcher,
+	req *FetchChatTemplateRequest,
+) error {
+	backoff := warmupRetryBackoff
+	for attempt := 1; ; attempt++ {
+		if w.warmupLimiter != nil {
+			if err := w.warmupLimiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		err := fetch(ctx, req)
+		if !errors.Is(err, ErrRateLimited) || attempt == warmupMaxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// newWarmupLimiter returns a limiter starting perSecond fetches a second, or
+// nil if perSecond is not positive.
+func newWarmupLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), 1)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWarmupConcurrency tests that Warmup fetches no more templates at once than configured.
+func TestWarmupConcurrency(t *testing.T) {
+	const concurrency = 3
+	wrapper := preprocessing.NewChatTemplatingProcessor(preprocessing.WithWarmupConcurrency(concurrency))
+
+	var inFlight, peak atomic.Int32
+	var mu sync.Mutex
+	fetched := make(map[string]bool)
+	preprocessing.SetWarmupFetcher(wrapper, func(_ context.Context, req *preprocessing.FetchChatTemplateRequest) error {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := peak.Load()
+			if current <= highest || peak.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		fetched[req.Model] = true
+		return nil
+	})
+
+	reqs := make([]preprocessing.FetchChatTemplateRequest, 20)
+	for i := range reqs {
+		reqs[i].Model = fmt.Sprintf("org/model-%d", i)
+	}
+	require.NoError(t, wrapper.Warmup(context.Background(), reqs))
+
+	assert.Len(t, fetched, len(reqs), "Every template should be fetched")
+	assert.LessOrEqual(t, peak.Load(), int32(concurrency), "No more fetches than the concurrency should run at once")
+	assert.Equal(t, int32(concurrency), peak.Load(), "Fetches should run concurrently up to the bound")
+}
+
+// TestWarmupRetriesRateLimited tests that Warmup retries fetches rate limited by the Hub, and only those.
+func TestWarmupRetriesRateLimited(t *testing.T) {
+	wrapper := preprocessing.NewChatTemplatingProcessor()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	preprocessing.SetWarmupFetcher(wrapper, func(_ context.Context, req *preprocessing.FetchChatTemplateRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[req.Model]++
+		switch {
+		case req.Model == "org/throttled" && attempts[req.Model] == 1:
+			return fmt.Errorf("%w: %s: 429", preprocessing.ErrRateLimited, req.Model)
+		case req.Model == "org/missing":
+			return fmt.Errorf("%w: %s", preprocessing.ErrModelNotFound, req.Model)
+		default:
+			return nil
+		}
+	})
+
+	err := wrapper.Warmup(context.Background(), []preprocessing.FetchChatTemplateRequest{
+		{Model: "org/throttled"},
+		{Model: "org/missing"},
+	})
+	require.ErrorIs(t, err, preprocessing.ErrModelNotFound, "Templates that cannot be fetched should be reported")
+	assert.NotErrorIs(t, err, preprocessing.ErrRateLimited, "The rate limited fetch should succeed when retried")
+	assert.Equal(t, 2, attempts["org/throttled"], "A rate limited fetch should be retried")
+	assert.Equal(t, 1, attempts["org/missing"], "Other failures should not be retried")
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderChatTemplateWarnings tests that a deprecation warning raised while rendering is reported.
+func TestRenderChatTemplateWarnings(t *testing.T) {
+	getGlobalWrapper()
+
+	processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithCustomFilters())
+	require.NoError(t, processor.RegisterJinjaFilter("legacy_upper",
+		`lambda s: warnings.warn("legacy_upper is deprecated", DeprecationWarning) or s.upper()`))
+	require.NoError(t, processor.Initialize())
+	t.Cleanup(processor.Finalize)
+
+	response, err := processor.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "hello"}, {Role: "user", Content: "again"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content | legacy_upper }}{% endfor %}",
+	})
+	require.NoError(t, err, "Warnings should not fail the render")
+	assert.Equal(t, "HELLOAGAIN", response.RenderedChats[0])
+	assert.Equal(t, []string{"DeprecationWarning: legacy_upper is deprecated"}, response.Warnings,
+		"The warning should be reported once")
+
+	response, err = processor.RenderChatTemplate(context.Background(), &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "hello"}},
+		ChatTemplate:  "{% for message in messages %}{{ message.content }}{% endfor %}",
+	})
+	require.NoError(t, err, "RenderChatTemplate should not return an error")
+	assert.Empty(t, response.Warnings, "Renders without warnings should report none")
+}
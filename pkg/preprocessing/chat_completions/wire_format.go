@@ -0,0 +1,90 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// WireFormat is the encoding of render requests and responses across the
+// CGO boundary. Both sides of the boundary select the matching Python entry
+// point, so the format only needs to be configured on the Go side.
+type WireFormat int
+
+const (
+	// WireFormatJSON encodes requests and responses as JSON strings.
+	WireFormatJSON WireFormat = iota
+	// WireFormatMsgpack encodes requests and responses as length-prefixed
+	// msgpack buffers, which are cheaper to produce and parse for large
+	// conversations. Requires the `msgpack` Python package.
+	WireFormatMsgpack
+)
+
+// String returns the name of the wire format.
+func (f WireFormat) String() string {
+	switch f {
+	case WireFormatJSON:
+		return "json"
+	case WireFormatMsgpack:
+		return "msgpack"
+	default:
+		return "unknown"
+	}
+}
+
+// renderRequestWire is a RenderJinjaTemplateRequest as sent to Python, with
+// the Go-only fields Python needs converted to values both wire formats
+// encode alike: msgpack would otherwise send times in UTC.
+type renderRequestWire struct {
+	*RenderJinjaTemplateRequest
+	FixedDateTime string `json:"fixed_date_time,omitempty"`
+}
+
+// newRenderRequestWire wraps req for sending to Python.
+func newRenderRequestWire(req *RenderJinjaTemplateRequest) *renderRequestWire {
+	wire := &renderRequestWire{RenderJinjaTemplateRequest: req}
+	if req.FixedDateTime != nil {
+		wire.FixedDateTime = req.FixedDateTime.Format(time.RFC3339)
+	}
+	return wire
+}
+
+// marshalMsgpack encodes v as msgpack, reusing the `json` struct tags so the
+// Python side sees the same field names as with JSON. Map keys are sorted,
+// as encoding/json does, so templates iterating over maps (e.g. tool
+// parameters) render byte-identical output for identical input.
+func marshalMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes msgpack data into v, using the `json` struct tags.
+func unmarshalMsgpack(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
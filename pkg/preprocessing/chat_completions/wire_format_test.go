@@ -0,0 +1,146 @@
+/*
+Copyright 2025 The llm-d Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preprocessing_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	preprocessing "github.com/llm-d/llm-d-kv-cache/pkg/preprocessing/chat_completions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const wireFormatTestTemplate = `{% for message in messages %}{{ message.role }}: {{ message.content }}
+{% endfor %}`
+
+// largeRenderRequest builds a request with many long messages.
+func largeRenderRequest(messages int) *preprocessing.RenderJinjaTemplateRequest {
+	conversation := make([]preprocessing.ChatMessage, 0, messages)
+	for i := 0; i < messages; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		conversation = append(conversation, preprocessing.ChatMessage{
+			Role:    role,
+			Content: fmt.Sprintf("Message %d: %s", i, strings.Repeat("lorem ipsum dolor sit amet ", 40)),
+		})
+	}
+
+	return &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: conversation,
+		ChatTemplate:  wireFormatTestTemplate,
+		ChatTemplateKWArgs: map[string]interface{}{
+			"bos_token": "<s>",
+			"eos_token": "</s>",
+		},
+	}
+}
+
+// TestRenderChatTemplateMsgpack tests that the msgpack wire format renders the same output as JSON.
+func TestRenderChatTemplateMsgpack(t *testing.T) {
+	getGlobalWrapper()
+
+	jsonProcessor := preprocessing.NewChatTemplatingProcessor()
+	require.NoError(t, jsonProcessor.Initialize())
+	t.Cleanup(jsonProcessor.Finalize)
+	msgpackProcessor := preprocessing.NewChatTemplatingProcessor(
+		preprocessing.WithWireFormat(preprocessing.WireFormatMsgpack))
+	require.NoError(t, msgpackProcessor.Initialize())
+	t.Cleanup(msgpackProcessor.Finalize)
+
+	request := largeRenderRequest(8)
+
+	jsonResponse, err := jsonProcessor.RenderChatTemplate(context.Background(), request)
+	require.NoError(t, err, "JSON render should not return an error")
+	msgpackResponse, err := msgpackProcessor.RenderChatTemplate(context.Background(), request)
+	require.NoError(t, err, "msgpack render should not return an error")
+
+	assert.Equal(t, jsonResponse.RenderedChats, msgpackResponse.RenderedChats,
+		"Both wire formats should render the same output")
+	assert.Equal(t, jsonResponse.GenerationIndices, msgpackResponse.GenerationIndices,
+		"Both wire formats should return the same generation indices")
+}
+
+// TestRenderChatTemplateDeterministic tests that renders iterating over maps are byte-stable in both wire formats.
+func TestRenderChatTemplateDeterministic(t *testing.T) {
+	getGlobalWrapper()
+
+	properties := make(map[string]interface{})
+	kwargs := make(map[string]interface{})
+	for i := range 32 {
+		properties[fmt.Sprintf("param_%02d", i)] = map[string]interface{}{"type": "string"}
+		kwargs[fmt.Sprintf("extra_%02d", i)] = i
+	}
+	request := &preprocessing.RenderJinjaTemplateRequest{
+		Conversations: []preprocessing.ChatMessage{{Role: "user", Content: "Call the tool"}},
+		Tools: []interface{}{map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":       "lookup",
+				"parameters": map[string]interface{}{"type": "object", "properties": properties},
+			},
+		}},
+		ChatTemplate: `{% for name in tools[0].function.parameters.properties %}{{ name }},{% endfor %}
+{% for message in messages %}{{ message.role }}: {{ message.content }}
+{% endfor %}`,
+		ChatTemplateKWArgs: kwargs,
+	}
+
+	for _, format := range []preprocessing.WireFormat{preprocessing.WireFormatJSON, preprocessing.WireFormatMsgpack} {
+		t.Run(format.String(), func(t *testing.T) {
+			processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithWireFormat(format))
+			require.NoError(t, processor.Initialize())
+			t.Cleanup(processor.Finalize)
+
+			var first string
+			for i := range 10 {
+				response, err := processor.RenderChatTemplate(context.Background(), request)
+				require.NoError(t, err, "RenderChatTemplate should not return an error")
+				if i == 0 {
+					first = response.RenderedChats[0]
+					continue
+				}
+				assert.Equal(t, []byte(first), []byte(response.RenderedChats[0]), "Renders should be byte-identical")
+			}
+		})
+	}
+}
+
+// BenchmarkRenderChatTemplateWireFormat compares the JSON and msgpack wire formats on a large request.
+func BenchmarkRenderChatTemplateWireFormat(b *testing.B) {
+	getGlobalWrapper()
+
+	request := largeRenderRequest(256)
+	for _, format := range []preprocessing.WireFormat{preprocessing.WireFormatJSON, preprocessing.WireFormatMsgpack} {
+		b.Run(format.String(), func(b *testing.B) {
+			processor := preprocessing.NewChatTemplatingProcessor(preprocessing.WithWireFormat(format))
+			require.NoError(b, processor.Initialize())
+			b.Cleanup(processor.Finalize)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := processor.RenderChatTemplate(context.Background(), request)
+				require.NoError(b, err, "Benchmark should not return errors")
+			}
+		})
+	}
+}